@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+var cleanupConfirmed bool
+
+var cleanupOrphansCmd = &cobra.Command{
+	Use:   "cleanup-orphans",
+	Short: "Delete ownership TXT records whose records no longer exist",
+	Long: "List the ownership TXT records of --txt-owner-id in the filtered domain that have no record of a " +
+		"managed type at the same name, e.g. left behind by earlier releases. The records are only printed " +
+		"unless --yes is given.",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		provider, logger, err := newCLIProvider()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = logger.Sync() }()
+
+		orphans, err := provider.CleanupOrphanedOwnershipRecords(cmd.Context(), cleanupConfirmed)
+		if len(orphans) > 0 {
+			if werr := writeRecordsTable(cmd.OutOrStdout(), orphans); werr != nil {
+				return werr
+			}
+		}
+		if err != nil {
+			return err
+		}
+		return writeCleanupSummary(cmd.OutOrStdout(), len(orphans), cleanupConfirmed, dryRun)
+	},
+}
+
+// writeCleanupSummary tells whether the orphaned records were deleted
+func writeCleanupSummary(w io.Writer, count int, confirmed, dryRun bool) error {
+	var err error
+	switch {
+	case count == 0:
+		_, err = fmt.Fprintln(w, "No orphaned ownership records found")
+	case !confirmed:
+		_, err = fmt.Fprintf(w, "Found %d orphaned ownership records, run with --yes to delete them\n", count)
+	case dryRun:
+		_, err = fmt.Fprintf(w, "Found %d orphaned ownership records, not deleted because dry-run is enabled\n", count)
+	default:
+		_, err = fmt.Fprintf(w, "Deleted %d orphaned ownership records\n", count)
+	}
+	return err
+}
+
+func init() {
+	cleanupOrphansCmd.Flags().BoolVar(&cleanupConfirmed, "yes", false, "Delete the orphaned records instead of only printing them")
+	rootCmd.AddCommand(cleanupOrphansCmd)
+}
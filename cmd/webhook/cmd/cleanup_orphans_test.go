@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCleanupSummary(t *testing.T) {
+	tests := []struct {
+		count     int
+		confirmed bool
+		dryRun    bool
+		want      string
+	}{
+		{count: 0, confirmed: true, want: "No orphaned ownership records found\n"},
+		{count: 3, want: "Found 3 orphaned ownership records, run with --yes to delete them\n"},
+		{count: 3, confirmed: true, dryRun: true, want: "Found 3 orphaned ownership records, not deleted because dry-run is enabled\n"},
+		{count: 3, confirmed: true, want: "Deleted 3 orphaned ownership records\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		require.NoError(t, writeCleanupSummary(&out, tt.count, tt.confirmed, tt.dryRun))
+		assert.Equal(t, tt.want, out.String())
+	}
+}
@@ -0,0 +1,392 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+
+	"github.com/netguru/myra-external-dns-webhook/internal/myrasecprovider"
+	"github.com/netguru/myra-external-dns-webhook/pkg/redact"
+)
+
+// flagEnvVars lists the environment variables of a flag besides WEBHOOK_<FLAG>, see configEnvVars.
+// WEBHOOK_LISTEN_ADDRESS_PORT is handled by initConfig, since it only holds the port.
+var flagEnvVars = map[string][]string{
+	"listen-address":              {"WEBHOOK_LISTEN_ADDRESS"},
+	"myrasec-api-key":             {"MYRASEC_API_KEY"},
+	"myrasec-api-secret":          {"MYRASEC_API_SECRET"},
+	"myrasec-api-key-file":        {"MYRASEC_API_KEY_FILE"},
+	"myrasec-api-secret-file":     {"MYRASEC_API_SECRET_FILE"},
+	"credentials-reload-interval": {"CREDENTIALS_RELOAD_INTERVAL"},
+	"base-url":                    {"BASE_URL"},
+	"myrasec-api-proxy":           {"MYRASEC_API_PROXY"},
+	"myrasec-api-ca-bundle":       {"MYRASEC_API_CA_BUNDLE"},
+	"user-agent-include-owner":    {"USER_AGENT_INCLUDE_OWNER"},
+	"myrasec-api-language":        {"MYRASEC_API_LANGUAGE"},
+	"dry-run":                     {"DRY_RUN"},
+	"dry-run-output":              {"DRY_RUN_OUTPUT"},
+	"dry-run-output-file":         {"DRY_RUN_OUTPUT_FILE"},
+	"continue-on-error":           {"CONTINUE_ON_ERROR"},
+	"disable-protection":          {"DISABLE_PROTECTION"},
+	"protection-per-type":         {"PROTECTION_PER_TYPE"},
+	"log-level":                   {"LOG_LEVEL"},
+	"log-format":                  {"LOG_FORMAT"},
+	"env":                         {"ENV"},
+	"domain-filter":               {"DOMAIN_FILTER"},
+	"exclude-domains":             {"EXCLUDE_DOMAINS"},
+	"domain-id":                   {"DOMAIN_ID"},
+	"domain-name":                 {"DOMAIN_NAME"},
+	"managed-record-types":        {"MANAGED_RECORD_TYPES"},
+	"adopt-existing-records":      {"ADOPT_EXISTING_RECORDS"},
+	"reclaim-missing-ownership":   {"RECLAIM_MISSING_OWNERSHIP"},
+	"strict-owner":                {"STRICT_OWNER"},
+	"delete-on-empty-targets":     {"DELETE_ON_EMPTY_TARGETS"},
+	"orphan-cleanup-interval":     {"ORPHAN_CLEANUP_INTERVAL"},
+	"wait-for-record-active":      {"WAIT_FOR_RECORD_ACTIVE"},
+	"apply-timeout":               {"APPLY_TIMEOUT"},
+	"max-deletions-per-sync":      {"MAX_DELETIONS_PER_SYNC"},
+	"max-changes-per-sync":        {"MAX_CHANGES_PER_SYNC"},
+	"concurrent-apply":            {"CONCURRENT_APPLY"},
+	"invalid-endpoints":           {"INVALID_ENDPOINTS"},
+	"notify-url":                  {"NOTIFY_URL"},
+	"notify-token":                {"NOTIFY_TOKEN"},
+	"notify-timeout":              {"NOTIFY_TIMEOUT"},
+	"ttl":                         {"TTL"},
+	"min-ttl":                     {"MIN_TTL"},
+	"max-ttl":                     {"MAX_TTL"},
+	"ttl-per-type":                {"TTL_PER_TYPE"},
+	"ttl-enforcement":             {"TTL_ENFORCEMENT"},
+	"validate-on-start":           {"VALIDATE_ON_START"},
+	"shutdown-timeout":            {"SHUTDOWN_TIMEOUT"},
+	"domain-cache-ttl":            {"DOMAIN_CACHE_TTL"},
+	"healthy-threshold":           {"HEALTHY_THRESHOLD"},
+}
+
+// secretFlags are never logged in clear text
+var secretFlags = map[string]bool{
+	"myrasec-api-key":    true,
+	"myrasec-api-secret": true,
+	"notify-token":       true,
+}
+
+// loadConfigFile reads the YAML config file into viper. Its keys are the flag names.
+// Unknown keys are reported with a warning.
+func loadConfigFile(flags *pflag.FlagSet, path string) error {
+	viper.SetConfigFile(path)
+	viper.SetConfigType("yaml")
+	if err := viper.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	for _, key := range viper.AllKeys() {
+		if flags.Lookup(key) == nil && !strings.HasPrefix(key, accountsKey) {
+			log.Printf("Warning: Unknown key %q in config file %s", key, path)
+		}
+	}
+	return nil
+}
+
+// bindConfig binds every flag to viper together with its environment variables, so viper
+// resolves each option from the command line, the environment and the config file.
+func bindConfig(flags *pflag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err != nil {
+			return
+		}
+		if err = viper.BindPFlag(f.Name, f); err != nil {
+			return
+		}
+		err = viper.BindEnv(append([]string{f.Name}, configEnvVars(f.Name)...)...)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to bind the configuration: %w", err)
+	}
+	return nil
+}
+
+// configEnvVars returns the environment variables of a flag in the order they are looked up:
+// those of flagEnvVars, then WEBHOOK_<FLAG>, e.g. WEBHOOK_DRY_RUN for --dry-run.
+func configEnvVars(name string) []string {
+	prefixed := "WEBHOOK_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+	envs := append([]string(nil), flagEnvVars[name]...)
+	for _, env := range envs {
+		if env == prefixed {
+			return envs
+		}
+	}
+	return append(envs, prefixed)
+}
+
+// applyConfigValues sets the flags that were not given on the command line from viper, which
+// resolves them from the environment and the config file. The flags are not marked as changed,
+// so viper keeps telling the command line apart from the other sources. Values that cannot be
+// parsed or are out of range are returned as one error, so they can be fixed together.
+func applyConfigValues(flags *pflag.FlagSet) error {
+	var errs []error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Changed || f.Name == "config" || !viper.IsSet(f.Name) {
+			return
+		}
+		value := flagValueString(viper.Get(f.Name))
+		if err := setFlagValue(f, value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q: %w", f.Name, value, err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// setFlagValue sets the flag to value if it is valid. Lists replace the default instead of
+// being appended to it.
+func setFlagValue(f *pflag.Flag, value string) error {
+	if err := checkConfigValue(f.Name, value); err != nil {
+		return err
+	}
+	if slice, ok := f.Value.(pflag.SliceValue); ok {
+		values, err := csv.NewReader(strings.NewReader(value)).Read()
+		if err != nil && value != "" {
+			return err
+		}
+		return slice.Replace(values)
+	}
+	return f.Value.Set(value)
+}
+
+// positiveFlags and nonNegativeFlags are the numbers and durations whose values from the
+// environment or the config file are range checked
+var (
+	positiveFlags    = []string{"min-ttl", "max-ttl", "domain-id", "notify-timeout", "shutdown-timeout", "domain-cache-ttl"}
+	nonNegativeFlags = []string{"ttl", "credentials-reload-interval", "orphan-cleanup-interval", "wait-for-record-active", "apply-timeout", "max-deletions-per-sync", "max-changes-per-sync", "healthy-threshold"}
+)
+
+// checkConfigValue checks that value is in the range of the flag, if it has one
+func checkConfigValue(name, value string) error {
+	positive, nonNegative := slices.Contains(positiveFlags, name), slices.Contains(nonNegativeFlags, name)
+	if !positive && !nonNegative {
+		return nil
+	}
+
+	var number float64
+	if d, err := time.ParseDuration(value); err == nil {
+		number = float64(d)
+	} else if n, err := strconv.Atoi(value); err == nil {
+		number = float64(n)
+	} else {
+		return fmt.Errorf("not a number or duration")
+	}
+
+	if positive && number <= 0 {
+		return fmt.Errorf("must be positive")
+	}
+	if number < 0 {
+		return fmt.Errorf("must not be negative")
+	}
+	return nil
+}
+
+// applySecretFiles reads the credentials from the configured files. A credentials file takes
+// precedence over the credential given directly.
+func applySecretFiles() error {
+	if apiKeyFile != "" {
+		value, err := readSecretFile(apiKeyFile)
+		if err != nil {
+			return fmt.Errorf("invalid MyraSec API key file: %w", err)
+		}
+		if myraSecAPIKey != "" {
+			log.Printf("Warning: Both an API key and an API key file are configured, using %s", apiKeyFile)
+		}
+		myraSecAPIKey = value
+	}
+	if apiSecretFile != "" {
+		value, err := readSecretFile(apiSecretFile)
+		if err != nil {
+			return fmt.Errorf("invalid MyraSec API secret file: %w", err)
+		}
+		if myraSecAPISecret != "" {
+			log.Printf("Warning: Both an API secret and an API secret file are configured, using %s", apiSecretFile)
+		}
+		myraSecAPISecret = value
+	}
+	return nil
+}
+
+// proxyPassword returns the password in the userinfo of a proxy URL, which is masked in the logs
+func proxyPassword(proxyURL string) string {
+	parsed, err := url.Parse(proxyURL)
+	if err != nil || parsed.User == nil {
+		return ""
+	}
+	password, _ := parsed.User.Password()
+	return password
+}
+
+// accountsKey is the list of MyraSec accounts in the config file, which has no flag
+const accountsKey = "accounts"
+
+// accountConfig is an entry of the accounts of the config file. The credentials may be read from
+// files instead, which take precedence like MYRASEC_API_KEY_FILE and MYRASEC_API_SECRET_FILE.
+type accountConfig struct {
+	APIKey        string   `mapstructure:"apiKey"`
+	APISecret     string   `mapstructure:"apiSecret"`
+	APIKeyFile    string   `mapstructure:"apiKeyFile"`
+	APISecretFile string   `mapstructure:"apiSecretFile"`
+	Domains       []string `mapstructure:"domains"`
+}
+
+// loadAccounts reads the MyraSec accounts from the config file
+func loadAccounts() error {
+	accounts = nil
+	var entries []accountConfig
+	if err := viper.UnmarshalKey(accountsKey, &entries); err != nil {
+		return fmt.Errorf("invalid accounts in the config file: %w", err)
+	}
+	for i, entry := range entries {
+		account := myrasecprovider.Account{APIKey: entry.APIKey, APISecret: entry.APISecret, Domains: entry.Domains}
+		var err error
+		if entry.APIKeyFile != "" {
+			if account.APIKey, err = readSecretFile(entry.APIKeyFile); err != nil {
+				return fmt.Errorf("invalid API key file of account %d: %w", i+1, err)
+			}
+		}
+		if entry.APISecretFile != "" {
+			if account.APISecret, err = readSecretFile(entry.APISecretFile); err != nil {
+				return fmt.Errorf("invalid API secret file of account %d: %w", i+1, err)
+			}
+		}
+		accounts = append(accounts, account)
+	}
+	if len(accounts) > 0 {
+		log.Printf("Loaded %d MyraSec accounts", len(accounts))
+	}
+	return nil
+}
+
+// accountSecrets returns the credentials of the accounts, which are masked in the logs
+func accountSecrets() []string {
+	var secrets []string
+	for _, account := range accounts {
+		secrets = append(secrets, account.APIKey, account.APISecret)
+	}
+	return secrets
+}
+
+// domainFilters returns the domain filter together with the domains of the accounts, which the
+// provider manages as well
+func domainFilters() []string {
+	filters := append([]string(nil), domainFilter...)
+	for _, account := range accounts {
+		filters = append(filters, account.Domains...)
+	}
+	return filters
+}
+
+// readSecretFile returns the content of a credentials file without surrounding whitespace.
+// A missing or empty file is an error.
+func readSecretFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file %s: %w", path, err)
+	}
+	value := strings.TrimSpace(string(content))
+	if value == "" {
+		return "", fmt.Errorf("secret file %s is empty", path)
+	}
+	return value, nil
+}
+
+// flagValueString converts a value from viper into the string form accepted by pflag.
+// YAML lists become comma separated values.
+func flagValueString(value interface{}) string {
+	switch v := value.(type) {
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, fmt.Sprint(item))
+		}
+		return strings.Join(items, ",")
+	case []string:
+		return strings.Join(v, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// providerConfig builds the provider configuration from the effective settings
+func providerConfig() myrasecprovider.Config {
+	return myrasecprovider.Config{
+		APIKey:               myraSecAPIKey,
+		APISecret:            myraSecAPISecret,
+		BaseURL:              baseURL,
+		Language:             apiLanguage,
+		DomainFilter:         endpoint.DomainFilter{Filters: domainFilter},
+		ExcludeDomains:       excludeDomains,
+		Accounts:             accounts,
+		DomainID:             pinnedDomainID,
+		DomainName:           pinnedDomainName,
+		DryRun:               dryRun,
+		TTL:                  ttl,
+		MinTTL:               minTTL,
+		MaxTTL:               maxTTL,
+		TTLPerType:           ttlPerType,
+		TTLEnforcement:       ttlEnforcement,
+		Owner:                owner,
+		Workers:              workers,
+		ContinueOnError:      continueOnError,
+		ManagedRecordTypes:   recordTypes,
+		AdoptExistingRecords: adoptExisting,
+		DomainCacheTTL:       domainCacheTTL,
+		DisableProtection:    disableProtection,
+		ProtectionPerType:    protectionPerType,
+		ReclaimOwnership:     reclaimOwnership,
+		StrictOwner:          strictOwner,
+		DeleteOnEmptyTargets: deleteOnEmpty,
+		MaxDeletionsPerSync:  maxDeletions,
+		MaxChangesPerSync:    maxChanges,
+		NotifyURL:            notifyURL,
+		NotifyToken:          notifyToken,
+		NotifyTimeout:        notifyTimeout,
+		ConcurrentApply:      concurrentApply,
+		InvalidEndpoints:     invalidEndpoints,
+		APIProxy:             apiProxy,
+		APICABundle:          apiCABundle,
+		UserAgentOwner:       userAgentOwner,
+		WaitForRecordActive:  waitRecordActive,
+		ApplyTimeout:         applyTimeout,
+		DryRunOutput:         dryRunOutput,
+		DryRunOutputFile:     dryRunOutputFile,
+		Environment:          environment,
+	}
+}
+
+// logEffectiveConfig logs the value of every flag, with the secrets redacted
+func logEffectiveConfig(logger *zap.Logger, flags *pflag.FlagSet) {
+	logger.Info("Effective configuration", effectiveConfig(flags)...)
+}
+
+// effectiveConfig returns a field with the value of every flag, with the secrets redacted
+func effectiveConfig(flags *pflag.FlagSet) []zap.Field {
+	var fields []zap.Field
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Name == "help" {
+			return
+		}
+		value := f.Value.String()
+		if secretFlags[f.Name] && value != "" {
+			value = redact.Mask
+		}
+		fields = append(fields, zap.String(f.Name, value))
+	})
+	return fields
+}
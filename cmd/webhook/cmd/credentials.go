@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/netguru/myra-external-dns-webhook/pkg/redact"
+)
+
+// credentialReloader is implemented by providers whose API credentials can be replaced at runtime
+type credentialReloader interface {
+	ReloadCredentials(apiKey, apiSecret string) error
+}
+
+// credentialsWatcher polls the credential files and reloads the provider credentials
+// when their content changes, so rotated keys are picked up without a restart.
+type credentialsWatcher struct {
+	logger     *zap.Logger
+	provider   credentialReloader
+	keyFile    string
+	secretFile string
+	apiKey     string
+	apiSecret  string
+	// pending holds changed credentials until they are read unchanged on the next check, so a
+	// check between the writes of a rotation does not pair the new key with the old secret
+	pending *credentialPair
+	// failed holds the credentials the provider refused, they are not retried until the files change
+	failed *credentialPair
+}
+
+// credentialPair is an API key together with its secret
+type credentialPair struct {
+	apiKey    string
+	apiSecret string
+}
+
+// run checks the credential files every interval until the context is done
+func (w *credentialsWatcher) run(ctx context.Context, interval time.Duration) {
+	w.logger.Info("Watching credential files for changes",
+		zap.String("key_file", w.keyFile),
+		zap.String("secret_file", w.secretFile),
+		zap.Duration("interval", interval))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+// check reloads the credentials if the content of the credential files changed and stayed the same
+// since the previous check
+func (w *credentialsWatcher) check() {
+	apiKey, apiSecret := w.apiKey, w.apiSecret
+	if w.keyFile != "" {
+		value, err := readSecretFile(w.keyFile)
+		if err != nil {
+			w.logger.Warn("Failed to read API key file, keeping current credentials", zap.Error(err))
+			return
+		}
+		apiKey = value
+	}
+	if w.secretFile != "" {
+		value, err := readSecretFile(w.secretFile)
+		if err != nil {
+			w.logger.Warn("Failed to read API secret file, keeping current credentials", zap.Error(err))
+			return
+		}
+		apiSecret = value
+	}
+
+	read := credentialPair{apiKey: apiKey, apiSecret: apiSecret}
+	if apiKey == w.apiKey && apiSecret == w.apiSecret {
+		w.pending, w.failed = nil, nil
+		return
+	}
+	redact.Add(apiKey, apiSecret)
+	if w.failed != nil && *w.failed == read {
+		return
+	}
+	if w.pending == nil || *w.pending != read {
+		w.pending = &read
+		w.logger.Debug("Credential files changed, reloading them once they are unchanged on the next check")
+		return
+	}
+	w.pending = nil
+
+	w.logger.Info("Credential files changed, reloading MyraSec API credentials",
+		zap.Bool("key_changed", apiKey != w.apiKey),
+		zap.Bool("secret_changed", apiSecret != w.apiSecret))
+	if err := w.provider.ReloadCredentials(apiKey, apiSecret); err != nil {
+		w.failed = &read
+		w.logger.Error("Failed to reload MyraSec API credentials, retrying once the files change again", zap.Error(err))
+		return
+	}
+	w.failed = nil
+	w.apiKey, w.apiSecret = apiKey, apiSecret
+}
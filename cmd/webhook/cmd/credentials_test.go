@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+
+	"github.com/netguru/myra-external-dns-webhook/internal/myrasecprovider"
+)
+
+// countingReloader records the credentials it is given and fails with err
+type countingReloader struct {
+	calls [][2]string
+	err   error
+}
+
+func (r *countingReloader) ReloadCredentials(apiKey, apiSecret string) error {
+	r.calls = append(r.calls, [2]string{apiKey, apiSecret})
+	return r.err
+}
+
+func TestCredentialsWatcherReloadsOnChange(t *testing.T) {
+	keyFile := writeFile(t, "api-key", "old-key")
+	secretFile := writeFile(t, "api-secret", "old-secret")
+	reloader := &countingReloader{}
+	watcher := &credentialsWatcher{
+		logger:     zap.NewNop(),
+		provider:   reloader,
+		keyFile:    keyFile,
+		secretFile: secretFile,
+		apiKey:     "old-key",
+		apiSecret:  "old-secret",
+	}
+
+	watcher.check()
+	assert.Empty(t, reloader.calls, "unchanged files must not reload")
+
+	require.NoError(t, os.WriteFile(secretFile, []byte(" \n"), 0o600))
+	watcher.check()
+	assert.Empty(t, reloader.calls, "an empty file keeps the current credentials")
+
+	require.NoError(t, os.WriteFile(keyFile, []byte("new-key\n"), 0o600))
+	require.NoError(t, os.WriteFile(secretFile, []byte("new-secret\n"), 0o600))
+	watcher.check()
+	watcher.check()
+	assert.Equal(t, [][2]string{{"new-key", "new-secret"}}, reloader.calls)
+}
+
+func TestCredentialsWatcherWaitsForTheRotation(t *testing.T) {
+	keyFile := writeFile(t, "api-key", "old-key")
+	secretFile := writeFile(t, "api-secret", "old-secret")
+	reloader := &countingReloader{}
+	watcher := &credentialsWatcher{
+		logger:     zap.NewNop(),
+		provider:   reloader,
+		keyFile:    keyFile,
+		secretFile: secretFile,
+		apiKey:     "old-key",
+		apiSecret:  "old-secret",
+	}
+
+	// The check lands between the writes of the key and the secret
+	require.NoError(t, os.WriteFile(keyFile, []byte("new-key"), 0o600))
+	watcher.check()
+	require.NoError(t, os.WriteFile(secretFile, []byte("new-secret"), 0o600))
+	watcher.check()
+	assert.Empty(t, reloader.calls, "the new key is never used with the old secret")
+
+	watcher.check()
+	assert.Equal(t, [][2]string{{"new-key", "new-secret"}}, reloader.calls)
+}
+
+func TestCredentialsWatcherDoesNotRetryFailedCredentials(t *testing.T) {
+	keyFile := writeFile(t, "api-key", "new-key")
+	reloader := &countingReloader{err: errors.New("the credentials of several accounts are configured")}
+	watcher := &credentialsWatcher{
+		logger:   zap.NewNop(),
+		provider: reloader,
+		keyFile:  keyFile,
+		apiKey:   "old-key",
+	}
+
+	for i := 0; i < 5; i++ {
+		watcher.check()
+	}
+	assert.Len(t, reloader.calls, 1, "failed credentials are not retried")
+
+	require.NoError(t, os.WriteFile(keyFile, []byte("newer-key"), 0o600))
+	watcher.check()
+	watcher.check()
+	assert.Len(t, reloader.calls, 2, "changed files are tried again")
+}
+
+func TestCredentialsWatcherRotatesProviderClient(t *testing.T) {
+	var mu sync.Mutex
+	var authorizations []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"error":false,"list":[{"id":42,"name":"example.com"}],"page":1,"count":1,"pageSize":50}`))
+	}))
+	t.Cleanup(server.Close)
+	lastAuthorization := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return authorizations[len(authorizations)-1]
+	}
+
+	keyFile := writeFile(t, "api-key", "old-key")
+	secretFile := writeFile(t, "api-secret", "old-secret")
+	provider, err := myrasecprovider.NewMyraSecDNSProvider(zap.NewNop(), myrasecprovider.Config{
+		APIKey:       "old-key",
+		APISecret:    "old-secret",
+		BaseURL:      server.URL,
+		DomainFilter: endpoint.NewDomainFilter([]string{"example.com"}),
+	})
+	require.NoError(t, err)
+
+	_, err = provider.Records(context.Background())
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(lastAuthorization(), "MYRA old-key:"), lastAuthorization())
+
+	watcher := &credentialsWatcher{
+		logger:     zap.NewNop(),
+		provider:   provider,
+		keyFile:    keyFile,
+		secretFile: secretFile,
+		apiKey:     "old-key",
+		apiSecret:  "old-secret",
+	}
+	require.NoError(t, os.WriteFile(keyFile, []byte("new-key"), 0o600))
+	require.NoError(t, os.WriteFile(secretFile, []byte("new-secret"), 0o600))
+	watcher.check()
+	watcher.check()
+
+	_, err = provider.Records(context.Background())
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(lastAuthorization(), "MYRA new-key:"), lastAuthorization())
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+
+	"github.com/netguru/myra-external-dns-webhook/internal/myrasecprovider"
+)
+
+// stateReporter is implemented by the provider, see myrasecprovider.State
+type stateReporter interface {
+	State() myrasecprovider.State
+}
+
+// dumpState logs the effective configuration, with the secrets redacted, and the state of the
+// provider at info level. It runs on SIGUSR1 to troubleshoot without restarting at debug level.
+func dumpState(logger *zap.Logger, flags *pflag.FlagSet, reporter stateReporter) {
+	logger.Info("State dump",
+		zap.Dict("config", effectiveConfig(flags)...),
+		zap.Any("state", reporter.State()))
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/netguru/myra-external-dns-webhook/internal/myrasecprovider"
+	"github.com/netguru/myra-external-dns-webhook/pkg/redact"
+)
+
+type fakeStateReporter struct {
+	state myrasecprovider.State
+}
+
+func (r fakeStateReporter) State() myrasecprovider.State {
+	return r.state
+}
+
+func TestDumpState(t *testing.T) {
+	resetConfig(t)
+	t.Cleanup(func() { resetConfig(t) })
+	require.NoError(t, rootCmd.PersistentFlags().Set("myrasec-api-secret", "super-secret-value"))
+
+	state := myrasecprovider.State{
+		Domains:               []myrasecprovider.StateDomain{{ID: 123, Name: "example.com"}},
+		DomainCacheAgeSeconds: 42,
+		Records:               7,
+		ApplyInProgress:       true,
+	}
+	core, logs := observer.New(zap.InfoLevel)
+	dumpState(zap.New(core), rootCmd.PersistentFlags(), fakeStateReporter{state: state})
+
+	require.Equal(t, 1, logs.Len())
+	entry := logs.All()[0]
+	assert.Equal(t, "State dump", entry.Message)
+	fields := entry.ContextMap()
+
+	config, ok := fields["config"].(map[string]interface{})
+	require.True(t, ok, "config is an object")
+	assert.Equal(t, redact.Mask, config["myrasec-api-secret"])
+	assert.Equal(t, "300", config["ttl"])
+	assert.Equal(t, state, fields["state"])
+}
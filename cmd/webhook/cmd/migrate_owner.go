@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateFromOwner string
+	migrateConfirmed bool
+)
+
+var migrateOwnerCmd = &cobra.Command{
+	Use:   "migrate-owner",
+	Short: "Rewrite ownership TXT records of a previous owner ID to --txt-owner-id",
+	Long: "List the ownership TXT records in the filtered domain whose heritage is external-dns and whose owner " +
+		"is exactly --from-owner, and rewrite their owner to --txt-owner-id, so that renaming the owner ID " +
+		"does not orphan the managed records. The records are only printed unless --yes is given.",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if migrateFromOwner == "" {
+			return fmt.Errorf("--from-owner is required")
+		}
+
+		provider, logger, err := newCLIProvider()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = logger.Sync() }()
+
+		records, err := provider.MigrateOwner(cmd.Context(), migrateFromOwner, migrateConfirmed)
+		if len(records) > 0 {
+			if werr := writeRecordsTable(cmd.OutOrStdout(), records); werr != nil {
+				return werr
+			}
+		}
+		if err != nil {
+			return err
+		}
+		return writeMigrateSummary(cmd.OutOrStdout(), len(records), migrateFromOwner, provider.Owner(), migrateConfirmed, dryRun)
+	},
+}
+
+// writeMigrateSummary tells whether the ownership records were rewritten
+func writeMigrateSummary(w io.Writer, count int, from, to string, confirmed, dryRun bool) error {
+	var err error
+	switch {
+	case count == 0:
+		_, err = fmt.Fprintf(w, "No ownership records of owner %q found\n", from)
+	case !confirmed:
+		_, err = fmt.Fprintf(w, "Found %d ownership records of owner %q, run with --yes to rewrite them to %q\n", count, from, to)
+	case dryRun:
+		_, err = fmt.Fprintf(w, "Found %d ownership records of owner %q, not rewritten because dry-run is enabled\n", count, from)
+	default:
+		_, err = fmt.Fprintf(w, "Rewrote %d ownership records from owner %q to %q\n", count, from, to)
+	}
+	return err
+}
+
+func init() {
+	migrateOwnerCmd.Flags().StringVar(&migrateFromOwner, "from-owner", "", "Owner ID to migrate the ownership records from")
+	migrateOwnerCmd.Flags().BoolVar(&migrateConfirmed, "yes", false, "Rewrite the records instead of only printing them")
+	rootCmd.AddCommand(migrateOwnerCmd)
+}
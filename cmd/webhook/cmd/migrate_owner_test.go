@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteMigrateSummary(t *testing.T) {
+	tests := []struct {
+		count     int
+		confirmed bool
+		dryRun    bool
+		want      string
+	}{
+		{count: 0, confirmed: true, want: "No ownership records of owner \"old\" found\n"},
+		{count: 3, want: "Found 3 ownership records of owner \"old\", run with --yes to rewrite them to \"new\"\n"},
+		{count: 3, confirmed: true, dryRun: true, want: "Found 3 ownership records of owner \"old\", not rewritten because dry-run is enabled\n"},
+		{count: 3, confirmed: true, want: "Rewrote 3 ownership records from owner \"old\" to \"new\"\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		require.NoError(t, writeMigrateSummary(&out, tt.count, "old", "new", tt.confirmed, tt.dryRun))
+		assert.Equal(t, tt.want, out.String())
+	}
+}
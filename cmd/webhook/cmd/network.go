@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/netguru/myra-external-dns-webhook/internal/myrasecprovider"
+	"github.com/netguru/myra-external-dns-webhook/pkg/api"
+)
+
+// networkProblems checks the listen address and the network settings of the provider, the MyraSec
+// API base URL, proxy and CA bundle and the notify URL, before anything is started. Every problem
+// found is returned, so a bad configuration is reported at once instead of one setting at a time
+// or as a bind error of the server.
+func networkProblems(listenAddress string, config myrasecprovider.Config) []string {
+	var problems []string
+	if _, err := api.ParseListenAddress(listenAddress); err != nil {
+		problems = append(problems, err.Error())
+	}
+	err := config.ValidateNetwork()
+	var joined interface{ Unwrap() []error }
+	if errors.As(err, &joined) {
+		for _, err := range joined.Unwrap() {
+			problems = append(problems, err.Error())
+		}
+	} else if err != nil {
+		problems = append(problems, err.Error())
+	}
+	return problems
+}
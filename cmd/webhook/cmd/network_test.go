@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/netguru/myra-external-dns-webhook/internal/myrasecprovider"
+)
+
+func TestNetworkProblems(t *testing.T) {
+	notPEM := writeFile(t, "ca.pem", "not a certificate")
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+
+	tests := []struct {
+		name          string
+		listenAddress string
+		config        myrasecprovider.Config
+		want          []string
+	}{
+		{name: "valid", listenAddress: ":8080", config: myrasecprovider.Config{
+			BaseURL: "https://apiv2.example.com/", APIProxy: "http://proxy.example.com:3128", NotifyURL: "https://hooks.example.com/dns",
+		}},
+		{name: "port with trailing colon", listenAddress: "8080:", want: []string{`invalid listen address "8080:"`}},
+		{name: "negative port", listenAddress: ":-1", want: []string{`invalid listen address ":-1"`}},
+		{name: "everything wrong", listenAddress: "localhost:99999", config: myrasecprovider.Config{
+			BaseURL:     "ftp://api.example.com",
+			APIProxy:    "proxy.example.com:3128",
+			APICABundle: notPEM,
+			NotifyURL:   "hooks.example.com",
+		}, want: []string{"invalid listen address", "invalid base URL", "invalid proxy URL", "contains no PEM certificates", "invalid notify URL"}},
+		{name: "missing CA bundle", listenAddress: "8080", config: myrasecprovider.Config{APICABundle: missing},
+			want: []string{"failed to read CA bundle"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := networkProblems(tt.listenAddress, tt.config)
+			assert.Len(t, problems, len(tt.want), "%q", problems)
+			for i := range tt.want {
+				if i < len(problems) {
+					assert.Contains(t, problems[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/netguru/myra-external-dns-webhook/internal/myrasecprovider"
+	"github.com/netguru/myra-external-dns-webhook/pkg/redact"
+)
+
+var recordsOutput string
+
+var recordsCmd = &cobra.Command{
+	Use:   "records",
+	Short: "Inspect the DNS records in MyraSec",
+}
+
+var recordsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the records of the filtered domains with their ownership",
+	Long: "List the records stored in MyraSec for the domains matching the domain filter, before any filtering " +
+		"by the webhook. Every record shows whether it is owned by this instance (owned), by another " +
+		"ExternalDNS instance (foreign) or has no ownership TXT record (none). No webhook server is started.",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if recordsOutput != "table" && recordsOutput != "json" {
+			return fmt.Errorf("unknown output format %q, expected table or json", recordsOutput)
+		}
+
+		provider, logger, err := newCLIProvider()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = logger.Sync() }()
+
+		records, err := provider.ZoneRecords(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		if recordsOutput == "json" {
+			return writeRecordsJSON(cmd.OutOrStdout(), records)
+		}
+		return writeRecordsTable(cmd.OutOrStdout(), records)
+	},
+}
+
+// newCLIProvider creates the provider for a CLI subcommand from the effective settings.
+// The logger writes to stderr so that it does not mix with the output of the command.
+func newCLIProvider() (*myrasecprovider.MyraSecDNSProvider, *zap.Logger, error) {
+	redact.Add(myraSecAPIKey, myraSecAPISecret, proxyPassword(apiProxy))
+	redact.Add(accountSecrets()...)
+
+	if err := requiredCredentials(); err != nil {
+		return nil, nil, err
+	}
+
+	logger, err := newLoggerTo(logLevel, logFormat, "stderr")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	provider, err := myrasecprovider.NewMyraSecDNSProvider(logger.With(zap.String("component", "myrasecprovider")), providerConfig())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize MyraSec provider: %w", err)
+	}
+	return provider, logger, nil
+}
+
+// writeRecordsTable writes the records as an aligned table
+func writeRecordsTable(w io.Writer, records []myrasecprovider.ZoneRecord) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DOMAIN\tNAME\tTYPE\tTTL\tENABLED\tMANAGED\tOWNERSHIP\tVALUE")
+	for _, r := range records {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n",
+			r.Domain, r.Name, r.Type, r.TTL, strconv.FormatBool(r.Enabled), strconv.FormatBool(r.Managed), r.Ownership, r.Value)
+	}
+	return tw.Flush()
+}
+
+// writeRecordsJSON writes the records as an indented JSON array
+func writeRecordsJSON(w io.Writer, records []myrasecprovider.ZoneRecord) error {
+	if records == nil {
+		records = []myrasecprovider.ZoneRecord{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
+
+func init() {
+	recordsListCmd.Flags().StringVarP(&recordsOutput, "output", "o", "table", "Output format (table, json)")
+	recordsCmd.AddCommand(recordsListCmd)
+	rootCmd.AddCommand(recordsCmd)
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/netguru/myra-external-dns-webhook/internal/myrasecprovider"
+)
+
+func TestWriteRecords(t *testing.T) {
+	records := []myrasecprovider.ZoneRecord{
+		{Domain: "example.com", Name: "app.example.com", Type: "A", Value: "1.2.3.4", TTL: 300, Enabled: true, Managed: true, Ownership: myrasecprovider.OwnershipOwned},
+		{Domain: "example.com", Name: "example.com", Type: "MX", Value: "mail.example.com", TTL: 3600, Ownership: myrasecprovider.OwnershipNone},
+	}
+
+	var table bytes.Buffer
+	require.NoError(t, writeRecordsTable(&table, records))
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, []string{"DOMAIN", "NAME", "TYPE", "TTL", "ENABLED", "MANAGED", "OWNERSHIP", "VALUE"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"example.com", "app.example.com", "A", "300", "true", "true", "owned", "1.2.3.4"}, strings.Fields(lines[1]))
+
+	var out bytes.Buffer
+	require.NoError(t, writeRecordsJSON(&out, records))
+	var decoded []myrasecprovider.ZoneRecord
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, records, decoded)
+
+	out.Reset()
+	require.NoError(t, writeRecordsJSON(&out, nil))
+	assert.Equal(t, "[]\n", out.String())
+}
@@ -1,11 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
-	"strconv"
+	"time"
 
 	"github.com/netguru/myra-external-dns-webhook/internal/myrasecprovider"
 	"github.com/netguru/myra-external-dns-webhook/pkg/api"
+	"github.com/netguru/myra-external-dns-webhook/pkg/redact"
+	"github.com/netguru/myra-external-dns-webhook/pkg/tracing"
+	"github.com/netguru/myra-external-dns-webhook/pkg/version"
 
 	"log"
 	"os"
@@ -15,23 +19,66 @@ import (
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/cobra"
-	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	"sigs.k8s.io/external-dns/endpoint"
 )
 
 var (
+	configFile        string
 	listenAddress     string
 	myraSecAPIKey     string
 	myraSecAPISecret  string
+	apiKeyFile        string
+	apiSecretFile     string
 	baseURL           string
+	apiProxy          string
+	apiCABundle       string
+	userAgentOwner    bool
+	apiLanguage       string
 	dryRun            bool
+	dryRunOutput      string
+	dryRunOutputFile  string
 	logLevel          string
+	logFormat         string
 	domainFilter      []string
+	excludeDomains    []string
+	pinnedDomainID    int
+	pinnedDomainName  string
 	ttl               int
+	minTTL            int
+	maxTTL            int
+	ttlPerType        []string
+	ttlEnforcement    string
+	owner             string
+	workers           int
+	continueOnError   bool
+	recordTypes       []string
+	adoptExisting     bool
+	reclaimOwnership  bool
+	strictOwner       bool
+	deleteOnEmpty     bool
+	maxDeletions      int
+	maxChanges        int
+	concurrentApply   string
+	invalidEndpoints  string
+	notifyURL         string
+	notifyToken       string
+	notifyTimeout     time.Duration
 	disableProtection bool
+	protectionPerType []string
+	shutdownTimeout   time.Duration
+	httpConfig        = api.DefaultConfig()
+	credentialsReload time.Duration
+	orphanCleanup     time.Duration
+	waitRecordActive  time.Duration
+	applyTimeout      time.Duration
+	validateStart     bool
+	noValidateStart   bool
+	domainCacheTTL    time.Duration
+	environment       string
+	// accounts are read from the config file only, see loadAccounts
+	accounts []myrasecprovider.Account
 )
 
 var rootCmd = &cobra.Command{
@@ -39,6 +86,10 @@ var rootCmd = &cobra.Command{
 	Short: "Webhook myrasecprovider for ExternalDNS to manage MyraSec DNS records",
 	Long:  "Webhook myrasecprovider for ExternalDNS to manage MyraSec DNS records through the MyraSec API",
 	Run: func(cmd *cobra.Command, args []string) {
+		// Mask the credentials wherever they would appear in logs or error details
+		redact.Add(myraSecAPIKey, myraSecAPISecret, notifyToken, proxyPassword(apiProxy))
+		redact.Add(accountSecrets()...)
+
 		// Initialize logger
 		logger := getLogger()
 		defer func() {
@@ -47,108 +98,215 @@ var rootCmd = &cobra.Command{
 			}
 		}()
 
+		info := version.Get()
+		logger.Info("Starting external-dns-myrasec-webhook",
+			zap.String("version", info.Version),
+			zap.String("commit", info.Commit),
+			zap.String("date", info.Date))
+
 		// Validate required parameters
 		if listenAddress == "" {
 			logger.Fatal("ERROR: Listen address is required but not set. Please set WEBHOOK_LISTEN_ADDRESS_PORT or WEBHOOK_LISTEN_ADDRESS environment variable.")
 		}
+		if problems := networkProblems(listenAddress, providerConfig()); len(problems) > 0 {
+			logger.Fatal("ERROR: Invalid network settings, fix all of them before restarting", zap.Strings("problems", problems))
+		}
+
+		if myraSecAPIKey == "" && len(accounts) == 0 {
+			logger.Fatal("ERROR: MYRASEC_API_KEY or MYRASEC_API_KEY_FILE is required but not set.")
+		}
 
-		if myraSecAPIKey == "" {
-			logger.Fatal("ERROR: MYRASEC_API_KEY is required but not set.")
+		if myraSecAPISecret == "" && len(accounts) == 0 {
+			logger.Fatal("ERROR: MYRASEC_API_SECRET or MYRASEC_API_SECRET_FILE is required but not set.")
 		}
 
-		if myraSecAPISecret == "" {
-			logger.Fatal("ERROR: MYRASEC_API_SECRET is required but not set.")
+		if err := httpConfig.Validate(); err != nil {
+			logger.Fatal("ERROR: Invalid HTTP server settings", zap.Error(err))
 		}
 
 		logger.Info("All required configuration parameters are present")
+		logEffectiveConfig(logger, cmd.Flags())
 
-		// Initialize domain filter
-		domainFilter := endpoint.DomainFilter{Filters: domainFilter}
+		// Export traces if an OTLP endpoint is configured
+		shutdownTracing, err := tracing.Setup(context.Background(), logger.With(zap.String("component", "tracing")))
+		if err != nil {
+			logger.Fatal("Failed to set up tracing", zap.Error(err))
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			if err := shutdownTracing(ctx); err != nil {
+				logger.Warn("Failed to flush traces", zap.Error(err))
+			}
+		}()
 
 		// Initialize MyraSec myrasecprovider
 		myraSecProvider, err := myrasecprovider.NewMyraSecDNSProvider(
 			logger.With(zap.String("component", "myrasecprovider")),
-			myrasecprovider.Config{
-				APIKey:            myraSecAPIKey,
-				APISecret:         myraSecAPISecret,
-				BaseURL:           baseURL,
-				DomainFilter:      domainFilter,
-				DryRun:            dryRun,
-				TTL:               ttl,
-				DisableProtection: disableProtection,
-			},
+			providerConfig(),
 		)
 		if err != nil {
 			logger.Fatal("Failed to initialize MyraSec myrasecprovider", zap.Error(err))
 		}
 
+		// Fail before the server starts, so the pod never becomes ready with bad credentials or filters
+		if validateStart && !noValidateStart {
+			if err := validateOnStart(logger, myraSecProvider, domainFilters(), excludeDomains); err != nil {
+				logger.Fatal("Startup validation failed, disable it with --no-validate-on-start", zap.Error(err))
+			}
+		}
+
+		// Pick up rotated credentials from the credential files
+		watchCtx, stopWatching := context.WithCancel(context.Background())
+		defer stopWatching()
+		if (apiKeyFile != "" || apiSecretFile != "") && credentialsReload > 0 {
+			watcher := &credentialsWatcher{
+				logger:     logger.With(zap.String("component", "credentials")),
+				provider:   myraSecProvider,
+				keyFile:    apiKeyFile,
+				secretFile: apiSecretFile,
+				apiKey:     myraSecAPIKey,
+				apiSecret:  myraSecAPISecret,
+			}
+			go watcher.run(watchCtx, credentialsReload)
+		}
+
+		// Keep the zone free of ownership records left behind without their records
+		if orphanCleanup > 0 {
+			go myraSecProvider.RunOrphanJanitor(watchCtx, orphanCleanup)
+		}
+
 		// Initialize API server
-		app := api.New(logger.With(zap.String("component", "api")), myraSecProvider)
+		app := api.NewWithConfig(logger.With(zap.String("component", "api")), myraSecProvider, httpConfig)
 
 		// Start listening for API requests
 		logger.Info("Starting webhook server", zap.String("address", listenAddress))
-		go func() {
-			if err := app.Listen(listenAddress); err != nil {
-				logger.Fatal("Failed to start server", zap.Error(err))
-			}
-		}()
+		serverErr, err := app.Listen(listenAddress)
+		if err != nil {
+			logger.Fatal("Failed to start server", zap.String("address", listenAddress), zap.Error(err))
+		}
 
-		// Wait for termination signal
+		// Wait for termination signal or for the server to stop on its own, SIGUSR1 dumps the state
 		sigCh := make(chan os.Signal, 1)
-		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-		<-sigCh
-		logger.Info("Shutting down server")
+		signal.Notify(sigCh, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGUSR1)
+	wait:
+		for {
+			select {
+			case err := <-serverErr:
+				if err != nil {
+					logger.Fatal("Server stopped unexpectedly", zap.Error(err))
+				}
+				return
+			case sig := <-sigCh:
+				if sig == syscall.SIGUSR1 {
+					dumpState(logger, cmd.Flags(), myraSecProvider)
+					continue
+				}
+				logger.Info("Shutting down server due to received signal",
+					zap.String("signal", sig.String()),
+					zap.Duration("grace_period", shutdownTimeout))
+				break wait
+			}
+		}
+
+		// Give in-flight requests the grace period to complete. The apply in progress finishes first,
+		// so the process does not exit between a record and its ownership TXT record; applies that
+		// did not start yet are refused.
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := myraSecProvider.Drain(ctx); err != nil {
+			logger.Error("Apply in progress did not finish within the grace period", zap.Error(err))
+		}
+		if err := app.Shutdown(ctx); err != nil {
+			logger.Error("Server did not shut down gracefully", zap.Error(err))
+		}
+		<-serverErr
+		logger.Info("Server stopped")
 	},
 }
 
-// getLogger creates a new logger with the configured log level
+// getLogger creates a new logger with the configured log level and format
 func getLogger() *zap.Logger {
+	logger, err := newLogger(logLevel, logFormat)
+	if err != nil {
+		log.Fatalf("Failed to create logger: %v", err)
+	}
+
+	logger.Info("Logger initialized", zap.String("level", logLevel), zap.String("format", logFormat))
+	return logger
+}
+
+// newLogger creates a logger writing to stdout in the given format, json or console.
+// The console format uses colored levels and a readable timestamp for local development.
+func newLogger(level, format string) (*zap.Logger, error) {
+	return newLoggerTo(level, format, "stdout")
+}
+
+// newLoggerTo creates a logger like newLogger that writes to the given output path.
+func newLoggerTo(level, format, output string) (*zap.Logger, error) {
+	zapLevel, err := parseLogLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "time",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		FunctionKey:    zapcore.OmitKey,
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.SecondsDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+
+	switch strings.ToLower(format) {
+	case "json":
+		format = "json"
+	case "console":
+		format = "console"
+		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		encoderConfig.EncodeTime = zapcore.TimeEncoderOfLayout("2006-01-02 15:04:05.000")
+		encoderConfig.EncodeDuration = zapcore.StringDurationEncoder
+	default:
+		return nil, fmt.Errorf("unknown log format %q, expected json or console", format)
+	}
+
 	cfg := zap.Config{
-		Level:             zap.NewAtomicLevelAt(getZapLogLevel()),
+		Level:             zap.NewAtomicLevelAt(zapLevel),
 		Development:       false,
 		DisableCaller:     false,
 		DisableStacktrace: false,
-		Encoding:          "json",
-		EncoderConfig: zapcore.EncoderConfig{
-			TimeKey:        "time",
-			LevelKey:       "level",
-			NameKey:        "logger",
-			CallerKey:      "caller",
-			FunctionKey:    zapcore.OmitKey,
-			MessageKey:     "msg",
-			StacktraceKey:  "stacktrace",
-			LineEnding:     zapcore.DefaultLineEnding,
-			EncodeLevel:    zapcore.LowercaseLevelEncoder,
-			EncodeTime:     zapcore.ISO8601TimeEncoder,
-			EncodeDuration: zapcore.SecondsDurationEncoder,
-			EncodeCaller:   zapcore.ShortCallerEncoder,
-		},
-		OutputPaths:      []string{"stdout"},
-		ErrorOutputPaths: []string{"stderr"},
-	}
-
-	logger, err := cfg.Build()
-	if err != nil {
-		log.Fatalf("Failed to create logger: %v", err)
+		Encoding:          format,
+		EncoderConfig:     encoderConfig,
+		OutputPaths:       []string{output},
+		ErrorOutputPaths:  []string{"stderr"},
 	}
 
-	logger.Info("Logger initialized", zap.String("level", logLevel))
-	return logger
+	return cfg.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return redact.NewCore(core, redact.Default())
+	}))
 }
 
-// getZapLogLevel converts the string log level to a zap log level
-func getZapLogLevel() zapcore.Level {
-	switch strings.ToLower(logLevel) {
+// parseLogLevel converts the string log level to a zap log level
+func parseLogLevel(level string) (zapcore.Level, error) {
+	switch strings.ToLower(level) {
 	case "debug":
-		return zapcore.DebugLevel
+		return zapcore.DebugLevel, nil
 	case "info":
-		return zapcore.InfoLevel
+		return zapcore.InfoLevel, nil
 	case "warn":
-		return zapcore.WarnLevel
+		return zapcore.WarnLevel, nil
 	case "error":
-		return zapcore.ErrorLevel
+		return zapcore.ErrorLevel, nil
+	case "fatal":
+		return zapcore.FatalLevel, nil
 	default:
-		return zapcore.InfoLevel
+		return zapcore.InfoLevel, fmt.Errorf("unknown log level %q, expected debug, info, warn, error or fatal", level)
 	}
 }
 
@@ -161,13 +319,66 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	// Define command line flags
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Path to a YAML config file, keyed by flag name")
 	rootCmd.PersistentFlags().StringVar(&listenAddress, "listen-address", "", "The address to listen on for HTTP requests")
 	rootCmd.PersistentFlags().StringVar(&myraSecAPIKey, "myrasec-api-key", "", "The MyraSec API key to use for authentication")
 	rootCmd.PersistentFlags().StringVar(&myraSecAPISecret, "myrasec-api-secret", "", "The MyraSec API secret to use for authentication")
+	rootCmd.PersistentFlags().StringVar(&apiKeyFile, "myrasec-api-key-file", "", "File to read the MyraSec API key from, takes precedence over --myrasec-api-key")
+	rootCmd.PersistentFlags().StringVar(&apiSecretFile, "myrasec-api-secret-file", "", "File to read the MyraSec API secret from, takes precedence over --myrasec-api-secret")
+	rootCmd.PersistentFlags().DurationVar(&credentialsReload, "credentials-reload-interval", 30*time.Second, "How often the credential files are checked for rotated credentials (0 disables)")
+	rootCmd.PersistentFlags().StringVar(&baseURL, "base-url", "", "Alternative MyraSec API base URL (e.g. a staging or mock API)")
+	rootCmd.PersistentFlags().StringVar(&apiProxy, "myrasec-api-proxy", "", "Proxy URL for the MyraSec API, overrides HTTPS_PROXY, HTTP_PROXY and NO_PROXY (e.g. http://proxy.example.com:3128); set on the process-wide HTTP client for the MyraSec API host only")
+	rootCmd.PersistentFlags().StringVar(&apiCABundle, "myrasec-api-ca-bundle", "", "PEM file with CA certificates trusted for the MyraSec API besides the system roots, e.g. of a TLS-intercepting proxy; set on the process-wide HTTP client for the MyraSec API host only")
+	rootCmd.PersistentFlags().BoolVar(&userAgentOwner, "user-agent-include-owner", false, "If true, the --txt-owner-id is added to the User-Agent of the MyraSec API requests, e.g. to identify the deployment in support tickets")
+	rootCmd.PersistentFlags().StringVar(&apiLanguage, "myrasec-api-language", myrasecprovider.DefaultLanguage, "Language of the MyraSec API client (en, de)")
 	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "If true, only print the changes that would be made")
-	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "The log level to use (debug, info, warn, error)")
+	rootCmd.PersistentFlags().StringVar(&dryRunOutput, "dry-run-output", "", "In dry-run mode, also write the plan of every sync as a JSON document (json, empty disables it)")
+	rootCmd.PersistentFlags().StringVar(&dryRunOutputFile, "dry-run-output-file", "", "File the --dry-run-output is written to, replaced on every sync (default stdout)")
+	rootCmd.PersistentFlags().StringVar(&environment, "env", "", "Name of the deployment environment, prod, production and staging skip records with private IPs")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "The log level to use (debug, info, warn, error, fatal)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "json", "The log format to use (json, console)")
 	rootCmd.PersistentFlags().StringSliceVar(&domainFilter, "domain-filter", []string{}, "Filter domain names to manage")
+	rootCmd.PersistentFlags().StringSliceVar(&excludeDomains, "exclude-domains", []string{}, "Domain names below the domain filter that are not managed (e.g. delegated child zones)")
+	rootCmd.PersistentFlags().IntVar(&pinnedDomainID, "domain-id", 0, "ID of the MyraSec domain to manage; together with --domain-name the domains of the account are not listed")
+	rootCmd.PersistentFlags().StringVar(&pinnedDomainName, "domain-name", "", "Name of the MyraSec domain given by --domain-id")
+	rootCmd.PersistentFlags().StringSliceVar(&recordTypes, "managed-record-types", myrasecprovider.SupportedRecordTypes, "Record types the webhook creates, updates and deletes; records of other types are left alone")
+	rootCmd.PersistentFlags().DurationVar(&domainCacheTTL, "domain-cache-ttl", myrasecprovider.DefaultDomainCacheTTL, "How long the domains of the MyraSec account are cached")
+	rootCmd.PersistentFlags().IntVar(&ttl, "ttl", 300, "Default TTL in seconds for records without a TTL, 0 to inherit the default TTL of their domain")
+	rootCmd.PersistentFlags().IntVar(&minTTL, "min-ttl", myrasecprovider.DefaultMinTTL, "Minimum record TTL in seconds, lower TTLs are raised to it")
+	rootCmd.PersistentFlags().IntVar(&maxTTL, "max-ttl", myrasecprovider.DefaultMaxTTL, "Maximum record TTL in seconds, higher TTLs are lowered to it")
+	rootCmd.PersistentFlags().StringSliceVar(&ttlPerType, "ttl-per-type", []string{}, "Default TTL in seconds per record type for records without a TTL, e.g. A=120,TXT=3600; overrides --ttl for these types")
+	rootCmd.PersistentFlags().StringVar(&ttlEnforcement, "ttl-enforcement", myrasecprovider.TTLEnforcementAlways, "Whether existing records whose endpoint has no TTL are updated to the default TTL, or keep their TTL (always, on-create-only)")
+	rootCmd.PersistentFlags().StringVar(&owner, "txt-owner-id", "", "Owner ID of the ownership TXT records, must match --txt-owner-id of ExternalDNS (default \"external-dns\")")
+	rootCmd.PersistentFlags().IntVar(&workers, "workers", myrasecprovider.DefaultWorkers, "Number of changes applied concurrently")
+	rootCmd.PersistentFlags().BoolVar(&adoptExisting, "adopt-existing-records", false, "If true, records that exist in MyraSec without an ownership TXT record are taken over instead of left alone")
+	rootCmd.PersistentFlags().BoolVar(&reclaimOwnership, "reclaim-missing-ownership", false, "If true, a missing ownership TXT record is recreated when ExternalDNS updates a record of this owner, instead of skipping the update")
+	rootCmd.PersistentFlags().BoolVar(&strictOwner, "strict-owner", false, "If true, plans with endpoints labelled with another owner than --txt-owner-id are refused instead of their changes skipped")
+	rootCmd.PersistentFlags().BoolVar(&deleteOnEmpty, "delete-on-empty-targets", false, "If true, the records of an updated endpoint without targets are deleted instead of kept until it has targets again")
+	rootCmd.PersistentFlags().DurationVar(&applyTimeout, "apply-timeout", 0, "How long a sync may apply changes before the remaining ones are aborted, independent of the request timeout (0 disables)")
+	rootCmd.PersistentFlags().DurationVar(&waitRecordActive, "wait-for-record-active", 0, "How long a sync waits until the MyraSec API lists each created record, e.g. for DNS01 challenges (0 disables)")
+	rootCmd.PersistentFlags().DurationVar(&orphanCleanup, "orphan-cleanup-interval", 0, "How often orphaned ownership TXT records are deleted in the background (0 disables)")
+	rootCmd.PersistentFlags().IntVar(&maxDeletions, "max-deletions-per-sync", 0, "Refuse plans that delete more records than this (0 disables the limit)")
+	rootCmd.PersistentFlags().IntVar(&maxChanges, "max-changes-per-sync", 0, "Refuse plans with more creations, updates and deletions in total than this (0 disables the limit)")
+	rootCmd.PersistentFlags().StringVar(&concurrentApply, "concurrent-apply", myrasecprovider.ConcurrentApplyWait, "What a sync does while another one is still applying changes: wait for it, or reject with a 409 (wait, reject)")
+	rootCmd.PersistentFlags().StringVar(&invalidEndpoints, "invalid-endpoints", myrasecprovider.InvalidEndpointsDrop, "What happens to endpoints with invalid record values: drop them from the plan, or reject the plan with a 422 (drop, reject)")
+	rootCmd.PersistentFlags().StringVar(&notifyURL, "notify-url", "", "URL that receives a JSON summary after each sync that changed the zone")
+	rootCmd.PersistentFlags().StringVar(&notifyToken, "notify-token", "", "Bearer token sent with the notifications to --notify-url")
+	rootCmd.PersistentFlags().DurationVar(&notifyTimeout, "notify-timeout", myrasecprovider.DefaultNotifyTimeout, "How long a notification may take before it is given up")
+	rootCmd.PersistentFlags().BoolVar(&continueOnError, "continue-on-error", false, "If true, the remaining changes are still applied after a change failed")
 	rootCmd.PersistentFlags().BoolVar(&disableProtection, "disable-protection", false, "If true, Myra protection would be disabled for DNS records")
+	rootCmd.PersistentFlags().StringSliceVar(&protectionPerType, "protection-per-type", []string{}, "Default Myra protection per record type for records without the protection annotation, e.g. A=true,MX=false; overrides --disable-protection for these types")
+	rootCmd.PersistentFlags().BoolVar(&validateStart, "validate-on-start", true, "If true, the server only starts when the MyraSec API accepts the credentials and the domain filter selects a domain")
+	rootCmd.PersistentFlags().BoolVar(&noValidateStart, "no-validate-on-start", false, "Start the server without the startup validation, same as --validate-on-start=false")
+	rootCmd.PersistentFlags().DurationVar(&shutdownTimeout, "shutdown-timeout", 30*time.Second, "How long to wait for in-flight requests to complete on shutdown")
+	rootCmd.PersistentFlags().DurationVar(&httpConfig.ReadTimeout, "http-read-timeout", api.DefaultReadTimeout, "Maximum duration for reading an HTTP request")
+	rootCmd.PersistentFlags().DurationVar(&httpConfig.WriteTimeout, "http-write-timeout", api.DefaultWriteTimeout, "Maximum duration for writing an HTTP response")
+	rootCmd.PersistentFlags().DurationVar(&httpConfig.IdleTimeout, "http-idle-timeout", api.DefaultIdleTimeout, "How long idle keep-alive connections are kept open")
+	rootCmd.PersistentFlags().IntVar(&httpConfig.MaxBodySize, "http-max-body-size", api.DefaultMaxBodySize, "Maximum HTTP request body size in bytes, larger requests get a 413")
+	rootCmd.PersistentFlags().BoolVar(&httpConfig.StrictMediaType, "strict-media-type", false, "If true, webhook requests without a Content-Type or Accept header are rejected")
+	rootCmd.PersistentFlags().BoolVar(&httpConfig.EnablePprof, "enable-pprof", false, "If true, the pprof profiles are served below /pprof/debug/pprof/ on --pprof-address")
+	rootCmd.PersistentFlags().StringVar(&httpConfig.PprofAddress, "pprof-address", api.DefaultPprofAddress, "Address the pprof profiles are served on, separate from the webhook listener")
+	rootCmd.PersistentFlags().DurationVar(&httpConfig.HealthyThreshold, "healthy-threshold", 0, "If set, /healthz reports 503 while syncs are attempted but have not succeeded for longer than this (0 disables)")
+	rootCmd.PersistentFlags().BoolVar(&httpConfig.LocalhostAllInterfaces, "listen-localhost-all-interfaces", false, "If true, a localhost:<port> listen address binds to all interfaces as in earlier releases")
 }
 
 func initConfig() {
@@ -180,73 +391,48 @@ func initConfig() {
 		log.Printf("Loaded configuration from .env file")
 	}
 
-	// Set up environment variable handling
-	viper.SetEnvPrefix("WEBHOOK")
-	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
-	viper.AutomaticEnv()
-
-	// Map environment variables to flags
-	if os.Getenv("WEBHOOK_LISTEN_ADDRESS_PORT") != "" {
-		listenAddress = ":" + os.Getenv("WEBHOOK_LISTEN_ADDRESS_PORT")
-	} else if os.Getenv("WEBHOOK_LISTEN_ADDRESS") != "" {
-		listenAddress = os.Getenv("WEBHOOK_LISTEN_ADDRESS")
+	// Every flag is bound to viper with its environment variables, see bindConfig. Values are
+	// resolved with the precedence flags > environment variables > config file > defaults.
+	flags := rootCmd.PersistentFlags()
+	if err := bindConfig(flags); err != nil {
+		log.Fatalf("Error: %v", err)
 	}
 
-	// Set default listen address if not provided
-	if listenAddress == "" {
-		listenAddress = ":8080"
-		log.Printf("No listen address configured, using default: %s", listenAddress)
-	}
-
-	if os.Getenv("MYRASEC_API_KEY") != "" && myraSecAPIKey == "" {
-		myraSecAPIKey = os.Getenv("MYRASEC_API_KEY")
+	configFile = viper.GetString("config")
+	if configFile != "" {
+		if err := loadConfigFile(flags, configFile); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		log.Printf("Loaded configuration from %s", configFile)
 	}
-
-	if os.Getenv("MYRASEC_API_SECRET") != "" && myraSecAPISecret == "" {
-		myraSecAPISecret = os.Getenv("MYRASEC_API_SECRET")
+	if err := loadAccounts(); err != nil {
+		log.Fatalf("Error: %v", err)
 	}
 
-	if os.Getenv("BASE_URL") != "" && baseURL == "" {
-		baseURL = os.Getenv("BASE_URL")
+	if err := applyConfigValues(flags); err != nil {
+		log.Fatalf("Error: %v", err)
 	}
 
-	// Check for optional environment variables
-	if os.Getenv("DRY_RUN") == "true" && !dryRun {
-		dryRun = true
+	// The port alone wins over the listen address from the environment or the config file
+	if port := os.Getenv("WEBHOOK_LISTEN_ADDRESS_PORT"); port != "" && !flags.Changed("listen-address") {
+		listenAddress = ":" + port
 	}
 
-	if os.Getenv("DISABLE_PROTECTION") == "true" && !disableProtection {
-		disableProtection = true
+	if disableProtection {
 		log.Printf("Myra protection is disabled")
 	}
 
-	if os.Getenv("LOG_LEVEL") != "" && logLevel == "info" {
-		logLevel = os.Getenv("LOG_LEVEL")
-	}
-
-	if os.Getenv("DOMAIN_FILTER") != "" && len(domainFilter) == 0 {
-		domainFilter = strings.Split(os.Getenv("DOMAIN_FILTER"), ",")
+	if environment != "" {
+		log.Printf("Environment: %s", environment)
 	}
 
-	ttl = 300
-	if os.Getenv("TTL") != "" {
-		ttlvar, _ := strconv.Atoi(os.Getenv("TTL"))
-		if ttlvar > 0 {
-			ttl = ttlvar
-		}
+	if err := applySecretFiles(); err != nil {
+		log.Fatalf("Error: %v", err)
 	}
 
-	if os.Getenv("ENV") != "" {
-		log.Printf("Enviroment: %s", os.Getenv("ENV"))
+	// Set default listen address if not provided
+	if listenAddress == "" {
+		listenAddress = ":8080"
+		log.Printf("No listen address configured, using default: %s", listenAddress)
 	}
-
-	// Bind viper environment variables to flags
-	rootCmd.PersistentFlags().VisitAll(func(f *pflag.Flag) {
-		if !f.Changed && viper.IsSet(f.Name) {
-			val := viper.Get(f.Name)
-			if err := rootCmd.PersistentFlags().Set(f.Name, fmt.Sprint(val)); err != nil {
-				log.Printf("Warning: Failed to set flag %s from environment variable: %v", f.Name, err)
-			}
-		}
-	})
 }
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := map[string]zapcore.Level{
+		"debug": zapcore.DebugLevel,
+		"info":  zapcore.InfoLevel,
+		"WARN":  zapcore.WarnLevel,
+		"error": zapcore.ErrorLevel,
+		"fatal": zapcore.FatalLevel,
+	}
+	for input, want := range tests {
+		got, err := parseLogLevel(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	_, err := parseLogLevel("verbose")
+	assert.ErrorContains(t, err, `unknown log level "verbose"`)
+}
+
+func TestNewLogger(t *testing.T) {
+	for _, format := range []string{"json", "console", "Console"} {
+		logger, err := newLogger("info", format)
+		require.NoError(t, err, format)
+		assert.True(t, logger.Core().Enabled(zapcore.InfoLevel))
+		assert.False(t, logger.Core().Enabled(zapcore.DebugLevel))
+	}
+
+	_, err := newLogger("info", "logfmt")
+	assert.ErrorContains(t, err, `unknown log format "logfmt"`)
+
+	_, err = newLogger("trace", "json")
+	assert.Error(t, err)
+}
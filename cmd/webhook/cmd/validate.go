@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+
+	"github.com/netguru/myra-external-dns-webhook/internal/myrasecprovider"
+	"github.com/netguru/myra-external-dns-webhook/pkg/api"
+	"github.com/netguru/myra-external-dns-webhook/pkg/redact"
+)
+
+// Status of a validation check
+const (
+	checkPass = "PASS"
+	checkWarn = "WARN"
+	checkFail = "FAIL"
+)
+
+// checkResult is the outcome of a single validation check
+type checkResult struct {
+	name    string
+	status  string
+	message string
+}
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the configuration and the connection to the MyraSec API",
+	Long: "Load the configuration like the webhook server, check the required settings, verify the credentials " +
+		"with the MyraSec API and evaluate the domain filter against the domains of the account. " +
+		"Exits with a non-zero status if a check fails, so it can run as an init container or in CI.",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		results := runValidation()
+		if err := writeValidationReport(cmd.OutOrStdout(), results); err != nil {
+			return err
+		}
+
+		failed := 0
+		for _, r := range results {
+			if r.status == checkFail {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("validation failed: %d of %d checks failed", failed, len(results))
+		}
+		return nil
+	},
+}
+
+// runValidation runs the checks against the effective settings. The API checks are skipped
+// when the credentials or the provider settings are invalid.
+func runValidation() []checkResult {
+	redact.Add(myraSecAPIKey, myraSecAPISecret, proxyPassword(apiProxy))
+	redact.Add(accountSecrets()...)
+
+	var results []checkResult
+	add := func(name string, err error, message string) bool {
+		if err != nil {
+			results = append(results, checkResult{name: name, status: checkFail, message: err.Error()})
+			return false
+		}
+		results = append(results, checkResult{name: name, status: checkPass, message: message})
+		return true
+	}
+
+	credentialsOK := add("credentials", requiredCredentials(), "API key and secret are set")
+
+	address, err := api.ParseListenAddress(listenAddress)
+	add("listen address", err, address)
+	add("http settings", httpConfig.Validate(), "limits are set")
+
+	if !credentialsOK {
+		return results
+	}
+
+	logger, err := newLoggerTo(logLevel, logFormat, "stderr")
+	if err != nil {
+		add("log settings", err, "")
+		logger = zap.NewNop()
+	}
+	defer func() { _ = logger.Sync() }()
+
+	provider, err := myrasecprovider.NewMyraSecDNSProvider(logger.With(zap.String("component", "myrasecprovider")), providerConfig())
+	if !add("provider settings", err, "TTL, record types, API language and base URL are valid") {
+		return results
+	}
+
+	domains, err := provider.AccountDomains()
+	message := fmt.Sprintf("credentials accepted, the account has %d domains", len(domains))
+	if pinnedDomainID > 0 {
+		message = fmt.Sprintf("credentials accepted, the records of the pinned domain %s are readable", pinnedDomainName)
+	}
+	if !add("MyraSec API", err, message) {
+		return results
+	}
+
+	status, message := checkDomainFilter(domainFilters(), excludeDomains, domains)
+	results = append(results, checkResult{name: "domain filter", status: status, message: message})
+	return results
+}
+
+// domainLister lists the domains of the MyraSec account, see MyraSecDNSProvider.AccountDomains
+type domainLister interface {
+	AccountDomains() ([]string, error)
+}
+
+// validateOnStart checks before the webhook server starts that the MyraSec API accepts the
+// credentials and that the domain filter selects a domain, so a broken deployment fails right
+// away instead of on the first request of ExternalDNS. Warnings of the domain filter are logged.
+func validateOnStart(logger *zap.Logger, provider domainLister, filters, excludes []string) error {
+	domains, err := provider.AccountDomains()
+	if errors.Is(err, myrasecprovider.ErrAuthenticationFailed) {
+		return fmt.Errorf("the MyraSec API rejected the credentials, check MYRASEC_API_KEY and MYRASEC_API_SECRET: %w", err)
+	}
+	if err != nil {
+		return fmt.Errorf("the domains of the MyraSec account could not be listed: %w", err)
+	}
+
+	status, message := checkDomainFilter(filters, excludes, domains)
+	switch status {
+	case checkFail:
+		return fmt.Errorf("the domain filter does not select a domain: %s", message)
+	case checkWarn:
+		logger.Warn("Domain filter", zap.String("message", message))
+	}
+	logger.Info("Startup validation passed", zap.Int("domains", len(domains)), zap.String("domain_filter", message))
+	return nil
+}
+
+// requiredCredentials reports a missing API key or secret. The credentials of accounts are
+// checked by the provider.
+func requiredCredentials() error {
+	if len(accounts) > 0 {
+		return nil
+	}
+	var missing []string
+	if myraSecAPIKey == "" {
+		missing = append(missing, "MYRASEC_API_KEY or MYRASEC_API_KEY_FILE")
+	}
+	if myraSecAPISecret == "" {
+		missing = append(missing, "MYRASEC_API_SECRET or MYRASEC_API_SECRET_FILE")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s must be set", strings.Join(missing, " and "))
+	}
+	return nil
+}
+
+// checkDomainFilter evaluates the domain filter against the domains of the account the way the
+// provider selects the domain: the first filter must be the name of a domain, every other filter
+// must match at least one domain.
+func checkDomainFilter(filters, excludes, domains []string) (string, string) {
+	available := "available: " + strings.Join(domains, ", ")
+	if len(domains) == 0 {
+		return checkFail, "the account has no domains"
+	}
+
+	if len(filters) == 0 {
+		if len(domains) > 1 {
+			return checkWarn, fmt.Sprintf("no domain filter set, the first domain %s is managed; set --domain-filter to choose one (%s)", domains[0], available)
+		}
+		return checkPass, fmt.Sprintf("no domain filter set, the only domain %s is managed", domains[0])
+	}
+
+	for _, filter := range filters {
+		matched := false
+		for _, domain := range domains {
+			if endpoint.NewDomainFilter([]string{filter}).Match(domain) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return checkFail, fmt.Sprintf("filter %s matches no domain; %s", filter, available)
+		}
+	}
+
+	selected := filters[0]
+	found := false
+	for _, domain := range domains {
+		if domain == selected {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return checkFail, fmt.Sprintf("first filter %s is not a domain of the account, the webhook would manage %s instead; %s", selected, domains[0], available)
+	}
+
+	if len(excludes) > 0 && endpoint.NewDomainFilter(excludes).Match(selected) {
+		return checkFail, fmt.Sprintf("domain %s is excluded by --exclude-domains %s", selected, strings.Join(excludes, ","))
+	}
+	return checkPass, fmt.Sprintf("domain %s is managed", selected)
+}
+
+// writeValidationReport writes one line per check
+func writeValidationReport(w io.Writer, results []checkResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.status, r.name, r.message)
+	}
+	return tw.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+}
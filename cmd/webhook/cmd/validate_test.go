@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/netguru/myra-external-dns-webhook/internal/myrasecprovider"
+)
+
+// fakeDomainLister returns fixed domains or an error
+type fakeDomainLister struct {
+	domains []string
+	err     error
+}
+
+func (f fakeDomainLister) AccountDomains() ([]string, error) {
+	return f.domains, f.err
+}
+
+func TestValidateOnStart(t *testing.T) {
+	tests := []struct {
+		name    string
+		lister  fakeDomainLister
+		filters []string
+		err     string // part of the error, "" if the validation passes
+	}{
+		{
+			name:    "valid",
+			lister:  fakeDomainLister{domains: []string{"example.com", "foo.de"}},
+			filters: []string{"example.com"},
+		},
+		{
+			name:   "no filter, several domains",
+			lister: fakeDomainLister{domains: []string{"example.com", "foo.de"}},
+		},
+		{
+			name:    "authentication failure",
+			lister:  fakeDomainLister{err: fmt.Errorf("failed to list domains: %w", myrasecprovider.ErrAuthenticationFailed)},
+			filters: []string{"example.com"},
+			err:     "the MyraSec API rejected the credentials",
+		},
+		{
+			name:    "API failure",
+			lister:  fakeDomainLister{err: fmt.Errorf("failed to list domains: %w", myrasecprovider.ErrAPIRequestFailed)},
+			filters: []string{"example.com"},
+			err:     "the domains of the MyraSec account could not be listed",
+		},
+		{
+			name:    "empty account",
+			lister:  fakeDomainLister{domains: []string{}},
+			filters: []string{"example.com"},
+			err:     "the account has no domains",
+		},
+		{
+			name:    "filter mismatch",
+			lister:  fakeDomainLister{domains: []string{"example.com", "foo.de"}},
+			filters: []string{"example.org"},
+			err:     "filter example.org matches no domain; available: example.com, foo.de",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateOnStart(zap.NewNop(), tt.lister, tt.filters, nil)
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCheckDomainFilter(t *testing.T) {
+	domains := []string{"example.com", "foo.de"}
+
+	tests := []struct {
+		name     string
+		filters  []string
+		excludes []string
+		domains  []string
+		status   string
+		message  string
+	}{
+		{name: "exact domain", filters: []string{"example.com"}, domains: domains, status: checkPass, message: "domain example.com is managed"},
+		{name: "unknown domain", filters: []string{"example.org"}, domains: domains, status: checkFail, message: "filter example.org matches no domain; available: example.com, foo.de"},
+		{name: "second filter unknown", filters: []string{"example.com", "bar.de"}, domains: domains, status: checkFail, message: "filter bar.de matches no domain"},
+		{name: "first filter is a parent", filters: []string{"com"}, domains: domains, status: checkFail, message: "first filter com is not a domain of the account, the webhook would manage example.com instead"},
+		{name: "excluded domain", filters: []string{"example.com"}, excludes: []string{"example.com"}, domains: domains, status: checkFail, message: "domain example.com is excluded"},
+		{name: "no filter, one domain", domains: []string{"example.com"}, status: checkPass, message: "the only domain example.com is managed"},
+		{name: "no filter, several domains", domains: domains, status: checkWarn, message: "set --domain-filter to choose one"},
+		{name: "no domains", filters: []string{"example.com"}, status: checkFail, message: "the account has no domains"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, message := checkDomainFilter(tt.filters, tt.excludes, tt.domains)
+			assert.Equal(t, tt.status, status)
+			assert.Contains(t, message, tt.message)
+		})
+	}
+}
+
+func TestRequiredCredentials(t *testing.T) {
+	key, secret := myraSecAPIKey, myraSecAPISecret
+	t.Cleanup(func() { myraSecAPIKey, myraSecAPISecret = key, secret })
+
+	myraSecAPIKey, myraSecAPISecret = "", ""
+	assert.EqualError(t, requiredCredentials(), "MYRASEC_API_KEY or MYRASEC_API_KEY_FILE and MYRASEC_API_SECRET or MYRASEC_API_SECRET_FILE must be set")
+
+	myraSecAPIKey = "key"
+	assert.ErrorContains(t, requiredCredentials(), "MYRASEC_API_SECRET")
+
+	myraSecAPISecret = "secret"
+	assert.NoError(t, requiredCredentials())
+}
+
+func TestWriteValidationReport(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, writeValidationReport(&out, []checkResult{
+		{name: "credentials", status: checkPass, message: "API key and secret are set"},
+		{name: "domain filter", status: checkFail, message: "filter example.org matches no domain"},
+	}))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "PASS  credentials"))
+	assert.True(t, strings.HasPrefix(lines[1], "FAIL  domain filter"))
+}
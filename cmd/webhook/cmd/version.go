@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/netguru/myra-external-dns-webhook/pkg/version"
+)
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the version of the webhook",
+	Run: func(cmd *cobra.Command, args []string) {
+		info := version.Get()
+		fmt.Fprintf(cmd.OutOrStdout(), "Version:    %s\nCommit:     %s\nBuild date: %s\nGo version: %s\n",
+			info.Version, info.Commit, info.Date, info.GoVersion)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(versionCmd)
+}
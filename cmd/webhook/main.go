@@ -1,11 +1,14 @@
 package main
 
 import (
+	"os"
+
 	"github.com/netguru/myra-external-dns-webhook/cmd/webhook/cmd"
 )
 
 func main() {
+	// cobra already printed the error
 	if err := cmd.Execute(); err != nil {
-		panic(err)
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,160 @@
+package myrasecprovider
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// Account is a MyraSec account managed by the webhook next to others, with the domains whose
+// records it holds.
+type Account struct {
+	APIKey    string
+	APISecret string
+	// Domains are matched like the domain filter, so a domain also covers its subdomains
+	Domains []string
+}
+
+// accountClient is the API client of one account and the filter of its domains.
+type accountClient struct {
+	client  MyraSecAPIClient
+	domains endpoint.DomainFilter
+}
+
+// accountsClient routes the calls of the provider to several MyraSec accounts: the domains of
+// all accounts are listed together, each account contributing the domains configured for it, and
+// the calls for a domain go to the account it was listed from.
+type accountsClient struct {
+	accounts []accountClient
+
+	mu sync.RWMutex
+	// owners maps the domain IDs of the last listing to the client of their account
+	owners map[int]MyraSecAPIClient
+}
+
+// newAccountsClient returns a client for the accounts, building the API client of every account
+// with newClient.
+func newAccountsClient(accounts []Account, newClient func(apiKey, apiSecret string) (MyraSecAPIClient, error)) (*accountsClient, error) {
+	c := &accountsClient{}
+	for i, account := range accounts {
+		if account.APIKey == "" || account.APISecret == "" {
+			return nil, fmt.Errorf("account %d: no API key or secret provided", i+1)
+		}
+		if len(account.Domains) == 0 {
+			return nil, fmt.Errorf("account %d: no domains provided", i+1)
+		}
+		client, err := newClient(account.APIKey, account.APISecret)
+		if err != nil {
+			return nil, fmt.Errorf("account %d: %w", i+1, err)
+		}
+		c.accounts = append(c.accounts, accountClient{
+			client:  client,
+			domains: endpoint.NewDomainFilter(idnFilters(account.Domains)),
+		})
+	}
+	return c, nil
+}
+
+// withAccountDomains returns the filters together with the domains of the accounts missing from
+// them, so the domain filter of the provider is the union of both.
+func withAccountDomains(filters []string, accounts []Account) []string {
+	var union []string
+	seen := map[string]bool{}
+	add := func(domain string) {
+		key := strings.ToLower(stripTrailingDot(strings.TrimSpace(domain)))
+		if key != "" && !seen[key] {
+			seen[key] = true
+			union = append(union, domain)
+		}
+	}
+	for _, filter := range filters {
+		add(filter)
+	}
+	for _, account := range accounts {
+		for _, domain := range account.Domains {
+			add(domain)
+		}
+	}
+	return union
+}
+
+// ListDomains lists the domains of every account that match the domains of the account. A domain
+// listed by several accounts belongs to the first.
+func (c *accountsClient) ListDomains(params map[string]string) ([]myrasec.Domain, error) {
+	var domains []myrasec.Domain
+	owners := map[int]MyraSecAPIClient{}
+	for i, account := range c.accounts {
+		listed, err := account.client.ListDomains(params)
+		if err != nil {
+			return nil, fmt.Errorf("account %d: %w", i+1, err)
+		}
+		for _, domain := range listed {
+			if _, ok := owners[domain.ID]; ok || !account.domains.Match(asciiName(domain.Name)) {
+				continue
+			}
+			owners[domain.ID] = account.client
+			domains = append(domains, domain)
+		}
+	}
+
+	c.mu.Lock()
+	c.owners = owners
+	c.mu.Unlock()
+	return domains, nil
+}
+
+// clientFor returns the client of the account of the domain, listing the domains first if the
+// domain is not known yet.
+func (c *accountsClient) clientFor(domainID int) (MyraSecAPIClient, error) {
+	c.mu.RLock()
+	client, ok := c.owners[domainID]
+	c.mu.RUnlock()
+	if ok {
+		return client, nil
+	}
+
+	if _, err := c.ListDomains(map[string]string{"pageSize": "9999"}); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if client, ok := c.owners[domainID]; ok {
+		return client, nil
+	}
+	return nil, fmt.Errorf("%w: ID %d belongs to none of the accounts", ErrDomainNotFound, domainID)
+}
+
+func (c *accountsClient) ListDNSRecords(domainId int, params map[string]string) ([]myrasec.DNSRecord, error) {
+	client, err := c.clientFor(domainId)
+	if err != nil {
+		return nil, err
+	}
+	return client.ListDNSRecords(domainId, params)
+}
+
+func (c *accountsClient) CreateDNSRecord(record *myrasec.DNSRecord, domainId int) (*myrasec.DNSRecord, error) {
+	client, err := c.clientFor(domainId)
+	if err != nil {
+		return nil, err
+	}
+	return client.CreateDNSRecord(record, domainId)
+}
+
+func (c *accountsClient) UpdateDNSRecord(record *myrasec.DNSRecord, domainId int) (*myrasec.DNSRecord, error) {
+	client, err := c.clientFor(domainId)
+	if err != nil {
+		return nil, err
+	}
+	return client.UpdateDNSRecord(record, domainId)
+}
+
+func (c *accountsClient) DeleteDNSRecord(record *myrasec.DNSRecord, domainId int) (*myrasec.DNSRecord, error) {
+	client, err := c.clientFor(domainId)
+	if err != nil {
+		return nil, err
+	}
+	return client.DeleteDNSRecord(record, domainId)
+}
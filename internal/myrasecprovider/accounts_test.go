@@ -0,0 +1,97 @@
+package myrasecprovider
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestAccountsRouting(t *testing.T) {
+	ownership := "heritage=external-dns,external-dns/owner=test-owner"
+	first := newFakeMyraSecClient(myrasec.Domain{ID: 1, Name: "example.com"}, myrasec.Domain{ID: 3, Name: "unmanaged.net"})
+	first.records[1] = []myrasec.DNSRecord{
+		{ID: 10, Name: "app.example.com", RecordType: "A", Value: "1.1.1.1", TTL: 300},
+		{ID: 11, Name: "app.example.com", RecordType: "TXT", Value: ownership, TTL: 300},
+	}
+	second := newFakeMyraSecClient(myrasec.Domain{ID: 2, Name: "example.org"})
+	second.records[2] = []myrasec.DNSRecord{
+		{ID: 20, Name: "app.example.org", RecordType: "A", Value: "2.2.2.2", TTL: 300},
+		{ID: 21, Name: "app.example.org", RecordType: "TXT", Value: ownership, TTL: 300},
+	}
+
+	client := &accountsClient{accounts: []accountClient{
+		{client: first, domains: endpoint.NewDomainFilter([]string{"example.com"})},
+		{client: second, domains: endpoint.NewDomainFilter([]string{"example.org"})},
+	}}
+	p := newTestProvider(client)
+	p.domainFilter = endpoint.NewDomainFilter([]string{"example.com", "example.org"})
+
+	records, err := p.Records(context.Background())
+	require.NoError(t, err)
+	assert.NotNil(t, findEndpoint(records, "app.example.com", endpoint.RecordTypeA))
+	assert.NotNil(t, findEndpoint(records, "app.example.org", endpoint.RecordTypeA))
+
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("new.example.com", endpoint.RecordTypeA, "3.3.3.3"),
+			endpoint.NewEndpoint("new.example.org", endpoint.RecordTypeA, "4.4.4.4"),
+		},
+		Delete: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "2.2.2.2"),
+		},
+	}))
+
+	names := func(c *fakeMyraSecClient, domainID int) []string {
+		var names []string
+		for _, r := range c.records[domainID] {
+			if r.RecordType == endpoint.RecordTypeA {
+				names = append(names, r.Name)
+			}
+		}
+		return names
+	}
+	assert.ElementsMatch(t, []string{"app.example.com", "new.example.com"}, names(first, 1))
+	assert.ElementsMatch(t, []string{"new.example.org"}, names(second, 2))
+	assert.Empty(t, first.records[2], "no record of the second account went to the first")
+	assert.Empty(t, second.records[1], "no record of the first account went to the second")
+
+	_, err = client.ListDNSRecords(3, nil)
+	assert.ErrorIs(t, err, ErrDomainNotFound, "domains outside the domains of their account are not managed")
+}
+
+func TestNewMyraSecDNSProviderAccounts(t *testing.T) {
+	accounts := []Account{
+		{APIKey: "key-a", APISecret: "secret-a", Domains: []string{"example.com"}},
+		{APIKey: "key-b", APISecret: "secret-b", Domains: []string{"Example.org.", "example.com"}},
+	}
+	p, err := NewMyraSecDNSProvider(zap.NewNop(), Config{
+		Accounts:     accounts,
+		DomainFilter: endpoint.NewDomainFilter([]string{"example.net"}),
+	})
+	require.NoError(t, err)
+	require.IsType(t, &accountsClient{}, p.client())
+	assert.Len(t, p.client().(*accountsClient).accounts, 2)
+
+	filter, err := json.Marshal(p.GetDomainFilter())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"include":["example.com","example.net","example.org"]}`, string(filter))
+
+	assert.Error(t, p.ReloadCredentials("key", "secret"), "the credentials of several accounts are not reloaded")
+
+	for name, config := range map[string]Config{
+		"API key besides accounts": {APIKey: "key", APISecret: "secret", Accounts: accounts},
+		"pinned domain":            {Accounts: accounts, DomainID: 1, DomainName: "example.com"},
+		"account without secret":   {Accounts: []Account{{APIKey: "key", Domains: []string{"example.com"}}}},
+		"account without domains":  {Accounts: []Account{{APIKey: "key", APISecret: "secret"}}},
+	} {
+		_, err := NewMyraSecDNSProvider(zap.NewNop(), config)
+		assert.Error(t, err, name)
+	}
+}
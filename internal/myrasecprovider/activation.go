@@ -0,0 +1,84 @@
+package myrasecprovider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"go.uber.org/zap"
+)
+
+// DefaultRecordActivePollInterval is how often the records of the domain are listed while a
+// created record is waited for, see Config.WaitForRecordActive.
+const DefaultRecordActivePollInterval = 2 * time.Second
+
+// waitForRecordActive lists the records of the domain until the created record appears with its
+// value, so that a sync only succeeds once MyraSec serves the records it created, e.g. for the
+// DNS01 challenges of cert-manager. It gives up after the configured timeout or when the context
+// is done. Without a timeout it returns right away.
+func (p *MyraSecDNSProvider) waitForRecordActive(ctx context.Context, zone string, domainID int, record *myrasec.DNSRecord) error {
+	if p.recordActiveTimeout <= 0 {
+		return nil
+	}
+	interval := p.recordActivePoll
+	if interval <= 0 {
+		interval = DefaultRecordActivePollInterval
+	}
+
+	start := time.Now()
+	deadline := time.NewTimer(p.recordActiveTimeout)
+	defer deadline.Stop()
+	for polls := 1; ; polls++ {
+		var records []myrasec.DNSRecord
+		err := traceAPICall(ctx, "ListDNSRecords", zone, nil, func() (err error) {
+			records, err = p.clientFor(ctx).ListDNSRecords(domainID, nil)
+			return err
+		})
+		if err != nil {
+			p.logger.Warn("Failed to list DNS records while waiting for the record to become active",
+				zap.String("name", record.Name),
+				zap.String("type", record.RecordType),
+				zap.Error(err))
+		} else if p.listsRecord(records, zone, record) {
+			elapsed := time.Since(start)
+			recordActivationSeconds.WithLabelValues("active").Observe(elapsed.Seconds())
+			p.logger.Info("Created DNS record is active",
+				zap.String("name", record.Name),
+				zap.String("type", record.RecordType),
+				zap.String("value", record.Value),
+				zap.Int("polls", polls),
+				zap.Duration("duration", elapsed))
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			recordActivationSeconds.WithLabelValues("aborted").Observe(time.Since(start).Seconds())
+			return fmt.Errorf("waiting for %s %s to become active: %w", record.RecordType, record.Name, ctx.Err())
+		case <-deadline.C:
+			recordActivationSeconds.WithLabelValues("timeout").Observe(time.Since(start).Seconds())
+			p.logger.Error("Created DNS record did not become active in time",
+				zap.String("name", record.Name),
+				zap.String("type", record.RecordType),
+				zap.String("value", record.Value),
+				zap.Int("polls", polls),
+				zap.Duration("timeout", p.recordActiveTimeout))
+			return fmt.Errorf("%s %s did not become active within %s", record.RecordType, record.Name, p.recordActiveTimeout)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// listsRecord reports whether the records of the zone contain the record with its value
+func (p *MyraSecDNSProvider) listsRecord(records []myrasec.DNSRecord, zone string, record *myrasec.DNSRecord) bool {
+	value := p.canonicalRecordValue(record.Value, record.RecordType)
+	for _, r := range records {
+		if sameName(recordName(r.Name, zone), recordName(record.Name, zone)) && strings.EqualFold(r.RecordType, record.RecordType) &&
+			p.canonicalRecordValue(r.Value, r.RecordType) == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package myrasecprovider
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// provisioningClient hides created records from the listings until they were listed hidden times,
+// like records MyraSec does not serve yet
+type provisioningClient struct {
+	*fakeMyraSecClient
+	hidden int
+
+	mu      sync.Mutex
+	pending map[int]int
+	lists   int
+}
+
+func (c *provisioningClient) CreateDNSRecord(record *myrasec.DNSRecord, domainId int) (*myrasec.DNSRecord, error) {
+	created, err := c.fakeMyraSecClient.CreateDNSRecord(record, domainId)
+	if err == nil {
+		c.mu.Lock()
+		c.pending[created.ID] = c.hidden
+		c.mu.Unlock()
+	}
+	return created, err
+}
+
+func (c *provisioningClient) ListDNSRecords(domainId int, params map[string]string) ([]myrasec.DNSRecord, error) {
+	records, err := c.fakeMyraSecClient.ListDNSRecords(domainId, params)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lists++
+	var served []myrasec.DNSRecord
+	for _, r := range records {
+		if c.pending[r.ID] > 0 {
+			c.pending[r.ID]--
+			continue
+		}
+		served = append(served, r)
+	}
+	return served, err
+}
+
+func TestWaitForRecordActive(t *testing.T) {
+	create := &plan.Changes{Create: []*endpoint.Endpoint{
+		endpoint.NewEndpoint("_acme-challenge.example.com", endpoint.RecordTypeTXT, "token"),
+	}}
+	newProvider := func(hidden int, timeout time.Duration) (*MyraSecDNSProvider, *provisioningClient, *observer.ObservedLogs) {
+		client := &provisioningClient{
+			fakeMyraSecClient: newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"}),
+			hidden:            hidden,
+			pending:           map[int]int{},
+		}
+		core, logs := observer.New(zap.InfoLevel)
+		p := newTestProvider(client)
+		p.logger = zap.New(core)
+		p.recordActiveTimeout = timeout
+		p.recordActivePoll = time.Millisecond
+		return p, client, logs
+	}
+
+	t.Run("appears on the second poll", func(t *testing.T) {
+		p, client, logs := newProvider(1, time.Second)
+		require.NoError(t, p.ApplyChanges(context.Background(), create))
+
+		// One listing of the zone before the changes, two polls for the challenge record
+		assert.Equal(t, 3, client.lists)
+		entries := logs.FilterMessage("Created DNS record is active").All()
+		require.Len(t, entries, 1)
+		assert.Equal(t, "_acme-challenge.example.com", entries[0].ContextMap()["name"])
+		assert.Equal(t, int64(2), entries[0].ContextMap()["polls"])
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		p, _, logs := newProvider(1000, 20*time.Millisecond)
+		err := p.ApplyChanges(context.Background(), create)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "did not become active")
+		assert.Equal(t, 1, logs.FilterMessage("Created DNS record did not become active in time").Len())
+	})
+
+	t.Run("respects the context", func(t *testing.T) {
+		p, _, _ := newProvider(1000, time.Minute)
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		err := p.ApplyChanges(ctx, create)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		p, client, _ := newProvider(1000, 0)
+		require.NoError(t, p.ApplyChanges(context.Background(), create))
+		assert.Equal(t, 1, client.lists, "only the zone is listed")
+	})
+}
@@ -0,0 +1,101 @@
+package myrasecprovider
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// adjustAction is the action label of the endpoints dropped by AdjustEndpoints in the metrics
+const adjustAction = "ADJUST"
+
+// AdjustEndpoints normalizes the desired endpoints before ExternalDNS plans the changes,
+// so that they compare equal to what Records returns for the same configuration.
+// Names are converted to punycode, lowercased and lose their trailing dot, as in Records. Endpoints outside the domain
+// filter, of unmanaged record types, with a set identifier or without targets are dropped, TTLs are clamped,
+// targets and the provider-specific properties normalized. Endpoints with invalid record values are dropped too, unless the plan
+// is to be refused. Every modification is logged at debug level.
+func (p *MyraSecDNSProvider) AdjustEndpoints(endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	endpoints = p.filterDomainEndpoints(endpoints)
+	endpoints = p.filterManagedEndpoints(endpoints)
+	endpoints = p.filterSetIdentifierEndpoints(endpoints)
+	endpoints = p.filterEmptyTargetEndpoints(endpoints)
+	for _, ep := range endpoints {
+		p.adjustProviderSpecific(ep)
+		p.adjustTTL(ep)
+		p.adjustTargets(ep)
+	}
+	return p.dropInvalidEndpoints(endpoints), nil
+}
+
+// normalizeDNSName returns the name in the form Records reports it: in punycode, lowercase and
+// without the trailing dot
+func normalizeDNSName(name string) string {
+	return stripTrailingDot(canonicalDNSName(name))
+}
+
+// canonicalDNSName returns the name without surrounding whitespace, in punycode and lowercase.
+// A trailing dot is kept, so an absolute name stays absolute. Names copied into annotations
+// would otherwise create records that differ from the existing ones only in case.
+func canonicalDNSName(name string) string {
+	return strings.ToLower(asciiName(strings.TrimSpace(name)))
+}
+
+// filterDomainEndpoints normalizes the names of the endpoints and drops the endpoints outside
+// the domain filter, which Records never reports.
+func (p *MyraSecDNSProvider) filterDomainEndpoints(endpoints []*endpoint.Endpoint) []*endpoint.Endpoint {
+	inFilter := endpoints[:0]
+	for _, ep := range endpoints {
+		if name := normalizeDNSName(ep.DNSName); name != ep.DNSName {
+			p.logger.Debug("Normalizing endpoint name",
+				zap.String("dnsName", ep.DNSName),
+				zap.String("adjusted_dnsName", name))
+			ep.DNSName = name
+		}
+		if !p.inDomainFilter(ep.DNSName) {
+			p.logger.Warn("Dropping endpoint outside the domain filter",
+				zap.String("dnsName", ep.DNSName),
+				zap.String("type", ep.RecordType))
+			outOfFilterChanges.WithLabelValues(adjustAction).Inc()
+			continue
+		}
+		inFilter = append(inFilter, ep)
+	}
+	return inFilter
+}
+
+// filterSetIdentifierEndpoints drops the endpoints with a set identifier. ExternalDNS sets it for
+// weighted and geo routing, which MyraSec does not support: endpoints differing only in their set
+// identifier would overwrite each other's records.
+func (p *MyraSecDNSProvider) filterSetIdentifierEndpoints(endpoints []*endpoint.Endpoint) []*endpoint.Endpoint {
+	supported := endpoints[:0]
+	for _, ep := range endpoints {
+		if ep.SetIdentifier != "" {
+			p.logger.Warn("Dropping endpoint with a set identifier, routing policies are not supported",
+				zap.String("dnsName", ep.DNSName),
+				zap.String("type", ep.RecordType),
+				zap.String("setIdentifier", ep.SetIdentifier))
+			setIdentifierEndpoints.WithLabelValues(ep.RecordType).Inc()
+			continue
+		}
+		supported = append(supported, ep)
+	}
+	return supported
+}
+
+// filterEmptyTargetEndpoints drops the endpoints without targets, e.g. of a Service whose load
+// balancer has no address yet. Creating them would only leave an ownership record behind.
+func (p *MyraSecDNSProvider) filterEmptyTargetEndpoints(endpoints []*endpoint.Endpoint) []*endpoint.Endpoint {
+	withTargets := endpoints[:0]
+	for _, ep := range endpoints {
+		if len(ep.Targets) == 0 {
+			p.logger.Warn("Dropping endpoint without targets",
+				zap.String("dnsName", ep.DNSName),
+				zap.String("type", ep.RecordType))
+			continue
+		}
+		withTargets = append(withTargets, ep)
+	}
+	return withTargets
+}
@@ -0,0 +1,108 @@
+package myrasecprovider
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestAdjustEndpoints(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	p := newTestProvider(newFakeMyraSecClient())
+	p.logger = zap.New(core)
+	p.domainFilter = endpoint.NewDomainFilterWithExclusions([]string{"example.com"}, []string{"internal.example.com"})
+	p.managedTypes = map[string]bool{endpoint.RecordTypeA: true, endpoint.RecordTypeCNAME: true, endpoint.RecordTypeTXT: true}
+	p.minTTL = DefaultMinTTL
+	p.maxTTL = DefaultMaxTTL
+
+	// Decoded from JSON, so names keep their case and trailing dot
+	endpoints := []*endpoint.Endpoint{
+		{DNSName: "App.Example.COM.", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}, RecordTTL: 30,
+			ProviderSpecific: endpoint.ProviderSpecific{{Name: providerSpecificProtection, Value: "False"}}},
+		{DNSName: "www.example.com", RecordType: endpoint.RecordTypeCNAME, Targets: endpoint.Targets{"app.example.com"}, RecordTTL: 172800},
+		{DNSName: "mail.example.com", RecordType: endpoint.RecordTypeMX, Targets: endpoint.Targets{"10 mx.example.com"}},
+		{DNSName: "app.example.org.", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+		{DNSName: "db.internal.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.0.0.1"}},
+		{DNSName: "bad.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"not-an-ip"}},
+		{DNSName: "TXT.example.com", RecordType: endpoint.RecordTypeTXT, Targets: endpoint.Targets{"hello"},
+			ProviderSpecific: endpoint.ProviderSpecific{{Name: "aws/weight", Value: "10"}}},
+	}
+
+	adjusted, err := p.AdjustEndpoints(endpoints)
+	require.NoError(t, err)
+
+	assert.Equal(t, []*endpoint.Endpoint{
+		{DNSName: "app.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}, RecordTTL: 300,
+			ProviderSpecific: endpoint.ProviderSpecific{{Name: providerSpecificProtection, Value: "false"}, {Name: providerSpecificEnabled, Value: "true"}}},
+		{DNSName: "www.example.com", RecordType: endpoint.RecordTypeCNAME, Targets: endpoint.Targets{"app.example.com"}, RecordTTL: 86400,
+			ProviderSpecific: endpoint.ProviderSpecific{{Name: providerSpecificProtection, Value: "true"}, {Name: providerSpecificEnabled, Value: "true"}}},
+		{DNSName: "txt.example.com", RecordType: endpoint.RecordTypeTXT, Targets: endpoint.Targets{"hello"},
+			ProviderSpecific: endpoint.ProviderSpecific{{Name: providerSpecificProtection, Value: "false"}, {Name: providerSpecificEnabled, Value: "true"}}},
+	}, adjusted)
+
+	assert.Equal(t, 3, logs.FilterMessage("Normalizing endpoint name").Len())
+	assert.Equal(t, 2, logs.FilterMessage("Dropping endpoint outside the domain filter").Len())
+	assert.Equal(t, 1, logs.FilterMessage("Dropping endpoint of an unmanaged record type").Len())
+	assert.Equal(t, 2, logs.FilterMessage("Adjusting endpoint TTL to the allowed range").Len())
+	assert.Equal(t, 4, logs.FilterMessage("Normalizing provider-specific properties").Len())
+	assert.Equal(t, 1, logs.FilterMessage("Dropping invalid endpoint").Len())
+
+	// Adjusting the result again changes nothing
+	logs.TakeAll()
+	again, err := p.AdjustEndpoints(append([]*endpoint.Endpoint(nil), adjusted...))
+	require.NoError(t, err)
+	assert.Equal(t, adjusted, again)
+	assert.Zero(t, logs.Len())
+}
+
+// TestAdjustEndpointsSetIdentifier tests that endpoints with a set identifier are dropped, so
+// endpoints differing only in it do not overwrite each other's records
+func TestAdjustEndpointsSetIdentifier(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	p := newTestProvider(newFakeMyraSecClient())
+	p.logger = zap.New(core)
+	p.domainFilter = endpoint.NewDomainFilter([]string{"example.com"})
+	p.minTTL = DefaultMinTTL
+	p.maxTTL = DefaultMaxTTL
+
+	before := testutil.ToFloat64(setIdentifierEndpoints.WithLabelValues(endpoint.RecordTypeA))
+	endpoints := []*endpoint.Endpoint{
+		{DNSName: "app.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}, SetIdentifier: "eu", RecordTTL: 300},
+		{DNSName: "app.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"5.6.7.8"}, SetIdentifier: "us", RecordTTL: 300},
+		{DNSName: "www.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}, RecordTTL: 300},
+	}
+
+	adjusted, err := p.AdjustEndpoints(endpoints)
+	require.NoError(t, err)
+	require.Len(t, adjusted, 1)
+	assert.Equal(t, "www.example.com", adjusted[0].DNSName)
+
+	dropped := logs.FilterMessage("Dropping endpoint with a set identifier, routing policies are not supported")
+	assert.Equal(t, 2, dropped.Len())
+	assert.Equal(t, zap.WarnLevel, dropped.All()[0].Level)
+	assert.Equal(t, before+2, testutil.ToFloat64(setIdentifierEndpoints.WithLabelValues(endpoint.RecordTypeA)))
+}
+
+func TestAdjustEndpointsEmptyTargets(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	p := newTestProvider(newFakeMyraSecClient())
+	p.logger = zap.New(core)
+	p.rejectInvalidEndpoints = true
+	p.minTTL = DefaultMinTTL
+	p.maxTTL = DefaultMaxTTL
+
+	adjusted, err := p.AdjustEndpoints([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("pending.example.com", endpoint.RecordTypeA),
+		endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+	})
+	require.NoError(t, err)
+	require.Len(t, adjusted, 1)
+	assert.Equal(t, "app.example.com", adjusted[0].DNSName)
+	assert.Equal(t, 1, logs.FilterMessage("Dropping endpoint without targets").Len())
+	assert.Zero(t, logs.FilterMessage("Invalid endpoint, the plan will be refused").Len())
+}
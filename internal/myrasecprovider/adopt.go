@@ -0,0 +1,93 @@
+package myrasecprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// preExistingRecords returns the records of the given type at dnsName that were not created by
+// ExternalDNS: the name has no ownership TXT record of any owner. Names owned by this instance
+// are reconciled as usual and return nil. foreignOwner is set when another ExternalDNS instance
+// owns the name.
+func (p *MyraSecDNSProvider) preExistingRecords(snapshot *zoneSnapshot, dnsName, recordType string) (records []myrasec.DNSRecord, foreignOwner string) {
+	all := snapshot.all()
+	for _, r := range all {
+		if r.RecordType != endpoint.RecordTypeTXT || !sameName(recordName(r.Name, snapshot.zoneName()), dnsName) {
+			continue
+		}
+		fields := parseOwnershipTXT(r.Value)
+		if fields["heritage"] != "external-dns" {
+			continue
+		}
+		if fields["external-dns/owner"] == p.owner {
+			return nil, ""
+		}
+		foreignOwner = fields["external-dns/owner"]
+	}
+	return p.findMatchingRecords(all, snapshot.zoneName(), dnsName, recordType), foreignOwner
+}
+
+// adoptRecords takes over the pre-existing records of ep whose values are desired: their TTL and
+// provider-specific properties are set to the desired values. The ownership TXT record is created
+// by the caller. It returns the canonical adopted values, which must not be created again.
+func (p *MyraSecDNSProvider) adoptRecords(ctx context.Context, snapshot *zoneSnapshot, dnsName string, ep *endpoint.Endpoint, ttl int, existing []myrasec.DNSRecord) (map[string]bool, error) {
+	desired := make(map[string]bool, len(ep.Targets))
+	for _, target := range ep.Targets {
+		desired[p.canonicalRecordValue(target, ep.RecordType)] = true
+	}
+
+	adopted := make(map[string]bool)
+	var errs []error
+	for i := range existing {
+		rec := &existing[i]
+		value := p.canonicalRecordValue(rec.Value, rec.RecordType)
+		if !desired[value] {
+			continue
+		}
+		if ctx.Err() != nil {
+			return adopted, abortErr(ctx, errs)
+		}
+
+		wanted := *rec
+		wanted.TTL = p.updatedTTL(rec, ep, ttl)
+		p.applyProviderSpecific(&wanted, ep)
+		if rec.TTL != wanted.TTL || rec.Active != wanted.Active || rec.Enabled != wanted.Enabled || rec.Comment != wanted.Comment {
+			if err := p.updateDNSRecord(ctx, snapshot, rec, &wanted); err != nil {
+				errs = append(errs, fmt.Errorf("adopting value %s: %w", rec.Value, err))
+				continue
+			}
+		}
+		adopted[value] = true
+
+		p.logger.Info("Adopted existing DNS record",
+			zap.String("dnsName", dnsName),
+			zap.String("type", rec.RecordType),
+			zap.String("value", rec.Value),
+			zap.Int("ttl", wanted.TTL))
+	}
+	return adopted, errors.Join(errs...)
+}
+
+// warnPreExisting logs once per name and type that a record is left alone because it was not
+// created by ExternalDNS, so repeated syncs do not repeat the warning.
+func (p *MyraSecDNSProvider) warnPreExisting(dnsName, recordType, foreignOwner string) {
+	if _, warned := p.preExistingWarned.LoadOrStore(recordType+" "+dnsName, struct{}{}); warned {
+		return
+	}
+	if foreignOwner != "" {
+		p.logger.Warn("Record is owned by another ExternalDNS instance, leaving it alone",
+			zap.String("dnsName", dnsName),
+			zap.String("type", recordType),
+			zap.String("owner", foreignOwner))
+		return
+	}
+	p.logger.Warn("Record already exists but was not created by ExternalDNS, leaving it alone. "+
+		"Delete it in MyraSec or run with --adopt-existing-records to manage it",
+		zap.String("dnsName", dnsName),
+		zap.String("type", recordType))
+}
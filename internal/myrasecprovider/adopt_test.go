@@ -0,0 +1,84 @@
+package myrasecprovider
+
+import (
+	"context"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestAdoptExistingRecords(t *testing.T) {
+	for _, adopt := range []bool{false, true} {
+		t.Run(map[bool]string{false: "adopt off", true: "adopt on"}[adopt], func(t *testing.T) {
+			client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+			// Created manually before ExternalDNS took over the zone
+			client.records[123] = []myrasec.DNSRecord{
+				{ID: 1, Name: "app.example.com", RecordType: endpoint.RecordTypeA, Value: "1.2.3.4", TTL: 3600, Active: false, Enabled: true},
+			}
+			core, logs := observer.New(zap.WarnLevel)
+			p := newTestProvider(client)
+			p.logger = zap.New(core)
+			p.adoptExisting = adopt
+
+			desired := endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.4", "5.6.7.8")
+			desired.RecordTTL = 600
+			changes := &plan.Changes{Create: []*endpoint.Endpoint{desired}}
+
+			// ExternalDNS repeats the create on every sync as long as the record is not reported
+			for i := 0; i < 3; i++ {
+				require.NoError(t, p.ApplyChanges(context.Background(), changes))
+			}
+
+			records, err := p.Records(context.Background())
+			require.NoError(t, err)
+			var targets []string
+			for _, ep := range records {
+				if stripTrailingDot(ep.DNSName) == "app.example.com" && ep.RecordType == endpoint.RecordTypeA {
+					targets = append(targets, ep.Targets...)
+				}
+			}
+
+			if !adopt {
+				assert.Empty(t, targets, "a record without ownership must not be taken over")
+				assert.Len(t, client.records[123], 1, "nothing must be created next to the existing record")
+				assert.Equal(t, 1, logs.FilterMessageSnippet("not created by ExternalDNS").Len(), "the warning is logged once")
+				return
+			}
+
+			assert.ElementsMatch(t, []string{"1.2.3.4", "5.6.7.8"}, targets, "the adopted records must be reported")
+
+			adopted := p.findMatchingRecords(client.records[123], "example.com", "app.example.com", endpoint.RecordTypeA)
+			require.Len(t, adopted, 2, "the existing record is kept and the missing value created")
+			for _, r := range adopted {
+				assert.Equal(t, 600, r.TTL, "value %s", r.Value)
+				assert.True(t, r.Active, "value %s", r.Value)
+				if r.Value == "1.2.3.4" {
+					assert.Equal(t, 1, r.ID, "the existing record is updated, not recreated")
+				}
+			}
+			assert.Zero(t, logs.FilterMessageSnippet("not created by ExternalDNS").Len())
+		})
+	}
+}
+
+func TestAdoptExistingRecordsOfOtherOwner(t *testing.T) {
+	client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+	client.records[123] = []myrasec.DNSRecord{
+		{ID: 1, Name: "app.example.com", RecordType: endpoint.RecordTypeA, Value: "1.2.3.4", TTL: 300},
+		{ID: 2, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: "heritage=external-dns,external-dns/owner=other", TTL: 300},
+	}
+	p := newTestProvider(client)
+	p.adoptExisting = true
+
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+	}))
+
+	assert.Len(t, client.records[123], 2, "records of another owner are never adopted")
+}
@@ -0,0 +1,113 @@
+package myrasecprovider
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// apiStatusPattern matches the HTTP status that myrasec-go puts into its error messages,
+// e.g. "Unauthorized (401)".
+var apiStatusPattern = regexp.MustCompile(`\((\d{3})\)`)
+
+// apiViolationPattern matches a line of the violation list in a myrasec-go error message,
+// e.g. "value: This value is already used.".
+var apiViolationPattern = regexp.MustCompile(`^([A-Za-z][\w.\[\]]*): (.*)$`)
+
+// apiErrorDetails holds what myrasec-go keeps of an API error response in its error message:
+// the HTTP status and the violation messages by property path.
+type apiErrorDetails struct {
+	status     int
+	violations map[string]string
+}
+
+// parseAPIError extracts the status and the violation list from an error of the MyraSec API client.
+// A message of the form "Bad Request (400):\nvalue: This value is already used.\n" results in the
+// status 400 and a violation of the property "value".
+func parseAPIError(err error) apiErrorDetails {
+	details := apiErrorDetails{violations: map[string]string{}}
+	lines := strings.Split(err.Error(), "\n")
+	if m := apiStatusPattern.FindStringSubmatch(lines[0]); m != nil {
+		details.status, _ = strconv.Atoi(m[1])
+	}
+	for _, line := range lines[1:] {
+		if m := apiViolationPattern.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			details.violations[m[1]] = m[2]
+		}
+	}
+	return details
+}
+
+// rejectsValue reports whether the API refused the request because of the value of the record.
+func (d apiErrorDetails) rejectsValue() bool {
+	if d.status != http.StatusBadRequest && d.status != http.StatusUnprocessableEntity {
+		return false
+	}
+	_, ok := d.violations["value"]
+	return ok
+}
+
+// createError maps the error of a failed record creation to ErrDuplicateRecord or ErrPrivateIPRejected.
+// The decision relies on the status and the violation path of the response, which do not depend on the
+// API language, together with the zone snapshot and the record value. The English messages are only
+// matched as a last resort.
+func (p *MyraSecDNSProvider) createError(err error, snapshot *zoneSnapshot, record *myrasec.DNSRecord) error {
+	privateIP := (record.RecordType == endpoint.RecordTypeA || record.RecordType == endpoint.RecordTypeAAAA) &&
+		isPrivateIP(record.Value)
+
+	if parseAPIError(err).rejectsValue() {
+		switch {
+		case snapshot.contains(record):
+			return fmt.Errorf("%w: %w", ErrDuplicateRecord, apiError(err))
+		case privateIP:
+			return fmt.Errorf("%w: %w", ErrPrivateIPRejected, apiError(err))
+		}
+	}
+
+	switch msg := err.Error(); {
+	case strings.Contains(msg, "This value is already used"):
+		p.logger.Warn("Recognized a duplicate record by the API error message only", zap.String("name", record.Name), zap.Error(err))
+		return fmt.Errorf("%w: %w", ErrDuplicateRecord, apiError(err))
+	case strings.Contains(msg, "private network range"):
+		p.logger.Warn("Recognized a rejected private IP address by the API error message only", zap.String("name", record.Name), zap.Error(err))
+		return fmt.Errorf("%w: %w", ErrPrivateIPRejected, apiError(err))
+	}
+	return apiError(err)
+}
+
+// apiError wraps an error of the MyraSec API client in the sentinel error of its kind, so the
+// HTTP handlers can tell authentication, rate limit and validation failures apart with errors.Is.
+func apiError(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, sentinel := range []error{ErrAuthenticationFailed, ErrRateLimited, ErrValidation, ErrAPIRequestFailed} {
+		if errors.Is(err, sentinel) {
+			return err
+		}
+	}
+
+	msg := err.Error()
+	var status int
+	if m := apiStatusPattern.FindStringSubmatch(msg); m != nil {
+		status, _ = strconv.Atoi(m[1])
+	}
+
+	sentinel := ErrAPIRequestFailed
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		sentinel = ErrAuthenticationFailed
+	case status == http.StatusTooManyRequests || strings.Contains(msg, myrasec.ErrorMsgRateLimitReached):
+		sentinel = ErrRateLimited
+	case status == http.StatusBadRequest || status == http.StatusUnprocessableEntity:
+		sentinel = ErrValidation
+	}
+	return fmt.Errorf("%w: %w", sentinel, err)
+}
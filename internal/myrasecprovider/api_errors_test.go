@@ -0,0 +1,93 @@
+package myrasecprovider
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/netguru/myra-external-dns-webhook/pkg/version"
+)
+
+// API error payloads in the format returned by the MyraSec API
+const (
+	duplicateValuePayload   = `{"error":true,"violationList":[{"propertyPath":"value","message":"This value is already used."}],"warningList":[],"targetObject":[]}`
+	duplicateValuePayloadDE = `{"error":true,"violationList":[{"propertyPath":"value","message":"Dieser Wert wird bereits verwendet."}],"warningList":[],"targetObject":[]}`
+	privateIPPayload        = `{"error":true,"violationList":[{"propertyPath":"value","message":"The IP address 10.0.0.1 is part of a private network range."}],"warningList":[],"targetObject":[]}`
+	privateIPPayloadDE      = `{"error":true,"violationList":[{"propertyPath":"value","message":"Die IP-Adresse 10.0.0.1 liegt in einem privaten Netzwerk."}],"warningList":[],"targetObject":[]}`
+	invalidTTLPayload       = `{"error":true,"violationList":[{"propertyPath":"ttl","message":"This value is not valid."}],"warningList":[],"targetObject":[]}`
+)
+
+// createErrorFor sends a record creation to a server answering with the given status and payload
+// and returns the error of the MyraSec API client.
+func createErrorFor(t *testing.T, status int, payload string, record *myrasec.DNSRecord) error {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(payload))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := newAPIClient("key", "secret", server.URL+"/%s", "de", version.UserAgent())
+	require.NoError(t, err)
+	_, err = client.CreateDNSRecord(record, 123)
+	require.Error(t, err)
+	return err
+}
+
+func TestParseAPIError(t *testing.T) {
+	details := parseAPIError(errors.New("Bad Request (400):\nvalue: This value is already used.\nttl: This value is not valid.\n"))
+	assert.Equal(t, http.StatusBadRequest, details.status)
+	assert.Equal(t, map[string]string{"value": "This value is already used.", "ttl": "This value is not valid."}, details.violations)
+	assert.True(t, details.rejectsValue())
+
+	details = parseAPIError(errors.New("Internal Server Error (500)"))
+	assert.Equal(t, http.StatusInternalServerError, details.status)
+	assert.Empty(t, details.violations)
+	assert.False(t, details.rejectsValue())
+}
+
+func TestCreateErrorMapping(t *testing.T) {
+	existing := myrasec.DNSRecord{ID: 1, Name: "app.example.com", RecordType: "A", Value: "1.1.1.1", TTL: 300}
+
+	for _, tt := range []struct {
+		name     string
+		status   int
+		payload  string
+		record   myrasec.DNSRecord
+		want     error
+		fallback bool
+	}{
+		{"duplicate", http.StatusBadRequest, duplicateValuePayload, existing, ErrDuplicateRecord, false},
+		{"duplicate in German", http.StatusBadRequest, duplicateValuePayloadDE, existing, ErrDuplicateRecord, false},
+		{"duplicate missing from the snapshot", http.StatusBadRequest, duplicateValuePayload,
+			myrasec.DNSRecord{Name: "new.example.com", RecordType: "A", Value: "2.2.2.2"}, ErrDuplicateRecord, true},
+		{"private IP", http.StatusBadRequest, privateIPPayload,
+			myrasec.DNSRecord{Name: "internal.example.com", RecordType: "A", Value: "10.0.0.1"}, ErrPrivateIPRejected, false},
+		{"private IP in German", http.StatusBadRequest, privateIPPayloadDE,
+			myrasec.DNSRecord{Name: "internal.example.com", RecordType: "A", Value: "10.0.0.1"}, ErrPrivateIPRejected, false},
+		{"other violation", http.StatusBadRequest, invalidTTLPayload,
+			myrasec.DNSRecord{Name: "app.example.com", RecordType: "A", Value: "1.1.1.1"}, ErrValidation, false},
+		{"server error", http.StatusBadGateway, ``,
+			myrasec.DNSRecord{Name: "app.example.com", RecordType: "A", Value: "1.1.1.1"}, ErrAPIRequestFailed, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.WarnLevel)
+			p := newTestProvider(newFakeMyraSecClient())
+			p.logger = zap.New(core)
+			snapshot := newZoneSnapshot(myrasec.Domain{ID: 123, Name: "example.com"}, []myrasec.DNSRecord{existing})
+
+			err := p.createError(createErrorFor(t, tt.status, tt.payload, &tt.record), snapshot, &tt.record)
+
+			assert.ErrorIs(t, err, tt.want)
+			assert.Equal(t, tt.fallback, logs.Len() > 0, "fallback to the message text")
+		})
+	}
+}
@@ -4,8 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/plan"
@@ -16,12 +20,29 @@ var ErrUpdateSlicesMismatch = errors.New("update slices have different lengths")
 
 // ApplyChangesWithWorkers applies DNS record changes using worker goroutines for parallel processing.
 // This is an alternative to the sequential ApplyChanges implementation.
-func (p *MyraSecDNSProvider) ApplyChangesWithWorkers(ctx context.Context, changes *plan.Changes) error {
+func (p *MyraSecDNSProvider) ApplyChangesWithWorkers(ctx context.Context, changes *plan.Changes) (err error) {
+	start := time.Now()
+
+	// Collect the mutations for the change notification and the sync status
+	report := &changeReport{}
+	ctx = withChangeReport(ctx, report)
+	defer func() { p.syncState.changesApplied(start, report.counts(), err) }()
+
+	// The whole call ends with the apply timeout, an abort before any task ran lists the plan
+	ctx, cancel := p.withApplyTimeout(ctx)
+	defer cancel()
+	defer func() {
+		if err != nil && applyTimedOut(ctx) && !errors.Is(err, ErrApplyTimeout) {
+			err = p.planTimeoutError(changes)
+		}
+	}()
+
 	p.logger.Info("Applying DNS changes with workers",
 		zap.Int("create", len(changes.Create)),
 		zap.Int("updateOld", len(changes.UpdateOld)),
 		zap.Int("updateNew", len(changes.UpdateNew)),
-		zap.Int("delete", len(changes.Delete)))
+		zap.Int("delete", len(changes.Delete)),
+		zap.Duration("timeout", p.applyTimeout))
 
 	// Validate input before proceeding
 	if len(changes.UpdateOld) != len(changes.UpdateNew) {
@@ -31,70 +52,208 @@ func (p *MyraSecDNSProvider) ApplyChangesWithWorkers(ctx context.Context, change
 		return ErrUpdateSlicesMismatch
 	}
 
+	// Several sources may produce the same hostname, their endpoints are applied once
+	changes = p.deduplicateChanges(changes)
+
 	// Check if there are any changes to apply
 	if len(changes.Create) == 0 && len(changes.UpdateNew) == 0 && len(changes.Delete) == 0 {
 		p.logger.Info("No changes to apply")
 		return nil
 	}
 
-	// Ensure we have a domain selected
-	selectedDomain, err := p.SelectDomain()
+	if err := p.checkChangeLimits(changes); err != nil {
+		return err
+	}
+
+	invalid, err := p.checkInvalidChanges(changes)
+	if err != nil {
+		return err
+	}
+
+	foreign, err := p.checkPlanOwner(changes)
+	if err != nil {
+		return err
+	}
+	owned := func(ep *endpoint.Endpoint) bool {
+		_, ok := foreign[ep]
+		return !ok
+	}
+
+	// Only one apply at a time mutates the zone, with the credentials current when it starts
+	release, err := p.acquireApply(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	ctx = p.withClient(ctx)
+
+	if err := ctx.Err(); err != nil {
+		p.logger.Warn("Not applying changes, context is done", zap.Error(err))
+		return err
+	}
+
+	// Ensure we have the domains selected
+	zones, err := p.selectZones()
 	if err != nil {
 		p.logger.Error("Failed to select domain", zap.Error(err))
 		return err
 	}
+	selectedDomain := zones[0]
 
 	p.logger.Debug("Selected domain for ApplyChangesWithWorkers method",
 		zap.String("domain_name", selectedDomain.Name),
-		zap.Int("domain_id", selectedDomain.ID))
+		zap.Int("domain_id", selectedDomain.ID),
+		zap.Int("domains", len(zones)))
 
-	// Set the domain name for use in worker processes
-	p.domainName = selectedDomain.Name
+	// Take one snapshot of the records per domain, shared by all tasks of the domain
+	snapshots := make(map[int]*zoneSnapshot, len(zones))
+	for i := range zones {
+		records, err := p.listDNSRecords(ctx, &zones[i])
+		if err != nil {
+			p.logger.Error("Failed to list DNS records", zap.String("domain", zones[i].Name), zap.Error(err))
+			return fmt.Errorf("failed to list DNS records: %w", apiError(err))
+		}
+		snapshots[zones[i].ID] = newZoneSnapshot(zones[i], records)
+	}
 
-	// Build tasks for all changes
+	// Every change goes to the most specific domain of its name
+	snapshotFor := func(ep *endpoint.Endpoint) *zoneSnapshot {
+		return snapshots[zoneFor(zones, ep.DNSName).ID]
+	}
+
+	// A CNAME cannot coexist with the records already in the zone either
+	if err := p.checkZoneConflicts(changes, snapshotFor, invalid); err != nil {
+		return err
+	}
+
+	// Names outside the domain filter are never changed, whatever the plan holds
+	inFilter := func(action string, ep *endpoint.Endpoint) bool {
+		return !p.outsideDomainFilter(action, snapshotFor(ep).zoneName(), ep)
+	}
+
+	// Build tasks for all changes, leaving out unmanaged record types, excluded domains, names
+	// outside the domain filter, invalid endpoints and endpoints of another owner
 	var tasks []changeTask
 
 	// Add creation tasks
 	for _, endpoint := range changes.Create {
-		tasks = append(tasks, changeTask{action: CREATE, change: endpoint})
+		if owned(endpoint) && p.acceptChange(CREATE, endpoint) && inFilter(CREATE, endpoint) && p.validChange(CREATE, endpoint, invalid) {
+			tasks = append(tasks, changeTask{action: CREATE, change: endpoint, snapshot: snapshotFor(endpoint)})
+		}
 	}
 
 	// Add update tasks
 	for i, endpoint := range changes.UpdateNew {
-		tasks = append(tasks, changeTask{
-			action:    UPDATE,
-			change:    endpoint,
-			oldChange: changes.UpdateOld[i],
-		})
+		if owned(changes.UpdateOld[i]) && owned(endpoint) && p.acceptChange(UPDATE, changes.UpdateOld[i]) && p.acceptChange(UPDATE, endpoint) &&
+			inFilter(UPDATE, changes.UpdateOld[i]) && inFilter(UPDATE, endpoint) && p.validChange(UPDATE, endpoint, invalid) {
+			tasks = append(tasks, changeTask{
+				action:    UPDATE,
+				change:    endpoint,
+				oldChange: changes.UpdateOld[i],
+				snapshot:  snapshotFor(endpoint),
+			})
+		}
 	}
 
 	// Add deletion tasks
 	for _, endpoint := range changes.Delete {
-		tasks = append(tasks, changeTask{action: DELETE, change: endpoint})
+		if owned(endpoint) && p.acceptChange(DELETE, endpoint) && inFilter(DELETE, endpoint) {
+			tasks = append(tasks, changeTask{action: DELETE, change: endpoint, snapshot: snapshotFor(endpoint)})
+		}
 	}
 
+	// In dry-run mode the full reconciliation runs, but mutations are only collected and reported
+	if p.dryRun {
+		report := &dryRunReport{}
+		ctx = withDryRunReport(ctx, report)
+		defer p.logDryRunSummary(report)
+		defer p.writeDryRunOutput(report)
+	}
+
+	// Changes skipped for the same reason are summarized once, not logged per record
+	skipped := &skipReport{}
+	ctx = withSkipReport(ctx, skipped)
+	defer p.logSkipSummary(skipped)
+
 	// Process all tasks with workers
-	return p.processTasksWithWorkers(ctx, tasks)
+	err = p.processTasksWithWorkers(ctx, tasks)
+	if p.notifier != nil && !report.empty() {
+		p.notifier.notify(report.notification(err, time.Since(start)))
+	}
+	return err
 }
 
-// processTasksWithWorkers processes DNS record tasks using multiple worker goroutines.
+// checkChangeLimits refuses a plan with more deletions or changes than allowed per sync, so that a
+// misconfigured source cannot wipe the zone. A limit of 0 is disabled.
+func (p *MyraSecDNSProvider) checkChangeLimits(changes *plan.Changes) error {
+	deletions := len(changes.Delete)
+	total := len(changes.Create) + len(changes.UpdateNew) + deletions
+
+	var err *ChangeLimitError
+	switch {
+	case p.maxDeletions > 0 && deletions > p.maxDeletions:
+		err = &ChangeLimitError{Kind: "deletions", Count: deletions, Limit: p.maxDeletions}
+	case p.maxChanges > 0 && total > p.maxChanges:
+		err = &ChangeLimitError{Kind: "changes", Count: total, Limit: p.maxChanges}
+	default:
+		return nil
+	}
+
+	rejectedPlans.WithLabelValues(err.Kind).Inc()
+	p.logger.Error("Refusing plan that exceeds the change limit, no changes were applied; investigate the ExternalDNS sources before raising the limit",
+		zap.String("limit", err.Kind),
+		zap.Int("count", err.Count),
+		zap.Int("max", err.Limit),
+		zap.Int("create", len(changes.Create)),
+		zap.Int("update", len(changes.UpdateNew)),
+		zap.Int("delete", deletions))
+	return err
+}
+
+// acceptChange reports whether the change of an endpoint may be applied.
+func (p *MyraSecDNSProvider) acceptChange(action string, ep *endpoint.Endpoint) bool {
+	return p.managedChange(action, ep) && !p.excludedChange(action, ep)
+}
+
+// validChange reports whether the desired endpoint of a change passed the validation. Invalid
+// changes are logged and skipped.
+func (p *MyraSecDNSProvider) validChange(action string, ep *endpoint.Endpoint, invalid map[*endpoint.Endpoint]string) bool {
+	reason, ok := invalid[ep]
+	if !ok {
+		return true
+	}
+	p.logger.Warn("Skipping change of an invalid endpoint",
+		zap.String("action", action),
+		zap.String("dnsName", ep.DNSName),
+		zap.String("type", ep.RecordType),
+		zap.String("reason", reason))
+	return false
+}
+
+// processTasksWithWorkers processes DNS record tasks using multiple worker goroutines. The tasks of
+// a name are applied one after the other by the same worker, deletions first, so that e.g. a CNAME
+// replaced by an A record is gone before the A record is created. Different names run in parallel.
 func (p *MyraSecDNSProvider) processTasksWithWorkers(ctx context.Context, tasks []changeTask) error {
 	if len(tasks) == 0 {
 		return nil
 	}
+	groups := groupTasksByName(tasks)
 
 	// Use configured worker count or default to 4
-	workerCount := 4
-	if len(tasks) < workerCount {
-		workerCount = len(tasks) // Don't create more workers than tasks
+	workerCount := p.workers
+	if workerCount <= 0 {
+		workerCount = DefaultWorkers
+	}
+	if len(groups) < workerCount {
+		workerCount = len(groups) // Don't create more workers than names
 	}
 
-	// Create channels for tasks and errors
-	taskChan := make(chan changeTask, len(tasks))
-	resultChan := make(chan error, len(tasks))
+	// Create channels for tasks and results
+	taskChan := make(chan []changeTask, len(groups))
+	resultChan := make(chan taskResult, len(tasks))
 
 	// Create a context that can be canceled
-	ctx, cancel := context.WithCancel(ctx)
+	workerCtx, cancel := context.WithCancel(ctx)
 	defer cancel() // Ensure all resources are cleaned up
 
 	// Start workers
@@ -103,17 +262,17 @@ func (p *MyraSecDNSProvider) processTasksWithWorkers(ctx context.Context, tasks
 		wg.Add(1)
 		go func(workerID int) {
 			defer wg.Done()
-			p.worker(ctx, workerID, taskChan, resultChan)
+			p.worker(workerCtx, cancel, workerID, taskChan, resultChan)
 		}(i)
 	}
 
 	// Send tasks to workers
 	go func() {
-		for _, task := range tasks {
+		for _, group := range groups {
 			select {
-			case taskChan <- task:
+			case taskChan <- group:
 				// Task sent successfully
-			case <-ctx.Done():
+			case <-workerCtx.Done():
 				// Context was canceled, stop sending tasks
 				return
 			}
@@ -121,69 +280,135 @@ func (p *MyraSecDNSProvider) processTasksWithWorkers(ctx context.Context, tasks
 		close(taskChan) // Signal that no more tasks will be sent
 	}()
 
-	// Collect results and capture first error
-	var firstErr error
-	for i := 0; i < len(tasks); i++ {
-		select {
-		case err := <-resultChan:
-			if err != nil && firstErr == nil {
-				firstErr = err
-				cancel() // Cancel context to stop other workers
-			}
-		case <-ctx.Done():
-			// Context was canceled externally
-			if firstErr == nil {
-				firstErr = ctx.Err()
-			}
-			break
+	// Collect results
+	var failures []*ChangeError
+	applied := make(map[changeTask]bool)
+	failed := make(map[changeTask]*ChangeError)
+	collect := func(result taskResult) {
+		if result.err == nil {
+			applied[result.task] = true
+			return
+		}
+		// Tasks aborted by the cancellation are not failures of their own
+		if workerCtx.Err() != nil && isContextError(result.err) {
+			return
+		}
+		failure := &ChangeError{
+			Action:     result.task.action,
+			DNSName:    result.task.change.DNSName,
+			RecordType: result.task.change.RecordType,
+			Err:        result.err,
+		}
+		failures = append(failures, failure)
+		failed[result.task] = failure
+	}
+
+	// The results are closed once all workers are done. Workers stop on cancellation without
+	// taking the remaining tasks, so the collection ends without waiting for every task.
+	go func() {
+		wg.Wait()
+		close(resultChan)
+	}()
+	for result := range resultChan {
+		collect(result)
+	}
+
+	if applyTimedOut(ctx) && len(applied) < len(tasks) {
+		return p.applyTimeoutError(tasks, applied, failed)
+	}
+
+	if len(failures) > 0 {
+		p.logger.Error("Failed to apply DNS changes", zap.Int("failed", len(failures)), zap.Int("total", len(tasks)))
+		if err := ctx.Err(); err != nil {
+			return errors.Join(err, &ChangesError{Failures: failures})
 		}
+		return &ChangesError{Failures: failures}
 	}
+	return ctx.Err()
+}
 
-	// Wait for all workers to finish
-	wg.Wait()
-	close(resultChan)
+// isContextError reports whether err was caused by a canceled or expired context
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
 
-	return firstErr
+// groupTasksByName groups the tasks by the name they change, in the order the names first appear.
+// Within a group the deletions come first, the other tasks keep their order.
+func groupTasksByName(tasks []changeTask) [][]changeTask {
+	var groups [][]changeTask
+	index := make(map[string]int)
+	for _, task := range tasks {
+		key := normalizeDNSName(task.change.DNSName)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], task)
+	}
+	for _, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool {
+			return group[i].action == DELETE && group[j].action != DELETE
+		})
+	}
+	return groups
 }
 
-// worker is a goroutine that processes tasks from the task channel
-func (p *MyraSecDNSProvider) worker(ctx context.Context, id int, taskChan <-chan changeTask, resultChan chan<- error) {
+// worker is a goroutine that processes the task groups from the task channel, the tasks of a group
+// in order. Unless continueOnError is set, a failed task cancels the remaining tasks.
+func (p *MyraSecDNSProvider) worker(ctx context.Context, cancel context.CancelFunc, id int, taskChan <-chan []changeTask, resultChan chan<- taskResult) {
 	for {
 		select {
-		case task, ok := <-taskChan:
+		case group, ok := <-taskChan:
 			if !ok {
 				// Channel closed, no more tasks
 				return
 			}
+			for _, task := range group {
+				// Don't start queued tasks once the context is done
+				if err := ctx.Err(); err != nil {
+					resultChan <- taskResult{task: task, err: err}
+					continue
+				}
 
-			// Skip actual API calls in dry-run mode
-			if p.dryRun {
-				p.logger.Info("Would process DNS record (dry-run)",
-					zap.Int("worker", id),
-					zap.String("action", task.action),
-					zap.String("name", task.change.DNSName),
-					zap.String("type", task.change.RecordType))
-				resultChan <- nil
-				continue
-			}
-
-			// Process the task based on action type
-			var err error
-			switch task.action {
-			case CREATE:
-				err = p.processCreateActions([]*endpoint.Endpoint{task.change})
-			case UPDATE:
-				err = p.processUpdateActions([]*endpoint.Endpoint{task.oldChange}, []*endpoint.Endpoint{task.change})
-			case DELETE:
-				err = p.processDeleteActions([]*endpoint.Endpoint{task.change})
-			default:
-				err = fmt.Errorf("unknown action: %s", task.action)
+				err := p.processTask(ctx, id, task)
+				if err != nil && !p.continueOnError {
+					cancel() // Stop the other workers before they pick up more tasks
+				}
+				resultChan <- taskResult{task: task, err: err}
 			}
 
-			resultChan <- err
-
 		case <-ctx.Done():
 			return
 		}
 	}
 }
+
+// processTask applies a single task based on its action type, in a span of its own
+func (p *MyraSecDNSProvider) processTask(ctx context.Context, id int, task changeTask) error {
+	p.logger.Debug("Processing DNS change",
+		zap.Int("worker", id),
+		zap.String("action", task.action),
+		zap.String("name", task.change.DNSName),
+		zap.String("type", task.change.RecordType))
+
+	taskCtx, span := startSpan(ctx, "change."+task.action, trace.WithAttributes(
+		attribute.String("dns.action", task.action),
+		attribute.String("dns.record.name", task.change.DNSName),
+		attribute.String("dns.record.type", task.change.RecordType),
+		attribute.Int("worker", id)))
+	var err error
+	switch task.action {
+	case CREATE:
+		err = p.processCreateActions(taskCtx, task.snapshot, []*endpoint.Endpoint{task.change})
+	case UPDATE:
+		err = p.processUpdateActions(taskCtx, task.snapshot, []*endpoint.Endpoint{task.oldChange}, []*endpoint.Endpoint{task.change})
+	case DELETE:
+		err = p.processDeleteActions(taskCtx, task.snapshot, []*endpoint.Endpoint{task.change})
+	default:
+		err = fmt.Errorf("unknown action: %s", task.action)
+	}
+	endSpan(span, err)
+	return err
+}
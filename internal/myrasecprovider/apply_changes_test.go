@@ -3,12 +3,23 @@ package myrasecprovider
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/netguru/myra-external-dns-webhook/pkg/api"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/plan"
 	"sigs.k8s.io/external-dns/provider"
@@ -59,16 +70,15 @@ func TestApplyChangesBasic(t *testing.T) {
 		{ID: 123, Name: "example.com"},
 	}
 
-	// Setup expectations for ListDomains
+	// Setup expectations for ListDomains and the zone snapshot
 	mockClient.On("ListDomains", mock.Anything).Return(domains, nil)
+	mockClient.On("ListDNSRecords", 123, mock.Anything).Return([]myrasec.DNSRecord{}, nil)
 
 	// Setup a test provider with the mock client
 	provider := &MyraSecDNSProvider{
 		BaseProvider: provider.BaseProvider{},
 		apiClient:    mockClient,
 		logger:       zap.NewNop(),
-		domainName:   "example.com",
-		domainId:     "123",
 		dryRun:       true, // Use dry run mode to avoid actual API calls
 		owner:        "test-owner",
 	}
@@ -107,8 +117,6 @@ func TestApplyChangesError(t *testing.T) {
 		BaseProvider: provider.BaseProvider{},
 		apiClient:    mockClient,
 		logger:       zap.NewNop(),
-		domainName:   "example.com",
-		domainId:     "123",
 		dryRun:       true,
 		owner:        "test-owner",
 	}
@@ -134,6 +142,40 @@ func TestApplyChangesError(t *testing.T) {
 	mockClient.AssertCalled(t, "ListDomains", mock.Anything)
 }
 
+// TestApplyChangesInvalidDomainID tests that a domain without a valid ID fails the sync instead
+// of turning the changes into silent no-ops
+func TestApplyChangesInvalidDomainID(t *testing.T) {
+	mockClient := new(MockMyraSecClient)
+	mockClient.On("ListDomains", mock.Anything).Return([]myrasec.Domain{{ID: 0, Name: "example.com"}}, nil)
+
+	p := newTestProvider(mockClient)
+	changes := &plan.Changes{
+		Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", "A", "1.1.1.1")},
+	}
+
+	err := p.ApplyChanges(context.Background(), changes)
+	assert.ErrorIs(t, err, ErrInvalidDomainID)
+	mockClient.AssertNotCalled(t, "DeleteDNSRecord", mock.Anything, mock.Anything)
+
+	// A record change in a zone without a valid domain ID fails as well
+	for name, change := range map[string]func(snapshot *zoneSnapshot, record *myrasec.DNSRecord) error{
+		"create": func(_ *zoneSnapshot, record *myrasec.DNSRecord) error {
+			return p.createDNSRecord(context.Background(), newZoneSnapshot(myrasec.Domain{Name: "example.com"}, nil), record.Name, record.RecordType, record.Value, record.TTL, nil)
+		},
+		"update": func(snapshot *zoneSnapshot, record *myrasec.DNSRecord) error {
+			return p.updateDNSRecord(context.Background(), snapshot, record, record)
+		},
+		"delete": func(snapshot *zoneSnapshot, record *myrasec.DNSRecord) error {
+			return p.deleteDNSRecord(context.Background(), snapshot, record)
+		},
+	} {
+		record := &myrasec.DNSRecord{ID: 11, Name: "app.example.com", RecordType: "A", Value: "1.1.1.1", TTL: 300}
+		assert.ErrorIs(t, change(newZoneSnapshot(myrasec.Domain{Name: "example.com"}, []myrasec.DNSRecord{*record}), record), ErrInvalidDomainID, name)
+	}
+	mockClient.AssertNotCalled(t, "CreateDNSRecord", mock.Anything, mock.Anything)
+	mockClient.AssertNotCalled(t, "UpdateDNSRecord", mock.Anything, mock.Anything)
+}
+
 // TestApplyChangesEmptyChanges tests that empty changes don't cause errors
 func TestApplyChangesEmptyChanges(t *testing.T) {
 	// Create a mock client
@@ -144,8 +186,6 @@ func TestApplyChangesEmptyChanges(t *testing.T) {
 		BaseProvider: provider.BaseProvider{},
 		apiClient:    mockClient,
 		logger:       zap.NewNop(),
-		domainName:   "example.com",
-		domainId:     "123",
 		dryRun:       true,
 		owner:        "test-owner",
 	}
@@ -175,8 +215,6 @@ func TestApplyChangesUnequalUpdateSlices(t *testing.T) {
 		BaseProvider: provider.BaseProvider{},
 		apiClient:    mockClient,
 		logger:       zap.NewNop(),
-		domainName:   "example.com",
-		domainId:     "123",
 		dryRun:       true,
 		owner:        "test-owner",
 	}
@@ -210,3 +248,1121 @@ func TestApplyChangesUnequalUpdateSlices(t *testing.T) {
 	// Assert an error occurred
 	assert.Error(t, err)
 }
+
+// cancellingClient cancels the context after the first record creation and counts mutating calls
+type cancellingClient struct {
+	*fakeMyraSecClient
+	cancel  context.CancelFunc
+	creates int
+	deletes int
+}
+
+func (c *cancellingClient) CreateDNSRecord(record *myrasec.DNSRecord, domainId int) (*myrasec.DNSRecord, error) {
+	c.creates++
+	c.cancel()
+	return c.fakeMyraSecClient.CreateDNSRecord(record, domainId)
+}
+
+func (c *cancellingClient) DeleteDNSRecord(record *myrasec.DNSRecord, domainId int) (*myrasec.DNSRecord, error) {
+	c.deletes++
+	return c.fakeMyraSecClient.DeleteDNSRecord(record, domainId)
+}
+
+// TestApplyChangesContextCancelledMidApply tests that no API calls are made once the context is cancelled
+func TestApplyChangesContextCancelledMidApply(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := &cancellingClient{
+		fakeMyraSecClient: newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"}),
+		cancel:            cancel,
+	}
+	p := newTestProvider(client)
+
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.1.1.1", "2.2.2.2", "3.3.3.3"),
+		},
+	}
+
+	err := p.ApplyChanges(ctx, changes)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	// Only the first target was created, neither the other targets nor the ownership TXT record
+	assert.Equal(t, 1, client.creates)
+	assert.Equal(t, 0, client.deletes)
+}
+
+// slowClient cancels the context on the first record creation and takes a while for every creation
+type slowClient struct {
+	*fakeMyraSecClient
+	cancel  context.CancelFunc
+	creates atomic.Int32
+}
+
+func (c *slowClient) CreateDNSRecord(record *myrasec.DNSRecord, domainId int) (*myrasec.DNSRecord, error) {
+	c.creates.Add(1)
+	c.cancel()
+	time.Sleep(10 * time.Millisecond)
+	return c.fakeMyraSecClient.CreateDNSRecord(record, domainId)
+}
+
+// TestProcessTasksCancelledMidApply tests that the workers stop taking tasks on cancellation and
+// the results are collected without waiting for the remaining tasks
+func TestProcessTasksCancelledMidApply(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := &slowClient{fakeMyraSecClient: newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"}), cancel: cancel}
+	p := newTestProvider(client)
+	p.workers = 2
+	p.continueOnError = true
+
+	snapshot := newZoneSnapshot(myrasec.Domain{ID: 123, Name: "example.com"}, nil)
+	var tasks []changeTask
+	for i := 0; i < 100; i++ {
+		ep := endpoint.NewEndpoint(fmt.Sprintf("app%d.example.com", i), endpoint.RecordTypeA, "1.2.3.4")
+		tasks = append(tasks, changeTask{action: CREATE, change: ep, snapshot: snapshot})
+	}
+
+	start := time.Now()
+	err := p.processTasksWithWorkers(ctx, tasks)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "the remaining tasks must not be processed")
+	assert.LessOrEqual(t, int(client.creates.Load()), 2, "only the tasks started before the cancellation call the API")
+}
+
+// TestApplyChangesContextAlreadyCancelled tests that a cancelled context aborts before calling the API
+func TestApplyChangesContextAlreadyCancelled(t *testing.T) {
+	mockClient := new(MockMyraSecClient)
+	p := newTestProvider(mockClient)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.1.1.1")},
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = p.Records(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	mockClient.AssertNotCalled(t, "ListDomains", mock.Anything)
+	mockClient.AssertNotCalled(t, "ListDNSRecords", mock.Anything, mock.Anything)
+}
+
+// TestApplyChangesDryRunReportsDiff tests that dry-run computes the full diff without mutating the zone
+func TestApplyChangesDryRunReportsDiff(t *testing.T) {
+	client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+	ownership := "heritage=external-dns,external-dns/owner=test-owner"
+	client.records[123] = []myrasec.DNSRecord{
+		{ID: 1, Name: "app.example.com", RecordType: "A", Value: "1.1.1.1", TTL: 300, Active: true, Enabled: true},
+		{ID: 2, Name: "app.example.com", RecordType: "TXT", Value: ownership, TTL: 300, Enabled: true},
+		{ID: 3, Name: "old.example.com", RecordType: "A", Value: "3.3.3.3", TTL: 300, Active: true, Enabled: true},
+		{ID: 4, Name: "old.example.com", RecordType: "TXT", Value: ownership, TTL: 300, Enabled: true},
+	}
+	before := append([]myrasec.DNSRecord(nil), client.records[123]...)
+
+	core, logs := observer.New(zap.InfoLevel)
+	p := newTestProvider(client)
+	p.logger = zap.New(core)
+	p.dryRun = true
+
+	changes := &plan.Changes{
+		Create:    []*endpoint.Endpoint{endpoint.NewEndpoint("new.example.com", "A", "2.2.2.2")},
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", "A", "1.1.1.1")},
+		UpdateNew: []*endpoint.Endpoint{
+			endpoint.NewEndpointWithTTL("app.example.com", "A", endpoint.TTL(600), "1.1.1.1", "1.1.1.2"),
+		},
+		Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("old.example.com", "A", "3.3.3.3")},
+	}
+
+	err := p.ApplyChanges(context.Background(), changes)
+	assert.NoError(t, err)
+	assert.Equal(t, before, client.records[123], "dry-run must not mutate records")
+
+	changesLogged := logs.FilterMessage("Would change DNS record (dry-run)").All()
+	// create A + ownership TXT, update TTL, create the new target, delete the old record and its ownership TXT
+	assert.Len(t, changesLogged, 6)
+
+	update := logs.FilterMessage("Would change DNS record (dry-run)").FilterField(zap.String("action", UPDATE)).All()
+	if assert.Len(t, update, 1) {
+		fields := update[0].ContextMap()
+		assert.Equal(t, int64(300), fields["old_ttl"])
+		assert.Equal(t, int64(600), fields["ttl"])
+	}
+
+	summary := logs.FilterMessage("Dry-run summary, no changes were made").All()
+	if assert.Len(t, summary, 1) {
+		fields := summary[0].ContextMap()
+		assert.Equal(t, int64(3), fields["create"])
+		assert.Equal(t, int64(1), fields["update"])
+		assert.Equal(t, int64(2), fields["delete"])
+		assert.Equal(t, int64(6), fields["total"])
+	}
+}
+
+// countingClient counts the zone listings made through the fake client
+type countingClient struct {
+	*fakeMyraSecClient
+	mu    sync.Mutex
+	lists int
+}
+
+func (c *countingClient) ListDNSRecords(domainId int, params map[string]string) ([]myrasec.DNSRecord, error) {
+	c.mu.Lock()
+	c.lists++
+	c.mu.Unlock()
+	return c.fakeMyraSecClient.ListDNSRecords(domainId, params)
+}
+
+// TestApplyChangesListsZoneOnce tests that a multi-task plan lists the zone records only once
+func TestApplyChangesListsZoneOnce(t *testing.T) {
+	client := &countingClient{fakeMyraSecClient: newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})}
+	ownership := "heritage=external-dns,external-dns/owner=test-owner"
+	for i, name := range []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com"} {
+		client.records[123] = append(client.records[123],
+			myrasec.DNSRecord{ID: 2*i + 1, Name: name, RecordType: "A", Value: "1.1.1.1", TTL: 300, Active: true, Enabled: true},
+			myrasec.DNSRecord{ID: 2*i + 2, Name: name, RecordType: "TXT", Value: ownership, TTL: 300, Enabled: true},
+		)
+	}
+	p := newTestProvider(client)
+
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("new.example.com", "A", "2.2.2.2")},
+		UpdateOld: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("a.example.com", "A", "1.1.1.1"),
+			endpoint.NewEndpoint("b.example.com", "A", "1.1.1.1"),
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("a.example.com", "A", "1.1.1.9"),
+			endpoint.NewEndpoint("b.example.com", "A", "1.1.1.9"),
+		},
+		Delete: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("c.example.com", "A", "1.1.1.1"),
+			endpoint.NewEndpoint("d.example.com", "A", "1.1.1.1"),
+		},
+	}
+
+	assert.NoError(t, p.ApplyChanges(context.Background(), changes))
+	assert.Equal(t, 1, client.lists)
+
+	records, _ := client.fakeMyraSecClient.ListDNSRecords(123, nil)
+	values := map[string]string{}
+	for _, r := range records {
+		if r.RecordType == "A" {
+			values[r.Name] = r.Value
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"a.example.com":   "1.1.1.9",
+		"b.example.com":   "1.1.1.9",
+		"new.example.com": "2.2.2.2",
+	}, values)
+}
+
+// TestApplyChangesUpdateDeletesCorrectRecord tests that an update removing one of two values deletes that record
+func TestApplyChangesUpdateDeletesCorrectRecord(t *testing.T) {
+	mockClient := new(MockMyraSecClient)
+	mockClient.On("ListDomains", mock.Anything).Return([]myrasec.Domain{{ID: 123, Name: "example.com"}}, nil)
+	mockClient.On("ListDNSRecords", 123, mock.Anything).Return([]myrasec.DNSRecord{
+		{ID: 11, Name: "app.example.com", RecordType: "A", Value: "1.1.1.1", TTL: 300, Active: true, Enabled: true},
+		{ID: 12, Name: "app.example.com", RecordType: "A", Value: "2.2.2.2", TTL: 300, Active: true, Enabled: true},
+		{ID: 13, Name: "app.example.com", RecordType: "TXT", Value: "heritage=external-dns,external-dns/owner=test-owner", TTL: 300, Enabled: true},
+	}, nil)
+	mockClient.On("DeleteDNSRecord", mock.MatchedBy(func(r *myrasec.DNSRecord) bool {
+		return r.ID == 11 && r.Value == "1.1.1.1"
+	}), 123).Return(&myrasec.DNSRecord{}, nil).Once()
+
+	p := newTestProvider(mockClient)
+
+	changes := &plan.Changes{
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", "A", "1.1.1.1", "2.2.2.2")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", "A", "2.2.2.2")},
+	}
+
+	assert.NoError(t, p.ApplyChanges(context.Background(), changes))
+	mockClient.AssertExpectations(t)
+	mockClient.AssertNumberOfCalls(t, "DeleteDNSRecord", 1)
+	mockClient.AssertNotCalled(t, "UpdateDNSRecord", mock.Anything, mock.Anything)
+}
+
+// TestApplyChangesDeleteRemovesOwnershipRecord tests the ownership TXT record cleanup on deletion
+func TestApplyChangesDeleteRemovesOwnershipRecord(t *testing.T) {
+	ownership := "heritage=external-dns,external-dns/owner=test-owner"
+
+	tests := []struct {
+		name      string
+		records   []myrasec.DNSRecord
+		delete    *endpoint.Endpoint
+		remaining []string
+	}{
+		{
+			name: "last record removes TXT",
+			records: []myrasec.DNSRecord{
+				{ID: 1, Name: "app.example.com", RecordType: "A", Value: "1.1.1.1", TTL: 300},
+				{ID: 2, Name: "app.example.com", RecordType: "A", Value: "2.2.2.2", TTL: 300},
+				{ID: 3, Name: "app.example.com", RecordType: "TXT", Value: ownership, TTL: 300},
+			},
+			delete:    endpoint.NewEndpoint("app.example.com", "A", "1.1.1.1", "2.2.2.2"),
+			remaining: nil,
+		},
+		{
+			name: "remaining target keeps TXT",
+			records: []myrasec.DNSRecord{
+				{ID: 1, Name: "app.example.com", RecordType: "A", Value: "1.1.1.1", TTL: 300},
+				{ID: 2, Name: "app.example.com", RecordType: "A", Value: "2.2.2.2", TTL: 300},
+				{ID: 3, Name: "app.example.com", RecordType: "TXT", Value: ownership, TTL: 300},
+			},
+			delete:    endpoint.NewEndpoint("app.example.com", "A", "1.1.1.1"),
+			remaining: []string{"A 2.2.2.2", "TXT " + ownership},
+		},
+		{
+			name: "other type still present keeps TXT",
+			records: []myrasec.DNSRecord{
+				{ID: 1, Name: "app.example.com", RecordType: "A", Value: "1.1.1.1", TTL: 300},
+				{ID: 2, Name: "app.example.com", RecordType: "AAAA", Value: "2001:db8::1", TTL: 300},
+				{ID: 3, Name: "app.example.com", RecordType: "TXT", Value: ownership, TTL: 300},
+			},
+			delete:    endpoint.NewEndpoint("app.example.com", "A", "1.1.1.1"),
+			remaining: []string{"AAAA 2001:db8::1", "TXT " + ownership},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+			client.records[123] = tt.records
+			p := newTestProvider(client)
+
+			err := p.ApplyChanges(context.Background(), &plan.Changes{Delete: []*endpoint.Endpoint{tt.delete}})
+			assert.NoError(t, err)
+
+			var remaining []string
+			for _, r := range client.records[123] {
+				remaining = append(remaining, r.RecordType+" "+r.Value)
+			}
+			assert.ElementsMatch(t, tt.remaining, remaining)
+		})
+	}
+}
+
+// TestApplyChangesEmptyTargets tests that endpoints without targets neither create an ownership
+// record nor remove the existing records, unless deleteOnEmptyTargets is set
+func TestApplyChangesEmptyTargets(t *testing.T) {
+	ownership := "heritage=external-dns,external-dns/owner=test-owner"
+
+	t.Run("create", func(t *testing.T) {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		core, logs := observer.New(zap.WarnLevel)
+		p := newTestProvider(client)
+		p.logger = zap.New(core)
+
+		err := p.ApplyChanges(context.Background(), &plan.Changes{
+			Create: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA)},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, client.records[123])
+		assert.Equal(t, 1, logs.FilterMessage("Skipping creation of endpoint without targets").Len())
+	})
+
+	for _, tt := range []struct {
+		name      string
+		delete    bool
+		remaining []string
+	}{
+		{name: "update keeps records", remaining: []string{"A 1.1.1.1", "TXT " + ownership}},
+		{name: "update with deleteOnEmptyTargets", delete: true, remaining: []string{"TXT " + ownership}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+			client.records[123] = []myrasec.DNSRecord{
+				{ID: 1, Name: "app.example.com", RecordType: endpoint.RecordTypeA, Value: "1.1.1.1", TTL: 300},
+				{ID: 2, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: ownership, TTL: 300},
+			}
+			p := newTestProvider(client)
+			p.deleteOnEmptyTargets = tt.delete
+
+			err := p.ApplyChanges(context.Background(), &plan.Changes{
+				UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.1.1.1")},
+				UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA)},
+			})
+			require.NoError(t, err)
+
+			var remaining []string
+			for _, r := range client.records[123] {
+				remaining = append(remaining, r.RecordType+" "+r.Value)
+			}
+			assert.ElementsMatch(t, tt.remaining, remaining)
+		})
+	}
+}
+
+// TestApplyChangesUpdateIdentityChange tests that update pairs changing name or type remove the old records
+func TestApplyChangesUpdateIdentityChange(t *testing.T) {
+	ownership := `"heritage=external-dns,external-dns/owner=test-owner"`
+
+	tests := []struct {
+		name      string
+		records   []myrasec.DNSRecord
+		old       *endpoint.Endpoint
+		new       *endpoint.Endpoint
+		remaining []string
+	}{
+		{
+			name: "rename",
+			records: []myrasec.DNSRecord{
+				{ID: 1, Name: "old.example.com", RecordType: "A", Value: "1.1.1.1", TTL: 300},
+				{ID: 2, Name: "old.example.com", RecordType: "TXT", Value: ownership, TTL: 300},
+			},
+			old: endpoint.NewEndpoint("old.example.com", "A", "1.1.1.1"),
+			new: endpoint.NewEndpoint("new.example.com", "A", "1.1.1.1"),
+			remaining: []string{
+				"new.example.com A 1.1.1.1",
+				"new.example.com TXT " + ownership,
+			},
+		},
+		{
+			name: "type change",
+			records: []myrasec.DNSRecord{
+				{ID: 1, Name: "app.example.com", RecordType: "CNAME", Value: "lb.example.net", TTL: 300},
+				{ID: 2, Name: "app.example.com", RecordType: "TXT", Value: ownership, TTL: 300},
+			},
+			old: endpoint.NewEndpoint("app.example.com", "CNAME", "lb.example.net"),
+			new: endpoint.NewEndpoint("app.example.com", "A", "1.1.1.1"),
+			remaining: []string{
+				"app.example.com A 1.1.1.1",
+				"app.example.com TXT " + ownership,
+			},
+		},
+		{
+			name: "rename of foreign records is skipped",
+			records: []myrasec.DNSRecord{
+				{ID: 1, Name: "old.example.com", RecordType: "A", Value: "1.1.1.1", TTL: 300},
+				{ID: 2, Name: "old.example.com", RecordType: "TXT", Value: "heritage=external-dns,external-dns/owner=other", TTL: 300},
+			},
+			old: endpoint.NewEndpoint("old.example.com", "A", "1.1.1.1"),
+			new: endpoint.NewEndpoint("new.example.com", "A", "1.1.1.1"),
+			remaining: []string{
+				"old.example.com A 1.1.1.1",
+				"old.example.com TXT heritage=external-dns,external-dns/owner=other",
+				"new.example.com A 1.1.1.1",
+				"new.example.com TXT " + ownership,
+			},
+		},
+		{
+			name: "no change",
+			records: []myrasec.DNSRecord{
+				{ID: 1, Name: "app.example.com", RecordType: "A", Value: "1.1.1.1", TTL: 300},
+				{ID: 2, Name: "app.example.com", RecordType: "TXT", Value: ownership, TTL: 300},
+			},
+			old: endpoint.NewEndpoint("app.example.com", "A", "1.1.1.1"),
+			new: endpoint.NewEndpoint("app.example.com", "A", "2.2.2.2"),
+			remaining: []string{
+				"app.example.com A 2.2.2.2",
+				"app.example.com TXT " + ownership,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+			client.records[123] = tt.records
+			p := newTestProvider(client)
+
+			err := p.ApplyChanges(context.Background(), &plan.Changes{
+				UpdateOld: []*endpoint.Endpoint{tt.old},
+				UpdateNew: []*endpoint.Endpoint{tt.new},
+			})
+			assert.NoError(t, err)
+
+			var remaining []string
+			for _, r := range client.records[123] {
+				remaining = append(remaining, r.Name+" "+r.RecordType+" "+r.Value)
+			}
+			assert.ElementsMatch(t, tt.remaining, remaining)
+		})
+	}
+}
+
+// TestConcurrentRecordsAndApplyChanges runs Records while changes are applied; run with -race
+func TestConcurrentRecordsAndApplyChanges(t *testing.T) {
+	client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+	client.records[123] = []myrasec.DNSRecord{
+		{ID: 1, Name: "app.example.com", RecordType: "A", Value: "1.1.1.1", TTL: 300},
+		{ID: 2, Name: "app.example.com", RecordType: "TXT", Value: "heritage=external-dns,external-dns/owner=test-owner", TTL: 300},
+	}
+	p := newTestProvider(client)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := p.Records(context.Background())
+			errs <- err
+		}()
+		go func(i int) {
+			defer wg.Done()
+			errs <- p.ApplyChanges(context.Background(), &plan.Changes{
+				Create:    []*endpoint.Endpoint{endpoint.NewEndpoint(fmt.Sprintf("host%d.example.com", i), "A", "2.2.2.2")},
+				UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", "A", "1.1.1.1")},
+				UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpointWithTTL("app.example.com", "A", endpoint.TTL(600+i), "1.1.1.1")},
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	endpoints, err := p.Records(context.Background())
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		assert.NotNil(t, findEndpoint(endpoints, fmt.Sprintf("host%d.example.com", i), "A"))
+	}
+}
+
+// rejectingClient rejects the creation of the listed values. With a barrier set, the rejected calls
+// wait for each other, so they are all in flight before the first failure cancels the remaining tasks.
+type rejectingClient struct {
+	*fakeMyraSecClient
+	reject  map[string]bool
+	barrier *sync.WaitGroup
+}
+
+func (c *rejectingClient) CreateDNSRecord(record *myrasec.DNSRecord, domainId int) (*myrasec.DNSRecord, error) {
+	if c.reject[record.Value] {
+		if c.barrier != nil {
+			c.barrier.Done()
+			c.barrier.Wait()
+		}
+		return nil, fmt.Errorf("value: invalid value %s", record.Value)
+	}
+	return c.fakeMyraSecClient.CreateDNSRecord(record, domainId)
+}
+
+// TestApplyChangesReportsAllFailures tests that every failed change is part of the returned error
+func TestApplyChangesReportsAllFailures(t *testing.T) {
+	client := &rejectingClient{
+		fakeMyraSecClient: newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"}),
+		reject:            map[string]bool{"10.0.0.1": true, "10.0.0.2": true},
+		barrier:           &sync.WaitGroup{},
+	}
+	client.barrier.Add(len(client.reject))
+	p := newTestProvider(client)
+	p.workers = 2
+
+	err := p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("one.example.com", endpoint.RecordTypeA, "10.0.0.1"),
+			endpoint.NewEndpoint("two.example.com", endpoint.RecordTypeA, "10.0.0.2"),
+		},
+	})
+
+	var changesErr *ChangesError
+	require.ErrorAs(t, err, &changesErr)
+	require.Len(t, changesErr.Failures, 2)
+
+	var failed []string
+	for _, f := range changesErr.Failures {
+		assert.Equal(t, CREATE, f.Action)
+		assert.Equal(t, endpoint.RecordTypeA, f.RecordType)
+		failed = append(failed, f.DNSName)
+	}
+	assert.ElementsMatch(t, []string{"one.example.com", "two.example.com"}, failed)
+	assert.ErrorContains(t, err, "2 DNS changes failed")
+	assert.ErrorContains(t, err, "invalid value 10.0.0.1")
+	assert.ErrorContains(t, err, "invalid value 10.0.0.2")
+}
+
+// TestApplyChangesContinueOnError tests that the changes after a failed one are only applied with continueOnError
+func TestApplyChangesContinueOnError(t *testing.T) {
+	changes := func() *plan.Changes {
+		return &plan.Changes{
+			Create: []*endpoint.Endpoint{
+				endpoint.NewEndpoint("bad1.example.com", endpoint.RecordTypeA, "10.0.0.1"),
+				endpoint.NewEndpoint("good1.example.com", endpoint.RecordTypeA, "10.0.1.1"),
+				endpoint.NewEndpoint("bad2.example.com", endpoint.RecordTypeA, "10.0.0.2"),
+				endpoint.NewEndpoint("good2.example.com", endpoint.RecordTypeA, "10.0.1.2"),
+				endpoint.NewEndpoint("good3.example.com", endpoint.RecordTypeA, "10.0.1.3"),
+			},
+		}
+	}
+	created := func(client *rejectingClient) []string {
+		var names []string
+		for _, r := range client.records[123] {
+			if r.RecordType == endpoint.RecordTypeA {
+				names = append(names, r.Name)
+			}
+		}
+		return names
+	}
+
+	for _, tt := range []struct {
+		name            string
+		continueOnError bool
+		created         []string
+		failed          []string
+	}{
+		{
+			name:    "fail fast",
+			created: nil,
+			failed:  []string{"bad1.example.com"},
+		},
+		{
+			name:            "continue on error",
+			continueOnError: true,
+			created:         []string{"good1.example.com", "good2.example.com", "good3.example.com"},
+			failed:          []string{"bad1.example.com", "bad2.example.com"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &rejectingClient{
+				fakeMyraSecClient: newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"}),
+				reject:            map[string]bool{"10.0.0.1": true, "10.0.0.2": true},
+			}
+			p := newTestProvider(client)
+			p.workers = 1 // Process the tasks in plan order
+			p.continueOnError = tt.continueOnError
+
+			err := p.ApplyChanges(context.Background(), changes())
+
+			var changesErr *ChangesError
+			require.ErrorAs(t, err, &changesErr)
+			var failed []string
+			for _, f := range changesErr.Failures {
+				failed = append(failed, f.DNSName)
+			}
+			assert.ElementsMatch(t, tt.failed, failed)
+			assert.ElementsMatch(t, tt.created, created(client))
+		})
+	}
+}
+
+// TestApplyChangesChangeLimits tests that plans above the deletion or change limit are refused without changes
+func TestApplyChangesChangeLimits(t *testing.T) {
+	seed := func() *fakeMyraSecClient {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		for i := 1; i <= 3; i++ {
+			name := fmt.Sprintf("app%d.example.com", i)
+			client.records[123] = append(client.records[123],
+				myrasec.DNSRecord{ID: i, Name: name, RecordType: endpoint.RecordTypeA, Value: "1.2.3.4", TTL: 300, Enabled: true},
+				myrasec.DNSRecord{ID: 10 + i, Name: name, RecordType: endpoint.RecordTypeTXT, Value: "heritage=external-dns,external-dns/owner=test-owner", TTL: 300, Enabled: true})
+		}
+		return client
+	}
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("new.example.com", endpoint.RecordTypeA, "1.2.3.5")},
+		Delete: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("app1.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("app2.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("app3.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+	}
+
+	for _, tt := range []struct {
+		name         string
+		maxDeletions int
+		maxChanges   int
+		kind         string
+	}{
+		{name: "disabled"},
+		{name: "deletions at the limit", maxDeletions: 3, maxChanges: 4},
+		{name: "too many deletions", maxDeletions: 2, kind: "deletions"},
+		{name: "too many changes", maxChanges: 3, kind: "changes"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			client := seed()
+			p := newTestProvider(client)
+			p.maxDeletions = tt.maxDeletions
+			p.maxChanges = tt.maxChanges
+
+			err := p.ApplyChanges(context.Background(), changes)
+			if tt.kind == "" {
+				require.NoError(t, err)
+				assert.Len(t, client.records[123], 2)
+				return
+			}
+
+			require.ErrorIs(t, err, ErrChangeLimitExceeded)
+			var limitErr *ChangeLimitError
+			require.ErrorAs(t, err, &limitErr)
+			assert.Equal(t, tt.kind, limitErr.Kind)
+			assert.Len(t, client.records[123], 6, "a refused plan must not change the zone")
+		})
+	}
+}
+
+// blockingClient holds every record creation until release is closed and signals each started creation
+type blockingClient struct {
+	*fakeMyraSecClient
+	started  chan string
+	release  chan struct{}
+	inFlight atomic.Int32
+	maxSeen  atomic.Int32
+}
+
+func (c *blockingClient) CreateDNSRecord(record *myrasec.DNSRecord, domainId int) (*myrasec.DNSRecord, error) {
+	n := c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+	for {
+		seen := c.maxSeen.Load()
+		if n <= seen || c.maxSeen.CompareAndSwap(seen, n) {
+			break
+		}
+	}
+	c.started <- record.Name
+	<-c.release
+	return c.fakeMyraSecClient.CreateDNSRecord(record, domainId)
+}
+
+// TestApplyChangesSerialized tests that a second apply does not touch the zone while the first one runs
+func TestApplyChangesSerialized(t *testing.T) {
+	newPlan := func(name string) *plan.Changes {
+		return &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint(name, endpoint.RecordTypeA, "1.2.3.4")}}
+	}
+
+	t.Run("wait", func(t *testing.T) {
+		client := &blockingClient{
+			fakeMyraSecClient: newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"}),
+			started:           make(chan string, 10),
+			release:           make(chan struct{}),
+		}
+		p := newTestProvider(client)
+		p.workers = 1
+
+		first := make(chan error, 1)
+		go func() { first <- p.ApplyChanges(context.Background(), newPlan("one.example.com")) }()
+		assert.Equal(t, "one.example.com", <-client.started)
+		assert.Equal(t, 1.0, testutil.ToFloat64(applyInProgress))
+
+		// A second apply waits for the first one, bounded by its context
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		err := p.ApplyChanges(ctx, newPlan("two.example.com"))
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		second := make(chan error, 1)
+		go func() { second <- p.ApplyChanges(context.Background(), newPlan("two.example.com")) }()
+		select {
+		case name := <-client.started:
+			t.Fatalf("second apply created %s while the first one was running", name)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(client.release)
+		require.NoError(t, <-first)
+		require.NoError(t, <-second)
+		assert.Equal(t, int32(1), client.maxSeen.Load(), "creations ran concurrently")
+		assert.Equal(t, 0.0, testutil.ToFloat64(applyInProgress))
+
+		endpoints, err := p.Records(context.Background())
+		require.NoError(t, err)
+		assert.NotNil(t, findEndpoint(endpoints, "one.example.com", endpoint.RecordTypeA))
+		assert.NotNil(t, findEndpoint(endpoints, "two.example.com", endpoint.RecordTypeA))
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		client := &blockingClient{
+			fakeMyraSecClient: newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"}),
+			started:           make(chan string, 10),
+			release:           make(chan struct{}),
+		}
+		p := newTestProvider(client)
+		p.workers = 1
+		p.rejectConcurrentApply = true
+
+		first := make(chan error, 1)
+		go func() { first <- p.ApplyChanges(context.Background(), newPlan("one.example.com")) }()
+		<-client.started
+
+		err := p.ApplyChanges(context.Background(), newPlan("two.example.com"))
+		require.ErrorIs(t, err, ErrApplyInProgress)
+
+		close(client.release)
+		require.NoError(t, <-first)
+
+		// Once the first apply is done, the next one runs
+		require.NoError(t, p.ApplyChanges(context.Background(), newPlan("two.example.com")))
+	})
+}
+
+// TestApplyChangesHandlerConcurrentPolicy tests the response of the webhook to a sync arriving
+// while another one is applying changes, for both policies
+func TestApplyChangesHandlerConcurrentPolicy(t *testing.T) {
+	post := func(app api.Api, name string) *http.Response {
+		body := fmt.Sprintf(`{"Create":[{"dnsName":%q,"recordType":"A","targets":["1.2.3.4"]}]}`, name)
+		resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/records", strings.NewReader(body)), -1)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	for _, policy := range []string{ConcurrentApplyWait, ConcurrentApplyReject} {
+		t.Run(policy, func(t *testing.T) {
+			client := &blockingClient{
+				fakeMyraSecClient: newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"}),
+				started:           make(chan string, 10),
+				release:           make(chan struct{}),
+			}
+			p := newTestProvider(client)
+			p.workers = 1
+			reject, err := rejectConcurrentApply(policy)
+			require.NoError(t, err)
+			p.rejectConcurrentApply = reject
+			app := api.New(zap.NewNop(), p)
+
+			first := make(chan *http.Response, 1)
+			go func() { first <- post(app, "one.example.com") }()
+			<-client.started
+
+			second := make(chan *http.Response, 1)
+			go func() { second <- post(app, "two.example.com") }()
+
+			if policy == ConcurrentApplyReject {
+				resp := <-second
+				assert.Equal(t, http.StatusConflict, resp.StatusCode)
+				assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+				close(client.release)
+				assert.Equal(t, http.StatusNoContent, (<-first).StatusCode)
+				return
+			}
+
+			select {
+			case resp := <-second:
+				t.Fatalf("second sync returned %d while the first one was running", resp.StatusCode)
+			case name := <-client.started:
+				t.Fatalf("second sync created %s while the first one was running", name)
+			case <-time.After(50 * time.Millisecond):
+			}
+			close(client.release)
+			assert.Equal(t, http.StatusNoContent, (<-first).StatusCode)
+			assert.Equal(t, http.StatusNoContent, (<-second).StatusCode)
+		})
+	}
+}
+
+// TestDrainWaitsForApplyInProgress tests that a shutdown lets the apply in progress finish together
+// with its ownership record and refuses the applies that did not start yet
+func TestDrainWaitsForApplyInProgress(t *testing.T) {
+	newPlan := func(name string) *plan.Changes {
+		return &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint(name, endpoint.RecordTypeA, "1.2.3.4")}}
+	}
+	client := &blockingClient{
+		fakeMyraSecClient: newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"}),
+		started:           make(chan string, 10),
+		release:           make(chan struct{}),
+	}
+	p := newTestProvider(client)
+	p.workers = 1
+
+	first := make(chan error, 1)
+	go func() { first <- p.ApplyChanges(context.Background(), newPlan("one.example.com")) }()
+	<-client.started
+
+	// A second apply waits for the first one
+	second := make(chan error, 1)
+	go func() { second <- p.ApplyChanges(context.Background(), newPlan("two.example.com")) }()
+
+	drained := make(chan error, 1)
+	go func() { drained <- p.Drain(context.Background()) }()
+
+	// The waiting apply is refused, the running one is not interrupted
+	require.ErrorIs(t, <-second, ErrShuttingDown)
+	select {
+	case err := <-drained:
+		t.Fatalf("drain returned while the apply was running: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	go func() {
+		for range client.started {
+		}
+	}()
+	close(client.release)
+	require.NoError(t, <-drained)
+
+	// When Drain returns, the apply has finished, including the ownership record
+	assert.Len(t, p.findMatchingRecords(client.records[123], "example.com", "one.example.com", endpoint.RecordTypeA), 1)
+	assert.Len(t, p.findMatchingRecords(client.records[123], "example.com", "one.example.com", endpoint.RecordTypeTXT), 1)
+	assert.Empty(t, p.findMatchingRecords(client.records[123], "example.com", "two.example.com", endpoint.RecordTypeA))
+	require.NoError(t, <-first)
+
+	// No apply starts after the drain
+	require.ErrorIs(t, p.ApplyChanges(context.Background(), newPlan("three.example.com")), ErrShuttingDown)
+}
+
+func TestDrainTimeout(t *testing.T) {
+	client := &blockingClient{
+		fakeMyraSecClient: newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"}),
+		started:           make(chan string, 10),
+		release:           make(chan struct{}),
+	}
+	defer close(client.release)
+	p := newTestProvider(client)
+
+	go func() {
+		_ = p.ApplyChanges(context.Background(), &plan.Changes{
+			Create: []*endpoint.Endpoint{endpoint.NewEndpoint("one.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+		})
+	}()
+	<-client.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, p.Drain(ctx), context.DeadlineExceeded)
+}
+
+// orderingClient records the order of the mutations and takes a while to delete, so a create
+// racing a delete of the same name would come first
+type orderingClient struct {
+	*fakeMyraSecClient
+	mu    sync.Mutex
+	calls []string
+}
+
+func (c *orderingClient) record(call string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, call)
+}
+
+func (c *orderingClient) CreateDNSRecord(record *myrasec.DNSRecord, domainId int) (*myrasec.DNSRecord, error) {
+	c.record("create " + record.RecordType + " " + record.Name)
+	return c.fakeMyraSecClient.CreateDNSRecord(record, domainId)
+}
+
+func (c *orderingClient) DeleteDNSRecord(record *myrasec.DNSRecord, domainId int) (*myrasec.DNSRecord, error) {
+	time.Sleep(20 * time.Millisecond)
+	c.record("delete " + record.RecordType + " " + record.Name)
+	return c.fakeMyraSecClient.DeleteDNSRecord(record, domainId)
+}
+
+// TestApplyChangesDeletesBeforeCreatesOfTheSameName tests that the deletions of a name finish before
+// its creations start, while other names are still applied in parallel
+func TestApplyChangesDeletesBeforeCreatesOfTheSameName(t *testing.T) {
+	client := &orderingClient{fakeMyraSecClient: newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})}
+	client.records[123] = []myrasec.DNSRecord{
+		{ID: 1, Name: "app.example.com", RecordType: endpoint.RecordTypeCNAME, Value: "lb.example.net", TTL: 300},
+		{ID: 2, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: `"heritage=external-dns,external-dns/owner=test-owner"`, TTL: 300},
+	}
+	p := newTestProvider(client)
+	p.workers = 4
+
+	// The plan lists the creation first, as ExternalDNS does
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+			endpoint.NewEndpoint("other.example.com", endpoint.RecordTypeA, "2.2.2.2"),
+		},
+		Delete: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeCNAME, "lb.example.net"),
+		},
+	}))
+
+	index := func(call string) int {
+		for i, c := range client.calls {
+			if c == call {
+				return i
+			}
+		}
+		t.Fatalf("no call %q in %v", call, client.calls)
+		return -1
+	}
+	assert.Less(t, index("delete CNAME app.example.com"), index("create A app.example.com"))
+	assert.Less(t, index("create A other.example.com"), index("delete CNAME app.example.com"),
+		"other names do not wait for the deletion")
+}
+
+func TestGroupTasksByName(t *testing.T) {
+	task := func(action, dnsName string) changeTask {
+		return changeTask{action: action, change: endpoint.NewEndpoint(dnsName, endpoint.RecordTypeA, "1.1.1.1")}
+	}
+	groups := groupTasksByName([]changeTask{
+		task(CREATE, "app.example.com"),
+		task(UPDATE, "www.example.com"),
+		task(DELETE, "App.Example.com."),
+		task(UPDATE, "app.example.com"),
+		task(DELETE, "app.example.com"),
+	})
+
+	var got [][]string
+	for _, group := range groups {
+		var actions []string
+		for _, task := range group {
+			actions = append(actions, task.action+" "+task.change.DNSName)
+		}
+		got = append(got, actions)
+	}
+	assert.Equal(t, [][]string{
+		{"DELETE App.Example.com", "DELETE app.example.com", "CREATE app.example.com", "UPDATE app.example.com"},
+		{"UPDATE www.example.com"},
+	}, got)
+}
+
+// TestApplyChangesTypeChange tests that an update pair changing the record type removes the records
+// of the old type before the new ones are created and keeps the ownership record of the name
+func TestApplyChangesTypeChange(t *testing.T) {
+	ownership := `"heritage=external-dns,external-dns/owner=test-owner"`
+	tests := []struct {
+		name     string
+		old      myrasec.DNSRecord
+		oldEp    *endpoint.Endpoint
+		newEp    *endpoint.Endpoint
+		wantCall string
+	}{
+		{
+			name:     "CNAME to A",
+			old:      myrasec.DNSRecord{ID: 1, Name: "app.example.com", RecordType: endpoint.RecordTypeCNAME, Value: "lb.example.net", TTL: 300},
+			oldEp:    endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeCNAME, "lb.example.net"),
+			newEp:    endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+			wantCall: "create A app.example.com",
+		},
+		{
+			name:     "A to CNAME",
+			old:      myrasec.DNSRecord{ID: 1, Name: "app.example.com", RecordType: endpoint.RecordTypeA, Value: "1.1.1.1", TTL: 300},
+			oldEp:    endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+			newEp:    endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeCNAME, "lb.example.net"),
+			wantCall: "create CNAME app.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &orderingClient{fakeMyraSecClient: newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})}
+			client.records[123] = []myrasec.DNSRecord{
+				tt.old,
+				{ID: 2, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: ownership, TTL: 300},
+			}
+			p := newTestProvider(client)
+
+			require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+				UpdateOld: []*endpoint.Endpoint{tt.oldEp},
+				UpdateNew: []*endpoint.Endpoint{tt.newEp},
+			}))
+			assert.Equal(t, []string{"delete " + tt.old.RecordType + " app.example.com", tt.wantCall}, client.calls,
+				"the old records are deleted first and the ownership record is kept")
+
+			var remaining []string
+			for _, r := range client.records[123] {
+				remaining = append(remaining, r.RecordType+" "+r.Value)
+			}
+			assert.ElementsMatch(t, []string{tt.newEp.RecordType + " " + tt.newEp.Targets[0], "TXT " + ownership}, remaining)
+		})
+	}
+
+	t.Run("foreign records are not touched", func(t *testing.T) {
+		client := &orderingClient{fakeMyraSecClient: newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})}
+		client.records[123] = []myrasec.DNSRecord{
+			{ID: 1, Name: "app.example.com", RecordType: endpoint.RecordTypeCNAME, Value: "lb.example.net", TTL: 300},
+			{ID: 2, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: `"heritage=external-dns,external-dns/owner=other"`, TTL: 300},
+		}
+		p := newTestProvider(client)
+
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+			UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeCNAME, "lb.example.net")},
+			UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.1.1.1")},
+		}))
+		assert.Empty(t, client.calls)
+	})
+
+	t.Run("failed creation is retried by the next sync", func(t *testing.T) {
+		client := &rejectingClient{
+			fakeMyraSecClient: newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"}),
+			reject:            map[string]bool{"1.1.1.1": true},
+		}
+		client.records[123] = []myrasec.DNSRecord{
+			{ID: 1, Name: "app.example.com", RecordType: endpoint.RecordTypeCNAME, Value: "lb.example.net", TTL: 300},
+			{ID: 2, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: ownership, TTL: 300},
+		}
+		p := newTestProvider(client)
+
+		err := p.ApplyChanges(context.Background(), &plan.Changes{
+			UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeCNAME, "lb.example.net")},
+			UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.1.1.1")},
+		})
+		require.Error(t, err)
+		require.Len(t, client.records[123], 1, "the CNAME is gone, the ownership record is kept")
+		assert.Equal(t, endpoint.RecordTypeTXT, client.records[123][0].RecordType)
+
+		// The name is still owned, the next sync creates the A record
+		client.reject = nil
+		records, err := p.Records(context.Background())
+		require.NoError(t, err)
+		assert.Nil(t, findEndpoint(records, "app.example.com", endpoint.RecordTypeCNAME))
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+			Create: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.1.1.1")},
+		}))
+		assert.Len(t, p.findMatchingRecords(client.records[123], "example.com", "app.example.com", endpoint.RecordTypeA), 1)
+		assert.Len(t, p.findMatchingRecords(client.records[123], "example.com", "app.example.com", endpoint.RecordTypeTXT), 1)
+	})
+}
+
+// TestApplyChangesKeepsRecordsNotAttributable tests that records at a name of this owner are only
+// deleted if they can be attributed to it, and kept with a warning otherwise
+func TestApplyChangesKeepsRecordsNotAttributable(t *testing.T) {
+	ours := `"heritage=external-dns,external-dns/owner=test-owner"`
+	foreign := `"heritage=external-dns,external-dns/owner=other"`
+	values := func(client *fakeMyraSecClient) []string {
+		var values []string
+		for _, r := range client.records[123] {
+			if r.RecordType == endpoint.RecordTypeA {
+				values = append(values, r.Value)
+			}
+		}
+		return values
+	}
+	newProvider := func(records ...myrasec.DNSRecord) (*fakeMyraSecClient, *MyraSecDNSProvider, *observer.ObservedLogs) {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		client.records[123] = records
+		core, logs := observer.New(zap.WarnLevel)
+		p := newTestProvider(client)
+		p.logger = zap.New(core)
+		return client, p, logs
+	}
+
+	t.Run("value unknown to ExternalDNS", func(t *testing.T) {
+		client, p, logs := newProvider(
+			myrasec.DNSRecord{ID: 1, Name: "app.example.com", RecordType: endpoint.RecordTypeA, Value: "1.1.1.1", TTL: 300},
+			myrasec.DNSRecord{ID: 2, Name: "app.example.com", RecordType: endpoint.RecordTypeA, Value: "9.9.9.9", TTL: 300},
+			myrasec.DNSRecord{ID: 3, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: ours, TTL: 300},
+		)
+
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+			UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.1.1.1")},
+			UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "2.2.2.2")},
+		}))
+		assert.ElementsMatch(t, []string{"9.9.9.9", "2.2.2.2"}, values(client), "only the value of this owner is replaced")
+		assert.Equal(t, 1, logs.FilterMessage("Keeping record not attributable to this owner").Len())
+	})
+
+	t.Run("name shared with another owner", func(t *testing.T) {
+		records := []myrasec.DNSRecord{
+			{ID: 1, Name: "app.example.com", RecordType: endpoint.RecordTypeA, Value: "1.1.1.1", TTL: 300},
+			{ID: 2, Name: "app.example.com", RecordType: endpoint.RecordTypeA, Value: "9.9.9.9", TTL: 300},
+			{ID: 3, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: foreign, TTL: 300},
+			{ID: 4, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: ours, TTL: 300},
+		}
+		client, p, logs := newProvider(records...)
+
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+			UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.1.1.1", "9.9.9.9")},
+			UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.1.1.1")},
+		}))
+		assert.ElementsMatch(t, []string{"1.1.1.1", "9.9.9.9"}, values(client))
+		assert.Equal(t, 1, logs.FilterMessage("Keeping record not attributable to this owner").Len())
+
+		client, p, _ = newProvider(records...)
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+			Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.1.1.1", "9.9.9.9")},
+		}))
+		assert.ElementsMatch(t, []string{"1.1.1.1", "9.9.9.9"}, values(client), "deletions keep them as well")
+	})
+}
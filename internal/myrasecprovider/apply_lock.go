@@ -0,0 +1,110 @@
+package myrasecprovider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// initApplyLock creates the channels of the apply lock and of the drain signal on first use
+func (p *MyraSecDNSProvider) initApplyLock() {
+	p.applyLockOnce.Do(func() {
+		p.applyLock = make(chan struct{}, 1)
+		p.draining = make(chan struct{})
+	})
+}
+
+// acquireApply makes ApplyChanges calls run one at a time, so two syncs never list and mutate the
+// zone concurrently. If another apply is running, it waits for it until the context is done, or
+// fails right away with ErrApplyInProgress if concurrent applies are rejected.
+// Once Drain was called it fails with ErrShuttingDown.
+// The returned function releases the lock.
+func (p *MyraSecDNSProvider) acquireApply(ctx context.Context) (func(), error) {
+	p.initApplyLock()
+
+	select {
+	case <-p.draining:
+		p.logger.Warn("Refusing changes, the webhook is shutting down")
+		return nil, ErrShuttingDown
+	default:
+	}
+
+	select {
+	case p.applyLock <- struct{}{}:
+	default:
+		if p.rejectConcurrentApply {
+			p.logger.Warn("Refusing changes, another apply is in progress")
+			return nil, ErrApplyInProgress
+		}
+		p.logger.Info("Waiting for the apply in progress to finish")
+		select {
+		case p.applyLock <- struct{}{}:
+		case <-p.draining:
+			p.logger.Warn("Refusing changes, the webhook is shutting down")
+			return nil, ErrShuttingDown
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for the apply in progress: %w", ctx.Err())
+		}
+	}
+
+	// Drain may have started while the lock was free, the apply must not start after it
+	select {
+	case <-p.draining:
+		<-p.applyLock
+		p.logger.Warn("Refusing changes, the webhook is shutting down")
+		return nil, ErrShuttingDown
+	default:
+	}
+
+	started := time.Now()
+	p.applyStartedAt.Store(&started)
+	applyInProgress.Set(1)
+	return func() {
+		applyInProgress.Set(0)
+		p.applyStartedAt.Store(nil)
+		<-p.applyLock
+	}, nil
+}
+
+// tryAcquireApply takes the apply lock if it is free, for background work that must not run next
+// to ApplyChanges and rather skips a round than waits for it. It fails once Drain was called.
+// The returned function releases the lock.
+func (p *MyraSecDNSProvider) tryAcquireApply() (func(), bool) {
+	p.initApplyLock()
+
+	select {
+	case <-p.draining:
+		return nil, false
+	default:
+	}
+
+	select {
+	case p.applyLock <- struct{}{}:
+		return func() { <-p.applyLock }, true
+	default:
+		return nil, false
+	}
+}
+
+// Drain prepares the shutdown: ApplyChanges calls that did not start yet, including those waiting
+// for the apply in progress, fail with ErrShuttingDown from now on, and Drain waits until the
+// apply in progress finished, so it does not leave records without their ownership TXT record.
+// It returns the context error if the apply does not finish before the context is done.
+func (p *MyraSecDNSProvider) Drain(ctx context.Context) error {
+	p.initApplyLock()
+	p.drainOnce.Do(func() {
+		close(p.draining)
+	})
+
+	select {
+	case p.applyLock <- struct{}{}:
+		// Keep the lock, no apply starts anymore
+		p.logger.Info("No apply in progress, drained")
+		return nil
+	case <-ctx.Done():
+		p.logger.Warn("The apply in progress did not finish before the shutdown", zap.Error(ctx.Err()))
+		return fmt.Errorf("waiting for the apply in progress: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,73 @@
+package myrasecprovider
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// withApplyTimeout returns the context of an ApplyChanges call. With an apply timeout it ends
+// once the timeout expired with ErrApplyTimeout as cause, whatever the deadline of the request,
+// so the remaining tasks are aborted instead of changing the zone long after ExternalDNS gave up.
+func (p *MyraSecDNSProvider) withApplyTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.applyTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, p.applyTimeout, ErrApplyTimeout)
+}
+
+// applyTimedOut reports whether the context of an ApplyChanges call ended by the apply timeout
+func applyTimedOut(ctx context.Context) bool {
+	return ctx.Err() != nil && context.Cause(ctx) == ErrApplyTimeout
+}
+
+// applyTimeoutError returns the error of an ApplyChanges call aborted by the apply timeout, listing
+// the tasks that were applied and those that were not. Failed tasks are listed with their error.
+func (p *MyraSecDNSProvider) applyTimeoutError(tasks []changeTask, applied map[changeTask]bool, failed map[changeTask]*ChangeError) *ApplyTimeoutError {
+	err := &ApplyTimeoutError{Timeout: p.applyTimeout}
+	for _, task := range tasks {
+		switch {
+		case applied[task]:
+			err.Applied = append(err.Applied, describeChange(task.action, task.change.RecordType, task.change.DNSName))
+		case failed[task] != nil:
+			err.NotApplied = append(err.NotApplied, failed[task].Error())
+		default:
+			err.NotApplied = append(err.NotApplied, describeChange(task.action, task.change.RecordType, task.change.DNSName))
+		}
+	}
+	p.logApplyTimeout(err)
+	return err
+}
+
+// planTimeoutError returns the error of an ApplyChanges call aborted by the apply timeout before
+// any change was applied, e.g. while it waited for another apply.
+func (p *MyraSecDNSProvider) planTimeoutError(changes *plan.Changes) *ApplyTimeoutError {
+	err := &ApplyTimeoutError{Timeout: p.applyTimeout}
+	for _, ep := range changes.Create {
+		err.NotApplied = append(err.NotApplied, describeChange(CREATE, ep.RecordType, ep.DNSName))
+	}
+	for _, ep := range changes.UpdateNew {
+		err.NotApplied = append(err.NotApplied, describeChange(UPDATE, ep.RecordType, ep.DNSName))
+	}
+	for _, ep := range changes.Delete {
+		err.NotApplied = append(err.NotApplied, describeChange(DELETE, ep.RecordType, ep.DNSName))
+	}
+	p.logApplyTimeout(err)
+	return err
+}
+
+// logApplyTimeout counts and logs an ApplyChanges call aborted by the apply timeout
+func (p *MyraSecDNSProvider) logApplyTimeout(err *ApplyTimeoutError) {
+	applyTimeouts.Inc()
+	p.logger.Error("Aborted applying DNS changes, the apply timeout expired",
+		zap.Duration("timeout", err.Timeout),
+		zap.Int("applied", len(err.Applied)),
+		zap.Int("not_applied", len(err.NotApplied)))
+}
+
+// describeChange names a change as in ChangeError
+func describeChange(action, recordType, dnsName string) string {
+	return fmt.Sprintf("%s %s record %s", action, recordType, dnsName)
+}
@@ -0,0 +1,83 @@
+package myrasecprovider
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// delayedClient takes delay to create the records whose name starts with "slow"
+type delayedClient struct {
+	*fakeMyraSecClient
+	delay time.Duration
+}
+
+func (c *delayedClient) CreateDNSRecord(record *myrasec.DNSRecord, domainId int) (*myrasec.DNSRecord, error) {
+	if strings.HasPrefix(record.Name, "slow") {
+		time.Sleep(c.delay)
+	}
+	return c.fakeMyraSecClient.CreateDNSRecord(record, domainId)
+}
+
+func TestApplyTimeout(t *testing.T) {
+	changes := &plan.Changes{Create: []*endpoint.Endpoint{
+		endpoint.NewEndpoint("fast.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+		endpoint.NewEndpoint("slow.example.com", endpoint.RecordTypeA, "2.2.2.2"),
+		endpoint.NewEndpoint("later.example.com", endpoint.RecordTypeA, "3.3.3.3"),
+	}}
+
+	t.Run("aborts the remaining changes", func(t *testing.T) {
+		client := &delayedClient{fakeMyraSecClient: newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"}), delay: 200 * time.Millisecond}
+		p := newTestProvider(client)
+		p.workers = 1
+		p.continueOnError = true
+		p.applyTimeout = 50 * time.Millisecond
+		before := testutil.ToFloat64(applyTimeouts)
+
+		start := time.Now()
+		err := p.ApplyChanges(context.Background(), changes)
+
+		var timeoutErr *ApplyTimeoutError
+		require.ErrorAs(t, err, &timeoutErr)
+		assert.ErrorIs(t, err, ErrApplyTimeout)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, []string{"CREATE A record fast.example.com"}, timeoutErr.Applied)
+		assert.Contains(t, timeoutErr.NotApplied, "CREATE A record later.example.com")
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, before+1, testutil.ToFloat64(applyTimeouts))
+
+		for _, r := range client.records[123] {
+			assert.NotEqual(t, "later.example.com", r.Name, "no change is started after the timeout")
+		}
+	})
+
+	t.Run("expires while waiting for another apply", func(t *testing.T) {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		p := newTestProvider(client)
+		p.applyTimeout = 20 * time.Millisecond
+		release, err := p.acquireApply(context.Background())
+		require.NoError(t, err)
+		defer release()
+
+		err = p.ApplyChanges(context.Background(), changes)
+		var timeoutErr *ApplyTimeoutError
+		require.ErrorAs(t, err, &timeoutErr)
+		assert.Empty(t, timeoutErr.Applied)
+		assert.Len(t, timeoutErr.NotApplied, 3)
+		assert.Empty(t, client.records[123])
+	})
+
+	t.Run("within the timeout", func(t *testing.T) {
+		p := newTestProvider(newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"}))
+		p.applyTimeout = time.Minute
+		require.NoError(t, p.ApplyChanges(context.Background(), changes))
+	})
+}
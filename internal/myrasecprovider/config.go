@@ -1,16 +1,308 @@
 package myrasecprovider
 
 import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
 	"sigs.k8s.io/external-dns/endpoint"
 )
 
+// Limits of the record TTL accepted by the MyraSec API, in seconds.
+const (
+	DefaultMinTTL = 300
+	DefaultMaxTTL = 86400
+)
+
+// DefaultWorkers is the number of changes applied concurrently by ApplyChanges.
+const DefaultWorkers = 4
+
+// DefaultDomainCacheTTL is how long the domains of the account are cached.
+const DefaultDomainCacheTTL = 10 * time.Minute
+
+// DefaultLanguage is the language of the MyraSec API client.
+const DefaultLanguage = myrasec.DefaultAPILanguage
+
+// Handling of an ApplyChanges call while another one is still running
+const (
+	ConcurrentApplyWait   = "wait"   // Wait for the running apply, bounded by the context of the call
+	ConcurrentApplyReject = "reject" // Fail right away with ErrApplyInProgress
+)
+
+// SupportedRecordTypes are the record types the provider can manage.
+var SupportedRecordTypes = []string{
+	endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME,
+	endpoint.RecordTypeMX, endpoint.RecordTypeTXT, endpoint.RecordTypeNS, endpoint.RecordTypeSRV,
+}
+
 // Config is used to configure the creation of the MyraSecDNSProvider.
 type Config struct {
-	APIKey            string
-	APISecret         string
-	BaseURL           string
-	DomainFilter      endpoint.DomainFilter
-	DryRun            bool
-	TTL               int
-	DisableProtection bool
+	APIKey         string
+	APISecret      string
+	BaseURL        string
+	Language       string
+	DomainFilter   endpoint.DomainFilter
+	ExcludeDomains []string
+	// Accounts replace APIKey and APISecret to manage the domains of several MyraSec accounts,
+	// their domains are added to DomainFilter
+	Accounts []Account
+	DryRun   bool
+	// TTL is the default TTL of records without a TTL, TTLInherit to use the default of their domain
+	TTL                int
+	MinTTL             int
+	MaxTTL             int
+	TTLPerType         []string
+	Owner              string
+	Workers            int
+	ContinueOnError    bool
+	ManagedRecordTypes []string
+	// AdoptExistingRecords takes over records that exist in MyraSec without an ownership TXT record
+	AdoptExistingRecords bool
+	DomainCacheTTL       time.Duration
+	DisableProtection    bool
+	// ProtectionPerType holds the default protection state per record type, e.g. "MX=false"
+	ProtectionPerType []string
+	// ReclaimOwnership recreates a missing ownership TXT record when the plan updates a record of this owner
+	ReclaimOwnership bool
+	// DeleteOnEmptyTargets deletes the records of an updated endpoint without targets instead of keeping them
+	DeleteOnEmptyTargets bool
+	// MaxDeletionsPerSync refuses plans with more deletions, 0 disables the limit
+	MaxDeletionsPerSync int
+	// MaxChangesPerSync refuses plans with more creations, updates and deletions in total, 0 disables the limit
+	MaxChangesPerSync int
+	// NotifyURL receives a JSON summary after ApplyChanges changed the zone, empty disables notifications
+	NotifyURL string
+	// NotifyToken is sent as bearer token with the notifications
+	NotifyToken   string
+	NotifyTimeout time.Duration
+	// ConcurrentApply is ConcurrentApplyWait (the default) or ConcurrentApplyReject
+	ConcurrentApply string
+	// TTLEnforcement is TTLEnforcementAlways (the default) or TTLEnforcementOnCreateOnly
+	TTLEnforcement string
+	// InvalidEndpoints is InvalidEndpointsDrop (the default) or InvalidEndpointsReject
+	InvalidEndpoints string
+	// StrictOwner refuses plans with endpoints labelled with another owner instead of skipping their changes
+	StrictOwner bool
+	// APIProxy is the proxy for the MyraSec API, empty uses HTTPS_PROXY, HTTP_PROXY and NO_PROXY
+	APIProxy string
+	// UserAgentOwner adds the owner ID to the User-Agent of the MyraSec API requests
+	UserAgentOwner bool
+	// ApplyTimeout aborts the remaining changes of an ApplyChanges call running longer, 0 disables it
+	ApplyTimeout time.Duration
+	// WaitForRecordActive is how long a created record is waited for until the API lists it, 0 disables waiting
+	WaitForRecordActive time.Duration
+	// APICABundle is a PEM file with certificates trusted for the MyraSec API besides the system roots
+	APICABundle string
+	// DomainID and DomainName pin the domain, so the domains of the account are never listed
+	DomainID   int
+	DomainName string
+	// DryRunOutput is DryRunOutputJSON to write the plan of every dry-run ApplyChanges, empty disables it
+	DryRunOutput string
+	// DryRunOutputFile receives the plan instead of stdout, it is replaced on every ApplyChanges
+	DryRunOutputFile string
+	// Environment is the name of the deployment environment, prod, production and staging
+	// skip records with private IPs, see isProduction
+	Environment string
+}
+
+// ValidateNetwork checks the base URL, the proxy URL, the CA bundle and the notify URL, and
+// returns all problems found joined into one error, so they can be reported together before the
+// provider is created.
+func (c Config) ValidateNetwork() error {
+	var errs []error
+	if c.BaseURL != "" {
+		if _, err := apiBaseURLFormat(c.BaseURL); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.APIProxy != "" {
+		if _, err := parseProxyURL(c.APIProxy); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.APICABundle != "" {
+		if _, err := loadCABundle(c.APICABundle); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.NotifyURL != "" {
+		if err := validateNotifyURL(c.NotifyURL); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// apiBaseURLFormat validates the configured base URL and converts it into the format string
+// used by the myrasec client, where %s is replaced by the API action (e.g. "domains").
+func apiBaseURLFormat(baseURL string) (string, error) {
+	if strings.Contains(baseURL, "%s") {
+		baseURL = strings.Replace(baseURL, "%s", "", 1)
+	}
+
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL %q: %w", baseURL, err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return "", fmt.Errorf("invalid base URL %q: scheme must be http or https", baseURL)
+	}
+	if parsed.Host == "" {
+		return "", fmt.Errorf("invalid base URL %q: missing host", baseURL)
+	}
+	if parsed.RawQuery != "" || parsed.Fragment != "" {
+		return "", fmt.Errorf("invalid base URL %q: query and fragment are not allowed", baseURL)
+	}
+
+	return strings.TrimRight(baseURL, "/") + "/%s", nil
+}
+
+// apiLanguage validates the configured API language against the languages supported by myrasec-go.
+// An empty language selects DefaultLanguage.
+func apiLanguage(language string) (string, error) {
+	if language == "" {
+		return DefaultLanguage, nil
+	}
+	if !myrasec.APILanguages[language] {
+		supported := make([]string, 0, len(myrasec.APILanguages))
+		for l := range myrasec.APILanguages {
+			supported = append(supported, l)
+		}
+		sort.Strings(supported)
+		return "", fmt.Errorf("unsupported MyraSec API language %q, supported are %s", language, strings.Join(supported, ", "))
+	}
+	return language, nil
+}
+
+// managedRecordTypes validates the configured record types against SupportedRecordTypes.
+// An empty list selects all supported types.
+func managedRecordTypes(recordTypes []string) (map[string]bool, error) {
+	if len(recordTypes) == 0 {
+		recordTypes = SupportedRecordTypes
+	}
+	managed := make(map[string]bool, len(recordTypes))
+	for _, t := range recordTypes {
+		t = strings.ToUpper(strings.TrimSpace(t))
+		if !supportedRecordType(t) {
+			return nil, fmt.Errorf("unsupported record type %q, supported are %s", t, strings.Join(SupportedRecordTypes, ", "))
+		}
+		managed[t] = true
+	}
+	return managed, nil
+}
+
+// ttlPerType parses the default TTLs per record type from entries like "A=120". Every type must
+// be supported and may be given once, every TTL must be positive.
+func ttlPerType(entries []string) (map[string]int, error) {
+	ttls := make(map[string]int, len(entries))
+	for _, entry := range entries {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		recordType, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid TTL per type %q, expected <type>=<seconds>", entry)
+		}
+		recordType = strings.ToUpper(strings.TrimSpace(recordType))
+		if !supportedRecordType(recordType) {
+			return nil, fmt.Errorf("unsupported record type %q in TTL per type, supported are %s", recordType, strings.Join(SupportedRecordTypes, ", "))
+		}
+		if _, ok := ttls[recordType]; ok {
+			return nil, fmt.Errorf("record type %s is given twice in TTL per type", recordType)
+		}
+		ttl, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("invalid TTL %q for record type %s, expected a positive number of seconds", value, recordType)
+		}
+		ttls[recordType] = ttl
+	}
+	return ttls, nil
+}
+
+// protectionPerType parses the default protection state per record type from entries like
+// "MX=false". Every type must be supported and may be given once.
+func protectionPerType(entries []string) (map[string]bool, error) {
+	protection := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		recordType, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid protection per type %q, expected <type>=<true|false>", entry)
+		}
+		recordType = strings.ToUpper(strings.TrimSpace(recordType))
+		if !supportedRecordType(recordType) {
+			return nil, fmt.Errorf("unsupported record type %q in protection per type, supported are %s", recordType, strings.Join(SupportedRecordTypes, ", "))
+		}
+		if _, ok := protection[recordType]; ok {
+			return nil, fmt.Errorf("record type %s is given twice in protection per type", recordType)
+		}
+		active, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid protection %q for record type %s, expected true or false", value, recordType)
+		}
+		protection[recordType] = active
+	}
+	return protection, nil
+}
+
+// ttlOnCreateOnly validates the configured TTL enforcement and reports whether the default TTL is
+// only used for new records. An empty value selects TTLEnforcementAlways.
+func ttlOnCreateOnly(mode string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", TTLEnforcementAlways:
+		return false, nil
+	case TTLEnforcementOnCreateOnly:
+		return true, nil
+	default:
+		return false, fmt.Errorf("unsupported TTL enforcement %q, supported are %s, %s", mode, TTLEnforcementAlways, TTLEnforcementOnCreateOnly)
+	}
+}
+
+// rejectConcurrentApply validates the configured handling of concurrent applies and reports
+// whether they are rejected. An empty value selects ConcurrentApplyWait.
+func rejectConcurrentApply(mode string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", ConcurrentApplyWait:
+		return false, nil
+	case ConcurrentApplyReject:
+		return true, nil
+	default:
+		return false, fmt.Errorf("unsupported concurrent apply mode %q, supported are %s, %s", mode, ConcurrentApplyWait, ConcurrentApplyReject)
+	}
+}
+
+// rejectInvalid validates the configured handling of invalid endpoints and reports whether they
+// are rejected. An empty value selects InvalidEndpointsDrop.
+func rejectInvalid(mode string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", InvalidEndpointsDrop:
+		return false, nil
+	case InvalidEndpointsReject:
+		return true, nil
+	default:
+		return false, fmt.Errorf("unsupported invalid endpoints mode %q, supported are %s, %s", mode, InvalidEndpointsDrop, InvalidEndpointsReject)
+	}
+}
+
+// pinnedDomain returns the domain pinned by its ID and name, or nil if neither is set. Both must
+// be given, and the name must pass the domain filter, otherwise no record would be managed.
+func pinnedDomain(id int, name string, filter endpoint.DomainFilter) (*myrasec.Domain, error) {
+	name = strings.ToLower(asciiName(strings.TrimSuffix(strings.TrimSpace(name), ".")))
+	if id == 0 && name == "" {
+		return nil, nil
+	}
+	if id <= 0 || name == "" {
+		return nil, fmt.Errorf("the domain ID and the domain name must be set together, got ID %d and name %q", id, name)
+	}
+	if len(filter.Filters) > 0 && !filter.Match(name) {
+		return nil, fmt.Errorf("pinned domain %s does not match the domain filter %s", name, strings.Join(filter.Filters, ","))
+	}
+	return &myrasec.Domain{ID: id, Name: name}, nil
 }
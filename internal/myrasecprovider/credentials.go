@@ -0,0 +1,70 @@
+package myrasecprovider
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// client returns the current MyraSec API client. Operations of several API calls use the client
+// pinned to their context instead, see withClient.
+func (p *MyraSecDNSProvider) client() MyraSecAPIClient {
+	p.clientMu.RLock()
+	defer p.clientMu.RUnlock()
+	return p.apiClient
+}
+
+type apiClientKey struct{}
+
+// withClient returns a context carrying the current MyraSec API client, so all API calls of an
+// operation like ApplyChanges finish with the same credentials when they are reloaded meanwhile.
+// A context already carrying a client is returned as is.
+func (p *MyraSecDNSProvider) withClient(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(apiClientKey{}).(MyraSecAPIClient); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, apiClientKey{}, p.client())
+}
+
+// clientFor returns the MyraSec API client pinned to the context, or the current one.
+func (p *MyraSecDNSProvider) clientFor(ctx context.Context) MyraSecAPIClient {
+	if api, ok := ctx.Value(apiClientKey{}).(MyraSecAPIClient); ok {
+		return api
+	}
+	return p.client()
+}
+
+// ReloadCredentials replaces the MyraSec API client by one using the given credentials,
+// e.g. after the mounted credential files were rotated.
+func (p *MyraSecDNSProvider) ReloadCredentials(apiKey, apiSecret string) error {
+	if apiKey == "" || apiSecret == "" {
+		credentialsReloads.WithLabelValues("failure").Inc()
+		return errors.New("API key and secret must not be empty")
+	}
+	if p.newClient == nil {
+		credentialsReloads.WithLabelValues("failure").Inc()
+		return errors.New("the API client of this provider cannot be rebuilt")
+	}
+	if _, ok := p.client().(*accountsClient); ok {
+		credentialsReloads.WithLabelValues("failure").Inc()
+		return errors.New("the credentials of several accounts are configured, they are not reloaded")
+	}
+
+	api, err := p.newClient(apiKey, apiSecret)
+	if err != nil {
+		credentialsReloads.WithLabelValues("failure").Inc()
+		p.logger.Error("Failed to rebuild MyraSec API client with new credentials", zap.Error(err))
+		return err
+	}
+
+	p.clientMu.Lock()
+	p.apiClient = api
+	p.clientMu.Unlock()
+
+	credentialsReloads.WithLabelValues("success").Inc()
+	credentialsLastReload.Set(float64(time.Now().Unix()))
+	p.logger.Info("Reloaded MyraSec API credentials")
+	return nil
+}
@@ -0,0 +1,107 @@
+package myrasecprovider
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// deduplicateChanges collapses the duplicates ExternalDNS may send when several sources produce
+// the same hostname: endpoints of a list with the same name and record type are merged into the
+// first one with the union of their targets, and repeated targets of an endpoint are removed.
+// Updates are merged by their desired endpoint, together with their current one. Every collapse
+// is logged, the endpoints of the plan are modified in place.
+func (p *MyraSecDNSProvider) deduplicateChanges(changes *plan.Changes) *plan.Changes {
+	deduplicated := &plan.Changes{
+		Create: p.deduplicateEndpoints(CREATE, changes.Create),
+		Delete: p.deduplicateEndpoints(DELETE, changes.Delete),
+	}
+
+	merged := make(map[string]int)
+	for i, newEp := range changes.UpdateNew {
+		oldEp := changes.UpdateOld[i]
+		key := endpointKey(newEp)
+		if j, ok := merged[key]; ok {
+			p.logCollapsed(UPDATE, newEp)
+			p.mergeTargets(deduplicated.UpdateNew[j], newEp)
+			p.mergeTargets(deduplicated.UpdateOld[j], oldEp)
+			continue
+		}
+		merged[key] = len(deduplicated.UpdateNew)
+		p.deduplicateTargets(UPDATE, newEp)
+		p.deduplicateTargets(UPDATE, oldEp)
+		deduplicated.UpdateNew = append(deduplicated.UpdateNew, newEp)
+		deduplicated.UpdateOld = append(deduplicated.UpdateOld, oldEp)
+	}
+	return deduplicated
+}
+
+// deduplicateEndpoints merges the endpoints with the same name and record type and removes their
+// repeated targets
+func (p *MyraSecDNSProvider) deduplicateEndpoints(action string, endpoints []*endpoint.Endpoint) []*endpoint.Endpoint {
+	var result []*endpoint.Endpoint
+	merged := make(map[string]*endpoint.Endpoint)
+	for _, ep := range endpoints {
+		key := endpointKey(ep)
+		if first, ok := merged[key]; ok {
+			p.logCollapsed(action, ep)
+			p.mergeTargets(first, ep)
+			continue
+		}
+		merged[key] = ep
+		p.deduplicateTargets(action, ep)
+		result = append(result, ep)
+	}
+	return result
+}
+
+// endpointKey identifies an endpoint by its normalized name and record type
+func endpointKey(ep *endpoint.Endpoint) string {
+	return normalizeDNSName(ep.DNSName) + "/" + strings.ToUpper(ep.RecordType)
+}
+
+// logCollapsed logs a duplicate endpoint merged into an earlier one of the plan
+func (p *MyraSecDNSProvider) logCollapsed(action string, ep *endpoint.Endpoint) {
+	p.logger.Info("Merging duplicate endpoint of the plan",
+		zap.String("action", action),
+		zap.String("dnsName", ep.DNSName),
+		zap.String("type", ep.RecordType),
+		zap.Strings("targets", ep.Targets))
+}
+
+// mergeTargets adds the targets of duplicate to ep that it does not have yet
+func (p *MyraSecDNSProvider) mergeTargets(ep, duplicate *endpoint.Endpoint) {
+	if duplicate == nil || ep == nil {
+		return
+	}
+	ep.Targets = append(ep.Targets, duplicate.Targets...)
+	p.deduplicateTargets("", ep)
+}
+
+// deduplicateTargets removes the targets of ep that repeat an earlier one, compared in their
+// canonical form. Removals are logged unless action is empty.
+func (p *MyraSecDNSProvider) deduplicateTargets(action string, ep *endpoint.Endpoint) {
+	if ep == nil {
+		return
+	}
+	seen := make(map[string]bool, len(ep.Targets))
+	targets := make(endpoint.Targets, 0, len(ep.Targets))
+	for _, target := range ep.Targets {
+		value := p.canonicalRecordValue(target, ep.RecordType)
+		if seen[value] {
+			if action != "" {
+				p.logger.Info("Removing duplicate target of the plan",
+					zap.String("action", action),
+					zap.String("dnsName", ep.DNSName),
+					zap.String("type", ep.RecordType),
+					zap.String("target", target))
+			}
+			continue
+		}
+		seen[value] = true
+		targets = append(targets, target)
+	}
+	ep.Targets = targets
+}
@@ -0,0 +1,88 @@
+package myrasecprovider
+
+import (
+	"context"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// recordValue matches a record by its name, type and value
+func recordValue(name, recordType, value string) interface{} {
+	return mock.MatchedBy(func(r *myrasec.DNSRecord) bool {
+		return r.Name == name && r.RecordType == recordType && r.Value == value
+	})
+}
+
+func TestApplyChangesDeduplicatesCreates(t *testing.T) {
+	mockClient := new(MockMyraSecClient)
+	mockClient.On("ListDomains", mock.Anything).Return([]myrasec.Domain{{ID: 123, Name: "example.com"}}, nil)
+	mockClient.On("ListDNSRecords", 123, mock.Anything).Return([]myrasec.DNSRecord{}, nil)
+	for _, value := range []string{"1.1.1.1", "2.2.2.2"} {
+		mockClient.On("CreateDNSRecord", recordValue("app.example.com", "A", value), 123).Return(&myrasec.DNSRecord{}, nil).Once()
+	}
+	mockClient.On("CreateDNSRecord", recordValue("app.example.com", "TXT", `"heritage=external-dns,external-dns/owner=test-owner"`), 123).
+		Return(&myrasec.DNSRecord{}, nil).Once()
+
+	p := newTestProvider(mockClient)
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.1.1.1", "1.1.1.1"),
+			endpoint.NewEndpoint("App.Example.com", endpoint.RecordTypeA, "2.2.2.2", "1.1.1.1"),
+			endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "2.2.2.2"),
+		},
+	}))
+	mockClient.AssertExpectations(t)
+	mockClient.AssertNumberOfCalls(t, "CreateDNSRecord", 3)
+}
+
+func TestApplyChangesDeduplicatesDeletes(t *testing.T) {
+	ownership := myrasec.DNSRecord{ID: 2, Name: "app.example.com", RecordType: "TXT", Value: "heritage=external-dns,external-dns/owner=test-owner", TTL: 300}
+	record := myrasec.DNSRecord{ID: 1, Name: "app.example.com", RecordType: "A", Value: "1.1.1.1", TTL: 300}
+	mockClient := new(MockMyraSecClient)
+	mockClient.On("ListDomains", mock.Anything).Return([]myrasec.Domain{{ID: 123, Name: "example.com"}}, nil)
+	mockClient.On("ListDNSRecords", 123, mock.Anything).Return([]myrasec.DNSRecord{record, ownership}, nil)
+	mockClient.On("DeleteDNSRecord", recordValue(record.Name, record.RecordType, record.Value), 123).Return(&record, nil).Once()
+	mockClient.On("DeleteDNSRecord", recordValue(ownership.Name, ownership.RecordType, ownership.Value), 123).Return(&ownership, nil).Once()
+
+	p := newTestProvider(mockClient)
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Delete: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+			endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.1.1.1", "1.1.1.1"),
+		},
+	}))
+	mockClient.AssertExpectations(t)
+	mockClient.AssertNumberOfCalls(t, "DeleteDNSRecord", 2)
+}
+
+func TestDeduplicateChanges(t *testing.T) {
+	p := newTestProvider(newFakeMyraSecClient())
+
+	changes := p.deduplicateChanges(&plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeCNAME, "lb.example.net", "LB.example.net."),
+			endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+		},
+		UpdateOld: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("web.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+			endpoint.NewEndpoint("web.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("web.example.com", endpoint.RecordTypeA, "2.2.2.2"),
+			endpoint.NewEndpoint("web.example.com", endpoint.RecordTypeA, "3.3.3.3", "2.2.2.2"),
+		},
+	})
+
+	require.Len(t, changes.Create, 2, "endpoints of different types are kept apart")
+	assert.Equal(t, endpoint.Targets{"lb.example.net"}, changes.Create[0].Targets)
+	require.Len(t, changes.UpdateNew, 1)
+	require.Len(t, changes.UpdateOld, 1)
+	assert.Equal(t, endpoint.Targets{"2.2.2.2", "3.3.3.3"}, changes.UpdateNew[0].Targets)
+	assert.Equal(t, endpoint.Targets{"1.1.1.1"}, changes.UpdateOld[0].Targets)
+}
@@ -1,8 +1,57 @@
 package myrasecprovider
 
-import "sigs.k8s.io/external-dns/endpoint"
+import (
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+)
 
 // GetDomainFilter returns the domain filter for the provider
 func (d *MyraSecDNSProvider) GetDomainFilter() endpoint.DomainFilterInterface {
 	return d.domainFilter
 }
+
+// inDomainFilter reports whether the webhook manages the name, the same way for every caller. The
+// name is normalized first, so its case, a trailing dot or the IDN form make no difference. As in
+// ExternalDNS, a filter matches its domain and every name below it, a filter with a leading dot
+// only the names below it, and no filter matches every name. A name of an excluded domain or below
+// it is never managed, an excluded child zone wins over its included parent.
+func (d *MyraSecDNSProvider) inDomainFilter(name string) bool {
+	name = normalizeDNSName(name)
+	if len(d.excludeFilter.Filters) > 0 && d.excludeFilter.Match(name) {
+		return false
+	}
+	return d.domainFilter.Match(name)
+}
+
+// excludedChange reports whether a change targets a name below one of the excluded domains.
+// Such changes are logged and not applied.
+func (d *MyraSecDNSProvider) excludedChange(action string, ep *endpoint.Endpoint) bool {
+	if len(d.excludeFilter.Filters) == 0 || !d.excludeFilter.Match(normalizeDNSName(ep.DNSName)) {
+		return false
+	}
+	d.logger.Warn("Skipping change of a record in an excluded domain",
+		zap.String("action", action),
+		zap.String("dnsName", ep.DNSName),
+		zap.String("type", ep.RecordType))
+	return true
+}
+
+// outsideDomainFilter reports whether a change targets a name outside the domain filter. The name
+// is checked as given, only the apex short forms stand for the zone, so that a foreign name is not
+// taken as relative and created below the zone. Records never reports such names, a plan with them
+// comes from a misbehaving client; the changes are logged, counted and not applied.
+func (d *MyraSecDNSProvider) outsideDomainFilter(action, zone string, ep *endpoint.Endpoint) bool {
+	name := normalizeDNSName(ep.DNSName)
+	if name == "" || name == apexRecordName {
+		name = normalizeDNSName(zone)
+	}
+	if d.inDomainFilter(name) {
+		return false
+	}
+	d.logger.Warn("Skipping change of a record outside the domain filter",
+		zap.String("action", action),
+		zap.String("dnsName", ep.DNSName),
+		zap.String("type", ep.RecordType))
+	outOfFilterChanges.WithLabelValues(action).Inc()
+	return true
+}
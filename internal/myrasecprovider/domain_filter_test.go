@@ -0,0 +1,170 @@
+package myrasecprovider
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestExcludeDomains(t *testing.T) {
+	server, _ := stubMyraAPI(t)
+	p, err := NewMyraSecDNSProvider(zap.NewNop(), Config{
+		APIKey:         "key",
+		APISecret:      "secret",
+		BaseURL:        server.URL,
+		DomainFilter:   endpoint.NewDomainFilter([]string{"example.com"}),
+		ExcludeDomains: []string{"internal.example.com"},
+	})
+	require.NoError(t, err)
+
+	filter, err := json.Marshal(p.GetDomainFilter())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"include":["example.com"],"exclude":["internal.example.com"]}`, string(filter))
+
+	ownership := "heritage=external-dns,external-dns/owner=test-owner"
+	client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+	client.records[123] = []myrasec.DNSRecord{
+		{ID: 1, Name: "app.example.com", RecordType: "A", Value: "1.1.1.1", TTL: 300},
+		{ID: 2, Name: "app.example.com", RecordType: "TXT", Value: ownership, TTL: 300},
+		{ID: 3, Name: "db.internal.example.com", RecordType: "A", Value: "3.3.3.3", TTL: 300},
+		{ID: 4, Name: "db.internal.example.com", RecordType: "TXT", Value: ownership, TTL: 300},
+	}
+	p.apiClient = client
+	p.owner = "test-owner"
+
+	endpoints, err := p.Records(context.Background())
+	require.NoError(t, err)
+	assert.NotNil(t, findEndpoint(endpoints, "app.example.com", "A"))
+	assert.Nil(t, findEndpoint(endpoints, "db.internal.example.com", "A"), "records of excluded domains are not returned")
+
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create:    []*endpoint.Endpoint{endpoint.NewEndpoint("web.internal.example.com", endpoint.RecordTypeA, "4.4.4.4")},
+		Delete:    []*endpoint.Endpoint{endpoint.NewEndpoint("db.internal.example.com", endpoint.RecordTypeA, "3.3.3.3")},
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("db.internal.example.com", endpoint.RecordTypeA, "3.3.3.3")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("db.internal.example.com", endpoint.RecordTypeA, "5.5.5.5")},
+	}))
+
+	var values []string
+	for _, r := range client.records[123] {
+		if r.RecordType == endpoint.RecordTypeA {
+			values = append(values, r.Name+" "+r.Value)
+		}
+	}
+	assert.ElementsMatch(t, []string{"app.example.com 1.1.1.1", "db.internal.example.com 3.3.3.3"}, values,
+		"records of excluded domains are not modified")
+}
+
+func TestApplyChangesOutsideDomainFilter(t *testing.T) {
+	ownership := "heritage=external-dns,external-dns/owner=test-owner"
+	client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+	client.records[123] = []myrasec.DNSRecord{
+		{ID: 1, Name: "app.example.com", RecordType: "A", Value: "1.1.1.1", TTL: 300},
+		{ID: 2, Name: "app.example.com", RecordType: "TXT", Value: ownership, TTL: 300},
+	}
+	existing := append([]myrasec.DNSRecord(nil), client.records[123]...)
+	p := newTestProvider(client)
+
+	before := testutil.ToFloat64(outOfFilterChanges.WithLabelValues(CREATE))
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create:    []*endpoint.Endpoint{endpoint.NewEndpoint("evil.org", endpoint.RecordTypeA, "6.6.6.6")},
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "1.1.1.1")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "6.6.6.6")},
+		Delete:    []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com.evil.org", endpoint.RecordTypeA, "1.1.1.1")},
+	}))
+	assert.Equal(t, existing, client.records[123], "no records are changed for names outside the domain filter")
+	assert.Equal(t, before+1, testutil.ToFloat64(outOfFilterChanges.WithLabelValues(CREATE)))
+
+	// Changes inside the filter in the same plan are still applied
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("evil.org", endpoint.RecordTypeA, "6.6.6.6"),
+			endpoint.NewEndpoint("web.example.com", endpoint.RecordTypeA, "2.2.2.2"),
+		},
+	}))
+	var names []string
+	for _, r := range client.records[123] {
+		names = append(names, r.Name)
+	}
+	assert.ElementsMatch(t, []string{"app.example.com", "app.example.com", "web.example.com", "web.example.com"}, names)
+}
+
+func TestAdjustEndpointsOutsideDomainFilter(t *testing.T) {
+	p := newTestProvider(newFakeMyraSecClient())
+
+	before := testutil.ToFloat64(outOfFilterChanges.WithLabelValues(adjustAction))
+	adjusted, err := p.AdjustEndpoints([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+		endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "1.1.1.1"),
+	})
+	require.NoError(t, err)
+	require.Len(t, adjusted, 1)
+	assert.Equal(t, "app.example.com", adjusted[0].DNSName)
+	assert.Equal(t, before+1, testutil.ToFloat64(outOfFilterChanges.WithLabelValues(adjustAction)))
+}
+
+// TestInDomainFilter tests the filter semantics and that Records, AdjustEndpoints and the guard of
+// ApplyChanges decide the same way for every name
+func TestInDomainFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters []string
+		dnsName string
+		want    bool
+	}{
+		{name: "apex", filters: []string{"example.com"}, dnsName: "example.com", want: true},
+		{name: "apex with trailing dot", filters: []string{"example.com"}, dnsName: "example.com.", want: true},
+		{name: "subdomain", filters: []string{"example.com"}, dnsName: "app.example.com", want: true},
+		{name: "nested subdomain", filters: []string{"example.com"}, dnsName: "a.b.c.example.com.", want: true},
+		{name: "other case", filters: []string{"example.com"}, dnsName: "App.Example.COM", want: true},
+		{name: "suffix without a dot", filters: []string{"example.com"}, dnsName: "notexample.com", want: false},
+		{name: "parent", filters: []string{"example.com"}, dnsName: "com", want: false},
+		{name: "other domain", filters: []string{"example.com"}, dnsName: "example.com.evil.org", want: false},
+		{name: "excluded child zone", filters: []string{"example.com"}, dnsName: "internal.example.com", want: false},
+		{name: "below excluded child zone", filters: []string{"example.com"}, dnsName: "db.Internal.example.com.", want: false},
+		{name: "sibling of excluded child zone", filters: []string{"example.com"}, dnsName: "external.example.com", want: true},
+		{name: "leading dot matches subdomains", filters: []string{".example.com"}, dnsName: "app.example.com", want: true},
+		{name: "leading dot skips the apex", filters: []string{".example.com"}, dnsName: "example.com", want: false},
+		{name: "IDN", filters: []string{"münchen.de"}, dnsName: "app.münchen.de", want: true},
+		{name: "IDN in punycode", filters: []string{"münchen.de"}, dnsName: "app.xn--mnchen-3ya.de", want: true},
+		{name: "no filter", dnsName: "app.example.org", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProvider(newFakeMyraSecClient())
+			filters := idnFilters(tt.filters)
+			p.domainFilter = endpoint.NewDomainFilterWithExclusions(filters, []string{"internal.example.com"})
+			p.excludeFilter = endpoint.NewDomainFilter([]string{"internal.example.com"})
+			p.managedTypes = map[string]bool{endpoint.RecordTypeA: true}
+
+			assert.Equal(t, tt.want, p.inDomainFilter(tt.dnsName))
+
+			zone := "example.com"
+			if len(filters) > 0 {
+				zone = stripTrailingDot(filters[0])
+			}
+			ep := endpoint.NewEndpoint(tt.dnsName, endpoint.RecordTypeA, "1.2.3.4")
+			assert.Equal(t, tt.want, !p.outsideDomainFilter(CREATE, zone, ep), "apply guard")
+
+			adjusted, err := p.AdjustEndpoints([]*endpoint.Endpoint{ep.DeepCopy()})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, len(adjusted) == 1, "AdjustEndpoints")
+
+			ownership := "heritage=external-dns,external-dns/owner=test-owner"
+			domain := myrasec.Domain{ID: 123, Name: zone}
+			records := []myrasec.DNSRecord{
+				{ID: 1, Name: normalizeDNSName(tt.dnsName), RecordType: "A", Value: "1.2.3.4", TTL: 300},
+				{ID: 2, Name: normalizeDNSName(tt.dnsName), RecordType: "TXT", Value: ownership, TTL: 300},
+			}
+			assert.Equal(t, tt.want, len(p.zoneEndpoints([]myrasec.Domain{domain}, &domain, records)) > 0, "Records")
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package myrasecprovider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// DryRunOutputJSON writes the plan computed by a dry-run ApplyChanges as a JSON document
+const DryRunOutputJSON = "json"
+
+// dryRunPlanVersion is the version of the dryRunPlan schema. It changes only when fields are
+// renamed or removed.
+const dryRunPlanVersion = 1
+
+// dryRunChange describes a single record mutation that would have been sent to the MyraSec API.
+type dryRunChange struct {
+	Action    string   `json:"action"`
+	Domain    string   `json:"domain"`
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	OldValues []string `json:"oldValues,omitempty"`
+	NewValues []string `json:"newValues,omitempty"`
+	OldTTL    int      `json:"oldTtl,omitempty"`
+	TTL       int      `json:"ttl,omitempty"`
+	Active    bool     `json:"active"`
+}
+
+// dryRunReport collects the changes computed during a dry-run ApplyChanges.
+// It is shared by all workers of a single ApplyChanges call.
+type dryRunReport struct {
+	mu      sync.Mutex
+	changes []dryRunChange
+}
+
+type dryRunReportKey struct{}
+
+// withDryRunReport returns a context carrying the given report.
+func withDryRunReport(ctx context.Context, report *dryRunReport) context.Context {
+	return context.WithValue(ctx, dryRunReportKey{}, report)
+}
+
+// dryRunReportFrom returns the report carried by the context, if any.
+func dryRunReportFrom(ctx context.Context) *dryRunReport {
+	report, _ := ctx.Value(dryRunReportKey{}).(*dryRunReport)
+	return report
+}
+
+// recordDryRunChange logs the change that would have been made and adds it to the report of the context.
+func (p *MyraSecDNSProvider) recordDryRunChange(ctx context.Context, change dryRunChange) {
+	p.logger.Info("Would change DNS record (dry-run)",
+		zap.String("action", change.Action),
+		zap.String("domain", change.Domain),
+		zap.String("name", change.Name),
+		zap.String("type", change.Type),
+		zap.Strings("old_values", change.OldValues),
+		zap.Strings("new_values", change.NewValues),
+		zap.Int("old_ttl", change.OldTTL),
+		zap.Int("ttl", change.TTL),
+		zap.Bool("active", change.Active))
+
+	if report := dryRunReportFrom(ctx); report != nil {
+		report.mu.Lock()
+		report.changes = append(report.changes, change)
+		report.mu.Unlock()
+	}
+}
+
+// counts returns the number of recorded changes per action.
+func (r *dryRunReport) counts() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := map[string]int{CREATE: 0, UPDATE: 0, DELETE: 0}
+	for _, change := range r.changes {
+		counts[change.Action]++
+	}
+	return counts
+}
+
+// logDryRunSummary logs the aggregate counts of the report.
+func (p *MyraSecDNSProvider) logDryRunSummary(report *dryRunReport) {
+	counts := report.counts()
+	p.logger.Info("Dry-run summary, no changes were made",
+		zap.Int("create", counts[CREATE]),
+		zap.Int("update", counts[UPDATE]),
+		zap.Int("delete", counts[DELETE]),
+		zap.Int("total", counts[CREATE]+counts[UPDATE]+counts[DELETE]))
+}
+
+// dryRunPlan is the JSON document written with DryRunOutputJSON at the end of a dry-run
+// ApplyChanges. The schema is stable:
+//
+//	{
+//	  "version": 1,               // dryRunPlanVersion
+//	  "counts": {"CREATE": 1, "UPDATE": 0, "DELETE": 0},
+//	  "changes": [                // sorted by domain, name, type and action
+//	    {
+//	      "action": "CREATE",     // CREATE, UPDATE or DELETE
+//	      "domain": "example.com",
+//	      "name": "app.example.com",
+//	      "type": "A",
+//	      "oldValues": ["..."],   // UPDATE and DELETE only
+//	      "newValues": ["..."],   // CREATE and UPDATE only
+//	      "oldTtl": 300,          // UPDATE and DELETE only
+//	      "ttl": 300,             // CREATE and UPDATE only
+//	      "active": true          // Myra protection of the record
+//	    }
+//	  ]
+//	}
+type dryRunPlan struct {
+	Version int            `json:"version"`
+	Counts  map[string]int `json:"counts"`
+	Changes []dryRunChange `json:"changes"`
+}
+
+// plan returns the recorded changes as a dryRunPlan. The changes are sorted, as the workers
+// record them in no particular order.
+func (r *dryRunReport) plan() dryRunPlan {
+	counts := r.counts()
+
+	r.mu.Lock()
+	changes := append([]dryRunChange{}, r.changes...)
+	r.mu.Unlock()
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Domain != b.Domain {
+			return a.Domain < b.Domain
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Action != b.Action {
+			return a.Action < b.Action
+		}
+		return strings.Join(append(a.OldValues, a.NewValues...), ",") < strings.Join(append(b.OldValues, b.NewValues...), ",")
+	})
+	return dryRunPlan{Version: dryRunPlanVersion, Counts: counts, Changes: changes}
+}
+
+// writeDryRunPlan writes the plan of the report as a single JSON document, followed by a newline
+func writeDryRunPlan(w io.Writer, report *dryRunReport) error {
+	data, err := json.MarshalIndent(report.plan(), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// writeDryRunOutput writes the plan of the report with DryRunOutputJSON, to the dry-run output
+// file, which it replaces, or else to stdout. Failures are logged, the dry-run still succeeds.
+func (p *MyraSecDNSProvider) writeDryRunOutput(report *dryRunReport) {
+	if p.dryRunOutput != DryRunOutputJSON {
+		return
+	}
+
+	var err error
+	if p.dryRunOutputFile == "" {
+		err = writeDryRunPlan(os.Stdout, report)
+	} else {
+		var f *os.File
+		f, err = os.Create(p.dryRunOutputFile)
+		if err == nil {
+			err = writeDryRunPlan(f, report)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	}
+	if err != nil {
+		p.logger.Error("Failed to write the dry-run plan",
+			zap.String("file", p.dryRunOutputFile),
+			zap.Error(err))
+	}
+}
+
+// dryRunOutput validates the format of the dry-run output, empty disables it
+func dryRunOutput(format string) (string, error) {
+	switch format = strings.ToLower(strings.TrimSpace(format)); format {
+	case "", DryRunOutputJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported dry-run output %q, supported is %s", format, DryRunOutputJSON)
+	}
+}
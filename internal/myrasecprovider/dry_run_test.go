@@ -0,0 +1,62 @@
+package myrasecprovider
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+// TestDryRunOutput tests the JSON plan of a dry-run ApplyChanges against the golden file
+func TestDryRunOutput(t *testing.T) {
+	client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+	ownership := "heritage=external-dns,external-dns/owner=test-owner"
+	client.records[123] = []myrasec.DNSRecord{
+		{ID: 1, Name: "app.example.com", RecordType: "A", Value: "1.1.1.1", TTL: 300, Active: true, Enabled: true},
+		{ID: 2, Name: "app.example.com", RecordType: "TXT", Value: ownership, TTL: 300, Enabled: true},
+		{ID: 3, Name: "old.example.com", RecordType: "A", Value: "3.3.3.3", TTL: 300, Active: true, Enabled: true},
+		{ID: 4, Name: "old.example.com", RecordType: "TXT", Value: ownership, TTL: 300, Enabled: true},
+	}
+
+	output := filepath.Join(t.TempDir(), "plan.json")
+	p := newTestProvider(client)
+	p.dryRun = true
+	p.dryRunOutput = DryRunOutputJSON
+	p.dryRunOutputFile = output
+
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create:    []*endpoint.Endpoint{endpoint.NewEndpoint("new.example.com", "A", "2.2.2.2")},
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", "A", "1.1.1.1")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpointWithTTL("app.example.com", "A", endpoint.TTL(600), "1.1.1.1", "1.1.1.2")},
+		Delete:    []*endpoint.Endpoint{endpoint.NewEndpoint("old.example.com", "A", "3.3.3.3")},
+	}))
+
+	got, err := os.ReadFile(output)
+	require.NoError(t, err)
+
+	golden := filepath.Join("testdata", "dry_run_plan.golden.json")
+	if *updateGolden {
+		require.NoError(t, os.WriteFile(golden, got, 0o644))
+	}
+	want, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(got))
+}
+
+func TestDryRunOutputFormat(t *testing.T) {
+	for _, format := range []string{"", "json", " JSON "} {
+		_, err := dryRunOutput(format)
+		assert.NoError(t, err, format)
+	}
+	_, err := dryRunOutput("yaml")
+	assert.ErrorContains(t, err, `unsupported dry-run output "yaml"`)
+}
@@ -17,9 +17,72 @@ var (
 	// ErrDomainNotFound is returned when the specified domain is not found
 	ErrDomainNotFound = errors.ErrDomainNotFound
 
+	// ErrInvalidDomainID is returned when the selected domain has no valid ID
+	ErrInvalidDomainID = errors.ErrInvalidDomainID
+
 	// ErrAPIRequestFailed is returned when a request to the MyraSec API fails
 	ErrAPIRequestFailed = errors.ErrAPIRequestFailed
 
+	// ErrAuthenticationFailed is returned when the MyraSec API rejects the credentials
+	ErrAuthenticationFailed = errors.ErrAuthenticationFailed
+
+	// ErrRateLimited is returned when the MyraSec API rate limit is reached
+	ErrRateLimited = errors.ErrRateLimited
+
+	// ErrValidation is returned when the MyraSec API rejects a record as invalid
+	ErrValidation = errors.ErrValidation
+
+	// ErrDuplicateRecord is returned when a record with the same name, type and value already exists
+	ErrDuplicateRecord = errors.ErrDuplicateRecord
+
+	// ErrPrivateIPRejected is returned when the MyraSec API refuses a record pointing to a private IP address
+	ErrPrivateIPRejected = errors.ErrPrivateIPRejected
+
 	// ErrInvalidJSONFormat is returned when the JSON payload cannot be parsed
 	ErrInvalidJSONFormat = errors.ErrInvalidJSONFormat
+
+	// ErrNameOutsideZone is returned when a DNS name does not belong to the selected zone
+	ErrNameOutsideZone = errors.ErrNameOutsideZone
+
+	// ErrChangeLimitExceeded is returned when a plan has more changes than allowed per sync
+	ErrChangeLimitExceeded = errors.ErrChangeLimitExceeded
+
+	// ErrInvalidEndpoints is returned when changes are refused because record values are invalid
+	ErrInvalidEndpoints = errors.ErrInvalidEndpoints
+
+	// ErrOwnerMismatch is returned when changes are refused because their endpoints carry another owner
+	ErrOwnerMismatch = errors.ErrOwnerMismatch
+
+	// ErrApplyInProgress is returned when changes are refused because another apply is still running
+	ErrApplyInProgress = errors.ErrApplyInProgress
+
+	// ErrShuttingDown is returned when changes are refused because the webhook is shutting down
+	ErrShuttingDown = errors.ErrShuttingDown
+
+	// ErrApplyTimeout is returned when the changes were not all applied within the apply timeout
+	ErrApplyTimeout = errors.ErrApplyTimeout
+)
+
+type (
+	// ChangeError is returned when a single DNS record change could not be applied
+	ChangeError = errors.ChangeError
+
+	// ChangesError lists the failed changes of an ApplyChanges call
+	ChangesError = errors.ChangesError
+
+	// ApplyTimeoutError lists the applied and the remaining changes of an ApplyChanges call
+	// aborted by the apply timeout
+	ApplyTimeoutError = errors.ApplyTimeoutError
+
+	// ChangeLimitError is returned when a plan is refused by the deletion or change limit
+	ChangeLimitError = errors.ChangeLimitError
+
+	// InvalidEndpoint is an endpoint refused by the validation of its record values
+	InvalidEndpoint = errors.InvalidEndpoint
+
+	// InvalidEndpointsError is returned when a plan is refused because endpoints are invalid
+	InvalidEndpointsError = errors.InvalidEndpointsError
+
+	// OwnerMismatchError is returned when a plan is refused because endpoints carry another owner
+	OwnerMismatchError = errors.OwnerMismatchError
 )
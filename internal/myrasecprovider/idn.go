@@ -0,0 +1,67 @@
+package myrasecprovider
+
+import (
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// asciiName converts the internationalized labels of a DNS name to their punycode form, as
+// MyraSec stores the names of its zones, so that names match whichever form ExternalDNS or MyraSec
+// use. Only labels with non-ASCII characters are converted, wildcards and underscores are kept.
+// A label that cannot be converted is left as is and fails the validation of the endpoint.
+func asciiName(name string) string {
+	if isASCII(name) {
+		return name
+	}
+	labels := strings.Split(name, ".")
+	for i, label := range labels {
+		if isASCII(label) {
+			continue
+		}
+		if ascii, err := idna.Lookup.ToASCII(label); err == nil {
+			labels[i] = ascii
+		}
+	}
+	return strings.Join(labels, ".")
+}
+
+// unicodeName converts the punycode labels of a DNS name to their unicode form, the reverse of
+// asciiName. Labels that are not valid punycode are kept.
+func unicodeName(name string) string {
+	labels := strings.Split(name, ".")
+	for i, label := range labels {
+		if !strings.HasPrefix(strings.ToLower(label), "xn--") {
+			continue
+		}
+		if unicode, err := idna.Lookup.ToUnicode(label); err == nil {
+			labels[i] = unicode
+		}
+	}
+	return strings.Join(labels, ".")
+}
+
+// idnFilters returns the domain filters in their punycode form, followed by the unicode form of
+// the internationalized ones. The provider matches names in punycode, while the sources of
+// ExternalDNS may produce either form and ExternalDNS filters them with the same domain filter.
+func idnFilters(filters []string) []string {
+	var ascii, unicode []string
+	for _, filter := range filters {
+		name := strings.ToLower(asciiName(stripTrailingDot(strings.TrimSpace(filter))))
+		ascii = append(ascii, name)
+		if u := unicodeName(name); u != name {
+			unicode = append(unicode, u)
+		}
+	}
+	return append(ascii, unicode...)
+}
+
+// isASCII reports whether s has only ASCII characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,105 @@
+package myrasecprovider
+
+import (
+	"context"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestASCIIName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"münchen.example.com", "xn--mnchen-3ya.example.com"},
+		{"MÜNCHEN.example.com", "xn--mnchen-3ya.example.com"},
+		{"app.münchen.de", "app.xn--mnchen-3ya.de"},
+		{"*.münchen.de", "*.xn--mnchen-3ya.de"},
+		{"_sip._tcp.münchen.de", "_sip._tcp.xn--mnchen-3ya.de"},
+		{"xn--mnchen-3ya.example.com", "xn--mnchen-3ya.example.com"},
+		{"App.Example.com", "App.Example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, asciiName(tt.name))
+		})
+	}
+
+	assert.Equal(t, "münchen.example.com", unicodeName("XN--Mnchen-3ya.example.com"))
+	assert.Equal(t, "xn--mnchen-3ya.example.com", normalizeDNSName("XN--Mnchen-3ya.Example.com."))
+	assert.True(t, sameName("münchen.example.com", "XN--MNCHEN-3YA.example.com"))
+}
+
+func TestIDNFilters(t *testing.T) {
+	assert.Equal(t, []string{"xn--mnchen-3ya.de", "example.com", "münchen.de"}, idnFilters([]string{"München.de.", "example.com"}))
+	assert.Equal(t, []string{"xn--mnchen-3ya.de", "münchen.de"}, idnFilters([]string{"xn--mnchen-3ya.de"}))
+	assert.Nil(t, idnFilters(nil))
+}
+
+// TestIDNRecordsRoundTrip tests that internationalized names match whichever form MyraSec stores
+// and ExternalDNS sends
+func TestIDNRecordsRoundTrip(t *testing.T) {
+	ownership := "heritage=external-dns,external-dns/owner=test-owner"
+	newZone := func(name string) (*fakeMyraSecClient, *MyraSecDNSProvider) {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "xn--mnchen-3ya.de"})
+		client.records[123] = []myrasec.DNSRecord{
+			{ID: 1, Name: name, RecordType: endpoint.RecordTypeA, Value: "1.2.3.4", TTL: 300},
+			{ID: 2, Name: name, RecordType: endpoint.RecordTypeTXT, Value: ownership, TTL: 300},
+		}
+		p := newTestProvider(client)
+		p.domainFilter = endpoint.NewDomainFilter(idnFilters([]string{"münchen.de"}))
+		return client, p
+	}
+
+	for _, stored := range []string{"app.münchen.de", "app.xn--mnchen-3ya.de", "App.XN--Mnchen-3ya.de"} {
+		t.Run(stored, func(t *testing.T) {
+			_, p := newZone(stored)
+			endpoints, err := p.Records(context.Background())
+			require.NoError(t, err)
+			var listed *endpoint.Endpoint
+			for _, ep := range endpoints {
+				if ep.RecordType == endpoint.RecordTypeA {
+					listed = ep
+				}
+			}
+			require.NotNil(t, listed)
+			assert.True(t, isASCII(listed.DNSName), listed.DNSName)
+
+			// The desired endpoint in unicode compares equal to the listed one
+			desired := endpoint.NewEndpoint("App.München.de", endpoint.RecordTypeA, "1.2.3.4")
+			adjusted, err := p.AdjustEndpoints([]*endpoint.Endpoint{desired})
+			require.NoError(t, err)
+			require.Len(t, adjusted, 1)
+			assert.Equal(t, "app.xn--mnchen-3ya.de", adjusted[0].DNSName)
+			assert.True(t, sameName(listed.DNSName, adjusted[0].DNSName))
+		})
+
+		t.Run(stored+" delete", func(t *testing.T) {
+			client, p := newZone(stored)
+			require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+				Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("app.münchen.de", endpoint.RecordTypeA, "1.2.3.4")},
+			}))
+			assert.Empty(t, client.records[123])
+		})
+	}
+
+	t.Run("create", func(t *testing.T) {
+		client, p := newZone("other.xn--mnchen-3ya.de")
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+			Create: []*endpoint.Endpoint{endpoint.NewEndpoint("neu.münchen.de", endpoint.RecordTypeA, "5.6.7.8")},
+		}))
+		var names []string
+		for _, r := range client.records[123] {
+			names = append(names, r.Name+" "+r.RecordType)
+		}
+		assert.ElementsMatch(t, []string{
+			"other.xn--mnchen-3ya.de A", "other.xn--mnchen-3ya.de TXT",
+			"neu.xn--mnchen-3ya.de A", "neu.xn--mnchen-3ya.de TXT",
+		}, names)
+	})
+}
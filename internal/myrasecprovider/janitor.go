@@ -0,0 +1,113 @@
+package myrasecprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"go.uber.org/zap"
+)
+
+// RunOrphanJanitor prunes the orphaned ownership TXT records of the managed domains every interval
+// until the context is done, see PruneOrphanedOwnershipRecords.
+func (p *MyraSecDNSProvider) RunOrphanJanitor(ctx context.Context, interval time.Duration) {
+	p.logger.Info("Pruning orphaned ownership records periodically", zap.Duration("interval", interval))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := p.PruneOrphanedOwnershipRecords(ctx); err != nil {
+				p.logger.Error("Failed to prune orphaned ownership records", zap.Error(err))
+			}
+		}
+	}
+}
+
+// PruneOrphanedOwnershipRecords deletes the ownership TXT records of this instance that have no
+// data record left in any managed domain and returns their number. It never runs next to
+// ApplyChanges: while an apply is in progress, or once the webhook drains, nothing is pruned.
+// More orphans than the deletion limit allows are not deleted at all, like a plan exceeding it.
+// In dry-run mode the deletions are only logged.
+func (p *MyraSecDNSProvider) PruneOrphanedOwnershipRecords(ctx context.Context) (int, error) {
+	release, ok := p.tryAcquireApply()
+	if !ok {
+		janitorRuns.WithLabelValues("skipped").Inc()
+		p.logger.Debug("Not pruning orphaned ownership records, an apply is in progress")
+		return 0, nil
+	}
+	defer release()
+	ctx = p.withClient(ctx)
+
+	zones, err := p.selectZones()
+	if err != nil {
+		janitorRuns.WithLabelValues("error").Inc()
+		return 0, err
+	}
+
+	type zoneOrphans struct {
+		snapshot *zoneSnapshot
+		records  []myrasec.DNSRecord
+	}
+	var found []zoneOrphans
+	total := 0
+	for i := range zones {
+		records, err := p.listDNSRecords(ctx, &zones[i])
+		if err != nil {
+			janitorRuns.WithLabelValues("error").Inc()
+			return 0, fmt.Errorf("failed to list DNS records of %s: %w", zones[i].Name, apiError(err))
+		}
+		if orphans := p.zoneOrphans(records, zones[i].Name); len(orphans) > 0 {
+			found = append(found, zoneOrphans{snapshot: newZoneSnapshot(zones[i], records), records: orphans})
+			total += len(orphans)
+		}
+	}
+
+	if total == 0 {
+		janitorRuns.WithLabelValues("success").Inc()
+		p.logger.Debug("No orphaned ownership records found")
+		return 0, nil
+	}
+	if p.maxDeletions > 0 && total > p.maxDeletions {
+		janitorRuns.WithLabelValues("refused").Inc()
+		p.logger.Error("Not pruning orphaned ownership records, there are more than the deletion limit",
+			zap.Int("count", total),
+			zap.Int("max", p.maxDeletions))
+		return 0, &ChangeLimitError{Kind: "deletions", Count: total, Limit: p.maxDeletions}
+	}
+
+	pruned := 0
+	var errs []error
+	for _, zone := range found {
+		for i := range zone.records {
+			if ctx.Err() != nil {
+				janitorRuns.WithLabelValues("error").Inc()
+				return pruned, abortErr(ctx, errs)
+			}
+			record := &zone.records[i]
+			if err := p.deleteDNSRecord(ctx, zone.snapshot, record); err != nil {
+				errs = append(errs, fmt.Errorf("ownership record %s: %w", recordName(record.Name, zone.snapshot.zoneName()), err))
+				continue
+			}
+			pruned++
+			if !p.dryRun {
+				prunedOwnershipRecords.WithLabelValues(zone.snapshot.zoneName()).Inc()
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		janitorRuns.WithLabelValues("error").Inc()
+	} else {
+		janitorRuns.WithLabelValues("success").Inc()
+	}
+	p.logger.Info("Pruned orphaned ownership records",
+		zap.Int("count", pruned),
+		zap.Bool("dry_run", p.dryRun))
+	return pruned, errors.Join(errs...)
+}
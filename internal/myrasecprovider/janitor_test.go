@@ -0,0 +1,113 @@
+package myrasecprovider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestPruneOrphanedOwnershipRecords(t *testing.T) {
+	owned := "heritage=external-dns,external-dns/owner=test-owner"
+	seed := func() *fakeMyraSecClient {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		client.records[123] = []myrasec.DNSRecord{
+			// In use by an A record
+			{ID: 1, Name: "app.example.com", RecordType: endpoint.RecordTypeA, Value: "1.2.3.4", TTL: 300},
+			{ID: 2, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: owned, TTL: 300},
+			// In use by a TXT data record
+			{ID: 3, Name: "spf.example.com", RecordType: endpoint.RecordTypeTXT, Value: "v=spf1 -all", TTL: 300},
+			{ID: 4, Name: "spf.example.com", RecordType: endpoint.RecordTypeTXT, Value: owned, TTL: 300},
+			// Orphaned
+			{ID: 5, Name: "old.example.com", RecordType: endpoint.RecordTypeTXT, Value: owned, TTL: 300},
+		}
+		return client
+	}
+	ids := func(records []myrasec.DNSRecord) []int {
+		var result []int
+		for _, r := range records {
+			result = append(result, r.ID)
+		}
+		return result
+	}
+
+	t.Run("deletes the orphan", func(t *testing.T) {
+		client := seed()
+		p := newTestProvider(client)
+		before := testutil.ToFloat64(prunedOwnershipRecords.WithLabelValues("example.com"))
+
+		pruned, err := p.PruneOrphanedOwnershipRecords(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, pruned)
+		assert.Equal(t, []int{1, 2, 3, 4}, ids(client.records[123]))
+		assert.Equal(t, before+1, testutil.ToFloat64(prunedOwnershipRecords.WithLabelValues("example.com")))
+	})
+
+	t.Run("skipped during an apply", func(t *testing.T) {
+		client := seed()
+		p := newTestProvider(client)
+		release, err := p.acquireApply(context.Background())
+		require.NoError(t, err)
+
+		pruned, err := p.PruneOrphanedOwnershipRecords(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, pruned, "nothing is pruned while an apply is in progress")
+		assert.Len(t, client.records[123], 5)
+
+		release()
+		pruned, err = p.PruneOrphanedOwnershipRecords(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, pruned)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		client := seed()
+		p := newTestProvider(client)
+		p.dryRun = true
+
+		pruned, err := p.PruneOrphanedOwnershipRecords(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, pruned)
+		assert.Len(t, client.records[123], 5)
+	})
+
+	t.Run("deletion limit", func(t *testing.T) {
+		client := seed()
+		client.records[123] = append(client.records[123],
+			myrasec.DNSRecord{ID: 6, Name: "gone.example.com", RecordType: endpoint.RecordTypeTXT, Value: owned, TTL: 300})
+		p := newTestProvider(client)
+		p.maxDeletions = 1
+
+		_, err := p.PruneOrphanedOwnershipRecords(context.Background())
+		var limitErr *ChangeLimitError
+		require.ErrorAs(t, err, &limitErr)
+		assert.Len(t, client.records[123], 6)
+	})
+}
+
+func TestRunOrphanJanitor(t *testing.T) {
+	client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+	client.records[123] = []myrasec.DNSRecord{
+		{ID: 1, Name: "old.example.com", RecordType: endpoint.RecordTypeTXT, Value: "heritage=external-dns,external-dns/owner=test-owner", TTL: 300},
+	}
+	p := newTestProvider(client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.RunOrphanJanitor(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		records, _ := client.ListDNSRecords(123, nil)
+		return len(records) == 0
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+}
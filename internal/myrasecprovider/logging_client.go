@@ -0,0 +1,76 @@
+package myrasecprovider
+
+import (
+	"time"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"go.uber.org/zap"
+)
+
+// loggingClient is a MyraSecAPIClient that logs every call of the client it wraps at debug level,
+// with its duration and outcome, to tell which operation is slow without tracing.
+type loggingClient struct {
+	client MyraSecAPIClient
+	logger *zap.Logger
+}
+
+// newLoggingClient wraps client in a loggingClient
+func newLoggingClient(logger *zap.Logger, client MyraSecAPIClient) *loggingClient {
+	return &loggingClient{client: client, logger: logger}
+}
+
+// log logs the operation that started at start and failed with err, if set
+func (c *loggingClient) log(operation string, start time.Time, err error, fields ...zap.Field) {
+	fields = append([]zap.Field{
+		zap.String("operation", operation),
+		zap.Duration("duration", time.Since(start)),
+	}, fields...)
+	if err != nil {
+		c.logger.Debug("MyraSec API call failed", append(fields, zap.Error(err))...)
+		return
+	}
+	c.logger.Debug("MyraSec API call succeeded", fields...)
+}
+
+// recordFields returns the log fields of the record, if any
+func recordFields(record *myrasec.DNSRecord) []zap.Field {
+	if record == nil {
+		return nil
+	}
+	return []zap.Field{zap.String("record_name", record.Name), zap.String("record_type", record.RecordType)}
+}
+
+func (c *loggingClient) ListDomains(params map[string]string) ([]myrasec.Domain, error) {
+	start := time.Now()
+	domains, err := c.client.ListDomains(params)
+	c.log("ListDomains", start, err, zap.Int("count", len(domains)))
+	return domains, err
+}
+
+func (c *loggingClient) ListDNSRecords(domainId int, params map[string]string) ([]myrasec.DNSRecord, error) {
+	start := time.Now()
+	records, err := c.client.ListDNSRecords(domainId, params)
+	c.log("ListDNSRecords", start, err, zap.Int("domain_id", domainId), zap.Int("count", len(records)))
+	return records, err
+}
+
+func (c *loggingClient) CreateDNSRecord(record *myrasec.DNSRecord, domainId int) (*myrasec.DNSRecord, error) {
+	start := time.Now()
+	created, err := c.client.CreateDNSRecord(record, domainId)
+	c.log("CreateDNSRecord", start, err, append(recordFields(record), zap.Int("domain_id", domainId))...)
+	return created, err
+}
+
+func (c *loggingClient) UpdateDNSRecord(record *myrasec.DNSRecord, domainId int) (*myrasec.DNSRecord, error) {
+	start := time.Now()
+	updated, err := c.client.UpdateDNSRecord(record, domainId)
+	c.log("UpdateDNSRecord", start, err, append(recordFields(record), zap.Int("domain_id", domainId))...)
+	return updated, err
+}
+
+func (c *loggingClient) DeleteDNSRecord(record *myrasec.DNSRecord, domainId int) (*myrasec.DNSRecord, error) {
+	start := time.Now()
+	deleted, err := c.client.DeleteDNSRecord(record, domainId)
+	c.log("DeleteDNSRecord", start, err, append(recordFields(record), zap.Int("domain_id", domainId))...)
+	return deleted, err
+}
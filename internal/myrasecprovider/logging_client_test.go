@@ -0,0 +1,58 @@
+package myrasecprovider
+
+import (
+	"errors"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLoggingClient(t *testing.T) {
+	mockClient := new(MockMyraSecClient)
+	core, logs := observer.New(zap.DebugLevel)
+	client := newLoggingClient(zap.New(core), mockClient)
+
+	record := &myrasec.DNSRecord{Name: "app.example.com", RecordType: "A", Value: "1.1.1.1"}
+	failure := errors.New("boom")
+	mockClient.On("ListDomains", map[string]string{"pageSize": "9999"}).Return([]myrasec.Domain{{ID: 123, Name: "example.com"}}, nil)
+	mockClient.On("ListDNSRecords", 123, map[string]string(nil)).Return([]myrasec.DNSRecord{*record}, nil)
+	mockClient.On("CreateDNSRecord", record, 123).Return(record, nil)
+	mockClient.On("UpdateDNSRecord", record, 123).Return((*myrasec.DNSRecord)(nil), failure)
+	mockClient.On("DeleteDNSRecord", record, 123).Return(record, nil)
+
+	domains, err := client.ListDomains(map[string]string{"pageSize": "9999"})
+	require.NoError(t, err)
+	assert.Equal(t, []myrasec.Domain{{ID: 123, Name: "example.com"}}, domains)
+	records, err := client.ListDNSRecords(123, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []myrasec.DNSRecord{*record}, records)
+	created, err := client.CreateDNSRecord(record, 123)
+	require.NoError(t, err)
+	assert.Same(t, record, created)
+	_, err = client.UpdateDNSRecord(record, 123)
+	assert.Same(t, failure, err)
+	_, err = client.DeleteDNSRecord(record, 123)
+	require.NoError(t, err)
+	mockClient.AssertExpectations(t)
+
+	entries := logs.All()
+	require.Len(t, entries, 5)
+	for i, operation := range []string{"ListDomains", "ListDNSRecords", "CreateDNSRecord", "UpdateDNSRecord", "DeleteDNSRecord"} {
+		fields := entries[i].ContextMap()
+		assert.Equal(t, zap.DebugLevel, entries[i].Level, operation)
+		assert.Equal(t, operation, fields["operation"])
+		assert.Contains(t, fields, "duration", operation)
+	}
+
+	assert.Equal(t, "MyraSec API call succeeded", entries[2].Message)
+	assert.Equal(t, int64(123), entries[2].ContextMap()["domain_id"])
+	assert.Equal(t, "app.example.com", entries[2].ContextMap()["record_name"])
+	assert.Equal(t, "A", entries[2].ContextMap()["record_type"])
+
+	assert.Equal(t, "MyraSec API call failed", entries[3].Message)
+	assert.Equal(t, "boom", entries[3].ContextMap()["error"])
+}
@@ -0,0 +1,150 @@
+package myrasecprovider
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const metricsNamespace = "myrasec_webhook"
+
+var (
+	credentialsReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "credentials_reloads_total",
+		Help:      "Number of MyraSec API credential reloads, by result.",
+	}, []string{"result"})
+
+	credentialsLastReload = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "credentials_last_reload_timestamp_seconds",
+		Help:      "Unix time of the last successful MyraSec API credential reload.",
+	})
+
+	unmanagedChanges = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "unmanaged_record_type_changes_total",
+		Help:      "Number of changes skipped because their record type is not managed, by action and record type.",
+	}, []string{"action", "record_type"})
+
+	outOfFilterChanges = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "out_of_filter_changes_total",
+		Help:      "Number of changes and endpoints skipped because their name is outside the domain filter, by action.",
+	}, []string{"action"})
+
+	setIdentifierEndpoints = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "set_identifier_endpoints_total",
+		Help:      "Number of endpoints dropped because they have a set identifier, by record type.",
+	}, []string{"record_type"})
+
+	rejectedPlans = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "rejected_plans_total",
+		Help:      "Number of plans refused because they exceed the deletion or change limit, by limit.",
+	}, []string{"limit"})
+
+	applyInProgress = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "apply_in_progress",
+		Help:      "1 while changes are applied to the zone, 0 otherwise.",
+	})
+
+	lastRecordsTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "last_records_timestamp_seconds",
+		Help:      "Unix time of the last successful listing of the zone records.",
+	})
+
+	lastApplyTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "last_apply_timestamp_seconds",
+		Help:      "Unix time the last ApplyChanges call finished, successful or not.",
+	})
+
+	lastSuccessfulApplyTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "last_successful_apply_timestamp_seconds",
+		Help:      "Unix time the last ApplyChanges call finished without error.",
+	})
+
+	lastApplySuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "last_apply_success",
+		Help:      "1 if the last ApplyChanges call finished without error, 0 otherwise.",
+	})
+
+	lastApplyDuration = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "last_apply_duration_seconds",
+		Help:      "Duration of the last ApplyChanges call.",
+	})
+
+	lastApplyChanges = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "last_apply_changes",
+		Help:      "Number of records changed by the last ApplyChanges call, by action.",
+	}, []string{"action"})
+
+	appliedChanges = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "applied_changes_total",
+		Help:      "Number of records changed in MyraSec, by action.",
+	}, []string{"action"})
+
+	notifications = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "notifications_total",
+		Help:      "Number of change notifications sent to the notify URL, by result.",
+	}, []string{"result"})
+
+	janitorRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "orphan_janitor_runs_total",
+		Help:      "Number of runs of the orphaned ownership record janitor, by result.",
+	}, []string{"result"})
+
+	prunedOwnershipRecords = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "pruned_ownership_records_total",
+		Help:      "Number of orphaned ownership TXT records deleted by the janitor, by domain.",
+	}, []string{"domain"})
+
+	recordActivationSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "record_activation_seconds",
+		Help:      "Time from the creation of a record until the MyraSec API listed it, by result (active, timeout, aborted).",
+		Buckets:   []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120},
+	}, []string{"result"})
+
+	applyTimeouts = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "apply_timeouts_total",
+		Help:      "Number of ApplyChanges calls aborted because the apply timeout expired.",
+	})
+
+	managedRecords = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "managed_records",
+		Help:      "Number of records owned by this instance as of the last listing, by domain and record type.",
+	}, []string{"domain", "type"})
+
+	foreignRecords = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "foreign_records",
+		Help:      "Number of records not owned by this instance as of the last listing, by domain and record type.",
+	}, []string{"domain", "type"})
+)
+
+// setRecordGauges replaces the record gauges of the domain with the given counts by record type,
+// so that types no longer present in the domain are dropped.
+func setRecordGauges(domain string, managed, foreign map[string]int) {
+	managedRecords.DeletePartialMatch(prometheus.Labels{"domain": domain})
+	foreignRecords.DeletePartialMatch(prometheus.Labels{"domain": domain})
+	for recordType, count := range managed {
+		managedRecords.WithLabelValues(domain, recordType).Set(float64(count))
+	}
+	for recordType, count := range foreign {
+		foreignRecords.WithLabelValues(domain, recordType).Set(float64(count))
+	}
+}
@@ -0,0 +1,108 @@
+package myrasecprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// MigrateOwner finds the ownership TXT records of the owner from in the selected domain and
+// rewrites them to the owner of this instance, so renaming the owner ID does not orphan the
+// records. Only records with heritage external-dns and exactly that owner are considered, and
+// only names passing the domain filter. The records are updated if apply is true; in dry-run
+// mode the updates are only reported. The records found are returned with their old value.
+func (p *MyraSecDNSProvider) MigrateOwner(ctx context.Context, from string, apply bool) ([]ZoneRecord, error) {
+	if from == "" || from == p.owner {
+		return nil, fmt.Errorf("the owner to migrate from must differ from the owner %q of this instance", p.owner)
+	}
+	ctx = p.withClient(ctx)
+
+	selectedDomain, err := p.SelectDomain()
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := p.listDNSRecords(ctx, selectedDomain)
+	if err != nil {
+		p.logger.Error("Failed to list DNS records", zap.String("domain", selectedDomain.Name), zap.Error(err))
+		return nil, fmt.Errorf("failed to list DNS records: %w", apiError(err))
+	}
+
+	var found []myrasec.DNSRecord
+	for _, r := range records {
+		if r.RecordType != endpoint.RecordTypeTXT || !isOwnedByExternalDNS(r.Value, from) {
+			continue
+		}
+		if !p.inDomainFilter(recordName(r.Name, selectedDomain.Name)) {
+			continue
+		}
+		found = append(found, r)
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		return recordName(found[i].Name, selectedDomain.Name) < recordName(found[j].Name, selectedDomain.Name)
+	})
+
+	result := make([]ZoneRecord, 0, len(found))
+	for _, r := range found {
+		result = append(result, ZoneRecord{
+			ID:        r.ID,
+			Domain:    selectedDomain.Name,
+			Name:      recordName(r.Name, selectedDomain.Name),
+			Type:      r.RecordType,
+			Value:     r.Value,
+			TTL:       r.TTL,
+			Enabled:   r.Enabled,
+			Managed:   true,
+			Ownership: OwnershipForeign,
+		})
+	}
+
+	p.logger.Info("Found ownership TXT records to migrate",
+		zap.String("domain", selectedDomain.Name),
+		zap.String("from", from),
+		zap.String("to", p.owner),
+		zap.Int("count", len(result)))
+	if !apply {
+		return result, nil
+	}
+
+	snapshot := newZoneSnapshot(*selectedDomain, records)
+	var errs []error
+	for i := range found {
+		if ctx.Err() != nil {
+			return result, abortErr(ctx, errs)
+		}
+		wanted := found[i]
+		wanted.Value = replaceOwnershipOwner(found[i].Value, p.owner)
+		if err := p.updateDNSRecord(ctx, snapshot, &found[i], &wanted); err != nil {
+			errs = append(errs, fmt.Errorf("ownership record %s: %w", result[i].Name, err))
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// replaceOwnershipOwner returns the ownership TXT value with the owner replaced. The other
+// fields, their order and the quotes around the value are kept.
+func replaceOwnershipOwner(txtValue, owner string) string {
+	value := strings.TrimSpace(txtValue)
+	quoted := len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`)
+
+	parts := strings.Split(strings.Trim(value, `"`), ",")
+	for i, part := range parts {
+		if key, _, ok := strings.Cut(part, "="); ok && strings.TrimSpace(key) == "external-dns/owner" {
+			parts[i] = "external-dns/owner=" + owner
+		}
+	}
+
+	value = strings.Join(parts, ",")
+	if quoted {
+		return `"` + value + `"`
+	}
+	return value
+}
@@ -0,0 +1,88 @@
+package myrasecprovider
+
+import (
+	"context"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestMigrateOwner(t *testing.T) {
+	seed := func() *fakeMyraSecClient {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		client.records[123] = []myrasec.DNSRecord{
+			{ID: 1, Name: "app.example.com", RecordType: endpoint.RecordTypeA, Value: "1.2.3.4", TTL: 300},
+			{ID: 2, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: "heritage=external-dns,external-dns/owner=external-dns,external-dns/resource=ingress/default/app", TTL: 300},
+			{ID: 3, Name: "@", RecordType: endpoint.RecordTypeTXT, Value: `"heritage=external-dns,external-dns/owner=external-dns"`, TTL: 300},
+			// Already owned by this instance
+			{ID: 4, Name: "new.example.com", RecordType: endpoint.RecordTypeTXT, Value: "heritage=external-dns,external-dns/owner=test-owner", TTL: 300},
+			// The owner only starts with the old value
+			{ID: 5, Name: "other.example.com", RecordType: endpoint.RecordTypeTXT, Value: "heritage=external-dns,external-dns/owner=external-dns-staging", TTL: 300},
+			// Not written by ExternalDNS
+			{ID: 6, Name: "fake.example.com", RecordType: endpoint.RecordTypeTXT, Value: "heritage=other,external-dns/owner=external-dns", TTL: 300},
+			{ID: 7, Name: "excluded.example.com", RecordType: endpoint.RecordTypeTXT, Value: "heritage=external-dns,external-dns/owner=external-dns", TTL: 300},
+		}
+		return client
+	}
+	values := func(client *fakeMyraSecClient) map[int]string {
+		result := make(map[int]string)
+		for _, r := range client.records[123] {
+			result[r.ID] = r.Value
+		}
+		return result
+	}
+	newProvider := func(client *fakeMyraSecClient) *MyraSecDNSProvider {
+		p := newTestProvider(client)
+		p.domainFilter = endpoint.NewDomainFilterWithExclusions([]string{"example.com"}, []string{"excluded.example.com"})
+		return p
+	}
+
+	t.Run("lists without updating", func(t *testing.T) {
+		client := seed()
+		before := values(client)
+
+		found, err := newProvider(client).MigrateOwner(context.Background(), "external-dns", false)
+		require.NoError(t, err)
+		require.Len(t, found, 2)
+		assert.Equal(t, 2, found[0].ID)
+		assert.Equal(t, 3, found[1].ID)
+		assert.Equal(t, "example.com", found[1].Name)
+		assert.Equal(t, before, values(client))
+	})
+
+	t.Run("rewrites the owner", func(t *testing.T) {
+		client := seed()
+		before := values(client)
+
+		found, err := newProvider(client).MigrateOwner(context.Background(), "external-dns", true)
+		require.NoError(t, err)
+		assert.Len(t, found, 2)
+
+		after := values(client)
+		assert.Equal(t, "heritage=external-dns,external-dns/owner=test-owner,external-dns/resource=ingress/default/app", after[2])
+		assert.Equal(t, `"heritage=external-dns,external-dns/owner=test-owner"`, after[3])
+		for _, id := range []int{1, 4, 5, 6, 7} {
+			assert.Equal(t, before[id], after[id], "record %d", id)
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		client := seed()
+		before := values(client)
+		p := newProvider(client)
+		p.dryRun = true
+
+		found, err := p.MigrateOwner(context.Background(), "external-dns", true)
+		require.NoError(t, err)
+		assert.Len(t, found, 2)
+		assert.Equal(t, before, values(client))
+	})
+
+	t.Run("same owner", func(t *testing.T) {
+		_, err := newProvider(seed()).MigrateOwner(context.Background(), "test-owner", true)
+		require.Error(t, err)
+	})
+}
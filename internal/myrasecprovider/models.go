@@ -15,4 +15,11 @@ type changeTask struct {
 	action    string
 	change    *endpoint.Endpoint
 	oldChange *endpoint.Endpoint // Used for update operations to track the old record state
+	snapshot  *zoneSnapshot      // Records of the domain the change is applied to
+}
+
+// taskResult is the outcome of a changeTask
+type taskResult struct {
+	task changeTask
+	err  error
 }
@@ -3,17 +3,25 @@ package myrasecprovider
 import (
 	"context"
 	"fmt"
-	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
 	"go.uber.org/zap"
 	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/plan"
 	"sigs.k8s.io/external-dns/provider"
+
+	"github.com/netguru/myra-external-dns-webhook/pkg/version"
 )
 
 const (
 	defaultOwnerTag = "external-dns" // Must match --txt-owner-id in ExternalDNS
+	apexRecordName  = "@"            // Short form of the zone apex name
+
+	domainRefreshInterval = 30 * time.Second // Minimum age of the domain cache for a forced refresh
 )
 
 // MyraSecAPIClient defines the interface for interacting with the MyraSec API
@@ -28,113 +36,468 @@ type MyraSecAPIClient interface {
 // MyraSecDNSProvider is the implementation of the MyraSec DNS provider
 type MyraSecDNSProvider struct {
 	provider.BaseProvider
-	apiClient         MyraSecAPIClient
-	logger            *zap.Logger
-	domainFilter      endpoint.DomainFilter
-	domainId          string
-	domainName        string
-	dryRun            bool
-	cachedDomains     []myrasec.Domain
-	ttl               int
-	owner             string
-	disableProtection bool
+	clientMu         sync.RWMutex
+	apiClient        MyraSecAPIClient
+	newClient        func(apiKey, apiSecret string) (MyraSecAPIClient, error)
+	logger           *zap.Logger
+	domainFilter     endpoint.DomainFilter
+	excludeFilter    endpoint.DomainFilter
+	zoneMu           sync.RWMutex
+	zones            []myrasec.Domain
+	pinnedDomain     *myrasec.Domain
+	dryRun           bool
+	domainsMu        sync.Mutex
+	cachedDomains    []myrasec.Domain
+	domainsFetchedAt time.Time
+	domainCacheTTL   time.Duration
+	ttl              int
+	ttlPerType       map[string]int
+	// ttlOnCreateOnly keeps the stored TTL of records whose endpoint has no TTL
+	ttlOnCreateOnly  bool
+	minTTL           int
+	maxTTL           int
+	owner            string
+	workers          int
+	continueOnError  bool
+	managedTypes     map[string]bool
+	adoptExisting    bool
+	reclaimOwnership bool
+	// deleteOnEmptyTargets deletes the records of an updated endpoint that has no targets
+	deleteOnEmptyTargets bool
+	preExistingWarned    sync.Map
+	disableProtection    bool
+	// protectionPerType overrides the default protection state of the listed record types
+	protectionPerType map[string]bool
+	maxDeletions      int
+	maxChanges        int
+	notifier          *notifier
+	// applyLock holds a token while ApplyChanges runs, see acquireApply
+	applyLockOnce         sync.Once
+	applyLock             chan struct{}
+	rejectConcurrentApply bool
+	// draining is closed by Drain
+	drainOnce sync.Once
+	draining  chan struct{}
+
+	rejectInvalidEndpoints bool
+
+	// domainsErr is the error of a domain filter matching none of the cached domains
+	domainsErr error
+
+	// strictOwner refuses plans with endpoints of another owner, see checkPlanOwner
+	strictOwner bool
+
+	// environment is the name of the deployment environment, see isProduction
+	environment string
+
+	// applyTimeout ends every ApplyChanges call, see withApplyTimeout
+	applyTimeout time.Duration
+
+	// recordActiveTimeout is how long a created record is waited for, see waitForRecordActive
+	recordActiveTimeout time.Duration
+	recordActivePoll    time.Duration
+
+	// dryRunOutput is DryRunOutputJSON or empty, dryRunOutputFile empty writes to stdout
+	dryRunOutput     string
+	dryRunOutputFile string
+
+	syncState syncState
+	// listedRecords is the number of records of the last successful Records call and
+	// applyStartedAt the start of the apply in progress, both for State
+	listedRecords  atomic.Int64
+	applyStartedAt atomic.Pointer[time.Time]
 }
 
 // NewMyraSecDNSProvider initializes a new MyraSec DNS provider.
 func NewMyraSecDNSProvider(logger *zap.Logger, providerConfig Config) (*MyraSecDNSProvider, error) {
-	if providerConfig.APIKey == "" {
-		return nil, fmt.Errorf("no API key provided")
+	if len(providerConfig.Accounts) > 0 {
+		if providerConfig.APIKey != "" || providerConfig.APISecret != "" {
+			return nil, fmt.Errorf("an API key and secret cannot be combined with accounts, configure them as an account")
+		}
+		if providerConfig.DomainID != 0 || providerConfig.DomainName != "" {
+			return nil, fmt.Errorf("a pinned domain cannot be combined with accounts")
+		}
+	} else {
+		if providerConfig.APIKey == "" {
+			return nil, fmt.Errorf("no API key provided")
+		}
+
+		if providerConfig.APISecret == "" {
+			return nil, fmt.Errorf("no API secret provided")
+		}
+	}
+
+	minTTL, maxTTL := providerConfig.MinTTL, providerConfig.MaxTTL
+	if minTTL <= 0 {
+		minTTL = DefaultMinTTL
+	}
+	if maxTTL <= 0 {
+		maxTTL = DefaultMaxTTL
+	}
+	if minTTL > maxTTL {
+		return nil, fmt.Errorf("minimum TTL %d is greater than maximum TTL %d", minTTL, maxTTL)
+	}
+	if providerConfig.MaxDeletionsPerSync < 0 || providerConfig.MaxChangesPerSync < 0 {
+		return nil, fmt.Errorf("the deletion and change limits must not be negative")
+	}
+
+	var apiBaseURL string
+	if providerConfig.BaseURL != "" {
+		var err error
+		apiBaseURL, err = apiBaseURLFormat(providerConfig.BaseURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	language, err := apiLanguage(providerConfig.Language)
+	if err != nil {
+		return nil, err
 	}
 
-	if providerConfig.APISecret == "" {
-		return nil, fmt.Errorf("no API secret provided")
+	managedTypes, err := managedRecordTypes(providerConfig.ManagedRecordTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	ttls, err := ttlPerType(providerConfig.TTLPerType)
+	if err != nil {
+		return nil, err
+	}
+
+	protection, err := protectionPerType(providerConfig.ProtectionPerType)
+	if err != nil {
+		return nil, err
+	}
+
+	ttlCreateOnly, err := ttlOnCreateOnly(providerConfig.TTLEnforcement)
+	if err != nil {
+		return nil, err
+	}
+
+	// The domains of the accounts are managed besides the domain filter
+	if len(providerConfig.Accounts) > 0 {
+		providerConfig.DomainFilter.Filters = withAccountDomains(providerConfig.DomainFilter.Filters, providerConfig.Accounts)
+	}
+
+	// Names are matched in their punycode form, see asciiName
+	providerConfig.DomainFilter.Filters = idnFilters(providerConfig.DomainFilter.Filters)
+	providerConfig.ExcludeDomains = idnFilters(providerConfig.ExcludeDomains)
+
+	pinned, err := pinnedDomain(providerConfig.DomainID, providerConfig.DomainName, providerConfig.DomainFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	rejectConcurrent, err := rejectConcurrentApply(providerConfig.ConcurrentApply)
+	if err != nil {
+		return nil, err
+	}
+
+	rejectInvalidEndpoints, err := rejectInvalid(providerConfig.InvalidEndpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	dryRunFormat, err := dryRunOutput(providerConfig.DryRunOutput)
+	if err != nil {
+		return nil, err
+	}
+
+	notifier, err := newNotifier(logger.With(zap.String("component", "notifier")), providerConfig.NotifyURL, providerConfig.NotifyToken, providerConfig.NotifyTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := configureAPITransport(logger, providerConfig.APIProxy, providerConfig.APICABundle, apiBaseURL); err != nil {
+		return nil, err
 	}
 
 	// Initialize the MyraSec API client
-	api, err := myrasec.New(
-		providerConfig.APIKey,
-		providerConfig.APISecret,
-	)
+	userAgent := version.UserAgent()
+	if providerConfig.UserAgentOwner {
+		owner := defaultOwnerTag
+		if providerConfig.Owner != "" {
+			owner = providerConfig.Owner
+		}
+		userAgent = version.UserAgentWithOwner(owner)
+	}
+	// Every call is logged at debug level, also those of clients created for rotated credentials
+	newClient := func(apiKey, apiSecret string) (MyraSecAPIClient, error) {
+		api, err := newAPIClient(apiKey, apiSecret, apiBaseURL, language, userAgent)
+		if err != nil {
+			return nil, err
+		}
+		return newLoggingClient(logger, api), nil
+	}
+	var api MyraSecAPIClient
+	if len(providerConfig.Accounts) > 0 {
+		api, err = newAccountsClient(providerConfig.Accounts, newClient)
+		if err == nil {
+			logger.Info("Managing several MyraSec accounts", zap.Int("accounts", len(providerConfig.Accounts)))
+		}
+	} else {
+		api, err = newClient(providerConfig.APIKey, providerConfig.APISecret)
+	}
 	if err != nil {
 		logger.Error("Failed to create MyraSec API client", zap.Error(err))
-		return nil, fmt.Errorf("failed to create MyraSec API client: %w", err)
+		return nil, err
 	}
 
-	// Set the API language to English to ensure consistent responses
-	api.Language = "en"
+	if apiBaseURL != "" {
+		logger.Info("Using alternative MyraSec API endpoint", zap.String("base_url", providerConfig.BaseURL))
+	}
+	logger.Info("Using MyraSec API language", zap.String("language", language))
 
 	provider := &MyraSecDNSProvider{
 		BaseProvider:      provider.BaseProvider{},
 		apiClient:         api,
+		newClient:         newClient,
 		logger:            logger,
 		domainFilter:      providerConfig.DomainFilter,
+		excludeFilter:     endpoint.NewDomainFilter(providerConfig.ExcludeDomains),
 		dryRun:            providerConfig.DryRun,
+		domainCacheTTL:    providerConfig.DomainCacheTTL,
 		ttl:               providerConfig.TTL,
+		ttlPerType:        ttls,
+		ttlOnCreateOnly:   ttlCreateOnly,
+		minTTL:            minTTL,
+		maxTTL:            maxTTL,
 		owner:             defaultOwnerTag,
+		workers:           DefaultWorkers,
+		continueOnError:   providerConfig.ContinueOnError,
+		managedTypes:      managedTypes,
+		adoptExisting:     providerConfig.AdoptExistingRecords,
+		reclaimOwnership:  providerConfig.ReclaimOwnership,
 		disableProtection: providerConfig.DisableProtection,
+		protectionPerType: protection,
+		maxDeletions:      providerConfig.MaxDeletionsPerSync,
+		maxChanges:        providerConfig.MaxChangesPerSync,
+		notifier:          notifier,
+		pinnedDomain:      pinned,
+
+		deleteOnEmptyTargets:   providerConfig.DeleteOnEmptyTargets,
+		rejectConcurrentApply:  rejectConcurrent,
+		rejectInvalidEndpoints: rejectInvalidEndpoints,
+
+		strictOwner: providerConfig.StrictOwner,
+
+		environment: providerConfig.Environment,
+
+		applyTimeout: providerConfig.ApplyTimeout,
+
+		recordActiveTimeout: providerConfig.WaitForRecordActive,
+		recordActivePoll:    DefaultRecordActivePollInterval,
+
+		dryRunOutput:     dryRunFormat,
+		dryRunOutputFile: providerConfig.DryRunOutputFile,
+	}
+	if len(providerConfig.ExcludeDomains) > 0 {
+		provider.domainFilter = endpoint.NewDomainFilterWithExclusions(providerConfig.DomainFilter.Filters, providerConfig.ExcludeDomains)
+		logger.Info("Excluding domains", zap.Strings("exclude_domains", provider.excludeFilter.Filters))
+	}
+	if providerConfig.Owner != "" {
+		provider.owner = providerConfig.Owner
+	}
+	if providerConfig.Workers > 0 {
+		provider.workers = providerConfig.Workers
+	}
+	if provider.ttl == TTLInherit {
+		logger.Info("Records without a TTL inherit the default TTL of their domain")
+	} else if clamped := provider.clampTTL(provider.ttl); clamped != provider.ttl {
+		logger.Warn("Default TTL is outside the allowed range, clamping",
+			zap.Int("ttl", provider.ttl), zap.Int("clamped_ttl", clamped))
+		provider.ttl = clamped
+	}
+	for recordType, ttl := range provider.ttlPerType {
+		if clamped := provider.clampTTL(ttl); clamped != ttl {
+			logger.Warn("Default TTL of the record type is outside the allowed range, clamping",
+				zap.String("type", recordType), zap.Int("ttl", ttl), zap.Int("clamped_ttl", clamped))
+			provider.ttlPerType[recordType] = clamped
+		}
+	}
+	if len(provider.ttlPerType) > 0 {
+		logger.Info("Using default TTLs per record type", zap.Any("ttl_per_type", provider.ttlPerType))
+	}
+	if len(provider.protectionPerType) > 0 {
+		logger.Info("Using default protection per record type", zap.Any("protection_per_type", provider.protectionPerType))
+	}
+	if provider.applyTimeout > 0 {
+		logger.Info("Aborting changes not applied within the apply timeout", zap.Duration("apply_timeout", provider.applyTimeout))
+	}
+	if provider.recordActiveTimeout > 0 {
+		logger.Info("Waiting for created records to become active", zap.Duration("timeout", provider.recordActiveTimeout))
+	}
+	if pinned != nil {
+		logger.Info("Using pinned domain, the domains of the account are not listed",
+			zap.String("domain", pinned.Name), zap.Int("domain_id", pinned.ID))
 	}
 
 	return provider, nil
 }
 
+// newAPIClient creates a MyraSec API client for the given credentials, sending userAgent with
+// every request. An empty apiBaseURL keeps the default MyraSec API endpoint.
+func newAPIClient(apiKey, apiSecret, apiBaseURL, language, userAgent string) (*myrasec.API, error) {
+	api, err := myrasec.New(apiKey, apiSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MyraSec API client: %w", err)
+	}
+
+	// The error handling does not depend on the language, see createError
+	if err := api.SetLanguage(language); err != nil {
+		return nil, err
+	}
+	api.UserAgent = userAgent
+
+	if apiBaseURL != "" {
+		api.BaseURL = apiBaseURL
+	}
+	return api, nil
+}
+
 // GetDomains retrieves all domains from the MyraSec API and applies filtering if configured
-// It also caches the domains for future use
+// It also caches the domains for the domain cache TTL
 func (p *MyraSecDNSProvider) GetDomains() ([]myrasec.Domain, error) {
-	// If we have cached domains, return them
-	if len(p.cachedDomains) > 0 {
+	return p.getDomains(false)
+}
+
+// AccountDomains returns the names of all domains of the MyraSec account, without the domain
+// filter and bypassing the cache. It is used to verify the credentials and the domain filter.
+// With a pinned domain only its records are listed, to confirm the access, and its name is returned.
+func (p *MyraSecDNSProvider) AccountDomains() ([]string, error) {
+	if p.pinnedDomain != nil {
+		if _, err := p.client().ListDNSRecords(p.pinnedDomain.ID, map[string]string{"pageSize": "1"}); err != nil {
+			return nil, fmt.Errorf("failed to list the records of the pinned domain %s: %w", p.pinnedDomain.Name, apiError(err))
+		}
+		return []string{p.pinnedDomain.Name}, nil
+	}
+
+	domains, err := p.client().ListDomains(map[string]string{"pageSize": "9999"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list domains: %w", apiError(err))
+	}
+	names := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		names = append(names, domain.Name)
+	}
+	return names, nil
+}
+
+// refreshDomains retrieves the domains from the MyraSec API before the cache expires.
+// Refreshes are limited to one per domainRefreshInterval.
+func (p *MyraSecDNSProvider) refreshDomains() ([]myrasec.Domain, error) {
+	return p.getDomains(true)
+}
+
+func (p *MyraSecDNSProvider) getDomains(force bool) ([]myrasec.Domain, error) {
+	if p.pinnedDomain != nil {
+		return []myrasec.Domain{*p.pinnedDomain}, nil
+	}
+
+	p.domainsMu.Lock()
+	defer p.domainsMu.Unlock()
+
+	cacheTTL := p.domainCacheTTL
+	if cacheTTL <= 0 {
+		cacheTTL = DefaultDomainCacheTTL
+	}
+
+	// If we have cached domains that did not expire, return them. A domain filter matching none
+	// of them is checked again once per domainRefreshInterval, the domain may be added any time.
+	maxAge := cacheTTL
+	if force || p.domainsErr != nil {
+		maxAge = min(cacheTTL, domainRefreshInterval)
+	}
+	if (len(p.cachedDomains) > 0 || p.domainsErr != nil) && time.Since(p.domainsFetchedAt) < maxAge {
 		p.logger.Debug("Using cached domains", zap.Int("count", len(p.cachedDomains)))
-		return p.cachedDomains, nil
+		return p.cachedDomains, p.domainsErr
 	}
 
-	p.logger.Debug("Retrieving domains from MyraSec API")
-	domains, err := p.apiClient.ListDomains(map[string]string{ "pageSize": "9999" })
+	p.logger.Debug("Retrieving domains from MyraSec API", zap.Bool("forced", force))
+	domains, err := p.client().ListDomains(map[string]string{"pageSize": "9999"})
 	if err != nil {
 		p.logger.Error("Failed to list domains", zap.Error(err))
-		return nil, fmt.Errorf("failed to list domains: %w", err)
+		return nil, fmt.Errorf("failed to list domains: %w", apiError(err))
 	}
 
 	p.logger.Debug("Domains retrieved", zap.Int("count", len(domains)))
 
-	// Filter domains if domain filter is configured
+	// Filter domains if domain filter is configured. A filter matching none of the domains is an
+	// error, the webhook must not fall back to a domain it was not meant to manage.
+	p.domainsErr = nil
 	if len(p.domainFilter.Filters) > 0 {
 		var filteredDomains []myrasec.Domain
 		for _, domain := range domains {
-			if p.domainFilter.Match(domain.Name) {
+			if p.inDomainFilter(domain.Name) {
 				filteredDomains = append(filteredDomains, domain)
 			}
 		}
 
 		if len(filteredDomains) == 0 {
-			p.logger.Warn("No domains match the configured filters",
+			p.domainsErr = noMatchingDomainsError(p.domainFilter.Filters, domains)
+			p.logger.Error("No domains match the configured filters",
 				zap.Strings("filters", p.domainFilter.Filters),
-				zap.Int("available_domains", len(domains)))
-			// Return all domains but with a warning
-			p.cachedDomains = domains
-			return domains, nil
+				zap.Strings("available_domains", domainNames(domains)))
+		} else {
+			p.logger.Debug("Filtered domains",
+				zap.Int("filtered_count", len(filteredDomains)),
+				zap.Int("total_count", len(domains)))
 		}
+		domains = filteredDomains
+	}
 
-		p.logger.Debug("Filtered domains",
-			zap.Int("filtered_count", len(filteredDomains)),
-			zap.Int("total_count", len(domains)))
+	// Cache the domains until the cache TTL expires
+	p.cachedDomains = domains
+	p.domainsFetchedAt = time.Now()
+	return domains, p.domainsErr
+}
 
-		// Cache the filtered domains
-		p.cachedDomains = filteredDomains
-		return filteredDomains, nil
+// noMatchingDomainsError returns the error of a domain filter matching none of the domains, with
+// the names of the domains that are visible to the credentials
+func noMatchingDomainsError(filters []string, domains []myrasec.Domain) error {
+	available := "the account has no domains"
+	if len(domains) > 0 {
+		available = "available: " + strings.Join(domainNames(domains), ", ")
 	}
+	return fmt.Errorf("%w: the domain filter %s matches none of the domains of the account (%s)",
+		ErrDomainNotFound, strings.Join(filters, ", "), available)
+}
 
-	// Cache all domains if no filter is applied
-	p.cachedDomains = domains
-	return domains, nil
+// domainNames returns the names of the domains
+func domainNames(domains []myrasec.Domain) []string {
+	names := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		names = append(names, domain.Name)
+	}
+	return names
 }
 
-// SelectDomain chooses the appropriate domain based on filters and available domains
-// It returns the selected domain and sets the provider's domainId and domainName
+// Ready reports whether the webhook can serve ExternalDNS: the MyraSec API lists the domains and
+// the domain filter selects at least one of them. The domains are served from the cache.
+func (p *MyraSecDNSProvider) Ready() error {
+	_, err := p.SelectDomain()
+	return err
+}
+
+// SelectDomain chooses the appropriate domain based on filters and available domains.
+// The provider keeps no selected domain, callers pass the result on explicitly.
 func (p *MyraSecDNSProvider) SelectDomain() (*myrasec.Domain, error) {
 	domains, err := p.GetDomains()
 	if err != nil {
 		return nil, err
 	}
 
+	// A domain added to the account after the cache was filled is picked up right away
+	if p.pinnedDomain == nil && len(p.domainFilter.Filters) > 0 && !containsDomain(domains, p.domainFilter.Filters[0]) {
+		p.logger.Debug("Domain filter not found in cached domains, refreshing",
+			zap.String("filter", p.domainFilter.Filters[0]))
+		domains, err = p.refreshDomains()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if len(domains) == 0 {
 		p.logger.Error("No domains found in MyraSec account")
 		return nil, ErrDomainNotFound
@@ -143,10 +506,12 @@ func (p *MyraSecDNSProvider) SelectDomain() (*myrasec.Domain, error) {
 	var selectedDomain *myrasec.Domain
 
 	// If we have domain filters, try to find a matching domain
-	if len(p.domainFilter.Filters) > 0 {
+	if p.pinnedDomain != nil {
+		selectedDomain = &domains[0]
+	} else if len(p.domainFilter.Filters) > 0 {
 		filterName := p.domainFilter.Filters[0]
 		for _, domain := range domains {
-			if domain.Name == filterName {
+			if asciiName(domain.Name) == filterName {
 				selectedDomain = &domain
 				p.logger.Debug("Using domain from filter",
 					zap.String("domain", domain.Name))
@@ -178,18 +543,32 @@ func (p *MyraSecDNSProvider) SelectDomain() (*myrasec.Domain, error) {
 		p.logger.Error("Failed to select a domain")
 		return nil, ErrDomainNotFound
 	}
-
-	// Set the domain ID and name in the provider
-	p.domainId = strconv.Itoa(selectedDomain.ID)
-	p.domainName = selectedDomain.Name
+	if selectedDomain.ID <= 0 {
+		return nil, fmt.Errorf("%w %d of domain %s", ErrInvalidDomainID, selectedDomain.ID, selectedDomain.Name)
+	}
 
 	p.logger.Debug("Selected domain",
 		zap.String("domain_name", selectedDomain.Name),
-		zap.String("domain_id", p.domainId))
+		zap.Int("domain_id", selectedDomain.ID))
 
 	return selectedDomain, nil
 }
 
+// Owner returns the owner ID written to the ownership TXT records of this instance
+func (p *MyraSecDNSProvider) Owner() string {
+	return p.owner
+}
+
+// containsDomain reports whether a domain with the given name is in domains
+func containsDomain(domains []myrasec.Domain, name string) bool {
+	for _, domain := range domains {
+		if asciiName(domain.Name) == asciiName(name) {
+			return true
+		}
+	}
+	return false
+}
+
 // ApplyChanges applies the given changes to the MyraSec DNS records
 func (p *MyraSecDNSProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
 	return p.ApplyChangesWithWorkers(ctx, changes)
@@ -0,0 +1,347 @@
+package myrasecprovider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+
+	"github.com/netguru/myra-external-dns-webhook/pkg/version"
+)
+
+// stubMyraAPI starts a server answering the domain list call and recording the request paths.
+// It also checks that requests identify the webhook build in the User-Agent header.
+func stubMyraAPI(t *testing.T) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var paths []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		if r.Header.Get("User-Agent") != version.UserAgent() {
+			t.Errorf("unexpected User-Agent %q", r.Header.Get("User-Agent"))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"error":false,"list":[{"id":42,"name":"example.com"}],"page":1,"count":1,"pageSize":50}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), paths...)
+	}
+}
+
+func TestNewMyraSecDNSProviderUsesBaseURL(t *testing.T) {
+	server, requests := stubMyraAPI(t)
+
+	p, err := NewMyraSecDNSProvider(zap.NewNop(), Config{
+		APIKey:    "key",
+		APISecret: "secret",
+		BaseURL:   server.URL + "/api/",
+	})
+	require.NoError(t, err)
+
+	domains, err := p.GetDomains()
+	require.NoError(t, err)
+	require.Len(t, domains, 1)
+	assert.Equal(t, 42, domains[0].ID)
+	assert.Equal(t, []string{"/api/domains"}, requests())
+}
+
+func TestUserAgentOwner(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   string
+	}{
+		{name: "without owner", config: Config{Owner: "prod-cluster"}, want: version.UserAgent()},
+		{name: "with owner", config: Config{Owner: "prod-cluster", UserAgentOwner: true}, want: version.UserAgentWithOwner("prod-cluster")},
+		{name: "with default owner", config: Config{UserAgentOwner: true}, want: version.UserAgentWithOwner(defaultOwnerTag)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userAgents := make(chan string, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userAgents <- r.Header.Get("User-Agent")
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"error":false,"list":[],"page":1,"count":0,"pageSize":50}`))
+			}))
+			t.Cleanup(server.Close)
+
+			config := tt.config
+			config.APIKey, config.APISecret, config.BaseURL = "key", "secret", server.URL
+			p, err := NewMyraSecDNSProvider(zap.NewNop(), config)
+			require.NoError(t, err)
+
+			_, err = p.AccountDomains()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, <-userAgents)
+		})
+	}
+	assert.Contains(t, version.UserAgentWithOwner("prod-cluster"), "(commit unknown; +owner=prod-cluster)")
+}
+
+func TestNewMyraSecDNSProviderInvalidBaseURL(t *testing.T) {
+	for _, baseURL := range []string{"ftp://example.com", "not a url", "https://", "https://example.com/?x=1", "://example.com"} {
+		_, err := NewMyraSecDNSProvider(zap.NewNop(), Config{
+			APIKey:    "key",
+			APISecret: "secret",
+			BaseURL:   baseURL,
+		})
+		assert.Error(t, err, baseURL)
+	}
+}
+
+func TestAPIBaseURLFormat(t *testing.T) {
+	tests := map[string]string{
+		"https://apiv2.myracloud.com":      "https://apiv2.myracloud.com/%s",
+		"https://apiv2.myracloud.com/":     "https://apiv2.myracloud.com/%s",
+		"https://apiv2.myracloud.com/%s":   "https://apiv2.myracloud.com/%s",
+		"http://localhost:8081/mock/api":   "http://localhost:8081/mock/api/%s",
+		"http://localhost:8081/mock/api//": "http://localhost:8081/mock/api/%s",
+	}
+	for input, want := range tests {
+		got, err := apiBaseURLFormat(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+}
+
+func TestReloadCredentials(t *testing.T) {
+	server, _ := stubMyraAPI(t)
+	p, err := NewMyraSecDNSProvider(zap.NewNop(), Config{APIKey: "key", APISecret: "secret", BaseURL: server.URL})
+	require.NoError(t, err)
+	old := p.client()
+
+	assert.Error(t, p.ReloadCredentials("", "secret"))
+	assert.Same(t, old, p.client(), "a failed reload keeps the current client")
+
+	require.NoError(t, p.ReloadCredentials("new-key", "new-secret"))
+	assert.NotSame(t, old, p.client())
+}
+
+// reloadingClient reloads the credentials of the provider when the records are listed, in the
+// middle of an apply
+type reloadingClient struct {
+	*fakeMyraSecClient
+	reload func()
+}
+
+func (c *reloadingClient) ListDNSRecords(domainId int, params map[string]string) ([]myrasec.DNSRecord, error) {
+	c.reload()
+	return c.fakeMyraSecClient.ListDNSRecords(domainId, params)
+}
+
+func TestReloadCredentialsDuringApply(t *testing.T) {
+	old := &reloadingClient{fakeMyraSecClient: newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})}
+	reloaded := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+	p := newTestProvider(old)
+	p.newClient = func(apiKey, apiSecret string) (MyraSecAPIClient, error) { return reloaded, nil }
+	old.reload = func() { require.NoError(t, p.ReloadCredentials("new-key", "new-secret")) }
+
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{
+		endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+	}}))
+	assert.Len(t, old.records[123], 2, "the apply finishes with the client it started with")
+	assert.Empty(t, reloaded.records[123])
+	assert.Same(t, reloaded, p.client(), "the next operation uses the new credentials")
+}
+
+// domainCountingClient counts the ListDomains calls of the wrapped client
+type domainCountingClient struct {
+	*fakeMyraSecClient
+	listDomains int
+}
+
+func (c *domainCountingClient) ListDomains(params map[string]string) ([]myrasec.Domain, error) {
+	c.listDomains++
+	return c.fakeMyraSecClient.ListDomains(params)
+}
+
+func TestDomainCacheExpiry(t *testing.T) {
+	client := &domainCountingClient{fakeMyraSecClient: newFakeMyraSecClient(myrasec.Domain{ID: 1, Name: "example.com"})}
+	p := newTestProvider(client)
+	p.domainFilter = endpoint.NewDomainFilter([]string{"example.com", "example.org"})
+	p.domainCacheTTL = 10 * time.Minute
+
+	domains, err := p.GetDomains()
+	require.NoError(t, err)
+	require.Len(t, domains, 1)
+
+	// A domain added to the account is not seen while the cache is valid
+	client.domains = append(client.domains, myrasec.Domain{ID: 2, Name: "example.org"})
+	domains, err = p.GetDomains()
+	require.NoError(t, err)
+	assert.Len(t, domains, 1)
+	assert.Equal(t, 1, client.listDomains)
+
+	// After the cache TTL the domains are fetched again
+	p.domainsFetchedAt = time.Now().Add(-11 * time.Minute)
+	domains, err = p.GetDomains()
+	require.NoError(t, err)
+	assert.Len(t, domains, 2)
+	assert.Equal(t, 2, client.listDomains)
+}
+
+func TestSelectDomainRefreshesForNewDomain(t *testing.T) {
+	client := &domainCountingClient{fakeMyraSecClient: newFakeMyraSecClient(myrasec.Domain{ID: 1, Name: "example.org"})}
+	p := newTestProvider(client)
+	p.domainFilter = endpoint.NewDomainFilter([]string{"example.com", "example.org"})
+
+	_, err := p.GetDomains()
+	require.NoError(t, err)
+
+	// The filtered domain is created after the cache was populated
+	client.domains = append(client.domains, myrasec.Domain{ID: 2, Name: "example.com"})
+	p.domainsFetchedAt = time.Now().Add(-time.Minute)
+
+	selected, err := p.SelectDomain()
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", selected.Name)
+	assert.Equal(t, 2, client.listDomains)
+
+	// Once found, the cache is used again
+	_, err = p.SelectDomain()
+	require.NoError(t, err)
+	assert.Equal(t, 2, client.listDomains)
+}
+
+func TestDomainFilterMatchingNothing(t *testing.T) {
+	client := &domainCountingClient{fakeMyraSecClient: newFakeMyraSecClient(
+		myrasec.Domain{ID: 1, Name: "example.com"},
+		myrasec.Domain{ID: 2, Name: "example.org"},
+	)}
+	p := newTestProvider(client)
+	p.domainFilter = endpoint.NewDomainFilter([]string{"exmaple.net"})
+
+	// The webhook neither falls back to another domain nor becomes ready
+	err := p.Ready()
+	assert.ErrorIs(t, err, ErrDomainNotFound)
+	assert.ErrorContains(t, err, "exmaple.net matches none of the domains of the account (available: example.com, example.org)")
+	_, err = p.Records(context.Background())
+	assert.ErrorIs(t, err, ErrDomainNotFound)
+	assert.Equal(t, 1, client.listDomains, "the result is cached")
+
+	t.Run("matches after a cache refresh", func(t *testing.T) {
+		client.domains = append(client.domains, myrasec.Domain{ID: 3, Name: "exmaple.net"})
+		assert.Error(t, p.Ready(), "the domains are not listed again right away")
+
+		p.domainsFetchedAt = time.Now().Add(-domainRefreshInterval)
+		require.NoError(t, p.Ready())
+		selected, err := p.SelectDomain()
+		require.NoError(t, err)
+		assert.Equal(t, "exmaple.net", selected.Name)
+		assert.Equal(t, 2, client.listDomains)
+	})
+}
+
+func TestPinnedDomain(t *testing.T) {
+	// The credentials may not list the domains of the account
+	client := &domainCountingClient{fakeMyraSecClient: newFakeMyraSecClient()}
+	client.records[42] = []myrasec.DNSRecord{
+		{ID: 1, Name: "app.example.com", RecordType: endpoint.RecordTypeA, Value: "1.2.3.4", TTL: 300},
+		{ID: 2, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: "heritage=external-dns,external-dns/owner=test-owner", TTL: 300},
+	}
+	p := newTestProvider(client)
+	pinned, err := pinnedDomain(42, "Example.com.", p.domainFilter)
+	require.NoError(t, err)
+	p.pinnedDomain = pinned
+
+	selected, err := p.SelectDomain()
+	require.NoError(t, err)
+	assert.Equal(t, myrasec.Domain{ID: 42, Name: "example.com"}, *selected)
+
+	records, err := p.Records(context.Background())
+	require.NoError(t, err)
+	assert.NotNil(t, findEndpoint(records, "app.example.com", endpoint.RecordTypeA))
+
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeA, "1.2.3.5")},
+	}))
+	assert.Len(t, p.findMatchingRecords(client.records[42], "example.com", "www.example.com", endpoint.RecordTypeA), 1)
+
+	domains, err := p.AccountDomains()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com"}, domains)
+	assert.Zero(t, client.listDomains, "the domains of the account must not be listed")
+}
+
+func TestPinnedDomainConfig(t *testing.T) {
+	config := func(id int, name string, filters ...string) Config {
+		return Config{APIKey: "key", APISecret: "secret", DomainID: id, DomainName: name, DomainFilter: endpoint.DomainFilter{Filters: filters}}
+	}
+
+	p, err := NewMyraSecDNSProvider(zap.NewNop(), config(42, "example.com", "example.com"))
+	require.NoError(t, err)
+	assert.Equal(t, &myrasec.Domain{ID: 42, Name: "example.com"}, p.pinnedDomain)
+
+	p, err = NewMyraSecDNSProvider(zap.NewNop(), config(0, ""))
+	require.NoError(t, err)
+	assert.Nil(t, p.pinnedDomain)
+
+	for name, c := range map[string]Config{
+		"name does not match the filter": config(42, "example.com", "example.org"),
+		"ID without name":                config(42, ""),
+		"name without ID":                config(0, "example.com"),
+		"negative ID":                    config(-1, "example.com"),
+	} {
+		_, err := NewMyraSecDNSProvider(zap.NewNop(), c)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestAPIErrorsAreTyped(t *testing.T) {
+	for _, tt := range []struct {
+		status int
+		body   string
+		want   error
+	}{
+		{http.StatusUnauthorized, `{"error":true,"errorMessage":"Invalid signature"}`, ErrAuthenticationFailed},
+		{http.StatusForbidden, `{"error":true,"errorMessage":"Access denied"}`, ErrAuthenticationFailed},
+		{http.StatusTooManyRequests, ``, ErrRateLimited},
+		{http.StatusBadRequest, `{"error":true,"violationList":[{"propertyPath":"value","message":"invalid"}]}`, ErrValidation},
+		{http.StatusUnprocessableEntity, `{"error":true,"errorMessage":"invalid"}`, ErrValidation},
+		{http.StatusBadGateway, ``, ErrAPIRequestFailed},
+	} {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			t.Cleanup(server.Close)
+
+			p, err := NewMyraSecDNSProvider(zap.NewNop(), Config{APIKey: "key", APISecret: "secret", BaseURL: server.URL})
+			require.NoError(t, err)
+
+			_, err = p.GetDomains()
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestNewMyraSecDNSProviderLanguage(t *testing.T) {
+	for language, want := range map[string]string{"": "en", "en": "en", "de": "de"} {
+		p, err := NewMyraSecDNSProvider(zap.NewNop(), Config{APIKey: "key", APISecret: "secret", Language: language})
+		require.NoError(t, err)
+		assert.Equal(t, want, p.client().(*loggingClient).client.(*myrasec.API).Language)
+	}
+
+	_, err := NewMyraSecDNSProvider(zap.NewNop(), Config{APIKey: "key", APISecret: "secret", Language: "fr"})
+	assert.ErrorContains(t, err, `unsupported MyraSec API language "fr", supported are de, en`)
+}
@@ -0,0 +1,217 @@
+package myrasecprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"go.uber.org/zap"
+
+	"github.com/netguru/myra-external-dns-webhook/pkg/redact"
+	"github.com/netguru/myra-external-dns-webhook/pkg/version"
+)
+
+// DefaultNotifyTimeout is how long a notification may take before it is given up.
+const DefaultNotifyTimeout = 5 * time.Second
+
+// appliedChange is a record mutation that was sent to the MyraSec API.
+type appliedChange struct {
+	action string
+	zone   string
+	name   string
+}
+
+// changeReport collects the mutations of a single ApplyChanges call for the notification and
+// the sync status.
+// It is shared by all workers of the call.
+type changeReport struct {
+	mu      sync.Mutex
+	changes []appliedChange
+}
+
+type changeReportKey struct{}
+
+// withChangeReport returns a context carrying the given report.
+func withChangeReport(ctx context.Context, report *changeReport) context.Context {
+	return context.WithValue(ctx, changeReportKey{}, report)
+}
+
+// recordAppliedChange adds a mutation of the record to the report of the context, if any.
+func (p *MyraSecDNSProvider) recordAppliedChange(ctx context.Context, snapshot *zoneSnapshot, action string, record *myrasec.DNSRecord) {
+	report, _ := ctx.Value(changeReportKey{}).(*changeReport)
+	if report == nil {
+		return
+	}
+	report.mu.Lock()
+	report.changes = append(report.changes, appliedChange{
+		action: action,
+		zone:   snapshot.zoneName(),
+		name:   recordName(record.Name, snapshot.zoneName()),
+	})
+	report.mu.Unlock()
+}
+
+// notification is the JSON summary posted to the notify URL after the zone changed. Domains lists
+// every domain with a changed record; Domain names it when a single domain changed, as is the case
+// without delegated subdomains.
+type notification struct {
+	Domain          string         `json:"domain,omitempty"`
+	Domains         []string       `json:"domains"`
+	Counts          map[string]int `json:"counts"`
+	Names           []string       `json:"names"`
+	Errors          []string       `json:"errors,omitempty"`
+	DurationSeconds float64        `json:"durationSeconds"`
+}
+
+// notification summarizes the report. The domains and names are sorted and each is listed once.
+func (r *changeReport) notification(err error, duration time.Duration) notification {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := notification{
+		Domains:         []string{},
+		Counts:          map[string]int{CREATE: 0, UPDATE: 0, DELETE: 0},
+		Names:           []string{},
+		DurationSeconds: duration.Seconds(),
+	}
+	seen := make(map[string]bool)
+	seenZones := make(map[string]bool)
+	for _, change := range r.changes {
+		n.Counts[change.action]++
+		if !seen[change.name] {
+			seen[change.name] = true
+			n.Names = append(n.Names, change.name)
+		}
+		if !seenZones[change.zone] {
+			seenZones[change.zone] = true
+			n.Domains = append(n.Domains, change.zone)
+		}
+	}
+	sort.Strings(n.Names)
+	sort.Strings(n.Domains)
+	if len(n.Domains) == 1 {
+		n.Domain = n.Domains[0]
+	}
+
+	var changesErr *ChangesError
+	if errors.As(err, &changesErr) {
+		for _, f := range changesErr.Failures {
+			n.Errors = append(n.Errors, redact.Error(f))
+		}
+	} else if err != nil {
+		n.Errors = append(n.Errors, redact.Error(err))
+	}
+	return n
+}
+
+// counts returns the number of recorded mutations by action.
+func (r *changeReport) counts() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := make(map[string]int)
+	for _, change := range r.changes {
+		counts[change.action]++
+	}
+	return counts
+}
+
+// empty reports whether no mutation was recorded.
+func (r *changeReport) empty() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.changes) == 0
+}
+
+// notifier posts a notification to a URL after ApplyChanges changed the zone. Notifications are
+// sent in the background; a failing endpoint is logged and does not affect the sync.
+type notifier struct {
+	url    string
+	token  string
+	client *http.Client
+	logger *zap.Logger
+	wg     sync.WaitGroup
+}
+
+// newNotifier returns a notifier for the URL, or nil if no URL is configured.
+func newNotifier(logger *zap.Logger, notifyURL, token string, timeout time.Duration) (*notifier, error) {
+	if notifyURL == "" {
+		return nil, nil
+	}
+	if err := validateNotifyURL(notifyURL); err != nil {
+		return nil, err
+	}
+	if timeout <= 0 {
+		timeout = DefaultNotifyTimeout
+	}
+	return &notifier{
+		url:    notifyURL,
+		token:  token,
+		client: &http.Client{Timeout: timeout},
+		logger: logger,
+	}, nil
+}
+
+// validateNotifyURL checks that the notify URL is an http or https URL
+func validateNotifyURL(notifyURL string) error {
+	parsed, err := url.Parse(notifyURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid notify URL %q: must be an http or https URL", notifyURL)
+	}
+	return nil
+}
+
+// notify sends the notification in the background.
+func (n *notifier) notify(payload notification) {
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		if err := n.send(payload); err != nil {
+			notifications.WithLabelValues("error").Inc()
+			n.logger.Warn("Failed to send change notification", zap.Error(err))
+			return
+		}
+		notifications.WithLabelValues("success").Inc()
+		n.logger.Debug("Sent change notification", zap.Strings("names", payload.Names))
+	}()
+}
+
+// wait blocks until the notifications sent so far are done.
+func (n *notifier) wait() {
+	n.wg.Wait()
+}
+
+// send posts the notification and checks the response status.
+func (n *notifier) send(payload notification) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", version.UserAgent())
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notification endpoint returned %s", resp.Status)
+	}
+	return nil
+}
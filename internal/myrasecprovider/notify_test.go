@@ -0,0 +1,197 @@
+package myrasecprovider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// notificationServer captures the notifications and authorization headers it receives
+type notificationServer struct {
+	*httptest.Server
+	mu             sync.Mutex
+	notifications  []notification
+	authorizations []string
+}
+
+func newNotificationServer(t *testing.T, status int) *notificationServer {
+	s := &notificationServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n notification
+		if assert.NoError(t, json.NewDecoder(r.Body).Decode(&n)) {
+			s.mu.Lock()
+			s.notifications = append(s.notifications, n)
+			s.authorizations = append(s.authorizations, r.Header.Get("Authorization"))
+			s.mu.Unlock()
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *notificationServer) received() []notification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]notification(nil), s.notifications...)
+}
+
+func newNotifyingProvider(t *testing.T, client MyraSecAPIClient, notifyURL string) *MyraSecDNSProvider {
+	p := newTestProvider(client)
+	n, err := newNotifier(zap.NewNop(), notifyURL, "s3cr3t-token", time.Second)
+	require.NoError(t, err)
+	p.notifier = n
+	return p
+}
+
+func TestApplyChangesNotification(t *testing.T) {
+	server := newNotificationServer(t, http.StatusOK)
+	client := &rejectingClient{
+		fakeMyraSecClient: newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"}),
+		reject:            map[string]bool{"10.0.0.1": true},
+	}
+	client.records[123] = []myrasec.DNSRecord{
+		{ID: 1, Name: "old.example.com", RecordType: endpoint.RecordTypeA, Value: "1.2.3.4", TTL: 300},
+		{ID: 2, Name: "old.example.com", RecordType: endpoint.RecordTypeTXT, Value: "heritage=external-dns,external-dns/owner=test-owner", TTL: 300},
+	}
+	p := newNotifyingProvider(t, client, server.URL)
+	p.continueOnError = true
+
+	err := p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.5"),
+			endpoint.NewEndpoint("bad.example.com", endpoint.RecordTypeA, "10.0.0.1"),
+		},
+		Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("old.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+	})
+	require.Error(t, err)
+	p.notifier.wait()
+
+	received := server.received()
+	require.Len(t, received, 1)
+	n := received[0]
+	assert.Equal(t, "example.com", n.Domain)
+	assert.Equal(t, []string{"example.com"}, n.Domains)
+	// The A and ownership TXT records of each name; the ownership record of the failed name is still created
+	assert.Equal(t, map[string]int{CREATE: 3, UPDATE: 0, DELETE: 2}, n.Counts)
+	assert.Equal(t, []string{"app.example.com", "bad.example.com", "old.example.com"}, n.Names)
+	require.Len(t, n.Errors, 1)
+	assert.Contains(t, n.Errors[0], "bad.example.com")
+	assert.Greater(t, n.DurationSeconds, 0.0)
+	assert.Equal(t, []string{"Bearer s3cr3t-token"}, server.authorizations)
+}
+
+// TestApplyChangesNotificationDelegatedDomains tests that the notification names the domains the
+// changes went to, not the first managed domain
+func TestApplyChangesNotificationDelegatedDomains(t *testing.T) {
+	apply := func(t *testing.T, create ...*endpoint.Endpoint) notification {
+		server := newNotificationServer(t, http.StatusOK)
+		client := newFakeMyraSecClient(
+			myrasec.Domain{ID: 1, Name: "example.com"},
+			myrasec.Domain{ID: 2, Name: "dev.example.com"},
+		)
+		p := newNotifyingProvider(t, client, server.URL)
+
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{Create: create}))
+		p.notifier.wait()
+		received := server.received()
+		require.Len(t, received, 1)
+		return received[0]
+	}
+
+	t.Run("delegated domain only", func(t *testing.T) {
+		n := apply(t, endpoint.NewEndpoint("api.dev.example.com", endpoint.RecordTypeA, "1.2.3.4"))
+		assert.Equal(t, "dev.example.com", n.Domain)
+		assert.Equal(t, []string{"dev.example.com"}, n.Domains)
+	})
+
+	t.Run("both domains", func(t *testing.T) {
+		n := apply(t,
+			endpoint.NewEndpoint("api.dev.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeA, "1.2.3.5"),
+		)
+		assert.Empty(t, n.Domain)
+		assert.Equal(t, []string{"dev.example.com", "example.com"}, n.Domains)
+		assert.Equal(t, []string{"api.dev.example.com", "www.example.com"}, n.Names)
+	})
+}
+
+func TestApplyChangesNotificationSkipped(t *testing.T) {
+	t.Run("dry-run", func(t *testing.T) {
+		server := newNotificationServer(t, http.StatusOK)
+		p := newNotifyingProvider(t, newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"}), server.URL)
+		p.dryRun = true
+
+		err := p.ApplyChanges(context.Background(), &plan.Changes{
+			Create: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+		})
+		require.NoError(t, err)
+		p.notifier.wait()
+		assert.Empty(t, server.received())
+	})
+
+	t.Run("no mutation", func(t *testing.T) {
+		server := newNotificationServer(t, http.StatusOK)
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		client.records[123] = []myrasec.DNSRecord{
+			{ID: 1, Name: "app.example.com", RecordType: endpoint.RecordTypeA, Value: "1.2.3.4", TTL: 300},
+			{ID: 2, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: "heritage=external-dns,external-dns/owner=test-owner", TTL: 300},
+		}
+		p := newNotifyingProvider(t, client, server.URL)
+
+		// Deleting a value that does not exist changes nothing
+		err := p.ApplyChanges(context.Background(), &plan.Changes{
+			Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "5.6.7.8")},
+		})
+		require.NoError(t, err)
+		p.notifier.wait()
+		assert.Empty(t, server.received())
+	})
+}
+
+func TestApplyChangesNotificationEndpointDown(t *testing.T) {
+	server := newNotificationServer(t, http.StatusServiceUnavailable)
+	client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+	p := newNotifyingProvider(t, client, server.URL)
+
+	err := p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+	})
+	require.NoError(t, err)
+	p.notifier.wait()
+	assert.Len(t, server.received(), 1)
+	assert.Len(t, client.records[123], 2)
+
+	// An unreachable endpoint does not fail the sync either
+	server.Close()
+	err = p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+	})
+	require.NoError(t, err)
+	p.notifier.wait()
+	assert.Len(t, client.records[123], 4)
+}
+
+func TestNewNotifier(t *testing.T) {
+	n, err := newNotifier(zap.NewNop(), "", "", 0)
+	require.NoError(t, err)
+	assert.Nil(t, n)
+
+	n, err = newNotifier(zap.NewNop(), "https://hooks.example.com/dns", "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultNotifyTimeout, n.client.Timeout)
+
+	_, err = newNotifier(zap.NewNop(), "hooks.example.com/dns", "", 0)
+	assert.ErrorContains(t, err, "invalid notify URL")
+}
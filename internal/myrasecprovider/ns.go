@@ -0,0 +1,26 @@
+package myrasecprovider
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// NS records delegate a subdomain to other nameservers. The nameservers of a delegation are managed
+// as one set: an update reconciles the stored records with the desired targets, and a deletion
+// removes all NS records at the name. The NS records of the zone apex belong to MyraSec and are
+// never changed.
+
+// apexNameservers reports whether dnsName and recordType are the NS records of the zone apex,
+// which are refused with a warning naming the action.
+func (p *MyraSecDNSProvider) apexNameservers(action, dnsName, zone, recordType string) bool {
+	if !strings.EqualFold(recordType, endpoint.RecordTypeNS) || !sameName(dnsName, zone) {
+		return false
+	}
+	p.logger.Warn("Refusing to change the NS records of the zone apex",
+		zap.String("action", action),
+		zap.String("dnsName", dnsName),
+		zap.String("domain", zone))
+	return true
+}
@@ -0,0 +1,119 @@
+package myrasecprovider
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// nameservers returns the IDs of the NS records at name by their value
+func nameservers(client *fakeMyraSecClient, name string) map[string]int {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	result := map[string]int{}
+	for _, r := range client.records[123] {
+		if r.Name == name && r.RecordType == endpoint.RecordTypeNS {
+			result[r.Value] = r.ID
+		}
+	}
+	return result
+}
+
+func newDelegationClient() *fakeMyraSecClient {
+	client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+	client.records[123] = []myrasec.DNSRecord{
+		{ID: 1, Name: "example.com", RecordType: "NS", Value: "ns1.myrasec.net", TTL: 86400},
+		{ID: 2, Name: "team-a.example.com", RecordType: "NS", Value: "ns1.other.com", TTL: 300},
+		{ID: 3, Name: "team-a.example.com", RecordType: "NS", Value: "ns2.other.com", TTL: 300},
+		{ID: 4, Name: "team-a.example.com", RecordType: "TXT", Value: "heritage=external-dns,external-dns/owner=test-owner", TTL: 300},
+	}
+	return client
+}
+
+func TestNameserverDelegation(t *testing.T) {
+	client := newDelegationClient()
+	p := newTestProvider(client)
+
+	endpoints, err := p.Records(context.Background())
+	require.NoError(t, err)
+	current := findEndpoint(endpoints, "team-a.example.com", endpoint.RecordTypeNS)
+	require.NotNil(t, current)
+	sort.Strings(current.Targets)
+	assert.Equal(t, endpoint.Targets{"ns1.other.com", "ns2.other.com"}, current.Targets)
+
+	// Adding a nameserver creates only its record
+	added := endpoint.NewEndpointWithTTL("team-a.example.com", endpoint.RecordTypeNS, 300, "ns1.other.com.", "ns2.other.com.", "NS3.other.com.")
+	adjusted, err := p.AdjustEndpoints([]*endpoint.Endpoint{added})
+	require.NoError(t, err)
+	assert.Equal(t, endpoint.Targets{"ns1.other.com", "ns2.other.com", "ns3.other.com"}, adjusted[0].Targets)
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		UpdateOld: []*endpoint.Endpoint{current},
+		UpdateNew: adjusted,
+	}))
+	got := nameservers(client, "team-a.example.com")
+	assert.Len(t, got, 3)
+	assert.Equal(t, 2, got["ns1.other.com"])
+	assert.Equal(t, 3, got["ns2.other.com"])
+	assert.Contains(t, got, "ns3.other.com")
+
+	// Removing a nameserver deletes only its record
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		UpdateOld: adjusted,
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpointWithTTL("team-a.example.com", endpoint.RecordTypeNS, 300, "ns1.other.com", "ns3.other.com")},
+	}))
+	got = nameservers(client, "team-a.example.com")
+	assert.Len(t, got, 2)
+	assert.Equal(t, 2, got["ns1.other.com"])
+	assert.NotContains(t, got, "ns2.other.com")
+
+	// The apex nameservers are left alone
+	assert.Equal(t, map[string]int{"ns1.myrasec.net": 1}, nameservers(client, "example.com"))
+}
+
+func TestNameserverDelegationDelete(t *testing.T) {
+	client := newDelegationClient()
+	p := newTestProvider(client)
+
+	// The delegation is deleted as a set, even if the plan lists only some of its nameservers
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("team-a.example.com", endpoint.RecordTypeNS, "ns1.other.com.")},
+	}))
+	assert.Empty(t, nameservers(client, "team-a.example.com"))
+	for _, r := range client.records[123] {
+		assert.NotEqual(t, "team-a.example.com", r.Name, "the ownership record is removed with the delegation")
+	}
+}
+
+func TestNameserverApexRefused(t *testing.T) {
+	client := newDelegationClient()
+	client.records[123] = append(client.records[123],
+		myrasec.DNSRecord{ID: 5, Name: "example.com", RecordType: "TXT", Value: "heritage=external-dns,external-dns/owner=test-owner", TTL: 300})
+	p := newTestProvider(client)
+
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create:    []*endpoint.Endpoint{endpoint.NewEndpoint("example.com", endpoint.RecordTypeNS, "ns9.other.com")},
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("example.com", endpoint.RecordTypeNS, "ns1.myrasec.net")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("example.com", endpoint.RecordTypeNS, "ns8.other.com")},
+		Delete:    []*endpoint.Endpoint{endpoint.NewEndpoint("@", endpoint.RecordTypeNS, "ns1.myrasec.net")},
+	}))
+	assert.Equal(t, map[string]int{"ns1.myrasec.net": 1}, nameservers(client, "example.com"))
+}
+
+func TestNameserverCreate(t *testing.T) {
+	client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+	p := newTestProvider(client)
+
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("team-b.example.com", endpoint.RecordTypeNS, "ns1.other.com.", "NS2.other.com")},
+	}))
+	got := nameservers(client, "team-b.example.com")
+	assert.Len(t, got, 2)
+	assert.Contains(t, got, "ns1.other.com")
+	assert.Contains(t, got, "ns2.other.com")
+}
@@ -0,0 +1,113 @@
+package myrasecprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// CleanupOrphanedOwnershipRecords finds the ownership TXT records of this instance in the selected
+// domain that have no data record of a managed type at their name, e.g. left behind by releases
+// that did not delete them. Only names passing the domain filter are considered.
+// The records are deleted if remove is true; the orphans are returned in either case.
+func (p *MyraSecDNSProvider) CleanupOrphanedOwnershipRecords(ctx context.Context, remove bool) ([]ZoneRecord, error) {
+	ctx = p.withClient(ctx)
+	selectedDomain, err := p.SelectDomain()
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := p.listDNSRecords(ctx, selectedDomain)
+	if err != nil {
+		p.logger.Error("Failed to list DNS records", zap.String("domain", selectedDomain.Name), zap.Error(err))
+		return nil, fmt.Errorf("failed to list DNS records: %w", apiError(err))
+	}
+
+	found := p.zoneOrphans(records, selectedDomain.Name)
+	var result []ZoneRecord
+	for _, r := range found {
+		result = append(result, ZoneRecord{
+			ID:        r.ID,
+			Domain:    selectedDomain.Name,
+			Name:      recordName(r.Name, selectedDomain.Name),
+			Type:      r.RecordType,
+			Value:     r.Value,
+			TTL:       r.TTL,
+			Enabled:   r.Enabled,
+			Managed:   true,
+			Ownership: OwnershipOwned,
+		})
+	}
+
+	p.logger.Info("Found orphaned ownership TXT records",
+		zap.String("domain", selectedDomain.Name),
+		zap.Int("count", len(result)))
+	if !remove {
+		return result, nil
+	}
+
+	snapshot := newZoneSnapshot(*selectedDomain, records)
+	var errs []error
+	for i := range found {
+		if ctx.Err() != nil {
+			return result, abortErr(ctx, errs)
+		}
+		if err := p.deleteDNSRecord(ctx, snapshot, &found[i]); err != nil {
+			errs = append(errs, fmt.Errorf("ownership record %s: %w", result[i].Name, err))
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// zoneOrphans returns the orphaned ownership TXT records of this instance among the records of
+// the zone whose names pass the domain filter, sorted by name.
+func (p *MyraSecDNSProvider) zoneOrphans(records []myrasec.DNSRecord, zone string) []myrasec.DNSRecord {
+	orphans := orphanedOwnershipRecords(records, zone, p.owner, p.managesRecordType)
+	sort.SliceStable(orphans, func(i, j int) bool {
+		return recordName(orphans[i].Name, zone) < recordName(orphans[j].Name, zone)
+	})
+
+	var found []myrasec.DNSRecord
+	for _, r := range orphans {
+		if p.inDomainFilter(recordName(r.Name, zone)) {
+			found = append(found, r)
+		}
+	}
+	return found
+}
+
+// orphanedOwnershipRecords returns the ownership TXT records of owner whose name has no other
+// record of a managed type. TXT records that are not ownership records count as data records.
+func orphanedOwnershipRecords(records []myrasec.DNSRecord, zone, owner string, managed func(string) bool) []myrasec.DNSRecord {
+	inUse := make(map[string]bool)
+	for _, r := range records {
+		if !managed(r.RecordType) {
+			continue
+		}
+		if r.RecordType == endpoint.RecordTypeTXT && isOwnershipTXT(r.Value) {
+			continue
+		}
+		inUse[nameKey(r.Name, zone)] = true
+	}
+
+	var orphans []myrasec.DNSRecord
+	for _, r := range records {
+		if r.RecordType != endpoint.RecordTypeTXT || !isOwnedByExternalDNS(r.Value, owner) {
+			continue
+		}
+		if !inUse[nameKey(r.Name, zone)] {
+			orphans = append(orphans, r)
+		}
+	}
+	return orphans
+}
+
+// isOwnershipTXT reports whether the TXT value is an ExternalDNS ownership record of any owner.
+func isOwnershipTXT(txtValue string) bool {
+	return parseOwnershipTXT(txtValue)["heritage"] == "external-dns"
+}
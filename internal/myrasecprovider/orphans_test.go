@@ -0,0 +1,91 @@
+package myrasecprovider
+
+import (
+	"context"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestCleanupOrphanedOwnershipRecords(t *testing.T) {
+	owned := "heritage=external-dns,external-dns/owner=test-owner"
+	seed := func() *fakeMyraSecClient {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		client.records[123] = []myrasec.DNSRecord{
+			// In use by an A record
+			{ID: 1, Name: "app.example.com", RecordType: endpoint.RecordTypeA, Value: "1.2.3.4", TTL: 300},
+			{ID: 2, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: owned, TTL: 300},
+			// Orphaned, the CNAME was deleted long ago
+			{ID: 3, Name: "old.example.com", RecordType: endpoint.RecordTypeTXT, Value: `"` + owned + `"`, TTL: 300},
+			// Orphaned apex record in the short form
+			{ID: 4, Name: "@", RecordType: endpoint.RecordTypeTXT, Value: owned, TTL: 300},
+			// In use by a TXT data record
+			{ID: 5, Name: "spf.example.com", RecordType: endpoint.RecordTypeTXT, Value: "v=spf1 -all", TTL: 300},
+			{ID: 6, Name: "spf.example.com", RecordType: endpoint.RecordTypeTXT, Value: owned, TTL: 300},
+			// Owned by another instance
+			{ID: 7, Name: "foreign.example.com", RecordType: endpoint.RecordTypeTXT, Value: "heritage=external-dns,external-dns/owner=other", TTL: 300},
+			// Only a record of an unmanaged type remains
+			{ID: 8, Name: "ptr.example.com", RecordType: "PTR", Value: "example.com", TTL: 300},
+			{ID: 9, Name: "ptr.example.com", RecordType: endpoint.RecordTypeTXT, Value: owned, TTL: 300},
+		}
+		return client
+	}
+	ids := func(records []ZoneRecord) []int {
+		var result []int
+		for _, r := range records {
+			result = append(result, r.ID)
+		}
+		return result
+	}
+
+	t.Run("lists without deleting", func(t *testing.T) {
+		client := seed()
+		p := newTestProvider(client)
+
+		orphans, err := p.CleanupOrphanedOwnershipRecords(context.Background(), false)
+		require.NoError(t, err)
+		assert.Equal(t, []int{4, 3, 9}, ids(orphans))
+		assert.Equal(t, "example.com", orphans[0].Name)
+		assert.Len(t, client.records[123], 9)
+	})
+
+	t.Run("deletes the orphans", func(t *testing.T) {
+		client := seed()
+		p := newTestProvider(client)
+
+		orphans, err := p.CleanupOrphanedOwnershipRecords(context.Background(), true)
+		require.NoError(t, err)
+		assert.Len(t, orphans, 3)
+
+		var remaining []int
+		for _, r := range client.records[123] {
+			remaining = append(remaining, r.ID)
+		}
+		assert.Equal(t, []int{1, 2, 5, 6, 7, 8}, remaining)
+	})
+
+	t.Run("respects the domain filter", func(t *testing.T) {
+		client := seed()
+		p := newTestProvider(client)
+		p.domainFilter = endpoint.NewDomainFilterWithExclusions([]string{"example.com"}, []string{"old.example.com"})
+
+		orphans, err := p.CleanupOrphanedOwnershipRecords(context.Background(), true)
+		require.NoError(t, err)
+		assert.Equal(t, []int{4, 9}, ids(orphans))
+		assert.Len(t, client.records[123], 7)
+	})
+
+	t.Run("dry-run keeps the records", func(t *testing.T) {
+		client := seed()
+		p := newTestProvider(client)
+		p.dryRun = true
+
+		orphans, err := p.CleanupOrphanedOwnershipRecords(context.Background(), true)
+		require.NoError(t, err)
+		assert.Len(t, orphans, 3)
+		assert.Len(t, client.records[123], 9)
+	})
+}
@@ -0,0 +1,57 @@
+package myrasecprovider
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// foreignOwner returns the owner label of the endpoint if it names another owner than the one of
+// the webhook. Endpoints without an owner label are not checked.
+func (p *MyraSecDNSProvider) foreignOwner(ep *endpoint.Endpoint) (string, bool) {
+	owner, ok := ep.Labels[endpoint.OwnerLabelKey]
+	if !ok || owner == "" || owner == p.owner {
+		return "", false
+	}
+	return owner, true
+}
+
+// checkPlanOwner compares the owner labels of all endpoints of the plan with the owner of the
+// webhook, so that an ExternalDNS instance pointed at the wrong webhook does not overwrite the
+// records of another instance. With a strict owner an OwnerMismatchError lists the endpoints of
+// other owners; otherwise they are returned, so that their changes are skipped.
+func (p *MyraSecDNSProvider) checkPlanOwner(changes *plan.Changes) (map[*endpoint.Endpoint]string, error) {
+	foreign := make(map[*endpoint.Endpoint]string)
+	var mismatches []InvalidEndpoint
+	for _, endpoints := range [][]*endpoint.Endpoint{changes.Create, changes.UpdateOld, changes.UpdateNew, changes.Delete} {
+		for _, ep := range endpoints {
+			if owner, ok := p.foreignOwner(ep); ok {
+				foreign[ep] = owner
+				mismatches = append(mismatches, InvalidEndpoint{DNSName: ep.DNSName, RecordType: ep.RecordType, Reason: fmt.Sprintf("owner %q", owner)})
+			}
+		}
+	}
+	if len(foreign) == 0 {
+		return nil, nil
+	}
+
+	names := make([]string, 0, maxSkippedNames)
+	for _, mismatch := range mismatches[:min(len(mismatches), maxSkippedNames)] {
+		names = append(names, mismatch.DNSName)
+	}
+	if p.strictOwner {
+		err := &OwnerMismatchError{Owner: p.owner, Endpoints: mismatches}
+		p.logger.Error("Refusing plan with endpoints of another owner, no changes were applied; check the --txt-owner-id of ExternalDNS and the webhook",
+			zap.String("owner", p.owner),
+			zap.Int("count", len(mismatches)),
+			zap.Strings("dnsNames", names))
+		return nil, err
+	}
+	p.logger.Warn("Skipping changes of endpoints of another owner; check the --txt-owner-id of ExternalDNS and the webhook",
+		zap.String("owner", p.owner),
+		zap.Int("count", len(mismatches)),
+		zap.Strings("dnsNames", names))
+	return foreign, nil
+}
@@ -0,0 +1,175 @@
+package myrasecprovider
+
+import (
+	"context"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestPlanOwner(t *testing.T) {
+	labelled := func(dnsName, target, owner string) *endpoint.Endpoint {
+		ep := endpoint.NewEndpoint(dnsName, endpoint.RecordTypeA, target)
+		if owner != "" {
+			ep.Labels[endpoint.OwnerLabelKey] = owner
+		}
+		return ep
+	}
+	names := func(client *fakeMyraSecClient) []string {
+		var names []string
+		for _, r := range client.records[123] {
+			if r.RecordType == endpoint.RecordTypeA {
+				names = append(names, r.Name)
+			}
+		}
+		return names
+	}
+
+	tests := []struct {
+		name    string
+		strict  bool
+		owner   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "matching owner", owner: "test-owner", want: []string{"app.example.com", "other.example.com"}},
+		{name: "absent owner", want: []string{"app.example.com", "other.example.com"}},
+		{name: "mismatching owner is skipped", owner: "other-owner", want: []string{"app.example.com"}},
+		{name: "mismatching owner with strict owner", strict: true, owner: "other-owner", wantErr: true},
+		{name: "matching owner with strict owner", strict: true, owner: "test-owner", want: []string{"app.example.com", "other.example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+			core, logs := observer.New(zap.WarnLevel)
+			p := newTestProvider(client)
+			p.logger = zap.New(core)
+			p.strictOwner = tt.strict
+
+			err := p.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{
+				labelled("app.example.com", "1.1.1.1", "test-owner"),
+				labelled("other.example.com", "2.2.2.2", tt.owner),
+			}})
+
+			if tt.wantErr {
+				var ownerErr *OwnerMismatchError
+				require.ErrorAs(t, err, &ownerErr)
+				assert.ErrorIs(t, err, ErrOwnerMismatch)
+				assert.Equal(t, "test-owner", ownerErr.Owner)
+				require.Len(t, ownerErr.Endpoints, 1)
+				assert.Equal(t, "other.example.com", ownerErr.Endpoints[0].DNSName)
+				assert.Empty(t, client.records[123], "no change of the plan is applied")
+				assert.Equal(t, 1, logs.FilterMessageSnippet("Refusing plan with endpoints of another owner").Len())
+				return
+			}
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, names(client))
+			skipped := tt.owner != "" && tt.owner != "test-owner"
+			assert.Equal(t, skipped, logs.FilterMessageSnippet("Skipping changes of endpoints of another owner").Len() == 1)
+		})
+	}
+
+	t.Run("updates and deletions of another owner", func(t *testing.T) {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		ownership := `"heritage=external-dns,external-dns/owner=test-owner"`
+		client.records[123] = []myrasec.DNSRecord{
+			{ID: 1, Name: "app.example.com", RecordType: endpoint.RecordTypeA, Value: "1.1.1.1", TTL: 300},
+			{ID: 2, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: ownership, TTL: 300},
+			{ID: 3, Name: "old.example.com", RecordType: endpoint.RecordTypeA, Value: "3.3.3.3", TTL: 300},
+			{ID: 4, Name: "old.example.com", RecordType: endpoint.RecordTypeTXT, Value: ownership, TTL: 300},
+		}
+		p := newTestProvider(client)
+
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+			UpdateOld: []*endpoint.Endpoint{labelled("app.example.com", "1.1.1.1", "other-owner")},
+			UpdateNew: []*endpoint.Endpoint{labelled("app.example.com", "2.2.2.2", "other-owner")},
+			Delete:    []*endpoint.Endpoint{labelled("old.example.com", "3.3.3.3", "other-owner")},
+		}))
+		assert.Len(t, client.records[123], 4, "the records stay untouched")
+		assert.Equal(t, "1.1.1.1", client.records[123][0].Value)
+	})
+}
+
+// TestUnrelatedTXTRecordAtOwnedName tests that an ownership record of this owner is found when the
+// name carries other TXT records listed after it, e.g. an SPF record added by hand
+func TestUnrelatedTXTRecordAtOwnedName(t *testing.T) {
+	zone := func() (*fakeMyraSecClient, *MyraSecDNSProvider) {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		client.records[123] = []myrasec.DNSRecord{
+			{ID: 1, Name: "app.example.com", RecordType: endpoint.RecordTypeA, Value: "1.1.1.1", TTL: 300, Enabled: true},
+			{ID: 2, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: `"heritage=external-dns,external-dns/owner=test-owner,external-dns/resource=ingress/default/app"`, TTL: 300, Enabled: true},
+			{ID: 3, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: "v=spf1 -all", TTL: 300, Enabled: true},
+		}
+		return client, newTestProvider(client)
+	}
+
+	t.Run("records", func(t *testing.T) {
+		_, p := zone()
+		endpoints, err := p.Records(context.Background())
+		require.NoError(t, err)
+		ep := findEndpoint(endpoints, "app.example.com", endpoint.RecordTypeA)
+		require.NotNil(t, ep)
+		assert.Equal(t, "test-owner", ep.Labels[endpoint.OwnerLabelKey])
+		assert.Equal(t, "ingress/default/app", ep.Labels[endpoint.ResourceLabelKey], "the resource of the ownership record")
+	})
+
+	t.Run("update", func(t *testing.T) {
+		client, p := zone()
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+			UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.1.1.1")},
+			UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "2.2.2.2")},
+		}))
+		a := p.findMatchingRecords(client.records[123], "example.com", "app.example.com", endpoint.RecordTypeA)
+		require.Len(t, a, 1)
+		assert.Equal(t, "2.2.2.2", a[0].Value)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		client, p := zone()
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+			Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.1.1.1")},
+		}))
+		var remaining []int
+		for _, r := range client.records[123] {
+			remaining = append(remaining, r.ID)
+		}
+		assert.Equal(t, []int{2, 3}, remaining, "the ownership record is kept for the TXT record at the name")
+	})
+
+	t.Run("zone records", func(t *testing.T) {
+		_, p := zone()
+		records, err := p.ZoneRecords(context.Background())
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		for _, r := range records {
+			assert.Equal(t, OwnershipOwned, r.Ownership, r.Type+" "+r.Value)
+		}
+	})
+}
+
+func TestZoneSnapshotOwners(t *testing.T) {
+	snapshot := newZoneSnapshot(myrasec.Domain{ID: 123, Name: "example.com"}, []myrasec.DNSRecord{
+		{ID: 1, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: `"heritage=external-dns,external-dns/owner=test-owner"`},
+		{ID: 2, Name: "App.example.com", RecordType: endpoint.RecordTypeTXT, Value: "heritage=external-dns,external-dns/owner=other"},
+		{ID: 3, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: "heritage=external-dns,external-dns/owner=test-owner"},
+		{ID: 4, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: "v=spf1 -all"},
+		{ID: 5, Name: "@", RecordType: endpoint.RecordTypeTXT, Value: "heritage=external-dns,external-dns/owner=other"},
+		{ID: 6, Name: "web.example.com", RecordType: endpoint.RecordTypeA, Value: "1.1.1.1"},
+	})
+
+	assert.Equal(t, []string{"test-owner", "other"}, snapshot.owners("app.example.com"), "every owner once, in listing order")
+	assert.Equal(t, []string{"other"}, snapshot.owners("example.com"))
+	assert.Empty(t, snapshot.owners("web.example.com"))
+
+	assert.True(t, snapshot.ownedBy("app.example.com", "test-owner"))
+	assert.True(t, snapshot.ownedBy("app.example.com", "other"))
+	assert.False(t, snapshot.ownedBy("example.com", "test-owner"))
+	assert.False(t, snapshot.ownedBy("web.example.com", "test-owner"))
+}
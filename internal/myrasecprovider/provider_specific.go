@@ -0,0 +1,208 @@
+package myrasecprovider
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// Provider-specific properties understood by the webhook. ExternalDNS maps the
+// annotation external-dns.alpha.kubernetes.io/webhook-<name> to webhook/<name>.
+const (
+	providerSpecificProtection = "webhook/myrasec-protection"
+	providerSpecificEnabled    = "webhook/myrasec-enabled"
+	providerSpecificComment    = "webhook/myrasec-comment"
+)
+
+// providerSpecificProperty describes a supported provider-specific property and how it maps
+// onto the fields of a Myra DNS record.
+type providerSpecificProperty struct {
+	name string
+	// supports reports whether the property applies to the given record type.
+	supports func(recordType string) bool
+	// defaultValue returns the value used when the endpoint does not set the property.
+	// An empty string means the property is omitted.
+	defaultValue func(p *MyraSecDNSProvider, recordType string) string
+	// normalize validates a value and returns its canonical form.
+	normalize func(value string) (string, error)
+	// read returns the property value stored on a Myra record.
+	read func(rec *myrasec.DNSRecord) string
+	// apply writes a (normalized) property value onto a Myra record.
+	apply func(rec *myrasec.DNSRecord, value string)
+}
+
+// providerSpecificProperties is the registry of supported properties, in the order they are emitted.
+var providerSpecificProperties = []providerSpecificProperty{
+	{
+		name:     providerSpecificProtection,
+		supports: func(string) bool { return true },
+		defaultValue: func(p *MyraSecDNSProvider, recordType string) string {
+			return strconv.FormatBool(p.protectionDefault(recordType))
+		},
+		normalize: normalizeBool,
+		read: func(rec *myrasec.DNSRecord) string {
+			return strconv.FormatBool(rec.Active)
+		},
+		apply: func(rec *myrasec.DNSRecord, value string) {
+			rec.Active = value == "true"
+		},
+	},
+	{
+		name:     providerSpecificEnabled,
+		supports: func(string) bool { return true },
+		defaultValue: func(*MyraSecDNSProvider, string) string {
+			return "true"
+		},
+		normalize: normalizeBool,
+		read: func(rec *myrasec.DNSRecord) string {
+			return strconv.FormatBool(rec.Enabled)
+		},
+		apply: func(rec *myrasec.DNSRecord, value string) {
+			rec.Enabled = value == "true"
+		},
+	},
+	{
+		name:     providerSpecificComment,
+		supports: func(string) bool { return true },
+		defaultValue: func(*MyraSecDNSProvider, string) string {
+			return ""
+		},
+		normalize: func(value string) (string, error) {
+			return strings.TrimSpace(value), nil
+		},
+		read: func(rec *myrasec.DNSRecord) string {
+			return rec.Comment
+		},
+		apply: func(rec *myrasec.DNSRecord, value string) {
+			rec.Comment = value
+		},
+	},
+}
+
+// adjustProviderSpecific validates the provider-specific properties of the endpoint, drops
+// unknown or unsupported ones and fills in defaults for the missing ones.
+func (p *MyraSecDNSProvider) adjustProviderSpecific(ep *endpoint.Endpoint) {
+	known := make(map[string]bool, len(providerSpecificProperties))
+	adjusted := endpoint.ProviderSpecific{}
+
+	for _, prop := range providerSpecificProperties {
+		known[prop.name] = true
+		if !prop.supports(ep.RecordType) {
+			continue
+		}
+
+		value := prop.defaultValue(p, ep.RecordType)
+		if raw, ok := ep.GetProviderSpecificProperty(prop.name); ok {
+			normalized, err := prop.normalize(raw)
+			if err != nil {
+				p.logger.Warn("Invalid provider-specific property, using default",
+					zap.String("dnsName", ep.DNSName),
+					zap.String("property", prop.name),
+					zap.String("value", raw),
+					zap.String("default", value),
+					zap.Error(err))
+			} else {
+				value = normalized
+			}
+		}
+
+		if value != "" {
+			adjusted = append(adjusted, endpoint.ProviderSpecificProperty{Name: prop.name, Value: value})
+		}
+	}
+
+	for _, property := range ep.ProviderSpecific {
+		if !known[property.Name] {
+			p.logger.Debug("Dropping unsupported provider-specific property",
+				zap.String("dnsName", ep.DNSName),
+				zap.String("property", property.Name))
+		}
+	}
+
+	if !equalProviderSpecific(ep.ProviderSpecific, adjusted) {
+		p.logger.Debug("Normalizing provider-specific properties",
+			zap.String("dnsName", ep.DNSName),
+			zap.Any("providerSpecific", ep.ProviderSpecific),
+			zap.Any("adjusted_providerSpecific", adjusted))
+	}
+	ep.ProviderSpecific = adjusted
+}
+
+// equalProviderSpecific reports whether both lists hold the same properties in the same order
+func equalProviderSpecific(a, b endpoint.ProviderSpecific) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// providerSpecificFromRecord reconstructs the provider-specific properties from a Myra record,
+// so that the result matches what adjustProviderSpecific produces for the same settings.
+func providerSpecificFromRecord(rec *myrasec.DNSRecord) endpoint.ProviderSpecific {
+	properties := endpoint.ProviderSpecific{}
+	for _, prop := range providerSpecificProperties {
+		if !prop.supports(rec.RecordType) {
+			continue
+		}
+		if value := prop.read(rec); value != "" {
+			properties = append(properties, endpoint.ProviderSpecificProperty{Name: prop.name, Value: value})
+		}
+	}
+	return properties
+}
+
+// applyProviderSpecific sets the record fields covered by provider-specific properties, using
+// the endpoint's values where present and the provider defaults otherwise.
+func (p *MyraSecDNSProvider) applyProviderSpecific(rec *myrasec.DNSRecord, ep *endpoint.Endpoint) {
+	rec.Active = p.protectionDefault(rec.RecordType)
+	rec.Enabled = true
+
+	for _, prop := range providerSpecificProperties {
+		if !prop.supports(rec.RecordType) {
+			continue
+		}
+
+		value := prop.defaultValue(p, rec.RecordType)
+		if ep != nil {
+			if raw, ok := ep.GetProviderSpecificProperty(prop.name); ok {
+				if normalized, err := prop.normalize(raw); err == nil {
+					value = normalized
+				}
+			}
+		}
+		prop.apply(rec, value)
+	}
+}
+
+// protectionDefault returns the protection state of records of the type whose endpoint does not
+// set it: the value of --protection-per-type, otherwise protected unless protection is disabled.
+// Types that cannot be routed through the Myra protection layer, like MX and TXT, are unprotected.
+func (p *MyraSecDNSProvider) protectionDefault(recordType string) bool {
+	if active, ok := p.protectionPerType[recordType]; ok {
+		return active
+	}
+	return canBeProtected(recordType) && !p.disableProtection
+}
+
+// canBeProtected returns true for record types that can be routed through the Myra protection layer.
+func canBeProtected(recordType string) bool {
+	return myrasec.DNSRecord{RecordType: recordType}.CanBeProtected()
+}
+
+// normalizeBool accepts the usual boolean spellings and returns "true" or "false".
+func normalizeBool(value string) (string, error) {
+	b, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return "", fmt.Errorf("not a boolean: %q", value)
+	}
+	return strconv.FormatBool(b), nil
+}
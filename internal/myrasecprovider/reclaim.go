@@ -0,0 +1,41 @@
+package myrasecprovider
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// reclaimableOwnership reports whether the ownership TXT record of an updated endpoint is to be
+// recreated: reclaimOwnership is set, the plan says this instance owns the endpoint and the name
+// has no ownership TXT record of any owner. Names owned by another instance are never reclaimed.
+func (p *MyraSecDNSProvider) reclaimableOwnership(snapshot *zoneSnapshot, dnsName string, ep *endpoint.Endpoint) bool {
+	if !p.reclaimOwnership || ep.Labels[endpoint.OwnerLabelKey] != p.owner {
+		return false
+	}
+	for _, r := range snapshot.all() {
+		if r.RecordType != endpoint.RecordTypeTXT || !sameName(recordName(r.Name, snapshot.zoneName()), dnsName) {
+			continue
+		}
+		if parseOwnershipTXT(r.Value)["heritage"] == "external-dns" {
+			return false
+		}
+	}
+	return true
+}
+
+// recreateOwnership recreates the missing ownership TXT record of the endpoint, e.g. after it was
+// deleted in the MyraSec UI, so the update of its records can proceed.
+func (p *MyraSecDNSProvider) recreateOwnership(ctx context.Context, snapshot *zoneSnapshot, dnsName string, ep *endpoint.Endpoint, ttl int) error {
+	txtVal := p.ownershipValue(ep)
+	if err := p.createDNSRecord(ctx, snapshot, dnsName, endpoint.RecordTypeTXT, txtVal, p.ownershipTTL(ttl), nil); err != nil {
+		p.logger.Error("Failed to recreate missing TXT ownership record", zap.String("dnsName", dnsName), zap.String("value", txtVal), zap.Error(err))
+		return err
+	}
+	p.logger.Warn("Recreated missing TXT ownership record",
+		zap.String("dnsName", dnsName),
+		zap.String("type", ep.RecordType),
+		zap.String("owner", p.owner))
+	return nil
+}
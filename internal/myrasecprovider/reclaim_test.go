@@ -0,0 +1,89 @@
+package myrasecprovider
+
+import (
+	"context"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestReclaimMissingOwnership(t *testing.T) {
+	tests := []struct {
+		name        string
+		reclaim     bool
+		ownership   []myrasec.DNSRecord
+		wantValue   string
+		wantTXT     []string
+		wantSkipped bool
+	}{
+		{
+			name:      "TXT missing",
+			reclaim:   true,
+			wantValue: "5.6.7.8",
+			wantTXT:   []string{`"heritage=external-dns,external-dns/owner=test-owner"`},
+		},
+		{
+			name:      "only an unrelated TXT",
+			reclaim:   true,
+			ownership: []myrasec.DNSRecord{{ID: 2, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: "v=spf1 -all", TTL: 300}},
+			wantValue: "5.6.7.8",
+			wantTXT:   []string{"v=spf1 -all", `"heritage=external-dns,external-dns/owner=test-owner"`},
+		},
+		{
+			name:        "TXT owned by someone else",
+			reclaim:     true,
+			ownership:   []myrasec.DNSRecord{{ID: 2, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: "heritage=external-dns,external-dns/owner=other", TTL: 300}},
+			wantValue:   "1.2.3.4",
+			wantTXT:     []string{"heritage=external-dns,external-dns/owner=other"},
+			wantSkipped: true,
+		},
+		{
+			name:        "reclaim off",
+			wantValue:   "1.2.3.4",
+			wantSkipped: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+			client.records[123] = append([]myrasec.DNSRecord{
+				{ID: 1, Name: "app.example.com", RecordType: endpoint.RecordTypeA, Value: "1.2.3.4", TTL: 300, Active: true, Enabled: true},
+			}, tt.ownership...)
+			core, logs := observer.New(zap.InfoLevel)
+			p := newTestProvider(client)
+			p.logger = zap.New(core)
+			p.reclaimOwnership = tt.reclaim
+
+			labels := endpoint.Labels{endpoint.OwnerLabelKey: "test-owner"}
+			old := endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.4")
+			old.Labels = labels
+			desired := endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "5.6.7.8")
+			desired.Labels = labels
+
+			require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+				UpdateOld: []*endpoint.Endpoint{old},
+				UpdateNew: []*endpoint.Endpoint{desired},
+			}))
+
+			records := p.findMatchingRecords(client.records[123], "example.com", "app.example.com", endpoint.RecordTypeA)
+			require.Len(t, records, 1)
+			assert.Equal(t, tt.wantValue, records[0].Value)
+
+			var txt []string
+			for _, r := range p.findMatchingRecords(client.records[123], "example.com", "app.example.com", endpoint.RecordTypeTXT) {
+				txt = append(txt, r.Value)
+			}
+			assert.ElementsMatch(t, tt.wantTXT, txt)
+
+			assert.Equal(t, tt.wantSkipped, logs.FilterMessage("Skipping update: not owned by this instance").Len() == 1)
+			assert.Equal(t, !tt.wantSkipped, logs.FilterMessage("Recreated missing TXT ownership record").Len() == 1)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package myrasecprovider
+
+import (
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// managesRecordType reports whether records of the type are managed by this provider.
+// Without a configured set, all supported types are managed.
+func (p *MyraSecDNSProvider) managesRecordType(recordType string) bool {
+	if p.managedTypes == nil {
+		return supportedRecordType(recordType)
+	}
+	return p.managedTypes[recordType]
+}
+
+// managedChange reports whether a change may be applied. Changes of an unmanaged record type
+// are logged and counted.
+func (p *MyraSecDNSProvider) managedChange(action string, ep *endpoint.Endpoint) bool {
+	if p.managesRecordType(ep.RecordType) {
+		return true
+	}
+	p.logger.Warn("Skipping change of an unmanaged record type",
+		zap.String("action", action),
+		zap.String("dnsName", ep.DNSName),
+		zap.String("type", ep.RecordType))
+	unmanagedChanges.WithLabelValues(action, ep.RecordType).Inc()
+	return false
+}
+
+// filterManagedEndpoints drops the endpoints of unmanaged record types, so ExternalDNS does not
+// plan changes for them.
+func (p *MyraSecDNSProvider) filterManagedEndpoints(endpoints []*endpoint.Endpoint) []*endpoint.Endpoint {
+	managed := endpoints[:0]
+	for _, ep := range endpoints {
+		if !p.managesRecordType(ep.RecordType) {
+			p.logger.Debug("Dropping endpoint of an unmanaged record type",
+				zap.String("dnsName", ep.DNSName),
+				zap.String("type", ep.RecordType))
+			continue
+		}
+		managed = append(managed, ep)
+	}
+	return managed
+}
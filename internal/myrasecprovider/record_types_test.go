@@ -0,0 +1,70 @@
+package myrasecprovider
+
+import (
+	"context"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestManagedRecordTypes(t *testing.T) {
+	managed, err := managedRecordTypes(nil)
+	require.NoError(t, err)
+	assert.Len(t, managed, len(SupportedRecordTypes))
+
+	managed, err = managedRecordTypes([]string{"a", " CNAME "})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"A": true, "CNAME": true}, managed)
+
+	_, err = managedRecordTypes([]string{"A", "PTR"})
+	assert.ErrorContains(t, err, `unsupported record type "PTR"`)
+
+	_, err = NewMyraSecDNSProvider(zap.NewNop(), Config{APIKey: "key", APISecret: "secret", ManagedRecordTypes: []string{"SOA"}})
+	assert.Error(t, err)
+}
+
+func TestUnmanagedRecordTypesAreLeftAlone(t *testing.T) {
+	ownership := "heritage=external-dns,external-dns/owner=test-owner"
+	client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+	client.records[123] = []myrasec.DNSRecord{
+		{ID: 1, Name: "app.example.com", RecordType: "A", Value: "1.1.1.1", TTL: 300},
+		{ID: 2, Name: "app.example.com", RecordType: "TXT", Value: ownership, TTL: 300},
+		{ID: 3, Name: "mail.example.com", RecordType: "MX", Value: "10 mx.example.com", TTL: 300},
+		{ID: 4, Name: "mail.example.com", RecordType: "TXT", Value: ownership, TTL: 300},
+	}
+	p := newTestProvider(client)
+	p.managedTypes, _ = managedRecordTypes([]string{"A", "AAAA", "CNAME", "TXT"})
+
+	endpoints, err := p.Records(context.Background())
+	require.NoError(t, err)
+	assert.NotNil(t, findEndpoint(endpoints, "app.example.com", "A"))
+	assert.Nil(t, findEndpoint(endpoints, "mail.example.com", "MX"))
+
+	adjusted, err := p.AdjustEndpoints([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+		endpoint.NewEndpoint("mail.example.com", endpoint.RecordTypeMX, "10 mx.example.com"),
+	})
+	require.NoError(t, err)
+	require.Len(t, adjusted, 1)
+	assert.Equal(t, endpoint.RecordTypeA, adjusted[0].RecordType)
+
+	before := testutil.ToFloat64(unmanagedChanges.WithLabelValues(DELETE, endpoint.RecordTypeMX))
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("web.example.com", endpoint.RecordTypeA, "2.2.2.2")},
+		Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("mail.example.com", endpoint.RecordTypeMX, "10 mx.example.com")},
+	}))
+
+	var remaining []string
+	for _, r := range client.records[123] {
+		remaining = append(remaining, r.Name+" "+r.RecordType)
+	}
+	assert.Contains(t, remaining, "mail.example.com MX", "the unmanaged record is kept")
+	assert.Contains(t, remaining, "web.example.com A")
+	assert.Equal(t, before+1, testutil.ToFloat64(unmanagedChanges.WithLabelValues(DELETE, endpoint.RecordTypeMX)))
+}
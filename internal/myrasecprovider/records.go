@@ -2,10 +2,10 @@ package myrasecprovider
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
-	"os"
-	"strconv"
+	"slices"
 	"strings"
 
 	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
@@ -13,74 +13,127 @@ import (
 	"sigs.k8s.io/external-dns/endpoint"
 )
 
-func (p *MyraSecDNSProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+func (p *MyraSecDNSProvider) Records(ctx context.Context) (_ []*endpoint.Endpoint, err error) {
 	p.logger.Debug("Attempting to list domains (Records)")
+	defer func() { p.syncState.recordsListed(err) }()
+	ctx = p.withClient(ctx)
 
-	selectedDomain, err := p.SelectDomain()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	zones, err := p.selectZones()
 	if err != nil {
 		p.logger.Error("Failed to select domain", zap.Error(err))
 		return nil, err
 	}
 
-	p.logger.Debug("Selected domain for Records method",
-		zap.String("domain_name", selectedDomain.Name),
-		zap.Int("domain_id", selectedDomain.ID))
+	var endpoints []*endpoint.Endpoint
+	total := 0
+	for i := range zones {
+		selectedDomain := &zones[i]
+		if err := ctx.Err(); err != nil {
+			p.logger.Debug("Records aborted, context is done", zap.Error(err))
+			return nil, err
+		}
+
+		p.logger.Debug("Selected domain for Records method",
+			zap.String("domain_name", selectedDomain.Name),
+			zap.Int("domain_id", selectedDomain.ID))
 
-	dnsRecords, err := p.apiClient.ListDNSRecords(selectedDomain.ID, nil)
-	if err != nil {
-		p.logger.Error("Failed to list DNS records",
-			zap.String("domain", selectedDomain.Name),
-			zap.Error(err))
-		return nil, fmt.Errorf("failed listing records: %w", err)
+		dnsRecords, err := p.listDNSRecords(ctx, selectedDomain)
+		if err != nil {
+			p.logger.Error("Failed to list DNS records",
+				zap.String("domain", selectedDomain.Name),
+				zap.Error(err))
+			return nil, fmt.Errorf("failed listing records: %w", apiError(err))
+		}
+
+		p.logger.Debug("DNS records retrieved", zap.String("domain", selectedDomain.Name), zap.Int("count", len(dnsRecords)))
+		total += len(dnsRecords)
+		endpoints = append(endpoints, p.zoneEndpoints(zones, selectedDomain, dnsRecords)...)
 	}
 
-	p.logger.Debug("DNS records retrieved", zap.Int("count", len(dnsRecords)))
+	if err := ctx.Err(); err != nil {
+		p.logger.Debug("Records aborted, context is done", zap.Error(err))
+		return nil, err
+	}
 
-	var endpoints []*endpoint.Endpoint
-	txtRecords := make(map[string]string)
+	p.listedRecords.Store(int64(total))
+	p.logger.Info("Processed DNS records",
+		zap.Int("total", total),
+		zap.Int("filtered", len(endpoints)))
 
-	// First, collect TXT records for ownership checks
+	return endpoints, nil
+}
+
+// zoneEndpoints returns the endpoints owned by this instance among the records of the selected
+// domain. Names that belong to a more specific domain of zones are left to that domain. The owned
+// and the foreign records of the domain are counted in the record gauges.
+func (p *MyraSecDNSProvider) zoneEndpoints(zones []myrasec.Domain, selectedDomain *myrasec.Domain, dnsRecords []myrasec.DNSRecord) []*endpoint.Endpoint {
+	var endpoints []*endpoint.Endpoint
+	delegations := make(map[string]*endpoint.Endpoint)
+	managed, foreign := map[string]int{}, map[string]int{}
+	defer setRecordGauges(selectedDomain.Name, managed, foreign)
+
+	// First, collect the owners of every name and the resources named by the ownership records of
+	// this owner. A name may carry other TXT records besides, they do not affect its ownership.
+	owners := nameOwners(dnsRecords, selectedDomain.Name)
+	resources := make(map[string]string)
 	for _, r := range dnsRecords {
-		if r.RecordType == endpoint.RecordTypeTXT {
-			txtRecords[r.Name] = r.Value
+		if r.RecordType == endpoint.RecordTypeTXT && isOwnedByExternalDNS(r.Value, p.owner) {
+			resources[nameKey(r.Name, selectedDomain.Name)] = extractResourceFromTXT(r.Value)
 		}
 	}
 
-	// Process non-TXT records
 	for _, r := range dnsRecords {
-		if !supportedRecordType(r.RecordType) {
+		// Names are returned without the trailing dot, like the sources of ExternalDNS produce them,
+		// otherwise the planner sees a change on every sync
+		name := recordName(r.Name, selectedDomain.Name)
+		if zoneFor(zones, name).ID != selectedDomain.ID {
+			p.logger.Debug("Skipping record of a delegated subdomain",
+				zap.String("dnsName", name),
+				zap.String("domain", selectedDomain.Name))
 			continue
 		}
 
-		dnsName := ensureTrailingDot(r.Name)
-		if !p.domainFilter.Match(dnsName) {
+		// Validate ownership: ownership records must be owned themselves, data records of any type,
+		// TXT included, by an ownership record of this owner at their name
+		key := nameKey(r.Name, selectedDomain.Name)
+		owned := slices.Contains(owners[key], p.owner)
+		if r.RecordType == endpoint.RecordTypeTXT && isOwnershipTXT(r.Value) {
+			owned = isOwnedByExternalDNS(r.Value, p.owner)
+		}
+		if !owned {
+			foreign[r.RecordType]++
 			continue
 		}
 
-		// Validate ownership for non-TXT records
-		if r.RecordType != endpoint.RecordTypeTXT {
-			txtVal, ok := txtRecords[r.Name]
-			if !ok || !isOwnedByExternalDNS(txtVal, p.owner) {
-				continue
-			}
-		} else {
-			// TXT records: must be owned
-			if !isOwnedByExternalDNS(r.Value, p.owner) {
+		if !p.managesRecordType(r.RecordType) || !p.inDomainFilter(name) {
+			continue
+		}
+		managed[r.RecordType]++
+
+		// The nameservers of a delegation are one endpoint, so ExternalDNS plans them as a set
+		if r.RecordType == endpoint.RecordTypeNS {
+			if ep, ok := delegations[strings.ToLower(name)]; ok {
+				ep.Targets = append(ep.Targets, p.formatRecordValue(r.Value, r.RecordType))
 				continue
 			}
 		}
 
-		ep := endpoint.NewEndpoint(dnsName, r.RecordType, r.Value)
+		ep := endpoint.NewEndpoint(name, r.RecordType, p.formatRecordValue(r.Value, r.RecordType))
 		if r.TTL > 0 {
 			ep.RecordTTL = endpoint.TTL(r.TTL)
 		}
+		ep.ProviderSpecific = providerSpecificFromRecord(&r)
 
 		ep.Labels = map[string]string{
 			endpoint.OwnerLabelKey: p.owner,
 		}
 
-		// Add resource label if present
-		if resource := extractResourceFromTXT(r.Value); resource != "" {
+		// Add the resource label of the ownership record if present
+		if resource := resources[key]; resource != "" {
 			ep.Labels[endpoint.ResourceLabelKey] = resource
 		}
 
@@ -90,41 +143,57 @@ func (p *MyraSecDNSProvider) Records(ctx context.Context) ([]*endpoint.Endpoint,
 			zap.Any("targets", ep.Targets))
 
 		endpoints = append(endpoints, ep)
+		if r.RecordType == endpoint.RecordTypeNS {
+			delegations[strings.ToLower(name)] = ep
+		}
 	}
+	return endpoints
+}
 
-	p.logger.Info("Processed DNS records",
-		zap.Int("total", len(dnsRecords)),
-		zap.Int("filtered", len(endpoints)))
-
-	return endpoints, nil
+// abortErr returns the failures collected before ctx was done, or the context error if there are none
+func abortErr(ctx context.Context, errs []error) error {
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return ctx.Err()
 }
 
 func extractResourceFromTXT(txtValue string) string {
-	parts := strings.Split(txtValue, ",")
-	for _, part := range parts {
-		if strings.HasPrefix(part, "external-dns/resource=") {
-			return strings.TrimPrefix(part, "external-dns/resource=")
-		}
-	}
-	return ""
+	return parseOwnershipTXT(txtValue)["external-dns/resource"]
 }
-func (p *MyraSecDNSProvider) processCreateActions(endpoints []*endpoint.Endpoint) error {
+func (p *MyraSecDNSProvider) processCreateActions(ctx context.Context, snapshot *zoneSnapshot, endpoints []*endpoint.Endpoint) error {
+	var errs []error
 	for _, ep := range endpoints {
+		if ctx.Err() != nil {
+			return abortErr(ctx, errs)
+		}
+
+		dnsName, err := p.ensureFullDNSName(canonicalDNSName(ep.DNSName), snapshot.zoneName())
+		if err != nil {
+			p.logger.Warn("Skipping creation of record outside the selected zone", zap.String("dnsName", ep.DNSName), zap.Error(err))
+			continue
+		}
 
-		dnsName := p.ensureFullDNSName(stripTrailingDot(ep.DNSName))
+		if p.apexNameservers(CREATE, dnsName, snapshot.zoneName(), ep.RecordType) {
+			continue
+		}
 
-		// If skipping private IP in production, handle here too:
-		if isProduction() && isPrivateEndpoint(ep) {
-			p.logger.Warn("Skipping creation of private IP record in production",
+		// Without targets only the ownership record would be created, which blocks the records later
+		if len(ep.Targets) == 0 {
+			p.logger.Warn("Skipping creation of endpoint without targets",
 				zap.String("dnsName", dnsName),
 				zap.String("recordType", ep.RecordType))
 			continue
 		}
-		// Set TTL
-		ttl := p.ttl
-		if ep.RecordTTL > 0 {
-			ttl = int(ep.RecordTTL)
+
+		// If skipping private IP in production, handle here too:
+		if p.isProduction() && isPrivateEndpoint(ep) {
+			p.skipChange(ctx, "Skipping creation of private IP record in production", dnsName,
+				zap.String("recordType", ep.RecordType))
+			continue
 		}
+		// Set TTL
+		ttl := p.recordTTL(ep)
 
 		// Format labels
 		if ep.Labels == nil {
@@ -132,178 +201,285 @@ func (p *MyraSecDNSProvider) processCreateActions(endpoints []*endpoint.Endpoint
 		}
 		ep.Labels[endpoint.OwnerLabelKey] = p.owner
 
+		// Records created outside of ExternalDNS are only taken over with adoptExisting
+		var adopted map[string]bool
+		existing, foreignOwner := p.preExistingRecords(snapshot, dnsName, ep.RecordType)
+		if len(existing) > 0 {
+			if !p.adoptExisting || foreignOwner != "" {
+				p.warnPreExisting(dnsName, ep.RecordType, foreignOwner)
+				continue
+			}
+			adopted, err = p.adoptRecords(ctx, snapshot, dnsName, ep, ttl, existing)
+			if err != nil {
+				p.logger.Error("Failed to adopt existing DNS records", zap.String("dnsName", dnsName), zap.String("type", ep.RecordType), zap.Error(err))
+				errs = append(errs, err)
+				continue
+			}
+		}
+
 		// Loop through targets
 		for _, target := range ep.Targets {
+			if ctx.Err() != nil {
+				return abortErr(ctx, errs)
+			}
 			val := p.formatRecordValue(target, ep.RecordType)
+			if adopted[p.canonicalRecordValue(target, ep.RecordType)] {
+				continue
+			}
 
 			// Create record
-			err := p.createDNSRecord(dnsName, ep.RecordType, val, ttl)
+			err := p.createDNSRecord(ctx, snapshot, dnsName, ep.RecordType, val, ttl, ep)
 			if err != nil {
 				p.logger.Error("Failed to create DNS record", zap.String("dnsName", dnsName), zap.String("type", ep.RecordType), zap.String("value", val), zap.Error(err))
+				errs = append(errs, fmt.Errorf("value %s: %w", val, err))
 				continue
 			}
 		}
 
-		// If non-TXT record, also create corresponding TXT record to declare ownership
-		if ep.RecordType != endpoint.RecordTypeTXT {
-			txtVal := fmt.Sprintf("heritage=external-dns,external-dns/owner=%s", p.owner)
-			if resource, ok := ep.Labels[endpoint.ResourceLabelKey]; ok {
-				txtVal += fmt.Sprintf(",external-dns/resource=%s", resource)
-			}
-
-			err := p.createDNSRecord(dnsName, endpoint.RecordTypeTXT, txtVal, ttl)
-			if err != nil {
-				p.logger.Error("Failed to create TXT ownership record", zap.String("dnsName", dnsName), zap.String("value", txtVal), zap.Error(err))
-				continue
-			}
+		// Also create the corresponding TXT record to declare ownership. A TXT endpoint gets one
+		// as well: it shares the name with the TXT data records, which are told apart by its value.
+		if ctx.Err() != nil {
+			return abortErr(ctx, errs)
+		}
+		txtVal := p.ownershipValue(ep)
+		err = p.createDNSRecord(ctx, snapshot, dnsName, endpoint.RecordTypeTXT, txtVal, p.ownershipTTL(ttl), nil)
+		if err != nil {
+			p.logger.Error("Failed to create TXT ownership record", zap.String("dnsName", dnsName), zap.String("value", txtVal), zap.Error(err))
+			errs = append(errs, fmt.Errorf("ownership record: %w", err))
+			continue
 		}
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
-func (p *MyraSecDNSProvider) processUpdateActions(oldEndpoints, newEndpoints []*endpoint.Endpoint) error {
+func (p *MyraSecDNSProvider) processUpdateActions(ctx context.Context, snapshot *zoneSnapshot, oldEndpoints, newEndpoints []*endpoint.Endpoint) error {
 	if len(oldEndpoints) != len(newEndpoints) {
 		return fmt.Errorf("mismatched endpoint lists: old=%d, new=%d", len(oldEndpoints), len(newEndpoints))
 	}
 
-	// Fetch domain-wide records once
-	domainID, err := strconv.Atoi(p.domainId)
-	if err != nil {
-		return fmt.Errorf("invalid domain ID: %w", err)
-	}
-	allRecords, err := p.apiClient.ListDNSRecords(domainID, nil)
-	if err != nil {
-		return fmt.Errorf("failed to list DNS records for update: %w", err)
-	}
+	var errs []error
+	for i, newEp := range newEndpoints {
+		if ctx.Err() != nil {
+			return abortErr(ctx, errs)
+		}
+		oldEp := oldEndpoints[i]
+		dnsName, err := p.ensureFullDNSName(canonicalDNSName(newEp.DNSName), snapshot.zoneName())
+		if err != nil {
+			p.logger.Warn("Skipping update of record outside the selected zone", zap.String("dnsName", newEp.DNSName), zap.Error(err))
+			continue
+		}
 
-	// Index TXT records for ownership checks
-	txtRecords := make(map[string]string)
-	for _, r := range allRecords {
-		if r.RecordType == endpoint.RecordTypeTXT {
-			txtRecords[r.Name] = r.Value
+		if p.apexNameservers(UPDATE, dnsName, snapshot.zoneName(), newEp.RecordType) {
+			continue
 		}
-	}
 
-	for _, newEp := range newEndpoints {
-		//oldEp := oldEndpoints[i]
-		dnsName := p.ensureFullDNSName(stripTrailingDot(newEp.DNSName))
+		if p.isProduction() && isPrivateEndpoint(newEp) {
+			p.skipChange(ctx, "Skipping private IP update in production", dnsName, zap.String("type", newEp.RecordType))
+			continue
+		}
 
-		if isProduction() && isPrivateEndpoint(newEp) {
-			p.logger.Warn("Skipping private IP update in production", zap.String("dnsName", dnsName), zap.String("type", newEp.RecordType))
+		// An endpoint without targets is usually not ready yet, its records are only removed with deleteOnEmptyTargets
+		if len(newEp.Targets) == 0 && !p.deleteOnEmptyTargets {
+			p.logger.Warn("Skipping update of endpoint without targets, keeping its records", zap.String("dnsName", dnsName), zap.String("type", newEp.RecordType))
 			continue
 		}
 
-		ttl := p.ttl
-		if newEp.RecordTTL > 0 {
-			ttl = int(newEp.RecordTTL)
+		// A renamed endpoint or a changed record type leaves the records of the old identity behind,
+		// so remove them before the records of the new identity are reconciled. A CNAME conflicts
+		// with any other record at its name, the old records must be gone before the new ones are
+		// created. A type change keeps the ownership record of the name, so a sync failing between
+		// the two steps leaves an owned name that the next sync completes.
+		if oldEp != nil && p.identityChanged(snapshot.zoneName(), oldEp, newEp) {
+			renamed := !p.sameEndpointName(snapshot.zoneName(), oldEp, newEp)
+			p.logger.Info("Endpoint identity changed, removing old records",
+				zap.String("oldName", stripTrailingDot(oldEp.DNSName)),
+				zap.String("oldType", oldEp.RecordType),
+				zap.String("newName", stripTrailingDot(newEp.DNSName)),
+				zap.String("newType", newEp.RecordType))
+			if err := p.deleteEndpoints(ctx, snapshot, []*endpoint.Endpoint{oldEp}, !renamed); err != nil {
+				errs = append(errs, fmt.Errorf("removing the %s records of %s: %w", oldEp.RecordType, stripTrailingDot(oldEp.DNSName), err))
+				continue
+			}
+
+			// Nothing exists under the new identity yet, create it together with its ownership record
+			if len(snapshot.owners(dnsName)) == 0 {
+				if err := p.processCreateActions(ctx, snapshot, []*endpoint.Endpoint{newEp}); err != nil {
+					errs = append(errs, err)
+				}
+				continue
+			}
 		}
 
+		// Use the zone records from the snapshot
+		allRecords := snapshot.all()
+
+		ttl := p.recordTTL(newEp)
+
 		// Ownership validation via corresponding TXT record
-		if txtVal, ok := txtRecords[stripTrailingDot(newEp.DNSName)]; !ok || !isOwnedByExternalDNS(txtVal, p.owner) {
-			p.logger.Warn("Skipping update: not owned by this instance", zap.String("dnsName", dnsName))
+		if p.reclaimableOwnership(snapshot, dnsName, newEp) {
+			if err := p.recreateOwnership(ctx, snapshot, dnsName, newEp, ttl); err != nil {
+				errs = append(errs, fmt.Errorf("ownership record: %w", err))
+				continue
+			}
+		} else if !snapshot.ownedBy(dnsName, p.owner) {
+			p.skipChange(ctx, "Skipping update: not owned by this instance", dnsName)
 			continue
 		}
 
-		existingRecords := p.findMatchingRecords(allRecords, dnsName, newEp.RecordType)
+		existingRecords := dataRecords(p.findMatchingRecords(allRecords, snapshot.zoneName(), dnsName, newEp.RecordType))
 
 		// Build set of current and desired values
+		// Index into the slice so every entry points to its own record
 		current := map[string]*myrasec.DNSRecord{}
-		for _, rec := range existingRecords {
-			current[rec.Value] = &rec
+		for i := range existingRecords {
+			current[p.canonicalRecordValue(existingRecords[i].Value, existingRecords[i].RecordType)] = &existingRecords[i]
 		}
 
-		desired := map[string]struct{}{}
+		// The desired values to create, by their canonical form
+		desired := map[string]string{}
 		for _, target := range newEp.Targets {
-			desired[p.formatRecordValue(target, newEp.RecordType)] = struct{}{}
+			desired[p.canonicalRecordValue(target, newEp.RecordType)] = p.formatRecordValue(target, newEp.RecordType)
 		}
 
 		// 1. Update TTLs and modified values
 		for val, rec := range current {
+			if ctx.Err() != nil {
+				return abortErr(ctx, errs)
+			}
 			if _, shouldExist := desired[val]; shouldExist {
-				if rec.TTL != ttl || rec.Active == p.disableProtection || rec.Name != dnsName {
-					rec.TTL = ttl
-					rec.Active = !p.disableProtection
-					rec.Name = dnsName
-					domainID, err := strconv.Atoi(p.domainId)
-					if err != nil {
-						p.logger.Error("Invalid domain ID", zap.Error(err))
-						continue
-					}
-					if _, err := p.apiClient.UpdateDNSRecord(rec, domainID); err != nil {
+				wanted := *rec
+				wanted.TTL = p.updatedTTL(rec, newEp, ttl)
+				// Only the apex short form is renamed, a name in another case is kept as stored
+				if !sameName(recordName(rec.Name, snapshot.zoneName()), dnsName) {
+					wanted.Name = dnsName
+				}
+				p.applyProviderSpecific(&wanted, newEp)
+				if rec.TTL != wanted.TTL || rec.Active != wanted.Active || rec.Enabled != wanted.Enabled ||
+					rec.Comment != wanted.Comment || rec.Name != wanted.Name {
+					if err := p.updateDNSRecord(ctx, snapshot, rec, &wanted); err != nil {
 						p.logger.Error("Failed to update record", zap.String("dnsName", dnsName), zap.String("value", val), zap.Error(err))
+						errs = append(errs, fmt.Errorf("value %s: %w", val, err))
 						continue
 					}
-					p.logger.Info("Updated record", zap.String("dnsName", dnsName), zap.String("value", val), zap.Int("ttl", ttl), zap.Bool("active", !p.disableProtection))
 				}
 				delete(desired, val) // Mark as processed so it's not created again later
+			} else if !p.attributableRecord(snapshot, dnsName, rec, oldEp) {
+				p.skipChange(ctx, "Keeping record not attributable to this owner", dnsName,
+					zap.String("type", rec.RecordType),
+					zap.String("value", rec.Value))
 			} else {
-				err := p.deleteDNSRecord(rec)
+				err := p.deleteDNSRecord(ctx, snapshot, rec)
 				if err != nil {
 					p.logger.Error("Failed to delete record during update",
 						zap.String("dnsName", rec.Name),
 						zap.String("type", rec.RecordType),
 						zap.String("value", rec.Value),
 						zap.Error(err))
+					errs = append(errs, fmt.Errorf("value %s: %w", rec.Value, err))
 					continue
 				}
-				p.logger.Info("Deleted record", zap.String("dnsName", dnsName), zap.String("type", rec.RecordType), zap.String("value", val))
 			}
 		}
 
 		// 2. Create any missing records
-		for val := range desired {
-			if err := p.createDNSRecord(dnsName, newEp.RecordType, val, ttl); err != nil {
+		for _, val := range desired {
+			if ctx.Err() != nil {
+				return abortErr(ctx, errs)
+			}
+			if err := p.createDNSRecord(ctx, snapshot, dnsName, newEp.RecordType, val, ttl, newEp); err != nil {
 				p.logger.Error("Failed to create record during update", zap.String("dnsName", dnsName), zap.String("value", val), zap.Error(err))
+				errs = append(errs, fmt.Errorf("value %s: %w", val, err))
 				continue
 			}
-			p.logger.Info("Created missing record during update", zap.String("dnsName", dnsName), zap.String("value", val))
 		}
 	}
-	return nil
+	return errors.Join(errs...)
 }
-func (p *MyraSecDNSProvider) processDeleteActions(endpoints []*endpoint.Endpoint) error {
-	if len(endpoints) == 0 {
-		return nil
-	}
 
-	// Fetch all records for the domain once
-	domainID, err := strconv.Atoi(p.domainId)
-	if err != nil {
-		return fmt.Errorf("invalid domain ID: %w", err)
+// identityChanged reports whether an update pair moves an endpoint to another DNS name or record type.
+func (p *MyraSecDNSProvider) identityChanged(zone string, oldEp, newEp *endpoint.Endpoint) bool {
+	return !p.sameEndpointName(zone, oldEp, newEp) || !strings.EqualFold(oldEp.RecordType, newEp.RecordType)
+}
+
+// attributableRecord reports whether the record can be attributed to this owner, so that it may be
+// deleted. Its name must carry the ownership record of this owner and of no other. The old endpoint
+// of an update, if any, must have the name and type of the record and list its value: ExternalDNS
+// only knows the values it listed before the plan, a value added since, e.g. by hand, is not its.
+// Records that cannot be attributed are kept.
+func (p *MyraSecDNSProvider) attributableRecord(snapshot *zoneSnapshot, dnsName string, rec *myrasec.DNSRecord, oldEp *endpoint.Endpoint) bool {
+	owners := snapshot.owners(dnsName)
+	if len(owners) != 1 || owners[0] != p.owner {
+		return false
 	}
-	allRecords, err := p.apiClient.ListDNSRecords(domainID, nil)
-	if err != nil {
-		return fmt.Errorf("failed to list DNS records for deletion: %w", err)
+	if oldEp == nil {
+		return true
 	}
-
-	// Index TXT records for ownership check
-	txtRecords := make(map[string]string)
-	for _, r := range allRecords {
-		if r.RecordType == endpoint.RecordTypeTXT {
-			txtRecords[r.Name] = r.Value
+	if !p.sameEndpointName(snapshot.zoneName(), oldEp, endpoint.NewEndpoint(dnsName, rec.RecordType)) ||
+		!strings.EqualFold(oldEp.RecordType, rec.RecordType) {
+		return false
+	}
+	value := p.canonicalRecordValue(rec.Value, rec.RecordType)
+	for _, target := range oldEp.Targets {
+		if p.canonicalRecordValue(target, oldEp.RecordType) == value {
+			return true
 		}
 	}
+	return false
+}
+
+// sameEndpointName reports whether both endpoints have the same name in the zone
+func (p *MyraSecDNSProvider) sameEndpointName(zone string, oldEp, newEp *endpoint.Endpoint) bool {
+	oldName, oldErr := p.ensureFullDNSName(canonicalDNSName(oldEp.DNSName), zone)
+	newName, newErr := p.ensureFullDNSName(canonicalDNSName(newEp.DNSName), zone)
+	if oldErr != nil || newErr != nil {
+		oldName, newName = normalizeDNSName(oldEp.DNSName), normalizeDNSName(newEp.DNSName)
+	}
+	return sameName(oldName, newName)
+}
+
+func (p *MyraSecDNSProvider) processDeleteActions(ctx context.Context, snapshot *zoneSnapshot, endpoints []*endpoint.Endpoint) error {
+	return p.deleteEndpoints(ctx, snapshot, endpoints, false)
+}
+
+// deleteEndpoints deletes the records of the endpoints owned by this instance. The ownership TXT
+// record of a name is removed with its last data record, unless keepOwnership is set.
+func (p *MyraSecDNSProvider) deleteEndpoints(ctx context.Context, snapshot *zoneSnapshot, endpoints []*endpoint.Endpoint, keepOwnership bool) error {
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	// Use the zone records from the snapshot
+	allRecords := snapshot.all()
 
+	var errs []error
 	for _, ep := range endpoints {
-		dnsName := p.ensureFullDNSName(stripTrailingDot(ep.DNSName))
+		if ctx.Err() != nil {
+			return abortErr(ctx, errs)
+		}
+		dnsName, err := p.ensureFullDNSName(canonicalDNSName(ep.DNSName), snapshot.zoneName())
+		if err != nil {
+			p.logger.Warn("Skipping deletion of record outside the selected zone", zap.String("dnsName", ep.DNSName), zap.Error(err))
+			continue
+		}
 
-		if isProduction() && isPrivateEndpoint(ep) {
-			p.logger.Warn("Skipping deletion of private IP in production",
-				zap.String("dnsName", dnsName),
+		if p.apexNameservers(DELETE, dnsName, snapshot.zoneName(), ep.RecordType) {
+			continue
+		}
+
+		if p.isProduction() && isPrivateEndpoint(ep) {
+			p.skipChange(ctx, "Skipping deletion of private IP in production", dnsName,
 				zap.String("type", ep.RecordType))
 			continue
 		}
 
 		// Ownership check
-		txtVal, ok := txtRecords[stripTrailingDot(ep.DNSName)]
-		if !ok || !isOwnedByExternalDNS(txtVal, p.owner) {
-			p.logger.Warn("Skipping delete: not owned by this instance",
-				zap.String("dnsName", dnsName))
+		if !snapshot.ownedBy(dnsName, p.owner) {
+			p.skipChange(ctx, "Skipping delete: not owned by this instance", dnsName)
 			continue
 		}
 
 		// Find all records matching this dnsName + recordType
-		matchingRecords := p.findMatchingRecords(allRecords, dnsName, ep.RecordType)
+		matchingRecords := dataRecords(p.findMatchingRecords(allRecords, snapshot.zoneName(), dnsName, ep.RecordType))
 		if len(matchingRecords) == 0 {
 			p.logger.Debug("No matching records to delete", zap.String("dnsName", dnsName), zap.String("type", ep.RecordType))
 			continue
@@ -312,69 +488,198 @@ func (p *MyraSecDNSProvider) processDeleteActions(endpoints []*endpoint.Endpoint
 		// Prepare target values to delete
 		targetsToDelete := make(map[string]bool)
 		for _, t := range ep.Targets {
-			targetsToDelete[p.formatRecordValue(t, ep.RecordType)] = true
+			targetsToDelete[p.canonicalRecordValue(t, ep.RecordType)] = true
 		}
 
 		for _, record := range matchingRecords {
-			if !targetsToDelete[record.Value] {
+			// The nameservers of a delegation are deleted as a set
+			if !targetsToDelete[p.canonicalRecordValue(record.Value, record.RecordType)] && ep.RecordType != endpoint.RecordTypeNS {
+				continue
+			}
+			if ctx.Err() != nil {
+				return abortErr(ctx, errs)
+			}
+			if !p.attributableRecord(snapshot, dnsName, &record, nil) {
+				p.skipChange(ctx, "Keeping record not attributable to this owner", dnsName,
+					zap.String("type", record.RecordType),
+					zap.String("value", record.Value))
 				continue
 			}
 
-			err := p.deleteDNSRecord(&record)
+			err := p.deleteDNSRecord(ctx, snapshot, &record)
 			if err != nil {
 				p.logger.Error("Failed to delete DNS record",
 					zap.String("dnsName", record.Name),
 					zap.String("type", record.RecordType),
 					zap.String("value", record.Value),
 					zap.Error(err))
+				errs = append(errs, fmt.Errorf("value %s: %w", record.Value, err))
 				continue
 			}
 		}
+
+		// Remove the ownership TXT record once the last data record at this name is gone
+		if !keepOwnership {
+			if err := p.deleteUnusedOwnershipRecords(ctx, snapshot, dnsName); err != nil {
+				errs = append(errs, err)
+			}
+		}
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
+// deleteUnusedOwnershipRecords deletes the TXT records owned by this instance at dnsName,
+// provided that no data records of any type remain at that name. TXT records other than
+// ownership records are data records as well.
+func (p *MyraSecDNSProvider) deleteUnusedOwnershipRecords(ctx context.Context, snapshot *zoneSnapshot, dnsName string) error {
+	var ownershipRecords []myrasec.DNSRecord
+	for _, record := range snapshot.all() {
+		if !sameName(recordName(record.Name, snapshot.zoneName()), dnsName) {
+			continue
+		}
+		if record.RecordType != endpoint.RecordTypeTXT || !isOwnershipTXT(record.Value) {
+			p.logger.Debug("Keeping ownership record, other records remain",
+				zap.String("dnsName", dnsName),
+				zap.String("remaining_type", record.RecordType))
+			return nil
+		}
+		if isOwnedByExternalDNS(record.Value, p.owner) {
+			ownershipRecords = append(ownershipRecords, record)
+		}
+	}
+
+	var errs []error
+	for _, record := range ownershipRecords {
+		if ctx.Err() != nil {
+			return abortErr(ctx, errs)
+		}
+		if err := p.deleteDNSRecord(ctx, snapshot, &record); err != nil {
+			p.logger.Error("Failed to delete ownership TXT record",
+				zap.String("dnsName", record.Name),
+				zap.String("value", record.Value),
+				zap.Error(err))
+			errs = append(errs, fmt.Errorf("ownership record: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// parseOwnershipTXT splits an ownership TXT value like "heritage=external-dns,external-dns/owner=x"
+// into its key/value pairs. Both the quoted form written by ExternalDNS and earlier versions of the
+// webhook without quotes are read, in any field order, as are values stored as several strings.
+func parseOwnershipTXT(txtValue string) map[string]string {
+	fields := make(map[string]string)
+	value := formatTXTValue(strings.TrimSpace(txtValue))
+	for _, part := range strings.Split(strings.Trim(value, "\""), ",") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		fields[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return fields
+}
+
+// ownershipValue returns the value of the ownership TXT record of the endpoint, byte for byte as
+// the TXT registry of ExternalDNS writes it: quoted, heritage first and the labels sorted, e.g.
+// "heritage=external-dns,external-dns/owner=x,external-dns/resource=ingress/default/app".
+// A zone moved to the TXT registry or another provider keeps its ownership this way.
+func (p *MyraSecDNSProvider) ownershipValue(ep *endpoint.Endpoint) string {
+	labels := endpoint.Labels{endpoint.OwnerLabelKey: p.owner}
+	if resource, ok := ep.Labels[endpoint.ResourceLabelKey]; ok {
+		labels[endpoint.ResourceLabelKey] = resource
+	}
+	return labels.SerializePlain(true)
+}
+
+// dataRecords returns the records without the ownership TXT records among them. The ownership
+// record of a name is not a target of its TXT endpoint, it is managed together with the endpoint.
+func dataRecords(records []myrasec.DNSRecord) []myrasec.DNSRecord {
+	var data []myrasec.DNSRecord
+	for _, r := range records {
+		if r.RecordType != endpoint.RecordTypeTXT || !isOwnershipTXT(r.Value) {
+			data = append(data, r)
+		}
+	}
+	return data
+}
+
+// isOwnedByExternalDNS reports whether the TXT value is an ExternalDNS ownership record of exactly the given owner.
 func isOwnedByExternalDNS(txtValue, owner string) bool {
-	return strings.Contains(txtValue, "heritage=external-dns") &&
-		strings.Contains(txtValue, fmt.Sprintf("external-dns/owner=%s", owner))
+	fields := parseOwnershipTXT(txtValue)
+	return fields["heritage"] == "external-dns" && fields["external-dns/owner"] == owner
 }
 
 // createDNSRecord is the underlying method used by processCreateActions or processUpdateActions.
-func (p *MyraSecDNSProvider) createDNSRecord(dnsName, recordType, value string, ttl int) error {
-	formattedValue := p.formatRecordValue(value, recordType)
+// The provider-specific properties of ep (if any) are applied to the created record.
+// In dry-run mode the record is only reported. With a timeout to wait for created records, the
+// record of an endpoint is only done once the API lists it. Ownership TXT records, created without
+// an endpoint, are stored with their value as is, quotes included.
+func (p *MyraSecDNSProvider) createDNSRecord(ctx context.Context, snapshot *zoneSnapshot, dnsName, recordType, value string, ttl int, ep *endpoint.Endpoint) error {
+	formattedValue := value
+	if ep != nil {
+		formattedValue = p.formatRecordValue(value, recordType)
+		if recordType == endpoint.RecordTypeTXT {
+			formattedValue = splitTXTValue(formattedValue)
+		}
+	}
 	record := &myrasec.DNSRecord{
 		Name:       dnsName,
 		Value:      formattedValue,
 		RecordType: recordType,
-		Active:     !p.disableProtection,
-		Enabled:    true,
 		TTL:        ttl,
 	}
+	p.applyProviderSpecific(record, ep)
+
+	// The record may exist under the name in another case, DNS names are case-insensitive
+	if snapshot.contains(record) {
+		p.logger.Debug("Record already exists, skipping creation",
+			zap.String("name", record.Name),
+			zap.String("type", record.RecordType),
+			zap.String("value", record.Value))
+		return nil
+	}
 
-	domainID, err := strconv.Atoi(p.domainId)
+	if p.dryRun {
+		p.recordDryRunChange(ctx, dryRunChange{
+			Action:    CREATE,
+			Domain:    snapshot.zoneName(),
+			Name:      record.Name,
+			Type:      record.RecordType,
+			NewValues: []string{record.Value},
+			TTL:       record.TTL,
+			Active:    record.Active,
+		})
+		snapshot.add(*record)
+		return nil
+	}
+
+	domainID, err := snapshot.domainID()
 	if err != nil {
-		return fmt.Errorf("invalid domain ID: %w", err)
+		return err
 	}
-	_, err = p.apiClient.CreateDNSRecord(record, domainID)
+	var created *myrasec.DNSRecord
+	err = traceAPICall(ctx, "CreateDNSRecord", snapshot.zoneName(), record, func() (err error) {
+		created, err = p.clientFor(ctx).CreateDNSRecord(record, domainID)
+		return err
+	})
 	if err != nil {
-		// Duplicate record
-		if strings.Contains(err.Error(), "This value is already used") {
+		err = p.createError(err, snapshot, record)
+		switch {
+		case errors.Is(err, ErrDuplicateRecord):
 			p.logger.Warn("Record already exists, skipping creation",
 				zap.String("name", record.Name),
 				zap.String("type", record.RecordType),
 				zap.String("value", record.Value))
 			return nil
-		}
-
-		// Private IP logic
-		if strings.Contains(err.Error(), "private network range") && isProduction() {
+		case errors.Is(err, ErrPrivateIPRejected) && p.isProduction():
 			p.logger.Warn("Private IP address detected, skipping creation in production mode",
 				zap.String("name", record.Name),
 				zap.String("type", record.RecordType),
 				zap.String("value", record.Value))
 			return nil
-		} else if strings.Contains(err.Error(), "private network range") {
+		case errors.Is(err, ErrPrivateIPRejected):
 			p.logger.Info("Creating DNS record with private IP in development mode",
 				zap.String("name", record.Name),
 				zap.String("type", record.RecordType),
@@ -390,31 +695,103 @@ func (p *MyraSecDNSProvider) createDNSRecord(dnsName, recordType, value string,
 		return err
 	}
 
+	if created != nil {
+		snapshot.add(*created)
+	}
+	p.recordAppliedChange(ctx, snapshot, CREATE, record)
+
 	p.logger.Info("Created DNS record",
 		zap.String("name", record.Name),
 		zap.String("type", record.RecordType),
 		zap.String("value", record.Value),
 		zap.Int("ttl", record.TTL))
+
+	// Ownership records are not waited for, only the records ExternalDNS asked for
+	if ep != nil {
+		return p.waitForRecordActive(ctx, snapshot.zoneName(), domainID, record)
+	}
 	return nil
 }
 
-// deleteDNSRecord is the underlying method used by processDeleteActions or processUpdateActions.
-func (p *MyraSecDNSProvider) deleteDNSRecord(record *myrasec.DNSRecord) error {
-	domainID, err := strconv.Atoi(p.domainId)
+// updateDNSRecord replaces the current record with the wanted state. It is used by processUpdateActions.
+// In dry-run mode the change is only reported.
+func (p *MyraSecDNSProvider) updateDNSRecord(ctx context.Context, snapshot *zoneSnapshot, current, wanted *myrasec.DNSRecord) error {
+	if p.dryRun {
+		p.recordDryRunChange(ctx, dryRunChange{
+			Action:    UPDATE,
+			Domain:    snapshot.zoneName(),
+			Name:      wanted.Name,
+			Type:      wanted.RecordType,
+			OldValues: []string{current.Value},
+			NewValues: []string{wanted.Value},
+			OldTTL:    current.TTL,
+			TTL:       wanted.TTL,
+			Active:    wanted.Active,
+		})
+		snapshot.replace(*wanted)
+		return nil
+	}
+
+	domainID, err := snapshot.domainID()
+	if err != nil {
+		return err
+	}
+
+	err = traceAPICall(ctx, "UpdateDNSRecord", snapshot.zoneName(), wanted, func() error {
+		_, err := p.clientFor(ctx).UpdateDNSRecord(wanted, domainID)
+		return err
+	})
 	if err != nil {
-		p.logger.Error("Invalid domain ID", zap.Error(err))
+		return apiError(err)
+	}
+	snapshot.replace(*wanted)
+	p.recordAppliedChange(ctx, snapshot, UPDATE, wanted)
+
+	p.logger.Info("Updated DNS record",
+		zap.String("dnsName", wanted.Name),
+		zap.String("type", wanted.RecordType),
+		zap.String("value", wanted.Value),
+		zap.Int("ttl", wanted.TTL),
+		zap.Bool("active", wanted.Active))
+	return nil
+}
+
+// deleteDNSRecord is the underlying method used by processDeleteActions or processUpdateActions.
+// In dry-run mode the deletion is only reported.
+func (p *MyraSecDNSProvider) deleteDNSRecord(ctx context.Context, snapshot *zoneSnapshot, record *myrasec.DNSRecord) error {
+	if p.dryRun {
+		p.recordDryRunChange(ctx, dryRunChange{
+			Action:    DELETE,
+			Domain:    snapshot.zoneName(),
+			Name:      record.Name,
+			Type:      record.RecordType,
+			OldValues: []string{record.Value},
+			OldTTL:    record.TTL,
+			Active:    record.Active,
+		})
+		snapshot.remove(*record)
 		return nil
 	}
 
-	_, err = p.apiClient.DeleteDNSRecord(record, domainID)
+	domainID, err := snapshot.domainID()
+	if err != nil {
+		return err
+	}
+
+	err = traceAPICall(ctx, "DeleteDNSRecord", snapshot.zoneName(), record, func() error {
+		_, err := p.clientFor(ctx).DeleteDNSRecord(record, domainID)
+		return err
+	})
 	if err != nil {
 		p.logger.Error("Failed to delete DNS record",
 			zap.String("dnsName", record.Name),
 			zap.String("type", record.RecordType),
 			zap.String("value", record.Value),
 			zap.Error(err))
-		return err
+		return apiError(err)
 	}
+	snapshot.remove(*record)
+	p.recordAppliedChange(ctx, snapshot, DELETE, record)
 
 	p.logger.Info("Deleted DNS record",
 		zap.String("dnsName", record.Name),
@@ -423,51 +800,110 @@ func (p *MyraSecDNSProvider) deleteDNSRecord(record *myrasec.DNSRecord) error {
 	return nil
 }
 
-// findMatchingRecords returns all records matching the given dnsName + recordType.
-func (p *MyraSecDNSProvider) findMatchingRecords(records []myrasec.DNSRecord, dnsName, recordType string) []myrasec.DNSRecord {
+// listDNSRecords returns all records of the domain.
+func (p *MyraSecDNSProvider) listDNSRecords(ctx context.Context, domain *myrasec.Domain) (records []myrasec.DNSRecord, err error) {
+	err = traceAPICall(ctx, "ListDNSRecords", domain.Name, nil, func() error {
+		records, err = p.clientFor(ctx).ListDNSRecords(domain.ID, nil)
+		return err
+	})
+	return records, err
+}
+
+// findMatchingRecords returns all records of the zone matching the given dnsName + recordType, ignoring case.
+func (p *MyraSecDNSProvider) findMatchingRecords(records []myrasec.DNSRecord, zone, dnsName, recordType string) []myrasec.DNSRecord {
 	var matching []myrasec.DNSRecord
 	for _, rec := range records {
-		if stripTrailingDot(rec.Name) == stripTrailingDot(dnsName) && rec.RecordType == recordType {
+		if sameName(recordName(rec.Name, zone), dnsName) && strings.EqualFold(rec.RecordType, recordType) {
 			matching = append(matching, rec)
 		}
 	}
 	return matching
 }
 
-// formatRecordValue cleans or adjusts the record value based on record type.
+// formatRecordValue returns the value of a record in the form used for the endpoints reported to
+// ExternalDNS and for the records created.
 func (p *MyraSecDNSProvider) formatRecordValue(value, recordType string) string {
-	if recordType == endpoint.RecordTypeTXT {
+	switch recordType {
+	case endpoint.RecordTypeTXT:
 		return formatTXTValue(value)
 	}
+	return canonicalTarget(value, recordType)
+}
+
+// canonicalRecordValue returns the form in which desired and stored values are compared, so a
+// value matches however MyraSec or ExternalDNS spell it: on top of formatRecordValue, IP
+// addresses get their canonical notation.
+func (p *MyraSecDNSProvider) canonicalRecordValue(value, recordType string) string {
+	value = strings.TrimSpace(p.formatRecordValue(value, recordType))
+	if recordType == endpoint.RecordTypeA || recordType == endpoint.RecordTypeAAAA {
+		if ip := net.ParseIP(value); ip != nil {
+			return ip.String()
+		}
+	}
 	return value
 }
 
-// ensureFullDNSName appends p.domainName if the dnsName is missing it.
-func (p *MyraSecDNSProvider) ensureFullDNSName(dnsName string) string {
-	if p.domainName == "" {
-		return dnsName
+// ensureFullDNSName appends the zone name if the dnsName is missing it.
+// A name with a trailing dot is absolute and is returned without the dot; it must belong to the
+// zone, otherwise ErrNameOutsideZone is returned. Internationalized names are returned in punycode.
+func (p *MyraSecDNSProvider) ensureFullDNSName(dnsName, zone string) (string, error) {
+	absolute := strings.HasSuffix(dnsName, ".")
+	dnsName = asciiName(stripTrailingDot(dnsName))
+	zone = asciiName(zone)
+	if zone == "" {
+		return dnsName, nil
 	}
-	// If it already ends with the domainName, skip
-	if strings.HasSuffix(dnsName, p.domainName) {
-		return dnsName
+	// The root of the zone may be given in its short form
+	if dnsName == "" || dnsName == apexRecordName {
+		return zone, nil
 	}
-	return dnsName + "." + p.domainName
+	// The zone apex and names below it are already fully qualified
+	if lower := strings.ToLower(dnsName); lower == strings.ToLower(zone) || strings.HasSuffix(lower, "."+strings.ToLower(zone)) {
+		return dnsName, nil
+	}
+	if absolute {
+		return "", fmt.Errorf("%w: %s is not in zone %s", ErrNameOutsideZone, dnsName, zone)
+	}
+	return dnsName + "." + zone, nil
+}
+
+// recordName returns the fully qualified name of a MyraSec record in the given zone, without the
+// trailing dot. The zone apex is stored under the zone name, but it can be listed in its short form,
+// either "@" or an empty name, so both are mapped to the zone name. Surrounding whitespace is
+// removed and internationalized names are returned in punycode, whichever form they are stored in.
+func recordName(name, zone string) string {
+	name = stripTrailingDot(strings.TrimSpace(name))
+	if name == "" || name == apexRecordName {
+		return asciiName(zone)
+	}
+	return asciiName(name)
+}
+
+// nameKey returns the key of a MyraSec record name in indexes by name. DNS names are
+// case-insensitive, so names that only differ in case share a key.
+func nameKey(name, zone string) string {
+	return strings.ToLower(recordName(name, zone))
+}
+
+// sameName reports whether two DNS names are equal, ignoring case and a trailing dot.
+func sameName(a, b string) bool {
+	return strings.EqualFold(asciiName(stripTrailingDot(a)), asciiName(stripTrailingDot(b)))
 }
 
 // supportedRecordType returns true if the record type is supported by ExternalDNS.
 func supportedRecordType(recordType string) bool {
-	switch recordType {
-	case endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME,
-		endpoint.RecordTypeMX, endpoint.RecordTypeTXT, endpoint.RecordTypeNS, endpoint.RecordTypeSRV:
-		return true
+	for _, t := range SupportedRecordTypes {
+		if t == recordType {
+			return true
+		}
 	}
 	return false
 }
 
 // isProduction checks if we're in a production-like environment.
 // It returns true for environments that should have production behavior (e.g., prod, production, staging).
-func isProduction() bool {
-	env := strings.ToLower(os.Getenv("ENV"))
+func (p *MyraSecDNSProvider) isProduction() bool {
+	env := strings.ToLower(p.environment)
 
 	// Consider these environments as production-like (requiring stricter rules)
 	prodEnvs := map[string]bool{
@@ -476,7 +912,7 @@ func isProduction() bool {
 		"staging":    true,
 	}
 
-	// If ENV is not set, default to non-production behavior
+	// If the environment is not set, default to non-production behavior
 	return prodEnvs[env]
 }
 
@@ -519,27 +955,6 @@ func isPrivateEndpoint(ep *endpoint.Endpoint) bool {
 	return false
 }
 
-// formatTXTValue sanitizes a TXT record value by removing quotes, newlines, etc.
-func formatTXTValue(value string) string {
-	value = strings.Trim(value, "\"'")
-	value = strings.ReplaceAll(value, "\"", "")
-	value = strings.ReplaceAll(value, "\n", " ")
-	value = strings.ReplaceAll(value, "\r", " ")
-	value = strings.ReplaceAll(value, "\t", " ")
-	return value
-}
-
-// ensureTrailingDot ensures the given name ends with a dot (common in ExternalDNS).
-func ensureTrailingDot(name string) string {
-	if name == "" {
-		return name
-	}
-	if !strings.HasSuffix(name, ".") {
-		return name + "."
-	}
-	return name
-}
-
 // stripTrailingDot removes any final dot in a DNS name.
 func stripTrailingDot(name string) string {
 	if strings.HasSuffix(name, ".") {
@@ -0,0 +1,858 @@
+package myrasecprovider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// fakeMyraSecClient is an in-memory implementation of the MyraSecAPIClient interface
+// that keeps the records it is given, so that apply and read paths can be tested together.
+type fakeMyraSecClient struct {
+	mu      sync.Mutex
+	domains []myrasec.Domain
+	records map[int][]myrasec.DNSRecord
+	nextID  int
+}
+
+func newFakeMyraSecClient(domains ...myrasec.Domain) *fakeMyraSecClient {
+	return &fakeMyraSecClient{
+		domains: domains,
+		records: make(map[int][]myrasec.DNSRecord),
+		nextID:  1000,
+	}
+}
+
+func (f *fakeMyraSecClient) ListDomains(params map[string]string) ([]myrasec.Domain, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]myrasec.Domain(nil), f.domains...), nil
+}
+
+func (f *fakeMyraSecClient) ListDNSRecords(domainId int, params map[string]string) ([]myrasec.DNSRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]myrasec.DNSRecord(nil), f.records[domainId]...), nil
+}
+
+func (f *fakeMyraSecClient) CreateDNSRecord(record *myrasec.DNSRecord, domainId int) (*myrasec.DNSRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, r := range f.records[domainId] {
+		if r.Name == record.Name && r.RecordType == record.RecordType && r.Value == record.Value {
+			return nil, fmt.Errorf("value: This value is already used")
+		}
+	}
+	created := *record
+	f.nextID++
+	created.ID = f.nextID
+	f.records[domainId] = append(f.records[domainId], created)
+	return &created, nil
+}
+
+func (f *fakeMyraSecClient) UpdateDNSRecord(record *myrasec.DNSRecord, domainId int) (*myrasec.DNSRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, r := range f.records[domainId] {
+		if r.ID == record.ID {
+			f.records[domainId][i] = *record
+			return record, nil
+		}
+	}
+	return nil, fmt.Errorf("record %d not found", record.ID)
+}
+
+func (f *fakeMyraSecClient) DeleteDNSRecord(record *myrasec.DNSRecord, domainId int) (*myrasec.DNSRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, r := range f.records[domainId] {
+		if r.ID == record.ID {
+			f.records[domainId] = append(f.records[domainId][:i], f.records[domainId][i+1:]...)
+			return record, nil
+		}
+	}
+	return nil, fmt.Errorf("record %d not found", record.ID)
+}
+
+// newTestProvider returns a provider for example.com backed by the given client.
+func newTestProvider(client MyraSecAPIClient) *MyraSecDNSProvider {
+	return &MyraSecDNSProvider{
+		apiClient:    client,
+		logger:       zap.NewNop(),
+		domainFilter: endpoint.NewDomainFilter([]string{"example.com"}),
+		ttl:          300,
+		owner:        "test-owner",
+	}
+}
+
+// findEndpoint returns the endpoint with the given name and type, or nil.
+func findEndpoint(endpoints []*endpoint.Endpoint, dnsName, recordType string) *endpoint.Endpoint {
+	for _, ep := range endpoints {
+		if stripTrailingDot(ep.DNSName) == stripTrailingDot(dnsName) && ep.RecordType == recordType {
+			return ep
+		}
+	}
+	return nil
+}
+
+func TestAdjustEndpointsProviderSpecific(t *testing.T) {
+	p := newTestProvider(newFakeMyraSecClient())
+
+	endpoints := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.4").
+			WithProviderSpecific(providerSpecificProtection, "False").
+			WithProviderSpecific(providerSpecificComment, " managed by test ").
+			WithProviderSpecific("webhook/unknown", "x").
+			WithProviderSpecific("aws/weight", "10"),
+		endpoint.NewEndpoint("txt.example.com", endpoint.RecordTypeTXT, "hello").
+			WithProviderSpecific(providerSpecificProtection, "true").
+			WithProviderSpecific(providerSpecificEnabled, "not-a-bool"),
+	}
+
+	adjusted, err := p.AdjustEndpoints(endpoints)
+	require.NoError(t, err)
+	require.Len(t, adjusted, 2)
+
+	assert.Equal(t, endpoint.ProviderSpecific{
+		{Name: providerSpecificProtection, Value: "false"},
+		{Name: providerSpecificEnabled, Value: "true"},
+		{Name: providerSpecificComment, Value: "managed by test"},
+	}, adjusted[0].ProviderSpecific)
+
+	// Invalid values fall back to the default
+	assert.Equal(t, endpoint.ProviderSpecific{
+		{Name: providerSpecificProtection, Value: "true"},
+		{Name: providerSpecificEnabled, Value: "true"},
+	}, adjusted[1].ProviderSpecific)
+}
+
+func TestProviderSpecificRoundTrip(t *testing.T) {
+	client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+	p := newTestProvider(client)
+
+	desired := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.4").
+			WithProviderSpecific(providerSpecificProtection, "false").
+			WithProviderSpecific(providerSpecificComment, "frontend"),
+		endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeCNAME, "app.example.com"),
+	}
+
+	adjusted, err := p.AdjustEndpoints(desired)
+	require.NoError(t, err)
+
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{Create: adjusted}))
+
+	records, err := p.Records(context.Background())
+	require.NoError(t, err)
+
+	for _, want := range adjusted {
+		got := findEndpoint(records, want.DNSName, want.RecordType)
+		require.NotNil(t, got, "missing %s %s", want.RecordType, want.DNSName)
+		assert.Equal(t, want.ProviderSpecific, got.ProviderSpecific, "properties of %s", want.DNSName)
+		assert.Equal(t, want.Targets, got.Targets)
+	}
+
+	// Adjusting the endpoints returned by Records must not change them
+	current := findEndpoint(records, "app.example.com", endpoint.RecordTypeA)
+	before := append(endpoint.ProviderSpecific(nil), current.ProviderSpecific...)
+	_, err = p.AdjustEndpoints([]*endpoint.Endpoint{current})
+	require.NoError(t, err)
+	assert.Equal(t, before, current.ProviderSpecific)
+}
+
+// TestProtectionPerType tests that records get the protection default of their type unless the
+// endpoint sets it, and that Records reports the stored state
+func TestProtectionPerType(t *testing.T) {
+	client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+	p := newTestProvider(client)
+	p.protectionPerType = map[string]bool{endpoint.RecordTypeCNAME: false}
+
+	desired, err := p.AdjustEndpoints([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("mail.example.com", endpoint.RecordTypeMX, "10 mx.example.com"),
+		endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeCNAME, "app.example.com"),
+		endpoint.NewEndpoint("relay.example.com", endpoint.RecordTypeMX, "10 mx.example.com").
+			WithProviderSpecific(providerSpecificProtection, "true"),
+	})
+	require.NoError(t, err)
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{Create: desired}))
+
+	active := map[string]bool{}
+	for _, rec := range client.records[123] {
+		if rec.RecordType != endpoint.RecordTypeTXT {
+			active[rec.Name] = rec.Active
+		}
+	}
+	assert.Equal(t, map[string]bool{
+		"app.example.com":   true,
+		"mail.example.com":  false,
+		"www.example.com":   false,
+		"relay.example.com": true,
+	}, active)
+
+	records, err := p.Records(context.Background())
+	require.NoError(t, err)
+	for _, want := range desired {
+		got := findEndpoint(records, want.DNSName, want.RecordType)
+		require.NotNil(t, got, "missing %s %s", want.RecordType, want.DNSName)
+		assert.Equal(t, want.ProviderSpecific, got.ProviderSpecific, "properties of %s", want.DNSName)
+	}
+}
+
+func TestNewMyraSecDNSProviderProtectionPerType(t *testing.T) {
+	p, err := NewMyraSecDNSProvider(zap.NewNop(), Config{APIKey: "key", APISecret: "secret", DisableProtection: true, ProtectionPerType: []string{"a=true", " MX = false ", ""}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{endpoint.RecordTypeA: true, endpoint.RecordTypeMX: false}, p.protectionPerType)
+	assert.True(t, p.protectionDefault(endpoint.RecordTypeA), "the type overrides --disable-protection")
+	assert.False(t, p.protectionDefault(endpoint.RecordTypeAAAA))
+	assert.False(t, p.protectionDefault(endpoint.RecordTypeTXT))
+
+	for _, entries := range [][]string{
+		{"A"},
+		{"A=maybe"},
+		{"A=true", "a=false"},
+		{"SPF=true"},
+	} {
+		_, err := NewMyraSecDNSProvider(zap.NewNop(), Config{APIKey: "key", APISecret: "secret", ProtectionPerType: entries})
+		assert.Error(t, err, "%v", entries)
+	}
+}
+
+// TestUnchangedRecordsPlanNothing tests that a sync without changes plans nothing, whether the
+// desired names carry a trailing dot or not
+func TestUnchangedRecordsPlanNothing(t *testing.T) {
+	client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+	p := newTestProvider(client)
+
+	desired := func() []*endpoint.Endpoint {
+		adjusted, err := p.AdjustEndpoints([]*endpoint.Endpoint{
+			endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+			// Decoded from JSON, so the trailing dot is not removed by NewEndpoint
+			{DNSName: "www.example.com.", RecordType: endpoint.RecordTypeCNAME, Targets: endpoint.Targets{"app.example.com"}},
+			endpoint.NewEndpoint("example.com", endpoint.RecordTypeA, "1.2.3.5"),
+		})
+		require.NoError(t, err)
+		for _, ep := range adjusted {
+			assert.Equal(t, stripTrailingDot(ep.DNSName), ep.DNSName)
+		}
+		return adjusted
+	}
+	calculate := func() *plan.Changes {
+		current, err := p.Records(context.Background())
+		require.NoError(t, err)
+		for _, ep := range current {
+			assert.NotEqual(t, '.', ep.DNSName[len(ep.DNSName)-1], "%s has a trailing dot", ep.DNSName)
+		}
+		return (&plan.Plan{
+			Current:        current,
+			Desired:        desired(),
+			Policies:       []plan.Policy{&plan.SyncPolicy{}},
+			DomainFilter:   endpoint.MatchAllDomainFilters{&p.domainFilter},
+			ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+			OwnerID:        p.owner,
+		}).Calculate().Changes
+	}
+
+	changes := calculate()
+	require.Len(t, changes.Create, 3)
+	require.NoError(t, p.ApplyChanges(context.Background(), changes))
+
+	changes = calculate()
+	assert.False(t, changes.HasChanges(), "planned %+v", changes)
+	require.NoError(t, p.ApplyChanges(context.Background(), changes))
+}
+
+// TestTXTRecordsPlanNothing tests that TXT records created by ApplyChanges are listed by Records
+// with their ownership, so the next sync plans nothing for them
+func TestTXTRecordsPlanNothing(t *testing.T) {
+	client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+	p := newTestProvider(client)
+
+	desired := func() []*endpoint.Endpoint {
+		adjusted, err := p.AdjustEndpoints([]*endpoint.Endpoint{
+			endpoint.NewEndpoint("selector._domainkey.example.com", endpoint.RecordTypeTXT, "v=DKIM1; k=rsa; p=abc"),
+			endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeTXT, "v=spf1 -all"),
+		})
+		require.NoError(t, err)
+		return adjusted
+	}
+	calculate := func() *plan.Changes {
+		records, err := p.Records(context.Background())
+		require.NoError(t, err)
+
+		// Like the TXT registry of ExternalDNS, the planner reads the ownership records as the
+		// labels of the names instead of planning them
+		var current []*endpoint.Endpoint
+		for _, ep := range records {
+			if ep.RecordType == endpoint.RecordTypeTXT && isOwnershipTXT(ep.Targets[0]) {
+				continue
+			}
+			assert.Equal(t, p.owner, ep.Labels[endpoint.OwnerLabelKey], "%s %s", ep.DNSName, ep.RecordType)
+			current = append(current, ep)
+		}
+		return (&plan.Plan{
+			Current:        current,
+			Desired:        desired(),
+			Policies:       []plan.Policy{&plan.SyncPolicy{}},
+			DomainFilter:   endpoint.MatchAllDomainFilters{&p.domainFilter},
+			ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeTXT},
+			OwnerID:        p.owner,
+		}).Calculate().Changes
+	}
+
+	changes := calculate()
+	require.Len(t, changes.Create, 3)
+	require.NoError(t, p.ApplyChanges(context.Background(), changes))
+	assert.Len(t, client.records[123], 5, "one ownership record per name")
+
+	changes = calculate()
+	assert.False(t, changes.HasChanges(), "planned %+v", changes)
+}
+
+func TestEnsureFullDNSName(t *testing.T) {
+	p := newTestProvider(newFakeMyraSecClient())
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "apex", input: "example.com", want: "example.com"},
+		{name: "apex with trailing dot", input: "example.com.", want: "example.com"},
+		{name: "subdomain", input: "app.example.com", want: "app.example.com"},
+		{name: "nested subdomain with trailing dot", input: "a.b.example.com.", want: "a.b.example.com"},
+		{name: "relative name", input: "app", want: "app.example.com"},
+		{name: "lookalike domain", input: "notexample.com", want: "notexample.com.example.com"},
+		{name: "lookalike with prefix", input: "myexample.com", want: "myexample.com.example.com"},
+		{name: "already qualified lookalike", input: "myexample.com.example.com", want: "myexample.com.example.com"},
+		{name: "absolute lookalike", input: "badexample.com.", wantErr: true},
+		{name: "absolute other zone", input: "app.example.org.", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.ensureFullDNSName(tt.input, "example.com")
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrNameOutsideZone)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestApexRecords(t *testing.T) {
+	ownership := "heritage=external-dns,external-dns/owner=test-owner"
+
+	t.Run("create apex A and round-trip", func(t *testing.T) {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		p := newTestProvider(client)
+
+		err := p.ApplyChanges(context.Background(), &plan.Changes{
+			Create: []*endpoint.Endpoint{endpoint.NewEndpoint("example.com", endpoint.RecordTypeA, "1.2.3.4")},
+		})
+		require.NoError(t, err)
+
+		var names []string
+		for _, r := range client.records[123] {
+			names = append(names, r.Name+" "+r.RecordType)
+		}
+		assert.ElementsMatch(t, []string{"example.com A", "example.com TXT"}, names)
+
+		endpoints, err := p.Records(context.Background())
+		require.NoError(t, err)
+		ep := findEndpoint(endpoints, "example.com", endpoint.RecordTypeA)
+		require.NotNil(t, ep)
+		assert.Equal(t, "example.com", ep.DNSName)
+		assert.Equal(t, endpoint.Targets{"1.2.3.4"}, ep.Targets)
+	})
+
+	t.Run("short apex form is read and kept", func(t *testing.T) {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		client.records[123] = []myrasec.DNSRecord{
+			{ID: 1, Name: "@", RecordType: endpoint.RecordTypeA, Value: "1.2.3.4", TTL: 300, Active: true, Enabled: true},
+			{ID: 2, Name: "@", RecordType: endpoint.RecordTypeTXT, Value: ownership, TTL: 300, Enabled: true},
+		}
+		p := newTestProvider(client)
+
+		endpoints, err := p.Records(context.Background())
+		require.NoError(t, err)
+		require.NotNil(t, findEndpoint(endpoints, "example.com", endpoint.RecordTypeA))
+		txt := findEndpoint(endpoints, "example.com", endpoint.RecordTypeTXT)
+		require.NotNil(t, txt)
+		assert.Equal(t, endpoint.Targets{ownership}, txt.Targets)
+
+		// A TTL change must update the apex record in place, without renaming it
+		old := endpoint.NewEndpointWithTTL("example.com", endpoint.RecordTypeA, 300, "1.2.3.4")
+		desired := endpoint.NewEndpointWithTTL("example.com", endpoint.RecordTypeA, 600, "1.2.3.4")
+		err = p.ApplyChanges(context.Background(), &plan.Changes{
+			UpdateOld: []*endpoint.Endpoint{old},
+			UpdateNew: []*endpoint.Endpoint{desired},
+		})
+		require.NoError(t, err)
+		require.Len(t, client.records[123], 2)
+		assert.Equal(t, "@", client.records[123][0].Name)
+		assert.Equal(t, 600, client.records[123][0].TTL)
+
+		// Deleting the last apex record also removes the apex ownership record
+		err = p.ApplyChanges(context.Background(), &plan.Changes{
+			Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("example.com", endpoint.RecordTypeA, "1.2.3.4")},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, client.records[123])
+	})
+}
+
+func TestIsOwnedByExternalDNS(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		owner string
+		want  bool
+	}{
+		{name: "exact owner", value: "heritage=external-dns,external-dns/owner=k8s", owner: "k8s", want: true},
+		{name: "with resource", value: "heritage=external-dns,external-dns/owner=k8s,external-dns/resource=ingress/default/app", owner: "k8s", want: true},
+		{name: "quoted value", value: `"heritage=external-dns,external-dns/owner=k8s,external-dns/resource=ingress/default/app"`, owner: "k8s", want: true},
+		{name: "longer owner with same prefix", value: "heritage=external-dns,external-dns/owner=k8s-prod", owner: "k8s", want: false},
+		{name: "quoted longer owner", value: `"heritage=external-dns,external-dns/owner=k8s-staging"`, owner: "k8s", want: false},
+		{name: "shorter owner", value: "heritage=external-dns,external-dns/owner=k8s", owner: "k8s-prod", want: false},
+		{name: "missing heritage", value: "external-dns/owner=k8s", owner: "k8s", want: false},
+		{name: "foreign heritage", value: "heritage=external-dns-legacy,external-dns/owner=k8s", owner: "k8s", want: false},
+		{name: "unrelated TXT", value: "v=spf1 include:example.com ~all", owner: "k8s", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isOwnedByExternalDNS(tt.value, tt.owner))
+		})
+	}
+}
+
+func TestExtractResourceFromTXT(t *testing.T) {
+	assert.Equal(t, "ingress/default/app", extractResourceFromTXT(`"heritage=external-dns,external-dns/owner=k8s,external-dns/resource=ingress/default/app"`))
+	assert.Empty(t, extractResourceFromTXT("heritage=external-dns,external-dns/owner=k8s"))
+}
+
+// TestOwnershipValueUpstreamFormat tests that ownership TXT values are written exactly like the
+// TXT registry of ExternalDNS writes them and that values of both forms are read back
+func TestOwnershipValueUpstreamFormat(t *testing.T) {
+	// Values as found in a zone managed by the TXT registry of ExternalDNS
+	upstream := []struct {
+		value    string
+		owner    string
+		resource string
+	}{
+		{value: `"heritage=external-dns,external-dns/owner=default"`, owner: "default"},
+		{value: `"heritage=external-dns,external-dns/owner=k8s-prod,external-dns/resource=ingress/default/app"`, owner: "k8s-prod", resource: "ingress/default/app"},
+		{value: `"heritage=external-dns,external-dns/owner=test-owner,external-dns/resource=service/kube-system/dns"`, owner: "test-owner", resource: "service/kube-system/dns"},
+	}
+
+	for _, tt := range upstream {
+		t.Run(tt.value, func(t *testing.T) {
+			p := newTestProvider(newFakeMyraSecClient())
+			p.owner = tt.owner
+			ep := endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.4")
+			if tt.resource != "" {
+				ep.Labels[endpoint.ResourceLabelKey] = tt.resource
+			}
+			assert.Equal(t, tt.value, p.ownershipValue(ep), "written byte for byte like upstream")
+
+			labels, err := endpoint.NewLabelsFromStringPlain(p.ownershipValue(ep))
+			require.NoError(t, err, "upstream reads the value")
+			assert.Equal(t, tt.owner, labels[endpoint.OwnerLabelKey])
+
+			for _, stored := range []string{tt.value, strings.Trim(tt.value, `"`)} {
+				assert.True(t, isOwnedByExternalDNS(stored, tt.owner), stored)
+				assert.Equal(t, tt.resource, extractResourceFromTXT(stored), stored)
+			}
+		})
+	}
+
+	t.Run("unquoted value of an earlier version", func(t *testing.T) {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		client.records[123] = []myrasec.DNSRecord{
+			{ID: 1, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: "heritage=external-dns,external-dns/owner=test-owner", TTL: 300},
+		}
+		p := newTestProvider(client)
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+			Create: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+		}))
+		assert.Len(t, p.findMatchingRecords(client.records[123], "example.com", "app.example.com", endpoint.RecordTypeTXT), 1,
+			"the ownership record is not created again in the quoted form")
+	})
+
+	// Fields in another order and values stored as several strings are read as well
+	assert.True(t, isOwnedByExternalDNS(`"external-dns/resource=ingress/default/app,heritage=external-dns,external-dns/owner=k8s"`, "k8s"))
+	assert.True(t, isOwnedByExternalDNS(`"heritage=external-dns," "external-dns/owner=k8s"`, "k8s"))
+}
+
+func TestTXTValueRoundTrip(t *testing.T) {
+	dkim := "v=DKIM1; k=rsa; p=MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAvQ8yR+9W3xQ2mX4hZcVfLs3y7bq1aW0XyTn2h5cJt+ZQ"
+	spf := "v=spf1 ip4:192.0.2.0/24 include:_spf.google.com ~all"
+	dmarc := "v=DMARC1; p=reject; rua=mailto:dmarc@example.com; pct=100"
+	ownership := "heritage=external-dns,external-dns/owner=test-owner"
+
+	t.Run("created values are stored exactly", func(t *testing.T) {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		p := newTestProvider(client)
+
+		err := p.ApplyChanges(context.Background(), &plan.Changes{
+			Create: []*endpoint.Endpoint{
+				endpoint.NewEndpoint("selector._domainkey.example.com", endpoint.RecordTypeTXT, `"`+dkim+`"`),
+				endpoint.NewEndpoint("example.com", endpoint.RecordTypeTXT, spf),
+				endpoint.NewEndpoint("_dmarc.example.com", endpoint.RecordTypeTXT, dmarc),
+			},
+		})
+		require.NoError(t, err)
+
+		stored := map[string]string{}
+		for _, r := range dataRecords(client.records[123]) {
+			stored[r.Name] = r.Value
+		}
+		assert.Equal(t, map[string]string{
+			"selector._domainkey.example.com": dkim,
+			"example.com":                     spf,
+			"_dmarc.example.com":              dmarc,
+		}, stored)
+	})
+
+	t.Run("created values are listed unchanged", func(t *testing.T) {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		p := newTestProvider(client)
+		created := map[string]string{
+			"selector._domainkey.example.com": dkim,
+			"example.com":                     spf,
+			"_dmarc.example.com":              dmarc,
+		}
+
+		var create []*endpoint.Endpoint
+		for name, value := range created {
+			create = append(create, endpoint.NewEndpoint(name, endpoint.RecordTypeTXT, value))
+		}
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{Create: create}))
+
+		records, err := p.Records(context.Background())
+		require.NoError(t, err)
+		listed := map[string]string{}
+		for _, ep := range records {
+			require.Len(t, ep.Targets, 1)
+			if isOwnershipTXT(ep.Targets[0]) {
+				continue
+			}
+			assert.Equal(t, "test-owner", ep.Labels[endpoint.OwnerLabelKey], ep.DNSName)
+			listed[ep.DNSName] = ep.Targets[0]
+		}
+		assert.Equal(t, created, listed)
+
+		// The listed endpoints are deleted with their ownership records
+		var deleted []*endpoint.Endpoint
+		for _, ep := range records {
+			if !isOwnershipTXT(ep.Targets[0]) {
+				deleted = append(deleted, ep)
+			}
+		}
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{Delete: deleted}))
+		assert.Empty(t, client.records[123])
+	})
+
+	t.Run("quoted ownership record is reported without quotes", func(t *testing.T) {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		client.records[123] = []myrasec.DNSRecord{
+			{ID: 1, Name: "example.com", RecordType: endpoint.RecordTypeTXT, Value: `"` + ownership + `"`, TTL: 300, Enabled: true},
+		}
+		p := newTestProvider(client)
+
+		records, err := p.Records(context.Background())
+		require.NoError(t, err)
+		ep := findEndpoint(records, "example.com", endpoint.RecordTypeTXT)
+		require.NotNil(t, ep)
+		assert.Equal(t, endpoint.Targets{ownership}, ep.Targets)
+	})
+
+	t.Run("quoted stored values match on update", func(t *testing.T) {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		client.records[123] = []myrasec.DNSRecord{
+			{ID: 1, Name: "example.com", RecordType: endpoint.RecordTypeTXT, Value: `"` + spf + `"`, TTL: 300, Enabled: true},
+			{ID: 2, Name: "example.com", RecordType: endpoint.RecordTypeTXT, Value: `"` + ownership + `"`, TTL: 300, Enabled: true},
+		}
+		p := newTestProvider(client)
+
+		err := p.ApplyChanges(context.Background(), &plan.Changes{
+			UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("example.com", endpoint.RecordTypeTXT, spf)},
+			UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpointWithTTL("example.com", endpoint.RecordTypeTXT, 600, spf)},
+		})
+		require.NoError(t, err)
+
+		// The record is updated in place, not replaced by an unquoted copy, and the ownership
+		// record is kept as it is
+		require.Len(t, client.records[123], 2)
+		for i, want := range []struct {
+			value string
+			ttl   int
+		}{{`"` + spf + `"`, 600}, {`"` + ownership + `"`, 300}} {
+			assert.Equal(t, i+1, client.records[123][i].ID)
+			assert.Equal(t, want.value, client.records[123][i].Value)
+			assert.Equal(t, want.ttl, client.records[123][i].TTL)
+		}
+	})
+
+	t.Run("quoted stored value matches on delete", func(t *testing.T) {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		client.records[123] = []myrasec.DNSRecord{
+			{ID: 1, Name: "example.com", RecordType: endpoint.RecordTypeTXT, Value: `"` + spf + `"`, TTL: 300, Enabled: true},
+			{ID: 2, Name: "example.com", RecordType: endpoint.RecordTypeTXT, Value: `"` + ownership + `"`, TTL: 300, Enabled: true},
+		}
+		p := newTestProvider(client)
+
+		err := p.ApplyChanges(context.Background(), &plan.Changes{
+			Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("example.com", endpoint.RecordTypeTXT, spf)},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, client.records[123], "the ownership record goes with the last TXT record of the name")
+	})
+}
+
+// TestStoredValueSpellings tests that deletions and updates match stored values spelled another
+// way than the desired targets
+func TestStoredValueSpellings(t *testing.T) {
+	ownership := "heritage=external-dns,external-dns/owner=test-owner"
+	tests := []struct {
+		name       string
+		recordType string
+		stored     string
+		target     string
+	}{
+		{name: "quoted TXT", recordType: endpoint.RecordTypeTXT, stored: `"v=spf1 -all"`, target: "v=spf1 -all"},
+		{name: "CNAME with trailing dot and upper case", recordType: endpoint.RecordTypeCNAME, stored: "Target.Example.org.", target: "target.example.org"},
+		{name: "AAAA in long notation", recordType: endpoint.RecordTypeAAAA, stored: "2001:DB8:0:0:0:0:0:1", target: "2001:db8::1"},
+		{name: "MX with trailing dot", recordType: endpoint.RecordTypeMX, stored: "10 MX.example.com.", target: "10 mx.example.com"},
+	}
+
+	for _, tt := range tests {
+		zone := func() (*fakeMyraSecClient, *MyraSecDNSProvider) {
+			client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+			client.records[123] = []myrasec.DNSRecord{
+				{ID: 1, Name: "app.example.com", RecordType: tt.recordType, Value: tt.stored, TTL: 300, Enabled: true},
+				{ID: 2, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: ownership, TTL: 300, Enabled: true},
+			}
+			return client, newTestProvider(client)
+		}
+
+		t.Run(tt.name+" delete", func(t *testing.T) {
+			client, p := zone()
+			require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+				Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", tt.recordType, tt.target)},
+			}))
+			for _, r := range client.records[123] {
+				assert.NotEqual(t, 1, r.ID, "the stored record must be deleted")
+			}
+		})
+
+		t.Run(tt.name+" update", func(t *testing.T) {
+			client, p := zone()
+			targets := []string{tt.target}
+			if tt.recordType == endpoint.RecordTypeTXT {
+				// The ownership record shares the name and type, it is one of the targets
+				targets = append(targets, ownership)
+			}
+			require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+				UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", tt.recordType, targets...)},
+				UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpointWithTTL("app.example.com", tt.recordType, 600, targets...)},
+			}))
+			records := p.findMatchingRecords(client.records[123], "example.com", "app.example.com", tt.recordType)
+			require.Len(t, records, len(targets), "the stored record is updated in place, not replaced")
+			assert.Equal(t, 1, records[0].ID)
+			assert.Equal(t, tt.stored, records[0].Value)
+			assert.Equal(t, 600, records[0].TTL)
+		})
+	}
+}
+
+// TestMixedCaseRecords tests that records stored with names in another case are matched like
+// lower case names and keep their stored name
+func TestMixedCaseRecords(t *testing.T) {
+	newZone := func() (*fakeMyraSecClient, *MyraSecDNSProvider) {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		client.records[123] = []myrasec.DNSRecord{
+			{ID: 1, Name: "App.Example.com", RecordType: endpoint.RecordTypeA, Value: "1.2.3.4", TTL: 300},
+			{ID: 2, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: "heritage=external-dns,external-dns/owner=test-owner", TTL: 300},
+		}
+		return client, newTestProvider(client)
+	}
+	names := func(client *fakeMyraSecClient) []string {
+		var names []string
+		for _, r := range client.records[123] {
+			names = append(names, r.Name+" "+r.RecordType)
+		}
+		return names
+	}
+
+	t.Run("records", func(t *testing.T) {
+		_, p := newZone()
+		endpoints, err := p.Records(context.Background())
+		require.NoError(t, err)
+		require.NotNil(t, findEndpoint(endpoints, "App.Example.com", endpoint.RecordTypeA))
+	})
+
+	t.Run("create", func(t *testing.T) {
+		client, p := newZone()
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+			Create: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+		}))
+		assert.ElementsMatch(t, []string{"App.Example.com A", "app.example.com TXT"}, names(client))
+	})
+
+	t.Run("update", func(t *testing.T) {
+		client, p := newZone()
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+			UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+			UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpointWithTTL("app.example.com", endpoint.RecordTypeA, 600, "1.2.3.4")},
+		}))
+		assert.ElementsMatch(t, []string{"App.Example.com A", "app.example.com TXT"}, names(client))
+		assert.Equal(t, 600, client.records[123][0].TTL)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		client, p := newZone()
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+			Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("APP.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+		}))
+		assert.Empty(t, names(client))
+	})
+
+	t.Run("owned by another instance", func(t *testing.T) {
+		client, p := newZone()
+		client.records[123][1].Value = "heritage=external-dns,external-dns/owner=other"
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+			Create: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.5")},
+		}))
+		assert.ElementsMatch(t, []string{"App.Example.com A", "app.example.com TXT"}, names(client))
+	})
+}
+
+// TestCanonicalEndpointNames tests that desired endpoints in another case or with surrounding
+// whitespace act on the existing records instead of creating duplicates
+func TestCanonicalEndpointNames(t *testing.T) {
+	ownership := "heritage=external-dns,external-dns/owner=test-owner"
+	newZone := func() (*fakeMyraSecClient, *MyraSecDNSProvider) {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		client.records[123] = []myrasec.DNSRecord{
+			{ID: 1, Name: "app.example.com", RecordType: endpoint.RecordTypeA, Value: "1.2.3.4", TTL: 300},
+			{ID: 2, Name: "APP.example.com", RecordType: endpoint.RecordTypeTXT, Value: ownership, TTL: 300},
+		}
+		return client, newTestProvider(client)
+	}
+	names := func(client *fakeMyraSecClient) []string {
+		var names []string
+		for _, r := range client.records[123] {
+			names = append(names, r.Name+" "+r.RecordType+" "+r.Value)
+		}
+		return names
+	}
+
+	t.Run("adjust", func(t *testing.T) {
+		_, p := newZone()
+		adjusted, err := p.AdjustEndpoints([]*endpoint.Endpoint{endpoint.NewEndpoint(" App.Example.COM. ", endpoint.RecordTypeA, "1.2.3.4")})
+		require.NoError(t, err)
+		require.Len(t, adjusted, 1)
+		assert.Equal(t, "app.example.com", adjusted[0].DNSName)
+	})
+
+	t.Run("create", func(t *testing.T) {
+		client, p := newZone()
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+			Create: []*endpoint.Endpoint{endpoint.NewEndpoint(" WWW.Example.com ", endpoint.RecordTypeA, "5.6.7.8")},
+		}))
+		assert.ElementsMatch(t, []string{
+			"app.example.com A 1.2.3.4", "APP.example.com TXT " + ownership,
+			"www.example.com A 5.6.7.8", "www.example.com TXT " + strconv.Quote(ownership),
+		}, names(client))
+	})
+
+	t.Run("update", func(t *testing.T) {
+		client, p := newZone()
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+			UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("App.Example.com", endpoint.RecordTypeA, "1.2.3.4")},
+			UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint(" APP.EXAMPLE.COM", endpoint.RecordTypeA, "5.6.7.8")},
+		}))
+		assert.ElementsMatch(t, []string{"app.example.com A 5.6.7.8", "APP.example.com TXT " + ownership}, names(client))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		client, p := newZone()
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+			Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("App.Example.com ", endpoint.RecordTypeA, "1.2.3.4")},
+		}))
+		assert.Empty(t, names(client))
+	})
+}
+
+func TestFindMatchingRecordsIgnoresCase(t *testing.T) {
+	p := newTestProvider(newFakeMyraSecClient())
+	records := []myrasec.DNSRecord{
+		{ID: 1, Name: "WWW.Example.COM", RecordType: endpoint.RecordTypeCNAME, Value: "app.example.com"},
+		{ID: 2, Name: "www.example.com", RecordType: endpoint.RecordTypeTXT, Value: "x"},
+		{ID: 3, Name: "www2.example.com", RecordType: endpoint.RecordTypeCNAME, Value: "app.example.com"},
+	}
+
+	matching := p.findMatchingRecords(records, "example.com", "www.example.com.", "cname")
+	require.Len(t, matching, 1)
+	assert.Equal(t, 1, matching[0].ID)
+}
+
+func TestRecordGauges(t *testing.T) {
+	ownership := "heritage=external-dns,external-dns/owner=test-owner"
+	client := newFakeMyraSecClient(myrasec.Domain{ID: 1, Name: "example.com"}, myrasec.Domain{ID: 2, Name: "dev.example.com"})
+	client.records[1] = []myrasec.DNSRecord{
+		{ID: 10, Name: "app.example.com", RecordType: endpoint.RecordTypeA, Value: "1.1.1.1"},
+		{ID: 11, Name: "app.example.com", RecordType: endpoint.RecordTypeA, Value: "1.1.1.2"},
+		{ID: 12, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: ownership},
+		{ID: 13, Name: "www.example.com", RecordType: endpoint.RecordTypeCNAME, Value: "app.example.com"},
+		{ID: 14, Name: "other.example.com", RecordType: endpoint.RecordTypeA, Value: "2.2.2.2"},
+		{ID: 15, Name: "other.example.com", RecordType: endpoint.RecordTypeTXT, Value: "heritage=external-dns,external-dns/owner=other"},
+		// Shadowed by the delegated domain, counted there
+		{ID: 16, Name: "api.dev.example.com", RecordType: endpoint.RecordTypeA, Value: "9.9.9.9"},
+	}
+	client.records[2] = []myrasec.DNSRecord{
+		{ID: 20, Name: "api.dev.example.com", RecordType: endpoint.RecordTypeAAAA, Value: "2001:db8::1"},
+		{ID: 21, Name: "api.dev.example.com", RecordType: endpoint.RecordTypeTXT, Value: ownership},
+		{ID: 22, Name: "@", RecordType: endpoint.RecordTypeNS, Value: "ns1.myrasecurity.com"},
+	}
+	p := newTestProvider(client)
+
+	_, err := p.Records(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(managedRecords.WithLabelValues("example.com", endpoint.RecordTypeA)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(managedRecords.WithLabelValues("example.com", endpoint.RecordTypeTXT)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(foreignRecords.WithLabelValues("example.com", endpoint.RecordTypeA)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(foreignRecords.WithLabelValues("example.com", endpoint.RecordTypeTXT)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(foreignRecords.WithLabelValues("example.com", endpoint.RecordTypeCNAME)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(managedRecords.WithLabelValues("dev.example.com", endpoint.RecordTypeAAAA)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(managedRecords.WithLabelValues("dev.example.com", endpoint.RecordTypeTXT)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(foreignRecords.WithLabelValues("dev.example.com", endpoint.RecordTypeNS)))
+
+	// Record types gone from the domain are dropped from the gauges
+	client.records[1] = client.records[1][:3]
+	_, err = p.Records(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, testutil.ToFloat64(managedRecords.WithLabelValues("example.com", endpoint.RecordTypeA)))
+	for _, recordType := range []string{endpoint.RecordTypeA, endpoint.RecordTypeTXT, endpoint.RecordTypeCNAME} {
+		assert.False(t, foreignRecords.DeleteLabelValues("example.com", recordType), recordType)
+	}
+	assert.Equal(t, 1.0, testutil.ToFloat64(foreignRecords.WithLabelValues("dev.example.com", endpoint.RecordTypeNS)))
+}
@@ -0,0 +1,69 @@
+package myrasecprovider
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// maxSkippedNames is the number of names logged with the summary of a skip reason.
+const maxSkippedNames = 5
+
+// skipReport counts the changes skipped for the same reason during a single ApplyChanges call, so
+// that a reason hit by many records is logged once per sync instead of once per record.
+// It is shared by all workers of the call.
+type skipReport struct {
+	mu     sync.Mutex
+	counts map[string]int
+	names  map[string][]string
+}
+
+type skipReportKey struct{}
+
+// withSkipReport returns a context carrying the given report.
+func withSkipReport(ctx context.Context, report *skipReport) context.Context {
+	return context.WithValue(ctx, skipReportKey{}, report)
+}
+
+// skipChange logs a change skipped for reason at debug level and counts it in the report of the
+// context. Without a report the change is logged as a warning.
+func (p *MyraSecDNSProvider) skipChange(ctx context.Context, reason, dnsName string, fields ...zap.Field) {
+	fields = append([]zap.Field{zap.String("dnsName", dnsName)}, fields...)
+	report, _ := ctx.Value(skipReportKey{}).(*skipReport)
+	if report == nil {
+		p.logger.Warn(reason, fields...)
+		return
+	}
+	p.logger.Debug(reason, fields...)
+
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	if report.counts == nil {
+		report.counts = map[string]int{}
+		report.names = map[string][]string{}
+	}
+	report.counts[reason]++
+	if len(report.names[reason]) < maxSkippedNames {
+		report.names[reason] = append(report.names[reason], dnsName)
+	}
+}
+
+// logSkipSummary logs every reason of the report once as a warning, with the number of skipped
+// changes and the first names. The single changes are logged at debug level.
+func (p *MyraSecDNSProvider) logSkipSummary(report *skipReport) {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	reasons := make([]string, 0, len(report.counts))
+	for reason := range report.counts {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		p.logger.Warn(reason,
+			zap.Int("count", report.counts[reason]),
+			zap.Strings("dnsNames", report.names[reason]))
+	}
+}
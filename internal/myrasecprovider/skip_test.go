@@ -0,0 +1,83 @@
+package myrasecprovider
+
+import (
+	"context"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestSkippedChangesAreSummarized(t *testing.T) {
+	// Records of another owner, none of them may be touched
+	client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+	for i, name := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		client.records[123] = append(client.records[123], myrasec.DNSRecord{ID: i + 1, Name: name, RecordType: "A", Value: "1.2.3.4", TTL: 300})
+	}
+
+	core, logs := observer.New(zap.DebugLevel)
+	p := newTestProvider(client)
+	p.logger = zap.New(core)
+	p.environment = "production"
+
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("internal1.example.com", endpoint.RecordTypeA, "10.0.0.1"),
+			endpoint.NewEndpoint("internal2.example.com", endpoint.RecordTypeA, "192.168.0.1"),
+		},
+		UpdateOld: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "5.6.7.8"),
+			endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "5.6.7.8"),
+		},
+		Delete: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+	}))
+	assert.Len(t, client.records[123], 3)
+
+	summary := func(reason string) map[string]interface{} {
+		entries := logs.FilterMessage(reason).FilterLevelExact(zapcore.WarnLevel).All()
+		require.Len(t, entries, 1, reason)
+		return entries[0].ContextMap()
+	}
+	assert.Equal(t, int64(2), summary("Skipping creation of private IP record in production")["count"])
+	assert.Equal(t, int64(2), summary("Skipping update: not owned by this instance")["count"])
+	assert.Equal(t, int64(1), summary("Skipping delete: not owned by this instance")["count"])
+	assert.ElementsMatch(t, []interface{}{"a.example.com", "b.example.com"}, summary("Skipping update: not owned by this instance")["dnsNames"])
+
+	// Every skipped change is still logged on its own at debug level
+	assert.Equal(t, 2, logs.FilterMessage("Skipping update: not owned by this instance").FilterLevelExact(zapcore.DebugLevel).Len())
+}
+
+func TestSkipSummaryLimitsNames(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	p := newTestProvider(newFakeMyraSecClient())
+	p.logger = zap.New(core)
+
+	report := &skipReport{}
+	ctx := withSkipReport(context.Background(), report)
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		p.skipChange(ctx, "Skipping", name+".example.com")
+	}
+	p.logSkipSummary(report)
+
+	entries := logs.FilterLevelExact(zapcore.WarnLevel).All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, int64(7), entries[0].ContextMap()["count"])
+	assert.Len(t, entries[0].ContextMap()["dnsNames"], maxSkippedNames)
+
+	// Without a report every skipped change is a warning
+	logs.TakeAll()
+	p.skipChange(context.Background(), "Skipping", "a.example.com")
+	assert.Equal(t, 1, logs.FilterLevelExact(zapcore.WarnLevel).Len())
+}
@@ -0,0 +1,131 @@
+package myrasecprovider
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// zoneSnapshot holds the records of a zone. It is listed once per ApplyChanges and shared by the
+// workers changing the zone; the mutation helpers keep it in sync with the changes they make (also
+// in dry-run mode), so a plan with N update/delete tasks costs one ListDNSRecords call instead of N.
+type zoneSnapshot struct {
+	zone    myrasec.Domain
+	mu      sync.RWMutex
+	records []myrasec.DNSRecord
+}
+
+func newZoneSnapshot(zone myrasec.Domain, records []myrasec.DNSRecord) *zoneSnapshot {
+	return &zoneSnapshot{zone: zone, records: append([]myrasec.DNSRecord(nil), records...)}
+}
+
+// zoneName returns the name of the domain the records belong to.
+func (s *zoneSnapshot) zoneName() string {
+	return s.zone.Name
+}
+
+// domainID returns the ID of the domain the records belong to, or ErrInvalidDomainID if it has none.
+func (s *zoneSnapshot) domainID() (int, error) {
+	if s.zone.ID <= 0 {
+		return 0, fmt.Errorf("%w %d of domain %q", ErrInvalidDomainID, s.zone.ID, s.zone.Name)
+	}
+	return s.zone.ID, nil
+}
+
+// all returns a copy of the records in the snapshot.
+func (s *zoneSnapshot) all() []myrasec.DNSRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]myrasec.DNSRecord(nil), s.records...)
+}
+
+// owners returns the owners named by the ownership TXT records at the name, each once.
+func (s *zoneSnapshot) owners(name string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return nameOwners(s.records, s.zone.Name)[nameKey(name, s.zone.Name)]
+}
+
+// ownedBy reports whether the ownership TXT records at the name include one of owner. Records of
+// other owners at the same name, e.g. an unrelated TXT record added by hand, do not matter.
+func (s *zoneSnapshot) ownedBy(name, owner string) bool {
+	return slices.Contains(s.owners(name), owner)
+}
+
+// nameOwners indexes the owners named by the ownership TXT records among records by the lower case
+// fully qualified record name, see nameKey. Each owner is listed once per name.
+func nameOwners(records []myrasec.DNSRecord, zone string) map[string][]string {
+	owners := make(map[string][]string)
+	for _, r := range records {
+		if r.RecordType != endpoint.RecordTypeTXT {
+			continue
+		}
+		fields := parseOwnershipTXT(r.Value)
+		if fields["heritage"] != "external-dns" {
+			continue
+		}
+		key := nameKey(r.Name, zone)
+		if !slices.Contains(owners[key], fields["external-dns/owner"]) {
+			owners[key] = append(owners[key], fields["external-dns/owner"])
+		}
+	}
+	return owners
+}
+
+// contains reports whether the zone has a record with the name, type and value of record.
+func (s *zoneSnapshot) contains(record *myrasec.DNSRecord) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, r := range s.records {
+		if strings.EqualFold(r.RecordType, record.RecordType) && sameValue(&r, record) &&
+			nameKey(r.Name, s.zone.Name) == nameKey(record.Name, s.zone.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameValue reports whether two records of the same type have the same value. TXT values match
+// with and without the quotes around them, so an ownership record written by an earlier version
+// of the webhook is not created again in the quoted form.
+func sameValue(a, b *myrasec.DNSRecord) bool {
+	if strings.EqualFold(a.RecordType, endpoint.RecordTypeTXT) {
+		return formatTXTValue(a.Value) == formatTXTValue(b.Value)
+	}
+	return a.Value == b.Value
+}
+
+// add records a newly created record.
+func (s *zoneSnapshot) add(record myrasec.DNSRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+}
+
+// replace records an updated record.
+func (s *zoneSnapshot) replace(record myrasec.DNSRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, r := range s.records {
+		if r.ID == record.ID {
+			s.records[i] = record
+			return
+		}
+	}
+}
+
+// remove records a deleted record.
+func (s *zoneSnapshot) remove(record myrasec.DNSRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, r := range s.records {
+		if r.ID == record.ID {
+			s.records = append(s.records[:i], s.records[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package myrasecprovider
+
+import (
+	"time"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+
+	"github.com/netguru/myra-external-dns-webhook/pkg/status"
+)
+
+// State is a point-in-time view of the provider for troubleshooting, see MyraSecDNSProvider.State
+type State struct {
+	// Domains are the cached domains of the account, or the pinned domain
+	Domains []StateDomain `json:"domains"`
+	// Zones are the domains the last sync managed
+	Zones []StateDomain `json:"zones"`
+	// DomainCacheAgeSeconds is how long ago the domains were listed, -1 while they were not
+	DomainCacheAgeSeconds float64 `json:"domainCacheAgeSeconds"`
+	// Records is the number of records of the last successful Records call. Records are not
+	// cached, every sync lists them.
+	Records int `json:"records"`
+	// RecordsAgeSeconds is how long ago Records last succeeded, -1 while it did not
+	RecordsAgeSeconds float64 `json:"recordsAgeSeconds"`
+	// ApplyInProgress tells whether ApplyChanges is running, since ApplyStartedAt
+	ApplyInProgress bool       `json:"applyInProgress"`
+	ApplyStartedAt  *time.Time `json:"applyStartedAt,omitempty"`
+	// Draining tells whether Drain was called and no further applies start
+	Draining bool        `json:"draining"`
+	Sync     status.Sync `json:"sync"`
+}
+
+// StateDomain is a domain in the State
+type StateDomain struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// State returns the current State of the provider
+func (p *MyraSecDNSProvider) State() State {
+	now := time.Now()
+	state := State{
+		Zones:                 stateDomains(p.knownZones()),
+		DomainCacheAgeSeconds: -1,
+		Records:               int(p.listedRecords.Load()),
+		RecordsAgeSeconds:     -1,
+		Sync:                  p.SyncStatus(),
+	}
+
+	if p.pinnedDomain != nil {
+		state.Domains = stateDomains([]myrasec.Domain{*p.pinnedDomain})
+	} else {
+		p.domainsMu.Lock()
+		state.Domains = stateDomains(p.cachedDomains)
+		if !p.domainsFetchedAt.IsZero() {
+			state.DomainCacheAgeSeconds = now.Sub(p.domainsFetchedAt).Seconds()
+		}
+		p.domainsMu.Unlock()
+	}
+
+	if state.Sync.LastRecords != nil {
+		state.RecordsAgeSeconds = now.Sub(*state.Sync.LastRecords).Seconds()
+	}
+	if started := p.applyStartedAt.Load(); started != nil {
+		state.ApplyInProgress = true
+		state.ApplyStartedAt = started
+	}
+
+	p.initApplyLock()
+	select {
+	case <-p.draining:
+		state.Draining = true
+	default:
+	}
+	return state
+}
+
+// stateDomains returns the IDs and names of domains
+func stateDomains(domains []myrasec.Domain) []StateDomain {
+	result := make([]StateDomain, 0, len(domains))
+	for _, domain := range domains {
+		result = append(result, StateDomain{ID: domain.ID, Name: domain.Name})
+	}
+	return result
+}
@@ -0,0 +1,47 @@
+package myrasecprovider
+
+import (
+	"context"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestState(t *testing.T) {
+	client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+	client.records[123] = []myrasec.DNSRecord{
+		{ID: 1, Name: "app.example.com", RecordType: "A", Value: "1.1.1.1", TTL: 300, Active: true, Enabled: true},
+	}
+	p := newTestProvider(client)
+
+	initial := p.State()
+	assert.Empty(t, initial.Domains)
+	assert.Equal(t, float64(-1), initial.DomainCacheAgeSeconds)
+	assert.Equal(t, float64(-1), initial.RecordsAgeSeconds)
+	assert.False(t, initial.ApplyInProgress)
+
+	_, err := p.Records(context.Background())
+	require.NoError(t, err)
+
+	listed := p.State()
+	assert.Equal(t, []StateDomain{{ID: 123, Name: "example.com"}}, listed.Domains)
+	assert.Equal(t, []StateDomain{{ID: 123, Name: "example.com"}}, listed.Zones)
+	assert.GreaterOrEqual(t, listed.DomainCacheAgeSeconds, float64(0))
+	assert.Equal(t, 1, listed.Records)
+	assert.GreaterOrEqual(t, listed.RecordsAgeSeconds, float64(0))
+
+	release, err := p.acquireApply(context.Background())
+	require.NoError(t, err)
+	applying := p.State()
+	assert.True(t, applying.ApplyInProgress)
+	assert.NotNil(t, applying.ApplyStartedAt)
+	release()
+	assert.False(t, p.State().ApplyInProgress)
+
+	require.NoError(t, p.Drain(context.Background()))
+	drained := p.State()
+	assert.True(t, drained.Draining)
+	assert.False(t, drained.ApplyInProgress)
+}
@@ -0,0 +1,103 @@
+package myrasecprovider
+
+import (
+	"sync"
+	"time"
+
+	"github.com/netguru/myra-external-dns-webhook/pkg/redact"
+	"github.com/netguru/myra-external-dns-webhook/pkg/status"
+)
+
+// syncState tracks the outcome of Records and ApplyChanges for the /status endpoint and the
+// sync metrics. An update changes the status and the metrics under one lock, so readers never
+// see half of it.
+type syncState struct {
+	mu     sync.RWMutex
+	status status.Sync
+}
+
+// recordsListed notes the end of a Records call
+func (s *syncState) recordsListed(err error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.LastRecordsAttempt = &now
+	if err != nil {
+		s.status.LastError = &status.Error{Operation: "records", Message: redact.Error(err), Time: now}
+		if s.status.RecordsFailingSince == nil {
+			s.status.RecordsFailingSince = &now
+		}
+		return
+	}
+	s.status.LastRecords = &now
+	s.status.RecordsFailingSince = nil
+	lastRecordsTimestamp.Set(float64(now.Unix()))
+}
+
+// changesApplied notes the end of an ApplyChanges call that started at start and changed the
+// counted records
+func (s *syncState) changesApplied(start time.Time, counts map[string]int, err error) {
+	now := time.Now()
+	duration := now.Sub(start)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.LastApply = &now
+	s.status.LastApplySucceeded = err == nil
+	s.status.LastApplyDurationSeconds = duration.Seconds()
+	s.status.LastApplyChanges = make(map[string]int, len(counts))
+	if s.status.TotalChanges == nil {
+		s.status.TotalChanges = make(map[string]int)
+	}
+	for _, action := range []string{CREATE, UPDATE, DELETE} {
+		s.status.LastApplyChanges[action] = counts[action]
+		s.status.TotalChanges[action] += counts[action]
+		lastApplyChanges.WithLabelValues(action).Set(float64(counts[action]))
+		appliedChanges.WithLabelValues(action).Add(float64(counts[action]))
+	}
+
+	lastApplyTimestamp.Set(float64(now.Unix()))
+	lastApplyDuration.Set(duration.Seconds())
+	if err != nil {
+		s.status.LastError = &status.Error{Operation: "applyChanges", Message: redact.Error(err), Time: now}
+		if s.status.ApplyFailingSince == nil {
+			s.status.ApplyFailingSince = &now
+		}
+		lastApplySuccess.Set(0)
+		return
+	}
+	s.status.LastSuccessfulApply = &now
+	s.status.ApplyFailingSince = nil
+	lastSuccessfulApplyTimestamp.Set(float64(now.Unix()))
+	lastApplySuccess.Set(1)
+}
+
+// snapshot returns a copy of the status that later updates do not change
+func (s *syncState) snapshot() status.Sync {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := s.status
+	snapshot.LastApplyChanges = copyCounts(s.status.LastApplyChanges)
+	snapshot.TotalChanges = copyCounts(s.status.TotalChanges)
+	if s.status.LastError != nil {
+		lastError := *s.status.LastError
+		snapshot.LastError = &lastError
+	}
+	return snapshot
+}
+
+// copyCounts copies the counts per action, with every action present
+func copyCounts(counts map[string]int) map[string]int {
+	result := map[string]int{CREATE: 0, UPDATE: 0, DELETE: 0}
+	for action, count := range counts {
+		result[action] = count
+	}
+	return result
+}
+
+// SyncStatus returns the outcome of the last Records and ApplyChanges calls, see status.Sync
+func (p *MyraSecDNSProvider) SyncStatus() status.Sync {
+	return p.syncState.snapshot()
+}
@@ -0,0 +1,108 @@
+package myrasecprovider
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestSyncStatus(t *testing.T) {
+	client := &rejectingClient{
+		fakeMyraSecClient: newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"}),
+		reject:            map[string]bool{"10.0.0.9": true},
+	}
+	p := newTestProvider(client)
+
+	initial := p.SyncStatus()
+	assert.Nil(t, initial.LastRecords)
+	assert.Nil(t, initial.LastApply)
+	assert.Nil(t, initial.LastError)
+	assert.Equal(t, map[string]int{CREATE: 0, UPDATE: 0, DELETE: 0}, initial.TotalChanges)
+
+	_, err := p.Records(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, p.SyncStatus().LastRecords)
+	assert.Equal(t, float64(p.SyncStatus().LastRecords.Unix()), testutil.ToFloat64(lastRecordsTimestamp))
+
+	totalCreated := testutil.ToFloat64(appliedChanges.WithLabelValues(CREATE))
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "10.0.0.1")},
+	}))
+
+	applied := p.SyncStatus()
+	require.NotNil(t, applied.LastApply)
+	assert.Equal(t, applied.LastApply, applied.LastSuccessfulApply)
+	assert.True(t, applied.LastApplySucceeded)
+	// The A record and its ownership TXT record
+	assert.Equal(t, map[string]int{CREATE: 2, UPDATE: 0, DELETE: 0}, applied.LastApplyChanges)
+	assert.Equal(t, 1.0, testutil.ToFloat64(lastApplySuccess))
+	assert.Equal(t, 2.0, testutil.ToFloat64(lastApplyChanges.WithLabelValues(CREATE)))
+	assert.Equal(t, totalCreated+2, testutil.ToFloat64(appliedChanges.WithLabelValues(CREATE)))
+
+	err = p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("bad.example.com", endpoint.RecordTypeA, "10.0.0.9")},
+	})
+	require.Error(t, err)
+
+	failed := p.SyncStatus()
+	assert.False(t, failed.LastApplySucceeded)
+	assert.Equal(t, applied.LastSuccessfulApply, failed.LastSuccessfulApply)
+	// The ownership TXT record was created before the A record was rejected
+	assert.Equal(t, map[string]int{CREATE: 1, UPDATE: 0, DELETE: 0}, failed.LastApplyChanges)
+	assert.Equal(t, map[string]int{CREATE: 3, UPDATE: 0, DELETE: 0}, failed.TotalChanges)
+	require.NotNil(t, failed.LastError)
+	assert.Equal(t, "applyChanges", failed.LastError.Operation)
+	assert.Contains(t, failed.LastError.Message, "10.0.0.9")
+	assert.Equal(t, 0.0, testutil.ToFloat64(lastApplySuccess))
+	require.NotNil(t, failed.ApplyFailingSince)
+	assert.Equal(t, failed.LastApply, failed.ApplyFailingSince)
+	assert.Nil(t, failed.RecordsFailingSince)
+
+	// A snapshot is not changed by later updates
+	failed.TotalChanges[CREATE] = 100
+	assert.Equal(t, 3, p.SyncStatus().TotalChanges[CREATE])
+
+	// A later failure keeps the time of the first one, a success clears it
+	_ = p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("bad.example.com", endpoint.RecordTypeA, "10.0.0.9")},
+	})
+	assert.Equal(t, failed.ApplyFailingSince, p.SyncStatus().ApplyFailingSince)
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("ok.example.com", endpoint.RecordTypeA, "10.0.0.2")},
+	}))
+	assert.Nil(t, p.SyncStatus().ApplyFailingSince)
+}
+
+func TestSyncStatusConcurrentReads(t *testing.T) {
+	p := newTestProvider(newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, _ = p.Records(context.Background())
+			_ = p.ApplyChanges(context.Background(), &plan.Changes{
+				Create: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "10.0.0.1")},
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				status := p.SyncStatus()
+				_ = status.TotalChanges[CREATE]
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.NotNil(t, p.SyncStatus().LastRecords)
+	assert.NotNil(t, p.SyncStatus().LastApply)
+}
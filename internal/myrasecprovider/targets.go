@@ -0,0 +1,60 @@
+package myrasecprovider
+
+import (
+	"net/netip"
+	"strings"
+
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// canonicalTarget returns a target in the form it is stored and reported: IPv6 addresses in their
+// canonical RFC 5952 form and hostnames as by hostnameTarget. An IPv6 address that does not parse
+// is returned unchanged, the validation refuses it.
+func canonicalTarget(value, recordType string) string {
+	if recordType != endpoint.RecordTypeAAAA {
+		return hostnameTarget(value, recordType)
+	}
+	if addr, err := netip.ParseAddr(strings.TrimSpace(value)); err == nil {
+		return addr.String()
+	}
+	return value
+}
+
+// hostnameTarget returns the value of a record whose target is a hostname in the form it is stored
+// and reported: the hostname lowercase and without the trailing dot, however ExternalDNS or MyraSec
+// spell it. The hostname is the whole value of CNAME and NS records and the last field of MX and
+// SRV records, after the preference or the priority, weight and port. Other values are returned
+// unchanged.
+func hostnameTarget(value, recordType string) string {
+	switch recordType {
+	case endpoint.RecordTypeCNAME, endpoint.RecordTypeNS:
+		return strings.ToLower(stripTrailingDot(strings.TrimSpace(value)))
+	case endpoint.RecordTypeMX, endpoint.RecordTypeSRV:
+		if fields := strings.Fields(value); len(fields) > 0 {
+			fields[len(fields)-1] = strings.ToLower(stripTrailingDot(fields[len(fields)-1]))
+			return strings.Join(fields, " ")
+		}
+	}
+	return value
+}
+
+// adjustTargets normalizes the targets of an endpoint like Records reports them, so that
+// "lb.example.net." and "lb.example.net", or two spellings of an IPv6 address, do not differ in
+// the plan.
+func (p *MyraSecDNSProvider) adjustTargets(ep *endpoint.Endpoint) {
+	targets := make(endpoint.Targets, len(ep.Targets))
+	changed := false
+	for i, target := range ep.Targets {
+		targets[i] = canonicalTarget(target, ep.RecordType)
+		changed = changed || targets[i] != target
+	}
+	if changed {
+		p.logger.Debug("Normalizing targets",
+			zap.String("dnsName", ep.DNSName),
+			zap.String("type", ep.RecordType),
+			zap.Strings("targets", ep.Targets),
+			zap.Strings("adjusted_targets", targets))
+		ep.Targets = targets
+	}
+}
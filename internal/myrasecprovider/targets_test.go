@@ -0,0 +1,148 @@
+package myrasecprovider
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestCanonicalTarget(t *testing.T) {
+	tests := []struct {
+		value      string
+		recordType string
+		want       string
+	}{
+		{value: "lb.example.net.", recordType: endpoint.RecordTypeCNAME, want: "lb.example.net"},
+		{value: " LB.Example.net ", recordType: endpoint.RecordTypeCNAME, want: "lb.example.net"},
+		{value: "ns1.example.net.", recordType: endpoint.RecordTypeNS, want: "ns1.example.net"},
+		{value: "10 Mail.example.net.", recordType: endpoint.RecordTypeMX, want: "10 mail.example.net"},
+		{value: "10 5 5060 sip.example.net.", recordType: endpoint.RecordTypeSRV, want: "10 5 5060 sip.example.net"},
+		{value: "1.2.3.4", recordType: endpoint.RecordTypeA, want: "1.2.3.4"},
+		{value: "2a01:4f8:0:1::2", recordType: endpoint.RecordTypeAAAA, want: "2a01:4f8:0:1::2"},
+		{value: "2a01:04f8:0000:0001:0000:0000:0000:0002", recordType: endpoint.RecordTypeAAAA, want: "2a01:4f8:0:1::2"},
+		{value: "2A01:4F8:0:1:0:0:0:2", recordType: endpoint.RecordTypeAAAA, want: "2a01:4f8:0:1::2"},
+		{value: "2001:db8:0:0:1:0:0:1", recordType: endpoint.RecordTypeAAAA, want: "2001:db8::1:0:0:1"},
+		{value: "not-an-address", recordType: endpoint.RecordTypeAAAA, want: "not-an-address"},
+		{value: "Text.", recordType: endpoint.RecordTypeTXT, want: "Text."},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, canonicalTarget(tt.value, tt.recordType), "%s %q", tt.recordType, tt.value)
+	}
+}
+
+// mutationCounter counts the records created, updated and deleted through the fake client
+type mutationCounter struct {
+	*fakeMyraSecClient
+	mu        sync.Mutex
+	mutations int
+}
+
+func (c *mutationCounter) count() {
+	c.mu.Lock()
+	c.mutations++
+	c.mu.Unlock()
+}
+
+func (c *mutationCounter) CreateDNSRecord(record *myrasec.DNSRecord, domainId int) (*myrasec.DNSRecord, error) {
+	c.count()
+	return c.fakeMyraSecClient.CreateDNSRecord(record, domainId)
+}
+
+func (c *mutationCounter) UpdateDNSRecord(record *myrasec.DNSRecord, domainId int) (*myrasec.DNSRecord, error) {
+	c.count()
+	return c.fakeMyraSecClient.UpdateDNSRecord(record, domainId)
+}
+
+func (c *mutationCounter) DeleteDNSRecord(record *myrasec.DNSRecord, domainId int) (*myrasec.DNSRecord, error) {
+	c.count()
+	return c.fakeMyraSecClient.DeleteDNSRecord(record, domainId)
+}
+
+// TestUnchangedHostnameTargetsMutateNothing tests that hostname targets differing from the stored
+// values only in the trailing dot or in case cause no API mutation in a full sync
+func TestUnchangedHostnameTargetsMutateNothing(t *testing.T) {
+	ownership := "heritage=external-dns,external-dns/owner=test-owner"
+	client := &mutationCounter{fakeMyraSecClient: newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})}
+	client.records[123] = []myrasec.DNSRecord{
+		// Stored with the trailing dot, desired without
+		{ID: 1, Name: "www.example.com", RecordType: endpoint.RecordTypeCNAME, Value: "lb.example.net.", TTL: 300, Active: true, Enabled: true},
+		{ID: 2, Name: "www.example.com", RecordType: endpoint.RecordTypeTXT, Value: ownership, TTL: 300, Enabled: true},
+		// Stored without the trailing dot, desired with
+		{ID: 3, Name: "shop.example.com", RecordType: endpoint.RecordTypeCNAME, Value: "shop.example.net", TTL: 300, Active: true, Enabled: true},
+		{ID: 4, Name: "shop.example.com", RecordType: endpoint.RecordTypeTXT, Value: ownership, TTL: 300, Enabled: true},
+		{ID: 5, Name: "example.com", RecordType: endpoint.RecordTypeMX, Value: "10 mail.example.net.", TTL: 300, Enabled: true},
+		{ID: 6, Name: "example.com", RecordType: endpoint.RecordTypeTXT, Value: ownership, TTL: 300, Enabled: true},
+	}
+	p := newTestProvider(client)
+
+	current, err := p.Records(context.Background())
+	require.NoError(t, err)
+	for _, ep := range current {
+		assert.Equal(t, canonicalTarget(ep.Targets[0], ep.RecordType), ep.Targets[0], "reported target of %s", ep.DNSName)
+	}
+
+	desired, err := p.AdjustEndpoints([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeCNAME, "lb.example.net"),
+		endpoint.NewEndpoint("shop.example.com", endpoint.RecordTypeCNAME, "Shop.example.net."),
+		endpoint.NewEndpoint("example.com", endpoint.RecordTypeMX, "10 mail.example.net"),
+	})
+	require.NoError(t, err)
+
+	changes := (&plan.Plan{
+		Current:        current,
+		Desired:        desired,
+		Policies:       []plan.Policy{&plan.SyncPolicy{}},
+		DomainFilter:   endpoint.MatchAllDomainFilters{&p.domainFilter},
+		ManagedRecords: []string{endpoint.RecordTypeCNAME, endpoint.RecordTypeMX},
+		OwnerID:        p.owner,
+	}).Calculate().Changes
+	assert.False(t, changes.HasChanges(), "planned %+v", changes)
+
+	// Even an update ExternalDNS plans for another reason leaves the records alone
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeCNAME, "lb.example.net.")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeCNAME, "lb.example.net")},
+	}))
+	assert.Zero(t, client.mutations)
+}
+
+// TestEquivalentIPv6TargetsMutateNothing tests that an AAAA record stored in another notation than
+// the desired address causes no API mutation in a full sync
+func TestEquivalentIPv6TargetsMutateNothing(t *testing.T) {
+	ownership := "heritage=external-dns,external-dns/owner=test-owner"
+	for _, stored := range []string{"2a01:04f8:0000:0001:0000:0000:0000:0002", "2A01:4F8:0:1:0:0:0:2", "2a01:4f8:0:1::2"} {
+		t.Run(stored, func(t *testing.T) {
+			client := &mutationCounter{fakeMyraSecClient: newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})}
+			client.records[123] = []myrasec.DNSRecord{
+				{ID: 1, Name: "app.example.com", RecordType: endpoint.RecordTypeAAAA, Value: stored, TTL: 300, Active: true, Enabled: true},
+				{ID: 2, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: ownership, TTL: 300, Enabled: true},
+			}
+			p := newTestProvider(client)
+
+			current, err := p.Records(context.Background())
+			require.NoError(t, err)
+			ep := findEndpoint(current, "app.example.com", endpoint.RecordTypeAAAA)
+			require.NotNil(t, ep)
+			assert.Equal(t, endpoint.Targets{"2a01:4f8:0:1::2"}, ep.Targets)
+
+			desired, err := p.AdjustEndpoints([]*endpoint.Endpoint{
+				endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeAAAA, "2a01:04f8:0:1:0:0:0:2"),
+			})
+			require.NoError(t, err)
+			assert.Equal(t, endpoint.Targets{"2a01:4f8:0:1::2"}, desired[0].Targets)
+
+			require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+				UpdateOld: []*endpoint.Endpoint{ep},
+				UpdateNew: desired,
+			}))
+			assert.Zero(t, client.mutations)
+		})
+	}
+}
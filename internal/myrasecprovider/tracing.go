@@ -0,0 +1,49 @@
+package myrasecprovider
+
+import (
+	"context"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/netguru/myra-external-dns-webhook/pkg/redact"
+)
+
+const tracerName = "github.com/netguru/myra-external-dns-webhook/internal/myrasecprovider"
+
+// startSpan starts a span of the provider. The tracer is looked up on every call, so the spans
+// go to the tracer provider installed at the time; by default it is a no-op.
+func startSpan(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// endSpan marks the span as failed if err is set and ends it. The error is redacted.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.SetStatus(codes.Error, redact.Error(err))
+	}
+	span.End()
+}
+
+// traceAPICall runs a MyraSec API call on the domain in a client span named after the operation.
+// The record, if any, adds its name and type to the span.
+func traceAPICall(ctx context.Context, operation, domain string, record *myrasec.DNSRecord, call func() error) error {
+	attrs := []attribute.KeyValue{
+		attribute.String("myrasec.operation", operation),
+		attribute.String("myrasec.domain", domain),
+	}
+	if record != nil {
+		attrs = append(attrs,
+			attribute.String("dns.record.name", recordName(record.Name, domain)),
+			attribute.String("dns.record.type", record.RecordType))
+	}
+	_, span := startSpan(ctx, "myrasec."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+	err := call()
+	endSpan(span, err)
+	return err
+}
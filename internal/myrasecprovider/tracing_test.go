@@ -0,0 +1,112 @@
+package myrasecprovider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// useInMemoryTracing installs a tracer provider recording into an in-memory exporter for the test
+func useInMemoryTracing(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = tp.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+// spanAttribute returns the value of the attribute of the span, or "".
+func spanAttribute(span tracetest.SpanStub, key string) string {
+	for _, kv := range span.Attributes {
+		if kv.Key == attribute.Key(key) {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestApplyChangesTracing(t *testing.T) {
+	exporter := useInMemoryTracing(t)
+
+	client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+	client.records[123] = []myrasec.DNSRecord{
+		{ID: 1, Name: "old.example.com", RecordType: endpoint.RecordTypeA, Value: "1.2.3.4", TTL: 300},
+		{ID: 2, Name: "old.example.com", RecordType: endpoint.RecordTypeTXT, Value: "heritage=external-dns,external-dns/owner=test-owner", TTL: 300},
+	}
+	p := newTestProvider(client)
+
+	ctx, root := otel.Tracer("test").Start(context.Background(), "request")
+	err := p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.5")},
+		Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("old.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+	})
+	root.End()
+	require.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	byID := make(map[string]tracetest.SpanStub)
+	for _, s := range spans {
+		assert.Equal(t, root.SpanContext().TraceID(), s.SpanContext.TraceID(), "span %s is not part of the trace", s.Name)
+		byID[s.SpanContext.SpanID().String()] = s
+	}
+	parentName := func(s tracetest.SpanStub) string {
+		return byID[s.Parent.SpanID().String()].Name
+	}
+
+	var tasks, calls []tracetest.SpanStub
+	for _, s := range spans {
+		switch {
+		case s.Name == "change.CREATE" || s.Name == "change.DELETE":
+			tasks = append(tasks, s)
+		case s.Name == "myrasec.ListDNSRecords":
+			assert.Equal(t, "request", parentName(s))
+			assert.Equal(t, "example.com", spanAttribute(s, "myrasec.domain"))
+		case strings.HasPrefix(s.Name, "myrasec."):
+			calls = append(calls, s)
+		}
+	}
+
+	require.Len(t, tasks, 2)
+	for _, task := range tasks {
+		assert.Equal(t, "request", parentName(task), "task %s", task.Name)
+	}
+
+	// The A record and its ownership TXT record are created and deleted by the tasks
+	require.Len(t, calls, 4)
+	for _, call := range calls {
+		parent := byID[call.Parent.SpanID().String()]
+		operation := spanAttribute(call, "myrasec.operation")
+		switch operation {
+		case "CreateDNSRecord":
+			assert.Equal(t, "change.CREATE", parent.Name)
+			assert.Equal(t, "app.example.com", spanAttribute(call, "dns.record.name"))
+		case "DeleteDNSRecord":
+			assert.Equal(t, "change.DELETE", parent.Name)
+			assert.Equal(t, "old.example.com", spanAttribute(call, "dns.record.name"))
+		default:
+			t.Errorf("unexpected API call %s", operation)
+		}
+		assert.Contains(t, []string{endpoint.RecordTypeA, endpoint.RecordTypeTXT}, spanAttribute(call, "dns.record.type"))
+	}
+}
+
+func TestTracingDisabledByDefault(t *testing.T) {
+	_, span := startSpan(context.Background(), "change.CREATE")
+	defer span.End()
+
+	assert.False(t, span.IsRecording())
+}
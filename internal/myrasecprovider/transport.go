@@ -0,0 +1,142 @@
+package myrasecprovider
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"go.uber.org/zap"
+)
+
+// configureAPITransport sets up how the MyraSec API is reached. Without an explicit proxy or CA
+// bundle the default transport is kept, which uses the HTTPS_PROXY, HTTP_PROXY and NO_PROXY
+// environment variables. The CA bundle is a PEM file whose certificates are trusted besides the
+// system roots, e.g. for a TLS-intercepting proxy.
+//
+// myrasec-go sends every request with http.DefaultClient and offers no way to replace it, so the
+// transport is installed there as an apiTransport, which only applies to the requests to the
+// MyraSec API host. Other users of http.DefaultClient keep their transport.
+func configureAPITransport(logger *zap.Logger, proxyURL, caBundle, apiBaseURL string) error {
+	var proxy *url.URL
+	if proxyURL != "" {
+		var err error
+		if proxy, err = parseProxyURL(proxyURL); err != nil {
+			return err
+		}
+	}
+	req, err := apiRequest(apiBaseURL)
+	if err != nil {
+		return err
+	}
+
+	proxyFunc := http.ProxyFromEnvironment
+	if proxy != nil || caBundle != "" {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if proxy != nil {
+			transport.Proxy = http.ProxyURL(proxy)
+		}
+		if caBundle != "" {
+			roots, err := loadCABundle(caBundle)
+			if err != nil {
+				return err
+			}
+			transport.TLSClientConfig = &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+			logger.Info("Trusting the CA bundle for the MyraSec API", zap.String("ca_bundle", caBundle))
+		}
+		proxyFunc = transport.Proxy
+		http.DefaultClient.Transport = newAPITransport(req.URL.Host, transport, http.DefaultClient.Transport)
+	}
+
+	logAPIProxy(logger, req, proxyFunc)
+	return nil
+}
+
+// apiTransport sends the requests to the MyraSec API host with the transport configured for it
+// and all other requests with the transport http.DefaultClient had before
+type apiTransport struct {
+	host     string
+	api      http.RoundTripper
+	fallback http.RoundTripper
+}
+
+// newAPITransport returns an apiTransport for host. A fallback that is an apiTransport itself, of
+// a provider created earlier, is replaced by its own fallback, so the transports do not chain.
+func newAPITransport(host string, api, fallback http.RoundTripper) *apiTransport {
+	if previous, ok := fallback.(*apiTransport); ok {
+		fallback = previous.fallback
+	}
+	if fallback == nil {
+		fallback = http.DefaultTransport
+	}
+	return &apiTransport{host: host, api: api, fallback: fallback}
+}
+
+func (t *apiTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == t.host {
+		return t.api.RoundTrip(req)
+	}
+	return t.fallback.RoundTrip(req)
+}
+
+// parseProxyURL checks an explicit proxy URL
+func parseProxyURL(proxyURL string) (*url.URL, error) {
+	parsed, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch parsed.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL %q: scheme must be http, https or socks5", parsed.Redacted())
+	}
+	if parsed.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: missing host", parsed.Redacted())
+	}
+	return parsed, nil
+}
+
+// loadCABundle returns the system roots with the certificates of the PEM file added
+func loadCABundle(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("CA bundle %s contains no PEM certificates", path)
+	}
+	return roots, nil
+}
+
+// apiRequest returns a request to the root of the MyraSec API, whose host the transport applies to
+func apiRequest(apiBaseURL string) (*http.Request, error) {
+	if apiBaseURL == "" {
+		apiBaseURL = myrasec.APIBaseURL
+	}
+	req, err := http.NewRequest(http.MethodGet, strings.Replace(apiBaseURL, "%s", "", 1), nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MyraSec API base URL: %w", err)
+	}
+	return req, nil
+}
+
+// logAPIProxy logs the proxy the requests to the MyraSec API go through, if any
+func logAPIProxy(logger *zap.Logger, req *http.Request, proxyFunc func(*http.Request) (*url.URL, error)) {
+	var proxy *url.URL
+	if proxyFunc != nil {
+		proxy, _ = proxyFunc(req)
+	}
+	if proxy == nil {
+		logger.Info("No proxy in effect for the MyraSec API", zap.String("api", req.URL.Host))
+		return
+	}
+	logger.Info("Using proxy for the MyraSec API", zap.String("api", req.URL.Host), zap.String("proxy", proxy.Redacted()))
+}
@@ -0,0 +1,159 @@
+package myrasecprovider
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const domainListResponse = `{"error":false,"list":[{"id":42,"name":"example.com"}],"page":1,"count":1,"pageSize":50}`
+
+// restoreDefaultTransport undoes the transport installed by configureAPITransport after the test
+func restoreDefaultTransport(t *testing.T) {
+	transport := http.DefaultClient.Transport
+	t.Cleanup(func() { http.DefaultClient.Transport = transport })
+}
+
+func TestAPIProxy(t *testing.T) {
+	restoreDefaultTransport(t)
+
+	// The API host does not resolve, the request only succeeds through the proxy
+	var mu sync.Mutex
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		proxied = append(proxied, r.URL.String())
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(domainListResponse))
+	}))
+	t.Cleanup(proxy.Close)
+
+	p, err := NewMyraSecDNSProvider(zap.NewNop(), Config{
+		APIKey:    "key",
+		APISecret: "secret",
+		BaseURL:   "http://myra-api.invalid",
+		APIProxy:  proxy.URL,
+	})
+	require.NoError(t, err)
+
+	domains, err := p.AccountDomains()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com"}, domains)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, proxied, 1)
+	assert.Contains(t, proxied[0], "http://myra-api.invalid/domains")
+}
+
+// recordingTransport answers every request itself and records its URL
+type recordingTransport struct {
+	mu   sync.Mutex
+	urls []string
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.urls = append(t.urls, req.URL.String())
+	t.mu.Unlock()
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+}
+
+func TestAPITransportOnlyForTheAPIHost(t *testing.T) {
+	restoreDefaultTransport(t)
+	previous := &recordingTransport{}
+	http.DefaultClient.Transport = previous
+
+	var mu sync.Mutex
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		proxied = append(proxied, r.URL.String())
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(domainListResponse))
+	}))
+	t.Cleanup(proxy.Close)
+
+	// Created twice, like several providers in one process, the transports must not chain
+	for i := 0; i < 2; i++ {
+		_, err := NewMyraSecDNSProvider(zap.NewNop(), Config{
+			APIKey:    "key",
+			APISecret: "secret",
+			BaseURL:   "http://myra-api.invalid",
+			APIProxy:  proxy.URL,
+		})
+		require.NoError(t, err)
+	}
+	transport, ok := http.DefaultClient.Transport.(*apiTransport)
+	require.True(t, ok)
+	assert.Same(t, previous, transport.fallback)
+
+	resp, err := http.DefaultClient.Get("http://other.invalid/v1/traces")
+	require.NoError(t, err)
+	resp.Body.Close()
+	resp, err = http.DefaultClient.Get("http://myra-api.invalid/domains")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, []string{"http://other.invalid/v1/traces"}, previous.urls, "other hosts keep their transport")
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"http://myra-api.invalid/domains"}, proxied)
+}
+
+func TestAPIProxyInvalid(t *testing.T) {
+	restoreDefaultTransport(t)
+
+	for _, proxyURL := range []string{"ftp://proxy.example.com", "http://", "://proxy"} {
+		_, err := NewMyraSecDNSProvider(zap.NewNop(), Config{APIKey: "key", APISecret: "secret", APIProxy: proxyURL})
+		assert.ErrorContains(t, err, "invalid proxy URL", proxyURL)
+	}
+}
+
+func TestAPICABundle(t *testing.T) {
+	restoreDefaultTransport(t)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(domainListResponse))
+	}))
+	t.Cleanup(server.Close)
+
+	t.Run("untrusted certificate", func(t *testing.T) {
+		p, err := NewMyraSecDNSProvider(zap.NewNop(), Config{APIKey: "key", APISecret: "secret", BaseURL: server.URL})
+		require.NoError(t, err)
+
+		_, err = p.AccountDomains()
+		assert.Error(t, err)
+	})
+
+	t.Run("trusted through the CA bundle", func(t *testing.T) {
+		bundle := filepath.Join(t.TempDir(), "ca.pem")
+		require.NoError(t, os.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+
+		p, err := NewMyraSecDNSProvider(zap.NewNop(), Config{APIKey: "key", APISecret: "secret", BaseURL: server.URL, APICABundle: bundle})
+		require.NoError(t, err)
+
+		domains, err := p.AccountDomains()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"example.com"}, domains)
+	})
+
+	t.Run("invalid bundle", func(t *testing.T) {
+		bundle := filepath.Join(t.TempDir(), "ca.pem")
+		require.NoError(t, os.WriteFile(bundle, []byte("not a certificate"), 0o600))
+
+		_, err := NewMyraSecDNSProvider(zap.NewNop(), Config{APIKey: "key", APISecret: "secret", APICABundle: bundle})
+		assert.ErrorContains(t, err, "contains no PEM certificates")
+	})
+}
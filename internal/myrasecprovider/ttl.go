@@ -0,0 +1,114 @@
+package myrasecprovider
+
+import (
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// Enforcement of the default TTL on existing records whose endpoint has no TTL
+const (
+	TTLEnforcementAlways       = "always"         // Update records to the default TTL
+	TTLEnforcementOnCreateOnly = "on-create-only" // Use the default TTL for new records only
+)
+
+// TTLInherit as default TTL stores records whose endpoint has no TTL without a TTL, so that they
+// inherit the default TTL of their domain.
+const TTLInherit = 0
+
+// clampTTL limits ttl to the configured range. A zero bound is not enforced.
+func (p *MyraSecDNSProvider) clampTTL(ttl int) int {
+	if p.minTTL > 0 && ttl < p.minTTL {
+		return p.minTTL
+	}
+	if p.maxTTL > 0 && ttl > p.maxTTL {
+		return p.maxTTL
+	}
+	return ttl
+}
+
+// defaultTTL returns the TTL of records of the type whose endpoint has no TTL: the default of the
+// type if configured, otherwise the provider default.
+func (p *MyraSecDNSProvider) defaultTTL(recordType string) int {
+	if ttl, ok := p.ttlPerType[recordType]; ok {
+		return ttl
+	}
+	return p.ttl
+}
+
+// ownershipTTL returns the TTL of the ownership TXT record of a record stored with ttl: the
+// default of TXT records if configured, otherwise the TTL of the record it owns.
+func (p *MyraSecDNSProvider) ownershipTTL(ttl int) int {
+	if txtTTL, ok := p.ttlPerType[endpoint.RecordTypeTXT]; ok {
+		return txtTTL
+	}
+	return ttl
+}
+
+// recordTTL returns the TTL to store for the endpoint: its own TTL if set, otherwise the
+// default of its record type, clamped to the range accepted by the MyraSec API. TTLInherit is
+// returned for an endpoint without a TTL if records inherit the TTL of their domain.
+func (p *MyraSecDNSProvider) recordTTL(ep *endpoint.Endpoint) int {
+	if ep.RecordTTL <= 0 {
+		return p.defaultTTL(ep.RecordType)
+	}
+
+	ttl := p.clampTTL(int(ep.RecordTTL))
+	if ttl != int(ep.RecordTTL) {
+		p.logger.Info("Clamping record TTL to the allowed range",
+			zap.String("dnsName", ep.DNSName),
+			zap.String("type", ep.RecordType),
+			zap.Int64("ttl", int64(ep.RecordTTL)),
+			zap.Int("clamped_ttl", ttl))
+	}
+	return ttl
+}
+
+// updatedTTL returns the TTL of the existing record rec after an update to ttl for the endpoint.
+// With TTLEnforcementOnCreateOnly a record whose endpoint has no TTL keeps its stored TTL, so a
+// changed default alone does not update it. A record inheriting the TTL of its domain is read
+// with the inherited TTL, so it keeps its stored TTL as well instead of being updated on every sync.
+func (p *MyraSecDNSProvider) updatedTTL(rec *myrasec.DNSRecord, ep *endpoint.Endpoint, ttl int) int {
+	if ep.RecordTTL > 0 {
+		return ttl
+	}
+	if ttl == TTLInherit {
+		return rec.TTL
+	}
+	if !p.ttlOnCreateOnly {
+		return ttl
+	}
+	if rec.TTL != ttl {
+		p.logger.Debug("Keeping the stored TTL of the record, the endpoint has none",
+			zap.String("dnsName", rec.Name),
+			zap.String("type", rec.RecordType),
+			zap.String("value", rec.Value),
+			zap.Int("ttl", rec.TTL),
+			zap.Int("default_ttl", ttl))
+	}
+	return rec.TTL
+}
+
+// adjustTTL normalizes the TTL of the endpoint to the value that will actually be stored,
+// so the desired state does not differ from the records on every sync. An endpoint without a
+// TTL gets the default of its record type if one is configured, so that records follow a
+// changed default, unless the default TTL is only enforced on creation.
+func (p *MyraSecDNSProvider) adjustTTL(ep *endpoint.Endpoint) {
+	if ep.RecordTTL <= 0 {
+		if ttl, ok := p.ttlPerType[ep.RecordType]; ok && !p.ttlOnCreateOnly {
+			p.logger.Debug("Setting the default TTL of the record type",
+				zap.String("dnsName", ep.DNSName),
+				zap.String("type", ep.RecordType),
+				zap.Int("ttl", ttl))
+			ep.RecordTTL = endpoint.TTL(ttl)
+		}
+		return
+	}
+	if ttl := p.clampTTL(int(ep.RecordTTL)); ttl != int(ep.RecordTTL) {
+		p.logger.Debug("Adjusting endpoint TTL to the allowed range",
+			zap.String("dnsName", ep.DNSName),
+			zap.Int64("ttl", int64(ep.RecordTTL)),
+			zap.Int("adjusted_ttl", ttl))
+		ep.RecordTTL = endpoint.TTL(ttl)
+	}
+}
@@ -0,0 +1,239 @@
+package myrasecprovider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestTTLClamping(t *testing.T) {
+	tests := []struct {
+		name       string
+		recordTTL  endpoint.TTL
+		wantStored int
+		wantAdjust endpoint.TTL
+	}{
+		{name: "below minimum", recordTTL: 30, wantStored: 300, wantAdjust: 300},
+		{name: "above maximum", recordTTL: 172800, wantStored: 86400, wantAdjust: 86400},
+		{name: "within range", recordTTL: 600, wantStored: 600, wantAdjust: 600},
+		{name: "unset", recordTTL: 0, wantStored: 300, wantAdjust: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+			p := newTestProvider(client)
+			p.minTTL = DefaultMinTTL
+			p.maxTTL = DefaultMaxTTL
+
+			ep := endpoint.NewEndpointWithTTL("app.example.com", endpoint.RecordTypeA, tt.recordTTL, "1.2.3.4")
+			adjusted, err := p.AdjustEndpoints([]*endpoint.Endpoint{ep.DeepCopy()})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantAdjust, adjusted[0].RecordTTL)
+
+			require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{ep}}))
+			records := p.findMatchingRecords(client.records[123], "example.com", "app.example.com", endpoint.RecordTypeA)
+			require.Len(t, records, 1)
+			assert.Equal(t, tt.wantStored, records[0].TTL)
+
+			// An update to an out-of-range TTL is clamped as well
+			desired := endpoint.NewEndpointWithTTL("app.example.com", endpoint.RecordTypeA, tt.recordTTL, "1.2.3.4")
+			desired.RecordTTL = tt.recordTTL * 2
+			require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+				UpdateOld: []*endpoint.Endpoint{ep},
+				UpdateNew: []*endpoint.Endpoint{desired},
+			}))
+			records = p.findMatchingRecords(client.records[123], "example.com", "app.example.com", endpoint.RecordTypeA)
+			require.Len(t, records, 1)
+			assert.Equal(t, p.recordTTL(desired), records[0].TTL)
+			assert.GreaterOrEqual(t, records[0].TTL, DefaultMinTTL)
+			assert.LessOrEqual(t, records[0].TTL, DefaultMaxTTL)
+		})
+	}
+}
+
+func TestNewMyraSecDNSProviderTTLRange(t *testing.T) {
+	p, err := NewMyraSecDNSProvider(zap.NewNop(), Config{APIKey: "key", APISecret: "secret", TTL: 60})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultMinTTL, p.minTTL)
+	assert.Equal(t, DefaultMaxTTL, p.maxTTL)
+	assert.Equal(t, DefaultMinTTL, p.ttl, "default TTL is clamped to the range")
+
+	_, err = NewMyraSecDNSProvider(zap.NewNop(), Config{APIKey: "key", APISecret: "secret", MinTTL: 3600, MaxTTL: 600})
+	assert.Error(t, err)
+}
+
+func TestTTLPerType(t *testing.T) {
+	client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+	p := newTestProvider(client)
+	p.ttlPerType = map[string]int{endpoint.RecordTypeA: 120, endpoint.RecordTypeTXT: 3600}
+
+	tests := []struct {
+		name       string
+		ep         *endpoint.Endpoint
+		wantStored int
+		wantAdjust endpoint.TTL
+	}{
+		{name: "endpoint TTL wins", ep: endpoint.NewEndpointWithTTL("ttl.example.com", endpoint.RecordTypeA, 600, "1.2.3.4"), wantStored: 600, wantAdjust: 600},
+		{name: "type default", ep: endpoint.NewEndpoint("type.example.com", endpoint.RecordTypeA, "1.2.3.4"), wantStored: 120, wantAdjust: 120},
+		{name: "global default", ep: endpoint.NewEndpoint("global.example.com", endpoint.RecordTypeCNAME, "target.example.org"), wantStored: 300, wantAdjust: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adjusted, err := p.AdjustEndpoints([]*endpoint.Endpoint{tt.ep.DeepCopy()})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantAdjust, adjusted[0].RecordTTL)
+
+			require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{tt.ep}}))
+			records := p.findMatchingRecords(client.records[123], "example.com", tt.ep.DNSName, tt.ep.RecordType)
+			require.Len(t, records, 1)
+			assert.Equal(t, tt.wantStored, records[0].TTL)
+
+			// The ownership record gets the default of TXT records
+			ownership := p.findMatchingRecords(client.records[123], "example.com", tt.ep.DNSName, endpoint.RecordTypeTXT)
+			require.Len(t, ownership, 1)
+			assert.Equal(t, 3600, ownership[0].TTL)
+
+			// An update without a TTL falls back the same way
+			desired := tt.ep.DeepCopy()
+			desired.Targets = endpoint.Targets{tt.ep.Targets[0]}
+			if tt.ep.RecordType == endpoint.RecordTypeA {
+				desired.Targets = endpoint.Targets{"5.6.7.8"}
+			}
+			require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+				UpdateOld: []*endpoint.Endpoint{tt.ep},
+				UpdateNew: []*endpoint.Endpoint{desired},
+			}))
+			records = p.findMatchingRecords(client.records[123], "example.com", tt.ep.DNSName, tt.ep.RecordType)
+			require.Len(t, records, 1)
+			assert.Equal(t, tt.wantStored, records[0].TTL)
+		})
+	}
+}
+
+func TestNewMyraSecDNSProviderTTLPerType(t *testing.T) {
+	p, err := NewMyraSecDNSProvider(zap.NewNop(), Config{APIKey: "key", APISecret: "secret", MinTTL: 60, TTLPerType: []string{"a=120", " TXT = 100000 ", ""}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{endpoint.RecordTypeA: 120, endpoint.RecordTypeTXT: DefaultMaxTTL}, p.ttlPerType, "types are normalized and TTLs clamped")
+	assert.Equal(t, 120, p.defaultTTL(endpoint.RecordTypeA))
+	assert.Equal(t, p.ttl, p.defaultTTL(endpoint.RecordTypeCNAME))
+
+	for _, entries := range [][]string{
+		{"A"},
+		{"A=abc"},
+		{"A=0"},
+		{"A=120", "a=300"},
+		{"SPF=300"},
+	} {
+		_, err := NewMyraSecDNSProvider(zap.NewNop(), Config{APIKey: "key", APISecret: "secret", TTLPerType: entries})
+		assert.Error(t, err, "%v", entries)
+	}
+}
+
+func TestTTLEnforcement(t *testing.T) {
+	tests := []struct {
+		mode       string
+		recordTTL  endpoint.TTL
+		wantStored int
+		wantLogged int
+	}{
+		{mode: TTLEnforcementAlways, wantStored: 300},
+		{mode: TTLEnforcementOnCreateOnly, wantStored: 3600, wantLogged: 1},
+		{mode: TTLEnforcementOnCreateOnly, recordTTL: 600, wantStored: 600},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s ttl %d", tt.mode, tt.recordTTL), func(t *testing.T) {
+			core, logs := observer.New(zap.DebugLevel)
+			client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+			p := newTestProvider(client)
+			p.logger = zap.New(core)
+			p.ttlPerType = map[string]int{endpoint.RecordTypeA: 300}
+			var err error
+			p.ttlOnCreateOnly, err = ttlOnCreateOnly(tt.mode)
+			require.NoError(t, err)
+
+			// Created by an earlier configuration with another TTL
+			current := endpoint.NewEndpointWithTTL("app.example.com", endpoint.RecordTypeA, 3600, "1.2.3.4")
+			require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{current}}))
+
+			desired, err := p.AdjustEndpoints([]*endpoint.Endpoint{endpoint.NewEndpointWithTTL("app.example.com", endpoint.RecordTypeA, tt.recordTTL, "1.2.3.4")})
+			require.NoError(t, err)
+			require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+				UpdateOld: []*endpoint.Endpoint{current},
+				UpdateNew: desired,
+			}))
+
+			records := p.findMatchingRecords(client.records[123], "example.com", "app.example.com", endpoint.RecordTypeA)
+			require.Len(t, records, 1)
+			assert.Equal(t, tt.wantStored, records[0].TTL)
+			assert.Equal(t, tt.wantLogged, logs.FilterMessage("Keeping the stored TTL of the record, the endpoint has none").Len())
+		})
+	}
+
+	_, err := NewMyraSecDNSProvider(zap.NewNop(), Config{APIKey: "key", APISecret: "secret", TTLEnforcement: "sometimes"})
+	assert.Error(t, err)
+}
+
+func TestTTLInherit(t *testing.T) {
+	tests := []struct {
+		name       string
+		ep         *endpoint.Endpoint
+		wantSent   int
+		wantStored int
+	}{
+		{name: "unset", ep: endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.4"), wantSent: TTLInherit, wantStored: 600},
+		{name: "explicit 0", ep: endpoint.NewEndpointWithTTL("app.example.com", endpoint.RecordTypeA, 0, "1.2.3.4"), wantSent: TTLInherit, wantStored: 600},
+		{name: "normal", ep: endpoint.NewEndpointWithTTL("app.example.com", endpoint.RecordTypeA, 900, "1.2.3.4"), wantSent: 900, wantStored: 900},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+			p := newTestProvider(client)
+			p.ttl = TTLInherit
+
+			desired, err := p.AdjustEndpoints([]*endpoint.Endpoint{tt.ep})
+			require.NoError(t, err)
+			require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{Create: desired}))
+			records := p.findMatchingRecords(client.records[123], "example.com", "app.example.com", endpoint.RecordTypeA)
+			require.Len(t, records, 1)
+			assert.Equal(t, tt.wantSent, records[0].TTL)
+
+			// The API stores a record without a TTL with the default TTL of the domain
+			for i, r := range client.records[123] {
+				if r.TTL == TTLInherit {
+					client.records[123][i].TTL = 600
+				}
+			}
+
+			current, err := p.Records(context.Background())
+			require.NoError(t, err)
+			ep := findEndpoint(current, "app.example.com", endpoint.RecordTypeA)
+			require.NotNil(t, ep)
+			assert.Equal(t, endpoint.TTL(tt.wantStored), ep.RecordTTL, "the TTL returned by the API is reported")
+
+			// A resync does not replace the inherited TTL
+			require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+				UpdateOld: []*endpoint.Endpoint{ep},
+				UpdateNew: desired,
+			}))
+			records = p.findMatchingRecords(client.records[123], "example.com", "app.example.com", endpoint.RecordTypeA)
+			require.Len(t, records, 1)
+			assert.Equal(t, tt.wantStored, records[0].TTL)
+		})
+	}
+
+	p, err := NewMyraSecDNSProvider(zap.NewNop(), Config{APIKey: "key", APISecret: "secret", TTL: TTLInherit})
+	require.NoError(t, err)
+	assert.Equal(t, TTLInherit, p.ttl, "the inherit mode is not clamped to the minimum TTL")
+}
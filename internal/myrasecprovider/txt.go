@@ -0,0 +1,69 @@
+package myrasecprovider
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// maxTXTStringLength is the maximum length in bytes of a single character-string of a TXT record.
+const maxTXTStringLength = 255
+
+// formatTXTValue returns the logical value of a TXT record. One layer of double quotes that
+// ExternalDNS may wrap around a value is removed and a value stored as several quoted
+// character-strings, such as a long DKIM key, is joined back together. The content is kept
+// exactly, DKIM keys and SPF policies must not change. Any other value with quotes inside is
+// returned as is.
+func formatTXTValue(value string) string {
+	if parts, ok := parseTXTStrings(value); ok {
+		return strings.Join(parts, "")
+	}
+	return value
+}
+
+// splitTXTValue returns the value to store for a logical TXT value. Values longer than
+// maxTXTStringLength bytes are split into quoted character-strings separated by a space,
+// shorter values are returned unchanged. A split never falls inside a multi-byte UTF-8 character.
+// Values with double quotes inside cannot be represented as quoted strings and are not split.
+func splitTXTValue(value string) string {
+	if len(value) <= maxTXTStringLength || strings.Contains(value, `"`) {
+		return value
+	}
+
+	var parts []string
+	for len(value) > maxTXTStringLength {
+		cut := maxTXTStringLength
+		for cut > 0 && !utf8.RuneStart(value[cut]) {
+			cut--
+		}
+		parts = append(parts, `"`+value[:cut]+`"`)
+		value = value[cut:]
+	}
+	parts = append(parts, `"`+value+`"`)
+	return strings.Join(parts, " ")
+}
+
+// parseTXTStrings splits a value made of one or more quoted character-strings separated by
+// whitespace into the unquoted strings. It reports false if the value has any other form.
+func parseTXTStrings(value string) ([]string, bool) {
+	var parts []string
+	rest := value
+	for rest != "" {
+		if !strings.HasPrefix(rest, `"`) {
+			return nil, false
+		}
+		end := strings.IndexByte(rest[1:], '"')
+		if end < 0 {
+			return nil, false
+		}
+		parts = append(parts, rest[1:end+1])
+		rest = rest[end+2:]
+
+		trimmed := strings.TrimLeft(rest, " \t")
+		if trimmed != "" && trimmed == rest {
+			// Strings must be separated by whitespace
+			return nil, false
+		}
+		rest = trimmed
+	}
+	return parts, len(parts) > 0
+}
@@ -0,0 +1,138 @@
+package myrasecprovider
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestFormatTXTValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "plain", value: "v=spf1 include:_spf.example.com ~all", want: "v=spf1 include:_spf.example.com ~all"},
+		{name: "one layer of quotes", value: `"v=spf1 -all"`, want: "v=spf1 -all"},
+		{name: "only one layer is removed", value: `""v=spf1 -all""`, want: `""v=spf1 -all""`},
+		{name: "several quoted strings are joined", value: `"v=DKIM1; k=rsa; p=MIIB" "IjANBgkq"`, want: "v=DKIM1; k=rsa; p=MIIBIjANBgkq"},
+		{name: "strings without separator", value: `"a""b"`, want: `"a""b"`},
+		{name: "interior quote", value: `say "hi"`, want: `say "hi"`},
+		{name: "single quotes are content", value: "'v=spf1 -all'", want: "'v=spf1 -all'"},
+		{name: "whitespace is kept", value: "a  b\tc", want: "a  b\tc"},
+		{name: "lone quote", value: `"`, want: `"`},
+		{name: "empty quoted", value: `""`, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, formatTXTValue(tt.value))
+		})
+	}
+}
+
+func TestSplitTXTValue(t *testing.T) {
+	t.Run("short values are unchanged", func(t *testing.T) {
+		assert.Equal(t, "v=spf1 -all", splitTXTValue("v=spf1 -all"))
+		exact := strings.Repeat("a", maxTXTStringLength)
+		assert.Equal(t, exact, splitTXTValue(exact))
+	})
+
+	t.Run("long value is split into 255 byte strings", func(t *testing.T) {
+		value := strings.Repeat("a", maxTXTStringLength) + strings.Repeat("b", maxTXTStringLength) + "c"
+		got := splitTXTValue(value)
+		parts, ok := parseTXTStrings(got)
+		require.True(t, ok)
+		assert.Equal(t, []string{strings.Repeat("a", maxTXTStringLength), strings.Repeat("b", maxTXTStringLength), "c"}, parts)
+		assert.Equal(t, value, formatTXTValue(got))
+	})
+
+	t.Run("multi-byte character at the boundary is kept whole", func(t *testing.T) {
+		value := strings.Repeat("a", maxTXTStringLength-1) + "ü" + "tail"
+		got := splitTXTValue(value)
+		parts, ok := parseTXTStrings(got)
+		require.True(t, ok)
+		require.Len(t, parts, 2)
+		assert.Equal(t, strings.Repeat("a", maxTXTStringLength-1), parts[0])
+		assert.Equal(t, "ütail", parts[1])
+		for _, part := range parts {
+			assert.True(t, utf8.ValidString(part))
+		}
+		assert.Equal(t, value, formatTXTValue(got))
+	})
+
+	t.Run("value with quotes is not split", func(t *testing.T) {
+		value := `"` + strings.Repeat("a", 300)
+		assert.Equal(t, value, splitTXTValue(value))
+	})
+}
+
+func TestLongTXTValueRoundTrip(t *testing.T) {
+	dkim := "v=DKIM1; k=rsa; p=" + strings.Repeat("MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEA", 9)
+	require.Greater(t, len(dkim), maxTXTStringLength)
+	name := "selector._domainkey.example.com"
+
+	t.Run("long value is stored chunked", func(t *testing.T) {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		p := newTestProvider(client)
+
+		err := p.ApplyChanges(context.Background(), &plan.Changes{
+			Create: []*endpoint.Endpoint{endpoint.NewEndpoint(name, endpoint.RecordTypeTXT, dkim)},
+		})
+		require.NoError(t, err)
+		records := dataRecords(client.records[123])
+		require.Len(t, records, 1)
+
+		stored := records[0].Value
+		parts, ok := parseTXTStrings(stored)
+		require.True(t, ok)
+		assert.Len(t, parts, 2)
+		for _, part := range parts {
+			assert.LessOrEqual(t, len(part), maxTXTStringLength)
+		}
+		assert.Equal(t, dkim, formatTXTValue(stored))
+	})
+
+	t.Run("chunked record matches the logical value", func(t *testing.T) {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		p := newTestProvider(client)
+
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+			Create: []*endpoint.Endpoint{endpoint.NewEndpoint(name, endpoint.RecordTypeTXT, dkim)},
+		}))
+		require.Len(t, client.records[123], 2, "the DKIM record and its ownership record")
+		id := dataRecords(client.records[123])[0].ID
+
+		// The record is listed with its logical value
+		listed, err := p.Records(context.Background())
+		require.NoError(t, err)
+		current := findEndpoint(listed, name, endpoint.RecordTypeTXT)
+		require.NotNil(t, current)
+		assert.Equal(t, endpoint.Targets{dkim}, current.Targets)
+
+		// An unchanged value is not recreated
+		err = p.ApplyChanges(context.Background(), &plan.Changes{
+			UpdateOld: []*endpoint.Endpoint{current},
+			UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpointWithTTL(name, endpoint.RecordTypeTXT, 600, dkim)},
+		})
+		require.NoError(t, err)
+		records := dataRecords(client.records[123])
+		require.Len(t, records, 1)
+		assert.Equal(t, id, records[0].ID)
+		assert.Equal(t, 600, records[0].TTL)
+
+		// The ownership record goes with the last TXT record of the name
+		err = p.ApplyChanges(context.Background(), &plan.Changes{
+			Delete: []*endpoint.Endpoint{endpoint.NewEndpoint(name, endpoint.RecordTypeTXT, dkim)},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, client.records[123])
+	})
+}
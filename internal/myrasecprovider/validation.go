@@ -0,0 +1,320 @@
+package myrasecprovider
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// Handling of endpoints whose record values fail the validation
+const (
+	InvalidEndpointsDrop   = "drop"   // Drop them from the plan with a warning
+	InvalidEndpointsReject = "reject" // Refuse the whole plan with InvalidEndpointsError
+)
+
+// maxTXTValueLength is the longest TXT value accepted, in bytes. Values longer than
+// maxTXTStringLength are stored as several character-strings, see splitTXTValue.
+const maxTXTValueLength = 4096
+
+// validateEndpoint checks the name, TTL and targets of the endpoint against the rules of its
+// record type and returns the first problem found, or "" if the endpoint is valid. Endpoints
+// without targets are not invalid, they are handled apart, see filterEmptyTargetEndpoints.
+func validateEndpoint(ep *endpoint.Endpoint) string {
+	if !isDNSName(normalizeDNSName(ep.DNSName), true) {
+		return fmt.Sprintf("invalid DNS name %q", ep.DNSName)
+	}
+	if ep.RecordTTL < 0 || ep.RecordTTL > math.MaxInt32 {
+		return fmt.Sprintf("TTL %d is outside the range 0 to %d", ep.RecordTTL, math.MaxInt32)
+	}
+	if ep.RecordType == endpoint.RecordTypeCNAME && len(ep.Targets) > 1 {
+		return fmt.Sprintf("a CNAME record has a single target, got %d", len(ep.Targets))
+	}
+
+	for _, target := range ep.Targets {
+		if reason := validateTarget(ep.RecordType, target); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// validateTarget checks a single target of the record type, see validateEndpoint.
+func validateTarget(recordType, target string) string {
+	switch recordType {
+	case endpoint.RecordTypeA:
+		if ip := net.ParseIP(target); ip == nil || ip.To4() == nil {
+			return fmt.Sprintf("target %q is not an IPv4 address", target)
+		}
+	case endpoint.RecordTypeAAAA:
+		if addr, err := netip.ParseAddr(target); err != nil || !addr.Is6() || addr.Is4In6() || addr.Zone() != "" {
+			return fmt.Sprintf("target %q is not an IPv6 address", target)
+		}
+	case endpoint.RecordTypeCNAME, endpoint.RecordTypeNS:
+		if !isDNSName(target, false) {
+			return fmt.Sprintf("target %q is not a hostname", target)
+		}
+	case endpoint.RecordTypeMX:
+		// "<preference> <exchange>"
+		fields := strings.Fields(target)
+		if len(fields) != 2 || !isUint16(fields[0]) || !isDNSName(fields[1], false) {
+			return fmt.Sprintf("target %q is not of the form \"<preference> <hostname>\"", target)
+		}
+	case endpoint.RecordTypeSRV:
+		// "<priority> <weight> <port> <target>", a target of "." means the service is not available
+		fields := strings.Fields(target)
+		if len(fields) != 4 || !isUint16(fields[0]) || !isUint16(fields[1]) || !isUint16(fields[2]) ||
+			(fields[3] != "." && !isDNSName(fields[3], false)) {
+			return fmt.Sprintf("target %q is not of the form \"<priority> <weight> <port> <hostname>\"", target)
+		}
+	case endpoint.RecordTypeTXT:
+		value := formatTXTValue(target)
+		if len(value) > maxTXTValueLength {
+			return fmt.Sprintf("TXT value of %d bytes exceeds %d bytes", len(value), maxTXTValueLength)
+		}
+		if len(value) > maxTXTStringLength && strings.Contains(value, `"`) {
+			return fmt.Sprintf("TXT value of %d bytes with quotes inside cannot be split into strings of %d bytes", len(value), maxTXTStringLength)
+		}
+	}
+	return ""
+}
+
+// isDNSName reports whether name is a valid DNS name: at most 253 bytes and labels of 1 to 63
+// letters, digits, hyphens or underscores that do not start or end with a hyphen. A trailing dot
+// is allowed. With wildcard, the first label may be "*".
+func isDNSName(name string, wildcard bool) bool {
+	name = stripTrailingDot(name)
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	for i, label := range strings.Split(name, ".") {
+		if wildcard && i == 0 && label == "*" {
+			continue
+		}
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isUint16 reports whether s is a decimal number from 0 to 65535.
+func isUint16(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 16)
+	return err == nil
+}
+
+// cnameConflicts returns the CNAME endpoints sharing their name with an endpoint of another type.
+// A CNAME cannot coexist with other records; TXT records are not counted, since the ownership
+// records of this provider live at the name of the records they own.
+func cnameConflicts(endpoints []*endpoint.Endpoint) map[*endpoint.Endpoint]string {
+	others := make(map[string][]string)
+	for _, ep := range endpoints {
+		if ep.RecordType != endpoint.RecordTypeCNAME && ep.RecordType != endpoint.RecordTypeTXT {
+			name := strings.ToLower(stripTrailingDot(ep.DNSName))
+			others[name] = append(others[name], ep.RecordType)
+		}
+	}
+
+	conflicts := make(map[*endpoint.Endpoint]string)
+	for _, ep := range endpoints {
+		if ep.RecordType != endpoint.RecordTypeCNAME {
+			continue
+		}
+		if types := others[strings.ToLower(stripTrailingDot(ep.DNSName))]; len(types) > 0 {
+			conflicts[ep] = fmt.Sprintf("a CNAME record cannot coexist with other records at the name (%s)", strings.Join(types, ", "))
+		}
+	}
+	return conflicts
+}
+
+// apexCNAMEReason explains why a CNAME endpoint at the apex of the zone is refused, or returns ""
+// for any other endpoint. A CNAME cannot coexist with the SOA and NS records of the zone, so
+// MyraSec either rejects it or the zone breaks. Support for ALIAS-style apex records would
+// translate such endpoints here instead of refusing them.
+func apexCNAMEReason(ep *endpoint.Endpoint, zone string) string {
+	if ep.RecordType != endpoint.RecordTypeCNAME || zone == "" || !sameName(ep.DNSName, zone) {
+		return ""
+	}
+	return fmt.Sprintf("CNAME record %s is at the apex of zone %s, where a CNAME is not allowed; "+
+		"use A/AAAA records or the AlternativeCNAME of the MyraSec domain instead", stripTrailingDot(ep.DNSName), zone)
+}
+
+// apexName returns the name of the apex of the zone dnsName belongs to: the most specific managed
+// domain, or the domain the first filter names while none was selected yet.
+func (p *MyraSecDNSProvider) apexName(dnsName string) string {
+	if zones := p.knownZones(); len(zones) > 0 {
+		return zoneFor(zones, dnsName).Name
+	}
+	if len(p.domainFilter.Filters) > 0 {
+		return stripTrailingDot(p.domainFilter.Filters[0])
+	}
+	return ""
+}
+
+// invalidEndpoints validates the endpoints, which together form the desired state of their names,
+// and returns the reason for each invalid one.
+func (p *MyraSecDNSProvider) invalidEndpoints(endpoints []*endpoint.Endpoint) map[*endpoint.Endpoint]string {
+	invalid := cnameConflicts(endpoints)
+	for _, ep := range endpoints {
+		if reason := validateEndpoint(ep); reason != "" {
+			invalid[ep] = reason
+		} else if reason := apexCNAMEReason(ep, p.apexName(ep.DNSName)); reason != "" {
+			invalid[ep] = reason
+		}
+	}
+	return invalid
+}
+
+// dropInvalidEndpoints removes the endpoints that fail the validation, so ExternalDNS does not
+// plan changes MyraSec would refuse. With InvalidEndpointsReject they are kept and ApplyChanges
+// refuses the plan.
+func (p *MyraSecDNSProvider) dropInvalidEndpoints(endpoints []*endpoint.Endpoint) []*endpoint.Endpoint {
+	invalid := p.invalidEndpoints(endpoints)
+	if len(invalid) == 0 {
+		return endpoints
+	}
+
+	valid := endpoints[:0]
+	for _, ep := range endpoints {
+		reason, ok := invalid[ep]
+		if !ok {
+			valid = append(valid, ep)
+			continue
+		}
+		if p.rejectInvalidEndpoints {
+			p.logger.Warn("Invalid endpoint, the plan will be refused",
+				zap.String("dnsName", ep.DNSName),
+				zap.String("type", ep.RecordType),
+				zap.String("reason", reason))
+			valid = append(valid, ep)
+			continue
+		}
+		p.logger.Warn("Dropping invalid endpoint",
+			zap.String("dnsName", ep.DNSName),
+			zap.String("type", ep.RecordType),
+			zap.String("reason", reason))
+	}
+	return valid
+}
+
+// checkInvalidChanges validates the desired endpoints of the plan. With InvalidEndpointsReject an
+// InvalidEndpointsError lists the invalid endpoints; otherwise their reasons are returned, so
+// that the changes are skipped.
+func (p *MyraSecDNSProvider) checkInvalidChanges(changes *plan.Changes) (map[*endpoint.Endpoint]string, error) {
+	invalid := p.invalidEndpoints(desiredEndpoints(changes))
+	if len(invalid) == 0 || !p.rejectInvalidEndpoints {
+		return invalid, nil
+	}
+	return nil, p.refuseInvalidChanges(changes, invalid)
+}
+
+// checkZoneConflicts adds the desired endpoints of the plan that conflict with the records in
+// their zone to invalid, see zoneCNAMEConflicts. With InvalidEndpointsReject an
+// InvalidEndpointsError lists them instead, before any change is applied.
+func (p *MyraSecDNSProvider) checkZoneConflicts(changes *plan.Changes, snapshotFor func(*endpoint.Endpoint) *zoneSnapshot, invalid map[*endpoint.Endpoint]string) error {
+	conflicts := p.zoneCNAMEConflicts(changes, snapshotFor)
+	if len(conflicts) == 0 {
+		return nil
+	}
+	if p.rejectInvalidEndpoints {
+		return p.refuseInvalidChanges(changes, conflicts)
+	}
+	for ep, reason := range conflicts {
+		invalid[ep] = reason
+	}
+	return nil
+}
+
+// refuseInvalidChanges returns the InvalidEndpointsError listing the invalid desired endpoints of
+// the plan.
+func (p *MyraSecDNSProvider) refuseInvalidChanges(changes *plan.Changes, invalid map[*endpoint.Endpoint]string) error {
+	err := &InvalidEndpointsError{}
+	for _, ep := range desiredEndpoints(changes) {
+		if reason, ok := invalid[ep]; ok {
+			err.Endpoints = append(err.Endpoints, InvalidEndpoint{DNSName: ep.DNSName, RecordType: ep.RecordType, Reason: reason})
+		}
+	}
+	p.logger.Error("Refusing plan with invalid endpoints, no changes were applied", zap.Error(err))
+	return err
+}
+
+// desiredEndpoints returns the endpoints the plan creates or updates to.
+func desiredEndpoints(changes *plan.Changes) []*endpoint.Endpoint {
+	desired := make([]*endpoint.Endpoint, 0, len(changes.Create)+len(changes.UpdateNew))
+	desired = append(desired, changes.Create...)
+	return append(desired, changes.UpdateNew...)
+}
+
+// zoneCNAMEConflicts returns the desired endpoints of the plan that conflict with the records
+// already in their zone, e.g. created by hand: a CNAME at a name with records of another type, or
+// a record of another type at the name of a CNAME. The records of the types the plan deletes from
+// the name or moves away from it are not counted, nor are TXT records, see cnameConflicts.
+func (p *MyraSecDNSProvider) zoneCNAMEConflicts(changes *plan.Changes, snapshotFor func(*endpoint.Endpoint) *zoneSnapshot) map[*endpoint.Endpoint]string {
+	// The record types the plan removes from each name
+	removed := make(map[string]map[string]bool)
+	remove := func(ep *endpoint.Endpoint) {
+		key := nameKey(ep.DNSName, snapshotFor(ep).zoneName())
+		if removed[key] == nil {
+			removed[key] = make(map[string]bool)
+		}
+		removed[key][ep.RecordType] = true
+	}
+	for _, ep := range changes.Delete {
+		remove(ep)
+	}
+	for i, ep := range changes.UpdateOld {
+		if p.identityChanged(snapshotFor(ep).zoneName(), ep, changes.UpdateNew[i]) {
+			remove(ep)
+		}
+	}
+
+	// The record types at each name of a zone, listed once per zone
+	zoneTypes := make(map[*zoneSnapshot]map[string][]string)
+	typesAt := func(snapshot *zoneSnapshot, key string) []string {
+		types, ok := zoneTypes[snapshot]
+		if !ok {
+			types = make(map[string][]string)
+			for _, r := range snapshot.all() {
+				name := nameKey(r.Name, snapshot.zoneName())
+				if r.RecordType != endpoint.RecordTypeTXT && !slices.Contains(types[name], r.RecordType) {
+					types[name] = append(types[name], r.RecordType)
+				}
+			}
+			zoneTypes[snapshot] = types
+		}
+		return types[key]
+	}
+
+	conflicts := make(map[*endpoint.Endpoint]string)
+	for _, ep := range desiredEndpoints(changes) {
+		if ep.RecordType == endpoint.RecordTypeTXT {
+			continue
+		}
+		snapshot := snapshotFor(ep)
+		key := nameKey(ep.DNSName, snapshot.zoneName())
+		var types []string
+		for _, recordType := range typesAt(snapshot, key) {
+			if removed[key][recordType] || (recordType == endpoint.RecordTypeCNAME) == (ep.RecordType == endpoint.RecordTypeCNAME) {
+				continue
+			}
+			types = append(types, recordType)
+		}
+		if len(types) > 0 {
+			conflicts[ep] = fmt.Sprintf("a CNAME record cannot coexist with other records at the name (%s in the zone)", strings.Join(types, ", "))
+		}
+	}
+	return conflicts
+}
@@ -0,0 +1,253 @@
+package myrasecprovider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestValidateEndpoint(t *testing.T) {
+	tests := []struct {
+		name    string
+		ep      *endpoint.Endpoint
+		invalid string // part of the reason, "" if valid
+	}{
+		{"A", endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.4", "5.6.7.8"), ""},
+		{"A with IPv6 target", endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "2001:db8::1"), "not an IPv4 address"},
+		{"A with hostname target", endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "host.example.com"), "not an IPv4 address"},
+		{"AAAA", endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeAAAA, "2001:db8::1"), ""},
+		{"AAAA with IPv4 target", endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeAAAA, "1.2.3.4"), "not an IPv6 address"},
+		{"AAAA with IPv4-mapped target", endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeAAAA, "::ffff:1.2.3.4"), "not an IPv6 address"},
+		{"AAAA with zone", endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeAAAA, "fe80::1%eth0"), "not an IPv6 address"},
+		{"AAAA not parsing", endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeAAAA, "2001:db8::1::2"), "not an IPv6 address"},
+		{"CNAME", endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeCNAME, "app.example.com"), ""},
+		{"CNAME with trailing dot", &endpoint.Endpoint{DNSName: "www.example.com", RecordType: endpoint.RecordTypeCNAME, Targets: endpoint.Targets{"app.example.com."}}, ""},
+		{"CNAME with two targets", endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeCNAME, "a.example.com", "b.example.com"), "single target"},
+		{"CNAME with URL target", endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeCNAME, "http://app.example.com"), "not a hostname"},
+		{"CNAME with empty label", endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeCNAME, "app..example.com"), "not a hostname"},
+		{"NS", endpoint.NewEndpoint("sub.example.com", endpoint.RecordTypeNS, "ns1.example.net"), ""},
+		{"NS with label starting with hyphen", endpoint.NewEndpoint("sub.example.com", endpoint.RecordTypeNS, "-ns1.example.net"), "not a hostname"},
+		{"MX", endpoint.NewEndpoint("example.com", endpoint.RecordTypeMX, "10 mail.example.com"), ""},
+		{"MX without preference", endpoint.NewEndpoint("example.com", endpoint.RecordTypeMX, "mail.example.com"), "<preference> <hostname>"},
+		{"MX with preference out of range", endpoint.NewEndpoint("example.com", endpoint.RecordTypeMX, "70000 mail.example.com"), "<preference> <hostname>"},
+		{"SRV", endpoint.NewEndpoint("_sip._tcp.example.com", endpoint.RecordTypeSRV, "10 5 5060 sip.example.com"), ""},
+		{"SRV without service", &endpoint.Endpoint{DNSName: "_sip._tcp.example.com", RecordType: endpoint.RecordTypeSRV, Targets: endpoint.Targets{"0 0 0 ."}}, ""},
+		{"SRV without port", endpoint.NewEndpoint("_sip._tcp.example.com", endpoint.RecordTypeSRV, "10 5 sip.example.com"), "<priority> <weight> <port> <hostname>"},
+		{"TXT", endpoint.NewEndpoint("example.com", endpoint.RecordTypeTXT, `"v=spf1 -all"`), ""},
+		{"TXT long value", endpoint.NewEndpoint("example.com", endpoint.RecordTypeTXT, strings.Repeat("a", 2048)), ""},
+		{"TXT too long", endpoint.NewEndpoint("example.com", endpoint.RecordTypeTXT, strings.Repeat("a", maxTXTValueLength+1)), "exceeds 4096 bytes"},
+		{"TXT long value with quotes", endpoint.NewEndpoint("example.com", endpoint.RecordTypeTXT, `a"b`+strings.Repeat("a", 300)), "cannot be split"},
+		{"wildcard name", endpoint.NewEndpoint("*.example.com", endpoint.RecordTypeA, "1.2.3.4"), ""},
+		{"invalid name", endpoint.NewEndpoint("app example.com", endpoint.RecordTypeA, "1.2.3.4"), "invalid DNS name"},
+		{"TTL", endpoint.NewEndpointWithTTL("app.example.com", endpoint.RecordTypeA, 3600, "1.2.3.4"), ""},
+		{"negative TTL", endpoint.NewEndpointWithTTL("app.example.com", endpoint.RecordTypeA, -1, "1.2.3.4"), "TTL -1"},
+		{"TTL too large", endpoint.NewEndpointWithTTL("app.example.com", endpoint.RecordTypeA, 1<<32, "1.2.3.4"), "outside the range"},
+		{"no targets", endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := validateEndpoint(tt.ep)
+			if tt.invalid == "" {
+				assert.Empty(t, reason)
+			} else {
+				assert.Contains(t, reason, tt.invalid)
+			}
+		})
+	}
+}
+
+func TestCNAMEConflicts(t *testing.T) {
+	cname := endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeCNAME, "app.example.com")
+	a := endpoint.NewEndpoint("WWW.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	txt := endpoint.NewEndpoint("other.example.com", endpoint.RecordTypeTXT, "x")
+	alone := endpoint.NewEndpoint("other.example.com", endpoint.RecordTypeCNAME, "app.example.com")
+
+	conflicts := cnameConflicts([]*endpoint.Endpoint{cname, a, txt, alone})
+	require.Len(t, conflicts, 1)
+	assert.Contains(t, conflicts[cname], "cannot coexist with other records at the name (A)")
+}
+
+// TestZoneCNAMEConflicts tests that a CNAME is not created at a name where the zone already has
+// records of another type, nor records of another type at the name of a CNAME
+func TestZoneCNAMEConflicts(t *testing.T) {
+	ownership := `"heritage=external-dns,external-dns/owner=test-owner"`
+	zone := func(records ...myrasec.DNSRecord) (*fakeMyraSecClient, *MyraSecDNSProvider) {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		client.records[123] = records
+		return client, newTestProvider(client)
+	}
+	types := func(client *fakeMyraSecClient) []string {
+		var types []string
+		for _, r := range dataRecords(client.records[123]) {
+			types = append(types, r.RecordType)
+		}
+		return types
+	}
+	cname := func() *endpoint.Endpoint {
+		return endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeCNAME, "app.example.com")
+	}
+	a := myrasec.DNSRecord{ID: 1, Name: "www.example.com", RecordType: endpoint.RecordTypeA, Value: "1.2.3.4", TTL: 300, Enabled: true}
+	mx := myrasec.DNSRecord{ID: 2, Name: "www.example.com", RecordType: endpoint.RecordTypeMX, Value: "10 mail.example.com", TTL: 300, Enabled: true}
+
+	t.Run("CNAME at a name with records is skipped", func(t *testing.T) {
+		client, p := zone(a, mx)
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{cname()}}))
+		assert.Equal(t, []string{endpoint.RecordTypeA, endpoint.RecordTypeMX}, types(client))
+	})
+
+	t.Run("CNAME at a name with records is refused", func(t *testing.T) {
+		client, p := zone(a, mx)
+		p.rejectInvalidEndpoints = true
+
+		err := p.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{
+			cname(),
+			endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.5"),
+		}})
+		var invalidErr *InvalidEndpointsError
+		require.ErrorAs(t, err, &invalidErr)
+		require.Len(t, invalidErr.Endpoints, 1)
+		assert.Equal(t, endpoint.RecordTypeCNAME, invalidErr.Endpoints[0].RecordType)
+		assert.Contains(t, invalidErr.Endpoints[0].Reason, "(A, MX in the zone)")
+		assert.Equal(t, []string{endpoint.RecordTypeA, endpoint.RecordTypeMX}, types(client), "nothing is applied")
+	})
+
+	t.Run("records at the name of a CNAME are skipped", func(t *testing.T) {
+		client, p := zone(
+			myrasec.DNSRecord{ID: 1, Name: "www.example.com", RecordType: endpoint.RecordTypeCNAME, Value: "app.example.com", TTL: 300, Enabled: true},
+		)
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeAAAA, "2001:db8::1"),
+		}}))
+		assert.Equal(t, []string{endpoint.RecordTypeCNAME}, types(client))
+	})
+
+	t.Run("records removed by the plan do not conflict", func(t *testing.T) {
+		client, p := zone(a, myrasec.DNSRecord{ID: 3, Name: "www.example.com", RecordType: endpoint.RecordTypeTXT, Value: ownership, TTL: 300, Enabled: true})
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+			Create: []*endpoint.Endpoint{cname()},
+			Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+		}))
+		assert.Equal(t, []string{endpoint.RecordTypeCNAME}, types(client))
+	})
+
+	t.Run("records replaced by the plan do not conflict", func(t *testing.T) {
+		client, p := zone(a, myrasec.DNSRecord{ID: 3, Name: "www.example.com", RecordType: endpoint.RecordTypeTXT, Value: ownership, TTL: 300, Enabled: true})
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+			UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+			UpdateNew: []*endpoint.Endpoint{cname()},
+		}))
+		assert.Equal(t, []string{endpoint.RecordTypeCNAME}, types(client))
+	})
+}
+
+func TestAdjustEndpointsDropsInvalid(t *testing.T) {
+	p := newTestProvider(newFakeMyraSecClient())
+
+	valid := endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	invalid := endpoint.NewEndpoint("bad.example.com", endpoint.RecordTypeA, "not-an-ip")
+
+	adjusted, err := p.AdjustEndpoints([]*endpoint.Endpoint{valid, invalid})
+	require.NoError(t, err)
+	assert.Equal(t, []*endpoint.Endpoint{valid}, adjusted)
+
+	p.rejectInvalidEndpoints = true
+	invalid = endpoint.NewEndpoint("bad.example.com", endpoint.RecordTypeA, "not-an-ip")
+	adjusted, err = p.AdjustEndpoints([]*endpoint.Endpoint{valid, invalid})
+	require.NoError(t, err)
+	assert.Len(t, adjusted, 2, "invalid endpoints are kept for ApplyChanges to refuse the plan")
+}
+
+func TestApplyChangesInvalidEndpoints(t *testing.T) {
+	changes := func() *plan.Changes {
+		return &plan.Changes{
+			Create: []*endpoint.Endpoint{
+				endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+				endpoint.NewEndpoint("bad.example.com", endpoint.RecordTypeAAAA, "1.2.3.4"),
+			},
+			UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("mail.example.com", endpoint.RecordTypeMX, "10 mx1.example.com")},
+			UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("mail.example.com", endpoint.RecordTypeMX, "mx2.example.com")},
+		}
+	}
+
+	t.Run("drop", func(t *testing.T) {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		p := newTestProvider(client)
+
+		require.NoError(t, p.ApplyChanges(context.Background(), changes()))
+
+		var created []string
+		for _, r := range client.records[123] {
+			created = append(created, r.Name+" "+r.RecordType)
+		}
+		assert.ElementsMatch(t, []string{"app.example.com A", "app.example.com TXT"}, created)
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		p := newTestProvider(client)
+		p.rejectInvalidEndpoints = true
+
+		err := p.ApplyChanges(context.Background(), changes())
+		require.ErrorIs(t, err, ErrInvalidEndpoints)
+
+		var invalidErr *InvalidEndpointsError
+		require.ErrorAs(t, err, &invalidErr)
+		require.Len(t, invalidErr.Endpoints, 2)
+		assert.Equal(t, "bad.example.com", invalidErr.Endpoints[0].DNSName)
+		assert.Contains(t, invalidErr.Endpoints[0].Reason, "not an IPv6 address")
+		assert.Equal(t, "mail.example.com", invalidErr.Endpoints[1].DNSName)
+		assert.Empty(t, client.records[123], "nothing is applied")
+	})
+}
+
+func TestApexCNAME(t *testing.T) {
+	apex := func() *endpoint.Endpoint {
+		return &endpoint.Endpoint{DNSName: "Example.com.", RecordType: endpoint.RecordTypeCNAME, Targets: endpoint.Targets{"lb.example.net"}}
+	}
+	www := endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeCNAME, "lb.example.net")
+
+	t.Run("reason", func(t *testing.T) {
+		assert.Contains(t, apexCNAMEReason(apex(), "example.com"), "CNAME record Example.com is at the apex of zone example.com")
+		assert.Contains(t, apexCNAMEReason(apex(), "example.com"), "AlternativeCNAME")
+		assert.Empty(t, apexCNAMEReason(www, "example.com"))
+		assert.Empty(t, apexCNAMEReason(endpoint.NewEndpoint("example.com", endpoint.RecordTypeA, "1.2.3.4"), "example.com"))
+		assert.Empty(t, apexCNAMEReason(apex(), ""))
+	})
+
+	t.Run("adjust endpoints", func(t *testing.T) {
+		p := newTestProvider(newFakeMyraSecClient())
+
+		adjusted, err := p.AdjustEndpoints([]*endpoint.Endpoint{apex(), www})
+		require.NoError(t, err)
+		assert.Equal(t, []*endpoint.Endpoint{www}, adjusted, "the domain filter names the apex before a domain is selected")
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		p := newTestProvider(client)
+
+		require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{apex()}}))
+		assert.Empty(t, client.records[123])
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+		p := newTestProvider(client)
+		p.rejectInvalidEndpoints = true
+
+		err := p.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{apex(), www}})
+		var invalidErr *InvalidEndpointsError
+		require.ErrorAs(t, err, &invalidErr)
+		require.Len(t, invalidErr.Endpoints, 1)
+		assert.Equal(t, endpoint.RecordTypeCNAME, invalidErr.Endpoints[0].RecordType)
+		assert.Contains(t, invalidErr.Endpoints[0].Reason, "AlternativeCNAME")
+		assert.Empty(t, client.records[123], "nothing is applied")
+	})
+}
@@ -0,0 +1,95 @@
+package myrasecprovider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// Ownership of a zone record as determined from the TXT record at its name
+const (
+	OwnershipOwned   = "owned"   // Owned by this instance
+	OwnershipForeign = "foreign" // Owned by another ExternalDNS instance
+	OwnershipNone    = "none"    // No ownership TXT record
+)
+
+// ZoneRecord is a record of a MyraSec zone as stored in MyraSec, before any filtering.
+type ZoneRecord struct {
+	ID        int    `json:"id"`
+	Domain    string `json:"domain"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Value     string `json:"value"`
+	TTL       int    `json:"ttl"`
+	Enabled   bool   `json:"enabled"`
+	Managed   bool   `json:"managed"`
+	Ownership string `json:"ownership"`
+}
+
+// ZoneRecords returns the records of every domain matching the domain filter, without the
+// filtering of Records. Each record carries the ownership the provider derives for its name
+// and whether it would be managed, i.e. its type is managed and its name passes the domain filter.
+func (p *MyraSecDNSProvider) ZoneRecords(ctx context.Context) ([]ZoneRecord, error) {
+	ctx = p.withClient(ctx)
+	domains, err := p.GetDomains()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []ZoneRecord
+	for _, domain := range domains {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		dnsRecords, err := p.listDNSRecords(ctx, &domain)
+		if err != nil {
+			p.logger.Error("Failed to list DNS records", zap.String("domain", domain.Name), zap.Error(err))
+			return nil, fmt.Errorf("failed listing records of %s: %w", domain.Name, apiError(err))
+		}
+
+		// The owners of every name, indexed by nameKey
+		owners := nameOwners(dnsRecords, domain.Name)
+
+		for _, r := range dnsRecords {
+			name := recordName(r.Name, domain.Name)
+			records = append(records, ZoneRecord{
+				ID:        r.ID,
+				Domain:    domain.Name,
+				Name:      name,
+				Type:      r.RecordType,
+				Value:     r.Value,
+				TTL:       r.TTL,
+				Enabled:   r.Enabled,
+				Managed:   p.managesRecordType(r.RecordType) && p.inDomainFilter(name),
+				Ownership: p.ownership(owners[nameKey(r.Name, domain.Name)]),
+			})
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Domain != records[j].Domain {
+			return records[i].Domain < records[j].Domain
+		}
+		if records[i].Name != records[j].Name {
+			return records[i].Name < records[j].Name
+		}
+		return records[i].Type < records[j].Type
+	})
+	return records, nil
+}
+
+// ownership classifies a name by the owners of its ownership TXT records
+func (p *MyraSecDNSProvider) ownership(owners []string) string {
+	switch {
+	case len(owners) == 0:
+		return OwnershipNone
+	case slices.Contains(owners, p.owner):
+		return OwnershipOwned
+	default:
+		return OwnershipForeign
+	}
+}
@@ -0,0 +1,52 @@
+package myrasecprovider
+
+import (
+	"context"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestZoneRecords(t *testing.T) {
+	client := newFakeMyraSecClient(myrasec.Domain{ID: 123, Name: "example.com"})
+	client.records[123] = []myrasec.DNSRecord{
+		{ID: 1, Name: "app.example.com", RecordType: endpoint.RecordTypeA, Value: "1.2.3.4", TTL: 300, Enabled: true},
+		{ID: 2, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: "heritage=external-dns,external-dns/owner=test-owner", TTL: 300, Enabled: true},
+		{ID: 3, Name: "other.example.com", RecordType: endpoint.RecordTypeCNAME, Value: "app.example.com", TTL: 300, Enabled: true},
+		{ID: 4, Name: "other.example.com", RecordType: endpoint.RecordTypeTXT, Value: `"heritage=external-dns,external-dns/owner=other"`, TTL: 300, Enabled: true},
+		{ID: 5, Name: "@", RecordType: endpoint.RecordTypeMX, Value: "mail.example.com", TTL: 3600, Enabled: true},
+		{ID: 6, Name: "mail.example.com", RecordType: "PTR", Value: "example.com", TTL: 300},
+	}
+	p := newTestProvider(client)
+
+	records, err := p.ZoneRecords(context.Background())
+	require.NoError(t, err)
+
+	got := map[string]ZoneRecord{}
+	for _, r := range records {
+		got[r.Name+" "+r.Type] = r
+	}
+	require.Len(t, got, 6)
+
+	assert.Equal(t, ZoneRecord{
+		ID: 1, Domain: "example.com", Name: "app.example.com", Type: endpoint.RecordTypeA, Value: "1.2.3.4",
+		TTL: 300, Enabled: true, Managed: true, Ownership: OwnershipOwned,
+	}, got["app.example.com A"])
+	assert.Equal(t, OwnershipOwned, got["app.example.com TXT"].Ownership)
+	assert.Equal(t, OwnershipForeign, got["other.example.com CNAME"].Ownership)
+	assert.Equal(t, OwnershipNone, got["example.com MX"].Ownership)
+	assert.False(t, got["mail.example.com PTR"].Managed)
+
+	// Records are sorted by name and type
+	var order []string
+	for _, r := range records {
+		order = append(order, r.Name+" "+r.Type)
+	}
+	assert.Equal(t, []string{
+		"app.example.com A", "app.example.com TXT", "example.com MX",
+		"mail.example.com PTR", "other.example.com CNAME", "other.example.com TXT",
+	}, order)
+}
@@ -0,0 +1,75 @@
+package myrasecprovider
+
+import (
+	"strings"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"go.uber.org/zap"
+)
+
+// selectZones returns the domains whose records are managed, the selected domain first. With a
+// domain filter every domain of the account matching the filter is managed, so that a delegated
+// subdomain registered as a MyraSec domain of its own is managed next to its parent. Without a
+// filter, or with a pinned domain, only the selected domain is.
+func (p *MyraSecDNSProvider) selectZones() ([]myrasec.Domain, error) {
+	selected, err := p.SelectDomain()
+	if err != nil {
+		return nil, err
+	}
+
+	zones := []myrasec.Domain{*selected}
+	if p.pinnedDomain == nil && len(p.domainFilter.Filters) > 0 {
+		domains, err := p.GetDomains()
+		if err != nil {
+			return nil, err
+		}
+		for _, domain := range domains {
+			if domain.ID == selected.ID || !p.inDomainFilter(domain.Name) {
+				continue
+			}
+			if domain.ID <= 0 {
+				p.logger.Warn("Ignoring domain without a valid ID",
+					zap.String("domain", domain.Name),
+					zap.Int("domain_id", domain.ID))
+				continue
+			}
+			zones = append(zones, domain)
+		}
+	}
+
+	p.zoneMu.Lock()
+	p.zones = zones
+	p.zoneMu.Unlock()
+
+	if len(zones) > 1 {
+		names := make([]string, 0, len(zones))
+		for _, zone := range zones {
+			names = append(names, zone.Name)
+		}
+		p.logger.Debug("Managing multiple domains", zap.Strings("domains", names))
+	}
+	return zones, nil
+}
+
+// knownZones returns the domains of the last selectZones.
+func (p *MyraSecDNSProvider) knownZones() []myrasec.Domain {
+	p.zoneMu.RLock()
+	defer p.zoneMu.RUnlock()
+	return p.zones
+}
+
+// zoneFor returns the most specific of the zones dnsName belongs to, so that a name in a delegated
+// subdomain goes to the subdomain rather than to its parent. A name in none of the zones, such as a
+// relative name, goes to the first zone. zones must not be empty.
+func zoneFor(zones []myrasec.Domain, dnsName string) myrasec.Domain {
+	name := normalizeDNSName(dnsName)
+	zone := zones[0]
+	longest := -1
+	for _, z := range zones {
+		zoneName := normalizeDNSName(z.Name)
+		if (name == zoneName || strings.HasSuffix(name, "."+zoneName)) && len(zoneName) > longest {
+			zone, longest = z, len(zoneName)
+		}
+	}
+	return zone
+}
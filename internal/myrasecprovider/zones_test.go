@@ -0,0 +1,109 @@
+package myrasecprovider
+
+import (
+	"context"
+	"testing"
+
+	myrasec "github.com/Myra-Security-GmbH/myrasec-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestZoneFor(t *testing.T) {
+	zones := []myrasec.Domain{
+		{ID: 1, Name: "example.com"},
+		{ID: 2, Name: "dev.example.com"},
+		{ID: 3, Name: "eu.dev.example.com"},
+	}
+
+	tests := []struct {
+		dnsName string
+		want    int
+	}{
+		{dnsName: "example.com", want: 1},
+		{dnsName: "app.example.com", want: 1},
+		{dnsName: "dev.example.com", want: 2},
+		{dnsName: "API.Dev.Example.com.", want: 2},
+		{dnsName: "app.eu.dev.example.com", want: 3},
+		{dnsName: "mydev.example.com", want: 1},
+		{dnsName: "app", want: 1},
+		{dnsName: "app.example.org", want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dnsName, func(t *testing.T) {
+			assert.Equal(t, tt.want, zoneFor(zones, tt.dnsName).ID)
+		})
+	}
+}
+
+// TestDelegatedSubdomainZones tests that a subdomain registered as a MyraSec domain of its own is
+// managed next to its parent, with every name in the most specific domain
+func TestDelegatedSubdomainZones(t *testing.T) {
+	ownership := "heritage=external-dns,external-dns/owner=test-owner"
+	client := newFakeMyraSecClient(
+		myrasec.Domain{ID: 1, Name: "example.com"},
+		myrasec.Domain{ID: 2, Name: "dev.example.com"},
+		myrasec.Domain{ID: 3, Name: "example.org"},
+	)
+	client.records[1] = []myrasec.DNSRecord{
+		{ID: 10, Name: "app.example.com", RecordType: endpoint.RecordTypeA, Value: "1.1.1.1", TTL: 300},
+		{ID: 11, Name: "app.example.com", RecordType: endpoint.RecordTypeTXT, Value: ownership, TTL: 300},
+		// A stale copy in the parent is shadowed by the delegated domain
+		{ID: 12, Name: "api.dev.example.com", RecordType: endpoint.RecordTypeA, Value: "9.9.9.9", TTL: 300},
+		{ID: 13, Name: "api.dev.example.com", RecordType: endpoint.RecordTypeTXT, Value: ownership, TTL: 300},
+	}
+	client.records[2] = []myrasec.DNSRecord{
+		{ID: 20, Name: "api.dev.example.com", RecordType: endpoint.RecordTypeA, Value: "2.2.2.2", TTL: 300},
+		{ID: 21, Name: "api.dev.example.com", RecordType: endpoint.RecordTypeTXT, Value: ownership, TTL: 300},
+	}
+	client.records[3] = []myrasec.DNSRecord{
+		{ID: 30, Name: "app.example.org", RecordType: endpoint.RecordTypeA, Value: "3.3.3.3", TTL: 300},
+		{ID: 31, Name: "app.example.org", RecordType: endpoint.RecordTypeTXT, Value: ownership, TTL: 300},
+	}
+	p := newTestProvider(client)
+
+	// Records aggregates the domains matching the filter
+	records, err := p.Records(context.Background())
+	require.NoError(t, err)
+	require.Len(t, records, 4, "A and ownership TXT of both names")
+	assert.Equal(t, endpoint.Targets{"1.1.1.1"}, findEndpoint(records, "app.example.com", endpoint.RecordTypeA).Targets)
+	assert.Equal(t, endpoint.Targets{"2.2.2.2"}, findEndpoint(records, "api.dev.example.com", endpoint.RecordTypeA).Targets)
+
+	// Changes go to the most specific domain of their name
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeA, "4.4.4.4"),
+			endpoint.NewEndpoint("web.dev.example.com", endpoint.RecordTypeA, "5.5.5.5"),
+		},
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("api.dev.example.com", endpoint.RecordTypeA, "2.2.2.2")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("api.dev.example.com", endpoint.RecordTypeA, "6.6.6.6")},
+	}))
+
+	assert.Len(t, p.findMatchingRecords(client.records[1], "example.com", "www.example.com", endpoint.RecordTypeA), 1)
+	assert.Empty(t, p.findMatchingRecords(client.records[1], "example.com", "web.dev.example.com", endpoint.RecordTypeA))
+	assert.Len(t, p.findMatchingRecords(client.records[2], "dev.example.com", "web.dev.example.com", endpoint.RecordTypeA), 1)
+	assert.Len(t, p.findMatchingRecords(client.records[2], "dev.example.com", "web.dev.example.com", endpoint.RecordTypeTXT), 1)
+
+	api := p.findMatchingRecords(client.records[2], "dev.example.com", "api.dev.example.com", endpoint.RecordTypeA)
+	require.Len(t, api, 1)
+	assert.Equal(t, "6.6.6.6", api[0].Value)
+	stale := p.findMatchingRecords(client.records[1], "example.com", "api.dev.example.com", endpoint.RecordTypeA)
+	require.Len(t, stale, 1)
+	assert.Equal(t, "9.9.9.9", stale[0].Value, "the parent domain is left alone")
+
+	// Deleting a name of the delegated domain removes it there
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("api.dev.example.com", endpoint.RecordTypeA, "6.6.6.6")},
+	}))
+	assert.Empty(t, p.findMatchingRecords(client.records[2], "dev.example.com", "api.dev.example.com", endpoint.RecordTypeA))
+	assert.Len(t, p.findMatchingRecords(client.records[1], "example.com", "api.dev.example.com", endpoint.RecordTypeA), 1)
+
+	// The apex of the delegated domain is an apex as well
+	invalid := p.invalidEndpoints([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("dev.example.com", endpoint.RecordTypeCNAME, "app.example.com"),
+	})
+	assert.Len(t, invalid, 1)
+}
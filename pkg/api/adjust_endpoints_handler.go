@@ -34,11 +34,8 @@ func (w webhook) AdjustEndpointsHandler(ctx *fiber.Ctx) error {
 				zap.String("fallback_error", fallbackErr.Error()),
 				zap.String("raw_body", string(body)))
 
-			ctx.Response().Header.Set(contentTypeHeader, contentTypePlaintext)
-			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   errors.ErrInvalidJSONFormat.Error(),
-				"details": "Request body could not be parsed as either structured format or array format",
-			})
+			return writeError(ctx, fiber.StatusBadRequest, newErrorResponse(errors.CodeInvalidJSON,
+				errors.ErrInvalidJSONFormat.Error(), fallbackErr))
 		}
 
 		w.logger.Debug("Parsed request using fallback array method",
@@ -51,11 +48,7 @@ func (w webhook) AdjustEndpointsHandler(ctx *fiber.Ctx) error {
 				zap.Error(err),
 				zap.String("error_type", "provider_error"))
 
-			ctx.Response().Header.Set(contentTypeHeader, contentTypePlaintext)
-			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":   errors.ErrAPIRequestFailed.Error(),
-				"details": err.Error(),
-			})
+			return providerError(ctx, err, "Failed to adjust endpoints")
 		}
 
 		w.logger.Debug("Adjusted endpoints successfully",
@@ -68,14 +61,14 @@ func (w webhook) AdjustEndpointsHandler(ctx *fiber.Ctx) error {
 		if err != nil {
 			w.logger.Error("Failed to marshal adjusted endpoints response",
 				zap.Error(err))
-			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":   "Failed to marshal adjusted endpoints response",
-				"details": err.Error(),
-			})
+			return writeError(ctx, fiber.StatusInternalServerError,
+				newErrorResponse(errors.CodeInternal, "Failed to marshal adjusted endpoints response", err))
 		}
 		return ctx.Send(response)
 	} else {
-		return errors.ErrInvalidJSONFormat
+		w.logger.Error("Refusing endpoints that are not sent as an array")
+		return writeError(ctx, fiber.StatusBadRequest, newErrorResponse(errors.CodeInvalidJSON,
+			errors.ErrInvalidJSONFormat.Error(), nil))
 	}
 
 }
@@ -3,78 +3,129 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"net"
 	"net/http"
-	"os"
-	"os/signal"
-	"strings"
-	"syscall"
-	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/gofiber/fiber/v2/middleware/compress"
 	"github.com/gofiber/fiber/v2/middleware/helmet"
 	fiberlogger "github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/pprof"
 	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 	"sigs.k8s.io/external-dns/provider"
 
+	"github.com/netguru/myra-external-dns-webhook/pkg/errors"
+
 	fiberrecover "github.com/gofiber/fiber/v2/middleware/recover"
 )
 
 type Api interface {
-	Listen(port string) error
+	Listen(address string) (<-chan error, error)
+	Shutdown(ctx context.Context) error
 	Test(req *http.Request, msTimeout ...int) (resp *http.Response, err error)
 }
 
 type api struct {
 	logger *zap.Logger
 	app    *fiber.App
+	config Config
+	// pprof serves the runtime profiles on their own listener, nil unless enabled
+	pprof *fiber.App
 }
 
 func (a api) Test(req *http.Request, msTimeout ...int) (resp *http.Response, err error) {
 	return a.app.Test(req, msTimeout...)
 }
 
-func (a api) Listen(address string) error {
+// Listen binds the given address and serves the webhook API on it in the background. A bind
+// failure, e.g. "address already in use", is returned right away. The channel receives the
+// outcome of serving once the server stopped, nil after Shutdown. Signal handling is left to the
+// caller, which stops the server through Shutdown. If enabled, the runtime profiles are served on
+// the pprof address as well.
+func (a api) Listen(address string) (<-chan error, error) {
+	ln, err := a.listen(address)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.listenPprof(); err != nil {
+		ln.Close()
+		return nil, err
+	}
+
+	served := make(chan error, 1)
 	go func() {
-		// Parse the address to ensure proper binding
-		listenAddress := address
-
-		// If the address starts with "localhost:", replace it with ":" to bind to all interfaces
-		if strings.HasPrefix(address, "localhost:") {
-			listenAddress = ":" + strings.Split(address, ":")[1]
-			a.logger.Info("Changed listen address from localhost to all interfaces",
-				zap.String("original", address),
-				zap.String("new", listenAddress))
-		} else if !strings.Contains(address, ":") {
-			// If no colon, assume it's just a port number
-			listenAddress = ":" + address
-		}
+		served <- a.app.Listener(ln)
+	}()
+	return served, nil
+}
 
-		a.logger.Debug("Starting server", zap.String("address", listenAddress))
-		err := a.app.Listen(listenAddress)
-		if err != nil {
-			a.logger.Fatal("Error starting the server", zap.String("error", err.Error()))
+// listen binds the listener of the server. The address is bound as given, so a localhost address
+// only accepts connections from the loopback interface.
+func (a api) listen(address string) (net.Listener, error) {
+	listenAddress, err := ParseListenAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	if host, port, _ := net.SplitHostPort(listenAddress); a.config.LocalhostAllInterfaces && host == "localhost" {
+		listenAddress = net.JoinHostPort("", port)
+		a.logger.Warn("Binding the localhost listen address to all interfaces",
+			zap.String("original", address),
+			zap.String("new", listenAddress))
+	}
+
+	ln, err := net.Listen("tcp", listenAddress)
+	if err != nil {
+		return nil, err
+	}
+	a.logger.Debug("Starting server", zap.String("address", ln.Addr().String()))
+	return ln, nil
+}
+
+// listenPprof binds the pprof address as given and serves the runtime profiles on it in the
+// background, if they are enabled
+func (a api) listenPprof() error {
+	if a.pprof == nil {
+		return nil
+	}
+	address, err := ParseListenAddress(a.config.PprofAddress)
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on the pprof address: %w", err)
+	}
+	a.logger.Warn("pprof endpoints are enabled",
+		zap.String("address", ln.Addr().String()),
+		zap.String("prefix", "/pprof"))
+
+	go func() {
+		if err := a.pprof.Listener(ln); err != nil {
+			a.logger.Error("pprof server stopped", zap.Error(err))
 		}
 	}()
+	return nil
+}
 
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
-	sig := <-sigCh
-
-	a.logger.Info(
-		"shutting down server due to received signal",
-		zap.String("signal", sig.String()),
-	)
+// Shutdown stops accepting new connections and waits for in-flight requests to complete,
+// or until the context is done.
+func (a api) Shutdown(ctx context.Context) error {
+	a.logger.Info("Shutting down server")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	if a.pprof != nil {
+		if err := a.pprof.ShutdownWithContext(ctx); err != nil {
+			a.logger.Warn("error shutting down pprof server", zap.Error(err))
+		}
+	}
 	err := a.app.ShutdownWithContext(ctx)
 	if err != nil {
 		a.logger.Error("error shutting down server", zap.String("error", err.Error()))
 	}
 
-	cancel()
-
 	return err
 }
 
@@ -83,14 +134,27 @@ type Provider interface {
 	provider.Provider
 }
 
+// New creates the webhook API server with the DefaultConfig.
 func New(logger *zap.Logger, provider provider.Provider) Api {
+	return NewWithConfig(logger, provider, DefaultConfig())
+}
+
+// NewWithConfig creates the webhook API server with the given server settings.
+func NewWithConfig(logger *zap.Logger, provider provider.Provider, config Config) Api {
+	logger.Info("HTTP server limits",
+		zap.Duration("read_timeout", config.ReadTimeout),
+		zap.Duration("write_timeout", config.WriteTimeout),
+		zap.Duration("idle_timeout", config.IdleTimeout),
+		zap.Int("max_body_size", config.MaxBodySize))
+
 	app := fiber.New(fiber.Config{
 		DisableStartupMessage: true,
 		JSONEncoder:           json.Marshal,
 		JSONDecoder:           json.Unmarshal,
-		ReadTimeout:           30 * time.Second,
-		WriteTimeout:          30 * time.Second,
-		IdleTimeout:           120 * time.Second,
+		ReadTimeout:           config.ReadTimeout,
+		WriteTimeout:          config.WriteTimeout,
+		IdleTimeout:           config.IdleTimeout,
+		BodyLimit:             config.MaxBodySize,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			logger.Error("Unhandled error in request",
 				zap.Error(err),
@@ -98,46 +162,57 @@ func New(logger *zap.Logger, provider provider.Provider) Api {
 				zap.String("method", c.Method()),
 				zap.String("ip", c.IP()))
 
-			code := fiber.StatusInternalServerError
-			if e, ok := err.(*fiber.Error); ok {
-				code = e.Code
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				return writeError(c, fiberErr.Code, newErrorResponse(statusCode(fiberErr.Code), fiberErr.Message, nil))
 			}
-
-			return c.Status(code).JSON(fiber.Map{
-				"error": err.Error(),
-			})
+			return providerError(c, err, "Internal server error")
 		},
 	})
 
+	webhookRoutes := webhook{
+		provider:         provider,
+		logger:           logger,
+		healthyThreshold: config.HealthyThreshold,
+	}
+
 	// Public health endpoint (no auth required)
-	app.Get("/healthz", Health)
+	app.Get("/healthz", webhookRoutes.Health)
+	app.Get("/readyz", webhookRoutes.Ready)
+	app.Get("/version", Version)
+	app.Get("/status", webhookRoutes.Status)
+	app.Get("/metrics", adaptor.HTTPHandler(promhttp.Handler()))
 
 	// Global middleware
+	app.Use(tracing())
 	app.Use(requestid.New())
 	app.Use(fiberlogger.New())
-	app.Use(pprof.New(pprof.Config{Prefix: "/pprof"}))
 	app.Use(fiberrecover.New())
 	app.Use(helmet.New())
 
-	webhookRoutes := webhook{
-		provider: provider,
-		logger:   logger,
-	}
-
 	// Create a group for authenticated routes
 	apiGroup := app.Group("/")
 
-	// Register routes with authentication
-	apiGroup.Get("/", webhookRoutes.GetDomainFilter)
-	apiGroup.Get("/records", webhookRoutes.Records)
-	apiGroup.Post("/records", webhookRoutes.ApplyChanges)
-	apiGroup.Post("/adjustendpoints", webhookRoutes.AdjustEndpointsHandler)
+	// Large record lists are compressed when the client accepts it
+	apiGroup.Use(compress.New())
 
-	// Add compatibility routes for ExternalDNS
-	apiGroup.Get("/webhook", webhookRoutes.GetDomainFilter)
+	// Register routes with authentication
+	negotiate := mediaTypes(logger, config.StrictMediaType)
+	apiGroup.Get("/", negotiate, webhookRoutes.GetDomainFilter)
+	apiGroup.Get("/records", negotiate, webhookRoutes.Records)
+	apiGroup.Post("/records", negotiate, webhookRoutes.ApplyChanges)
+	apiGroup.Post("/adjustendpoints", negotiate, webhookRoutes.AdjustEndpointsHandler)
 
-	return &api{
+	a := &api{
 		logger: logger,
 		app:    app,
+		config: config,
+	}
+	// Profiles expose internals of the process, so they are only served when enabled and on their
+	// own listener, which is bound to localhost by default
+	if config.EnablePprof {
+		a.pprof = fiber.New(fiber.Config{DisableStartupMessage: true})
+		a.pprof.Use(pprof.New(pprof.Config{Prefix: "/pprof"}))
 	}
+	return a
 }
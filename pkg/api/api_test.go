@@ -0,0 +1,871 @@
+package api
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+
+	"github.com/netguru/myra-external-dns-webhook/pkg/api/mock"
+	myraerrors "github.com/netguru/myra-external-dns-webhook/pkg/errors"
+	"github.com/netguru/myra-external-dns-webhook/pkg/redact"
+	"github.com/netguru/myra-external-dns-webhook/pkg/status"
+	"github.com/netguru/myra-external-dns-webhook/pkg/version"
+)
+
+// freeAddress returns a loopback address with a port that is currently unused
+func freeAddress(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// waitForServer polls the health endpoint until the server accepts connections
+func waitForServer(t *testing.T, address string) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		resp, err := http.Get("http://" + address + "/healthz")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestShutdownWaitsForInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	var completed atomic.Bool
+
+	provider := &mock.MockProvider{
+		ApplyChangesFn: func(ctx context.Context, changes *plan.Changes) error {
+			close(started)
+			time.Sleep(300 * time.Millisecond)
+			completed.Store(true)
+			return nil
+		},
+	}
+
+	app := New(zap.NewNop(), provider)
+	address := freeAddress(t)
+
+	serverErr, err := app.Listen(address)
+	require.NoError(t, err)
+	waitForServer(t, address)
+
+	type result struct {
+		status int
+		err    error
+	}
+	responses := make(chan result, 1)
+	go func() {
+		resp, err := http.Post("http://"+address+"/records", MediaTypeFormatAndVersion, strings.NewReader(`{"Create":[]}`))
+		if err != nil {
+			responses <- result{err: err}
+			return
+		}
+		resp.Body.Close()
+		responses <- result{status: resp.StatusCode}
+	}()
+
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, app.Shutdown(ctx))
+
+	res := <-responses
+	require.NoError(t, res.err)
+	assert.Equal(t, http.StatusNoContent, res.status)
+	assert.True(t, completed.Load(), "in-flight apply should have completed")
+
+	select {
+	case err := <-serverErr:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Listen did not return after Shutdown")
+	}
+}
+
+func TestVersionEndpoint(t *testing.T) {
+	app := New(zap.NewNop(), &mock.MockProvider{})
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/version", nil))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var info version.Info
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&info))
+	assert.Equal(t, version.Get(), info)
+}
+
+// statusProvider is a provider reporting a fixed sync status
+type statusProvider struct {
+	mock.MockProvider
+	status status.Sync
+}
+
+func (p *statusProvider) SyncStatus() status.Sync {
+	return p.status
+}
+
+func TestStatusEndpoint(t *testing.T) {
+	applied := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	app := New(zap.NewNop(), &statusProvider{status: status.Sync{
+		LastApply:                &applied,
+		LastSuccessfulApply:      &applied,
+		LastApplySucceeded:       true,
+		LastApplyDurationSeconds: 1.5,
+		LastApplyChanges:         map[string]int{"CREATE": 2, "UPDATE": 0, "DELETE": 1},
+		TotalChanges:             map[string]int{"CREATE": 5, "UPDATE": 1, "DELETE": 1},
+	}})
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/status", nil))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "2024-05-01T12:00:00Z", body["lastSuccessfulApply"])
+	assert.Equal(t, true, body["lastApplySucceeded"])
+	assert.Equal(t, 1.5, body["lastApplyDurationSeconds"])
+	assert.Equal(t, map[string]interface{}{"CREATE": 2.0, "UPDATE": 0.0, "DELETE": 1.0}, body["lastApplyChanges"])
+	assert.NotContains(t, body, "lastRecords")
+	assert.NotContains(t, body, "lastError")
+
+	t.Run("provider without status", func(t *testing.T) {
+		app := New(zap.NewNop(), &mock.MockProvider{})
+
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/status", nil))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		var body errorResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, "not_found", body.Code)
+	})
+}
+
+func TestHealthyThreshold(t *testing.T) {
+	now := time.Now()
+	ago := func(d time.Duration) *time.Time {
+		at := now.Add(-d)
+		return &at
+	}
+	lastError := &status.Error{Operation: "records", Message: "invalid credentials", Time: *ago(time.Minute)}
+
+	tests := []struct {
+		name       string
+		threshold  time.Duration
+		status     status.Sync
+		wantStatus int
+	}{
+		{
+			name:      "failing for longer than the threshold",
+			threshold: 30 * time.Minute,
+			status: status.Sync{
+				LastRecordsAttempt:  ago(time.Minute),
+				RecordsFailingSince: ago(2 * time.Hour),
+				LastError:           lastError,
+			},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:      "apply failing for longer than the threshold",
+			threshold: 30 * time.Minute,
+			status: status.Sync{
+				LastRecords:        ago(time.Minute),
+				LastRecordsAttempt: ago(time.Minute),
+				LastApply:          ago(time.Minute),
+				ApplyFailingSince:  ago(time.Hour),
+				LastError:          lastError,
+			},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:      "failing within the threshold",
+			threshold: 30 * time.Minute,
+			status: status.Sync{
+				LastRecordsAttempt:  ago(time.Minute),
+				RecordsFailingSince: ago(10 * time.Minute),
+				LastError:           lastError,
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:      "no recent attempts",
+			threshold: 30 * time.Minute,
+			status: status.Sync{
+				LastRecordsAttempt:  ago(2 * time.Hour),
+				RecordsFailingSince: ago(3 * time.Hour),
+				LastError:           lastError,
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "no attempts at all",
+			threshold:  30 * time.Minute,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "threshold disabled",
+			status: status.Sync{
+				LastRecordsAttempt:  ago(time.Minute),
+				RecordsFailingSince: ago(2 * time.Hour),
+				LastError:           lastError,
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.HealthyThreshold = tt.threshold
+			app := NewWithConfig(zap.NewNop(), &statusProvider{status: tt.status}, config)
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, "healthy", body["message"])
+				return
+			}
+			assert.Equal(t, "Unhealthy", body["error"])
+			assert.Equal(t, "unhealthy", body["code"])
+			assert.Contains(t, body["details"], "longer than the healthy threshold of 30m0s")
+			assert.Equal(t, "invalid credentials", body["lastError"].(map[string]interface{})["message"])
+		})
+	}
+}
+
+type readinessProvider struct {
+	mock.MockProvider
+	err error
+}
+
+func (p *readinessProvider) Ready() error {
+	return p.err
+}
+
+func TestReady(t *testing.T) {
+	for name, tt := range map[string]struct {
+		provider   provider.Provider
+		wantStatus int
+		wantBody   string
+	}{
+		"ready": {
+			provider:   &readinessProvider{},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"message":"ready"}`,
+		},
+		"not ready": {
+			provider:   &readinessProvider{err: fmt.Errorf("%w: the domain filter example.net matches none of the domains of the account (available: example.com)", myraerrors.ErrDomainNotFound)},
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   `{"error":"Not ready","code":"not_ready","details":"the webhook is not ready: domain not found: the domain filter example.net matches none of the domains of the account (available: example.com)"}`,
+		},
+		"without readiness check": {
+			provider:   &mock.MockProvider{},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"message":"ready"}`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			app := New(zap.NewNop(), tt.provider)
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.wantBody, string(body))
+		})
+	}
+}
+
+func TestErrorDetailsAreRedacted(t *testing.T) {
+	redact.Add("s3cr3t-api-secret")
+	t.Cleanup(redact.Default().Reset)
+
+	provider := &mock.MockProvider{
+		RecordsFn: func(ctx context.Context) ([]*endpoint.Endpoint, error) {
+			return nil, errors.New("request signed with s3cr3t-api-secret was rejected")
+		},
+	}
+	app := New(zap.NewNop(), provider)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/records", nil))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.NotContains(t, string(body), "s3cr3t-api-secret")
+	assert.Contains(t, string(body), "request signed with [REDACTED] was rejected")
+}
+
+func TestProviderErrorStatus(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"domain not found", myraerrors.ErrDomainNotFound, http.StatusNotFound, myraerrors.CodeDomainNotFound},
+		{"authentication failed", fmt.Errorf("failed to list domains: %w", myraerrors.ErrAuthenticationFailed), http.StatusUnauthorized, myraerrors.CodeAuthenticationFailed},
+		{"rate limited", fmt.Errorf("%w: Too Many Requests (429)", myraerrors.ErrRateLimited), http.StatusTooManyRequests, myraerrors.CodeRateLimited},
+		{"validation error", &myraerrors.ChangesError{Failures: []*myraerrors.ChangeError{
+			{Action: "CREATE", DNSName: "app.example.com", RecordType: "A", Err: myraerrors.ErrValidation},
+		}}, http.StatusUnprocessableEntity, myraerrors.CodeValidation},
+		{"API failure", fmt.Errorf("%w: Bad Gateway (502)", myraerrors.ErrAPIRequestFailed), http.StatusBadGateway, myraerrors.CodeAPIRequestFailed},
+		{"other error", errors.New("boom"), http.StatusInternalServerError, myraerrors.CodeInternal},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &mock.MockProvider{
+				RecordsFn: func(ctx context.Context) ([]*endpoint.Endpoint, error) {
+					return nil, tt.err
+				},
+				ApplyChangesFn: func(ctx context.Context, changes *plan.Changes) error {
+					return tt.err
+				},
+			}
+			app := New(zap.NewNop(), provider)
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/records", nil))
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, tt.status, resp.StatusCode, "GET /records")
+
+			resp, err = app.Test(httptest.NewRequest(http.MethodPost, "/records", strings.NewReader(`{"Create":[]}`)))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tt.status, resp.StatusCode, "POST /records")
+
+			assert.Equal(t, fiber.MIMEApplicationJSON, resp.Header.Get("Content-Type"))
+
+			var body errorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.NotEmpty(t, body.Error)
+			assert.Equal(t, tt.code, body.Code)
+			assert.Equal(t, tt.err.Error(), body.Details)
+		})
+	}
+}
+
+func TestApplyChangesChangeLimit(t *testing.T) {
+	provider := &mock.MockProvider{
+		ApplyChangesFn: func(ctx context.Context, changes *plan.Changes) error {
+			return &myraerrors.ChangeLimitError{Kind: "deletions", Count: 120, Limit: 50}
+		},
+	}
+	app := New(zap.NewNop(), provider)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/records", strings.NewReader(`{"Delete":[]}`)))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	var body struct {
+		Error   string `json:"error"`
+		Code    string `json:"code"`
+		Details string `json:"details"`
+		Limit   string `json:"limit"`
+		Count   int    `json:"count"`
+		Max     int    `json:"max"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "Plan refused, it exceeds the change limit", body.Error)
+	assert.Equal(t, myraerrors.CodeChangeLimitExceeded, body.Code)
+	assert.Equal(t, "plan exceeds the change limit: plan has 120 deletions, at most 50 are allowed per sync", body.Details)
+	assert.Equal(t, "deletions", body.Limit)
+	assert.Equal(t, 120, body.Count)
+	assert.Equal(t, 50, body.Max)
+}
+
+func TestApplyChangesInvalidEndpoints(t *testing.T) {
+	provider := &mock.MockProvider{
+		ApplyChangesFn: func(ctx context.Context, changes *plan.Changes) error {
+			return &myraerrors.InvalidEndpointsError{Endpoints: []myraerrors.InvalidEndpoint{
+				{DNSName: "bad.example.com", RecordType: "AAAA", Reason: `target "1.2.3.4" is not an IPv6 address`},
+			}}
+		},
+	}
+	app := New(zap.NewNop(), provider)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/records", strings.NewReader(`{"Create":[]}`)))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	var body struct {
+		Error   string                       `json:"error"`
+		Invalid []myraerrors.InvalidEndpoint `json:"invalid"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "Plan refused, it has invalid endpoints", body.Error)
+	require.Len(t, body.Invalid, 1)
+	assert.Equal(t, "bad.example.com", body.Invalid[0].DNSName)
+	assert.Equal(t, "AAAA", body.Invalid[0].RecordType)
+}
+
+// TestApplyChangesPartialFailure tests that the error response lists the failed changes of a plan
+func TestApplyChangesPartialFailure(t *testing.T) {
+	redact.Add("s3cr3t-api-secret")
+	t.Cleanup(redact.Default().Reset)
+
+	provider := &mock.MockProvider{
+		ApplyChangesFn: func(ctx context.Context, changes *plan.Changes) error {
+			return &myraerrors.ChangesError{Failures: []*myraerrors.ChangeError{
+				{Action: "CREATE", DNSName: changes.Create[1].DNSName, RecordType: "A", Err: errors.New("request signed with s3cr3t-api-secret timed out")},
+				{Action: "DELETE", DNSName: changes.Delete[0].DNSName, RecordType: "CNAME", Err: errors.New("connection reset")},
+			}}
+		},
+	}
+	app := New(zap.NewNop(), provider)
+
+	changes := `{
+		"Create": [
+			{"dnsName": "a.example.com", "recordType": "A", "targets": ["1.1.1.1"]},
+			{"dnsName": "b.example.com", "recordType": "A", "targets": ["2.2.2.2"]},
+			{"dnsName": "c.example.com", "recordType": "A", "targets": ["3.3.3.3"]}
+		],
+		"UpdateOld": [{"dnsName": "d.example.com", "recordType": "A", "targets": ["4.4.4.4"]}],
+		"UpdateNew": [{"dnsName": "d.example.com", "recordType": "A", "targets": ["5.5.5.5"]}],
+		"Delete": [{"dnsName": "e.example.com", "recordType": "CNAME", "targets": ["a.example.com"]}]
+	}`
+	resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/records", strings.NewReader(changes)))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode, "ExternalDNS retries the plan")
+
+	raw, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "s3cr3t-api-secret")
+
+	var body struct {
+		Error    string          `json:"error"`
+		Total    int             `json:"total"`
+		Failed   int             `json:"failed"`
+		Failures []changeFailure `json:"failures"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &body))
+	assert.Equal(t, "Failed to apply DNS changes", body.Error)
+	assert.Equal(t, 5, body.Total)
+	assert.Equal(t, 2, body.Failed)
+	assert.Equal(t, []changeFailure{
+		{DNSName: "b.example.com", RecordType: "A", Action: "CREATE", Error: "request signed with [REDACTED] timed out"},
+		{DNSName: "e.example.com", RecordType: "CNAME", Action: "DELETE", Error: "connection reset"},
+	}, body.Failures)
+}
+
+func TestChangeFailuresCapped(t *testing.T) {
+	failures := make([]*myraerrors.ChangeError, maxReportedFailures+10)
+	for i := range failures {
+		failures[i] = &myraerrors.ChangeError{Action: "CREATE", DNSName: fmt.Sprintf("app%d.example.com", i), RecordType: "A", Err: errors.New("boom")}
+	}
+	list := changeFailures(failures)
+	require.Len(t, list, maxReportedFailures)
+	assert.Equal(t, "app0.example.com", list[0].DNSName)
+}
+
+func TestApplyChangesInProgress(t *testing.T) {
+	provider := &mock.MockProvider{
+		ApplyChangesFn: func(ctx context.Context, changes *plan.Changes) error {
+			return myraerrors.ErrApplyInProgress
+		},
+	}
+	app := New(zap.NewNop(), provider)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/records", strings.NewReader(`{"Create":[]}`)))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "5", resp.Header.Get("Retry-After"))
+
+	var body errorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, myraerrors.CodeApplyInProgress, body.Code)
+}
+
+func TestApplyChangesShuttingDown(t *testing.T) {
+	provider := &mock.MockProvider{
+		ApplyChangesFn: func(ctx context.Context, changes *plan.Changes) error {
+			return myraerrors.ErrShuttingDown
+		},
+	}
+	app := New(zap.NewNop(), provider)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/records", strings.NewReader(`{"Create":[]}`)))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "5", resp.Header.Get("Retry-After"))
+}
+
+func TestTracingContinuesIncomingTrace(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	var providerSpan trace.SpanContext
+	provider := &mock.MockProvider{
+		ApplyChangesFn: func(ctx context.Context, changes *plan.Changes) error {
+			providerSpan = trace.SpanContextFromContext(ctx)
+			return nil
+		},
+	}
+	app := New(zap.NewNop(), provider)
+
+	req := httptest.NewRequest(http.MethodPost, "/records", strings.NewReader(`{"Create":[]}`))
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "POST /records", span.Name)
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent.SpanID().String())
+	assert.True(t, span.Parent.IsRemote())
+	assert.Contains(t, span.Attributes, attribute.Int("http.response.status_code", http.StatusNoContent))
+
+	// The provider runs in the span of the request
+	assert.Equal(t, span.SpanContext.SpanID(), providerSpan.SpanID())
+}
+
+func TestGetDomainFilterContract(t *testing.T) {
+	for name, tt := range map[string]struct {
+		filter endpoint.DomainFilter
+		want   string
+	}{
+		"no filter": {
+			want: `{"include":[],"exclude":[]}`,
+		},
+		"include": {
+			filter: endpoint.NewDomainFilter([]string{"example.org", "example.com"}),
+			want:   `{"include":["example.com","example.org"],"exclude":[]}`,
+		},
+		"exclusions": {
+			filter: endpoint.NewDomainFilterWithExclusions([]string{"example.com"}, []string{"internal.example.com"}),
+			want:   `{"include":["example.com"],"exclude":["internal.example.com"]}`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			app := New(zap.NewNop(), &mock.MockProvider{DomainFilter: tt.filter})
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, MediaTypeFormatAndVersion, resp.Header.Get("Content-Type"))
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(body))
+
+			// The response always carries both lists
+			var raw map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(body, &raw))
+			assert.NotEqual(t, "null", string(raw["include"]))
+			assert.NotEqual(t, "null", string(raw["exclude"]))
+
+			// ExternalDNS reads the response into an endpoint.DomainFilter
+			var got endpoint.DomainFilter
+			require.NoError(t, json.Unmarshal(body, &got))
+			for _, domain := range []string{"example.com", "app.example.com", "db.internal.example.com", "example.org", "example.net"} {
+				assert.Equal(t, tt.filter.Match(domain), got.Match(domain), domain)
+			}
+		})
+	}
+}
+
+func TestWebhookAliasRemoved(t *testing.T) {
+	app := New(zap.NewNop(), &mock.MockProvider{DomainFilter: endpoint.NewDomainFilter([]string{"example.com"})})
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/webhook", nil))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "ExternalDNS only negotiates on /")
+}
+
+func TestMaxBodySize(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxBodySize = 64
+	app := NewWithConfig(zap.NewNop(), &mock.MockProvider{}, config)
+	address := freeAddress(t)
+	_, err := app.Listen(address)
+	require.NoError(t, err)
+	defer app.Shutdown(context.Background())
+	waitForServer(t, address)
+
+	resp, err := http.Post("http://"+address+"/records", MediaTypeFormatAndVersion, strings.NewReader(`{"Create":[]}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, err = http.Post("http://"+address+"/records", MediaTypeFormatAndVersion, strings.NewReader(`{"Create":[`+strings.Repeat(" ", 128)+`]}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	assert.Equal(t, fiber.MIMEApplicationJSON, resp.Header.Get("Content-Type"))
+
+	var body errorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "request_entity_too_large", body.Code)
+	assert.NotEmpty(t, body.Error)
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.NoError(t, DefaultConfig().Validate())
+
+	config := DefaultConfig()
+	config.WriteTimeout = 0
+	assert.ErrorContains(t, config.Validate(), "write timeout")
+
+	config = DefaultConfig()
+	config.MaxBodySize = -1
+	assert.ErrorContains(t, config.Validate(), "max body size")
+}
+
+func TestListenBindsRequestedAddress(t *testing.T) {
+	a := NewWithConfig(zap.NewNop(), &mock.MockProvider{}, DefaultConfig()).(*api)
+
+	ln, err := a.listen("localhost:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	host, _, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+	assert.True(t, net.ParseIP(host).IsLoopback(), "localhost should bind to loopback only, got %s", ln.Addr())
+
+	ln2, err := a.listen("127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln2.Close()
+	assert.Equal(t, "127.0.0.1", ln2.Addr().(*net.TCPAddr).IP.String())
+}
+
+func TestListenReturnsBindError(t *testing.T) {
+	address := freeAddress(t)
+	first := New(zap.NewNop(), &mock.MockProvider{})
+	_, err := first.Listen(address)
+	require.NoError(t, err)
+	defer first.Shutdown(context.Background())
+
+	second := New(zap.NewNop(), &mock.MockProvider{})
+	served, err := second.Listen(address)
+	assert.ErrorContains(t, err, "address already in use")
+	assert.Nil(t, served)
+}
+
+func TestListenLocalhostAllInterfacesOptIn(t *testing.T) {
+	config := DefaultConfig()
+	config.LocalhostAllInterfaces = true
+	a := NewWithConfig(zap.NewNop(), &mock.MockProvider{}, config).(*api)
+
+	ln, err := a.listen("localhost:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	assert.True(t, ln.Addr().(*net.TCPAddr).IP.IsUnspecified(), "expected all interfaces, got %s", ln.Addr())
+}
+
+func TestListenIPv6Loopback(t *testing.T) {
+	a := NewWithConfig(zap.NewNop(), &mock.MockProvider{}, DefaultConfig()).(*api)
+
+	ln, err := a.listen("[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback not available: %v", err)
+	}
+	defer ln.Close()
+	assert.Equal(t, "::1", ln.Addr().(*net.TCPAddr).IP.String())
+}
+
+func TestListenRejectsMalformedAddress(t *testing.T) {
+	a := NewWithConfig(zap.NewNop(), &mock.MockProvider{}, DefaultConfig()).(*api)
+
+	_, err := a.listen("::1:8888")
+	assert.ErrorContains(t, err, "invalid listen address")
+}
+
+func TestPprofEndpoints(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("enabled=%t", enabled), func(t *testing.T) {
+			config := DefaultConfig()
+			config.EnablePprof = enabled
+			a := NewWithConfig(zap.NewNop(), &mock.MockProvider{}, config).(*api)
+
+			resp, err := a.Test(httptest.NewRequest(http.MethodGet, "/pprof/debug/pprof/", nil))
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusNotFound, resp.StatusCode, "never served on the webhook listener")
+
+			if !enabled {
+				assert.Nil(t, a.pprof)
+				return
+			}
+			resp, err = a.pprof.Test(httptest.NewRequest(http.MethodGet, "/pprof/debug/pprof/", nil))
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		})
+	}
+}
+
+func TestPprofListener(t *testing.T) {
+	config := DefaultConfig()
+	config.EnablePprof = true
+
+	t.Run("serves the profiles on the pprof address", func(t *testing.T) {
+		config.PprofAddress = freeAddress(t)
+		a := NewWithConfig(zap.NewNop(), &mock.MockProvider{}, config)
+		served, err := a.Listen(freeAddress(t))
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, a.Shutdown(context.Background()))
+			<-served
+		})
+
+		require.Eventually(t, func() bool {
+			resp, err := http.Get("http://" + config.PprofAddress + "/pprof/debug/pprof/")
+			if err != nil {
+				return false
+			}
+			resp.Body.Close()
+			return resp.StatusCode == http.StatusOK
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("pprof address in use", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer ln.Close()
+		config.PprofAddress = ln.Addr().String()
+
+		_, err = NewWithConfig(zap.NewNop(), &mock.MockProvider{}, config).Listen(freeAddress(t))
+		assert.ErrorContains(t, err, "pprof address")
+	})
+}
+
+func TestRecordsCompression(t *testing.T) {
+	var records []*endpoint.Endpoint
+	for i := 0; i < 50; i++ {
+		records = append(records, endpoint.NewEndpoint(fmt.Sprintf("host%d.example.com", i), endpoint.RecordTypeA, "192.0.2.1"))
+	}
+	app := New(zap.NewNop(), &mock.MockProvider{
+		RecordsFn: func(ctx context.Context) ([]*endpoint.Endpoint, error) {
+			return records, nil
+		},
+	})
+	expected, err := json.Marshal(records)
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		name           string
+		acceptEncoding string
+		wantEncoding   string
+	}{
+		{name: "without Accept-Encoding"},
+		{name: "with gzip", acceptEncoding: "gzip", wantEncoding: "gzip"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/records", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, MediaTypeFormatAndVersion, resp.Header.Get("Content-Type"))
+			assert.Equal(t, tt.wantEncoding, resp.Header.Get("Content-Encoding"))
+			assert.Equal(t, []string{"Accept-Encoding"}, resp.Header.Values("Vary"))
+
+			var body io.Reader = resp.Body
+			if tt.wantEncoding == "gzip" {
+				body, err = gzip.NewReader(resp.Body)
+				require.NoError(t, err)
+			}
+			content, err := io.ReadAll(body)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(expected), string(content))
+		})
+	}
+}
+
+func TestAdjustEndpointsErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		body   string
+		err    error
+		status int
+		code   string
+	}{
+		{"malformed body", `[{"dnsName":`, nil, http.StatusBadRequest, myraerrors.CodeInvalidJSON},
+		{"endpoints not sent as an array", `{"endpoints":[]}`, nil, http.StatusBadRequest, myraerrors.CodeInvalidJSON},
+		{"provider error", `[]`, fmt.Errorf("%w: Bad Gateway (502)", myraerrors.ErrAPIRequestFailed), http.StatusBadGateway, myraerrors.CodeAPIRequestFailed},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &mock.MockProvider{
+				AdjustEndpointsFn: func(endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+					return nil, tt.err
+				},
+			}
+			app := New(zap.NewNop(), provider)
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/adjustendpoints", strings.NewReader(tt.body)))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tt.status, resp.StatusCode)
+			assert.Equal(t, fiber.MIMEApplicationJSON, resp.Header.Get("Content-Type"))
+
+			var body errorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.NotEmpty(t, body.Error)
+			assert.Equal(t, tt.code, body.Code)
+		})
+	}
+}
@@ -8,8 +8,16 @@ import (
 	"sigs.k8s.io/external-dns/plan"
 
 	"github.com/netguru/myra-external-dns-webhook/pkg/errors"
+	"github.com/netguru/myra-external-dns-webhook/pkg/redact"
 )
 
+// applyRetryAfter is the Retry-After in seconds sent when changes are refused because another
+// apply is in progress or the webhook is shutting down
+const applyRetryAfter = "5"
+
+// maxReportedFailures caps the failed changes listed in the error response of ApplyChanges
+const maxReportedFailures = 50
+
 func (w webhook) ApplyChanges(ctx *fiber.Ctx) error {
 	w.logger.Info("ApplyChanges endpoint called",
 		zap.String("remote_ip", ctx.IP()),
@@ -30,9 +38,8 @@ func (w webhook) ApplyChanges(ctx *fiber.Ctx) error {
 		if err := json.Unmarshal(body, &endpoints); err != nil {
 			w.logger.Error("Failed to parse request body as either plan.Changes or array of endpoints",
 				zap.String(logFieldError, err.Error()))
-			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": errors.ErrInvalidJSONFormat.Error(),
-			})
+			return writeError(ctx, fiber.StatusBadRequest,
+				newErrorResponse(errors.CodeInvalidJSON, errors.ErrInvalidJSONFormat.Error(), err))
 		}
 
 		// Successfully parsed as array of endpoints
@@ -47,37 +54,64 @@ func (w webhook) ApplyChanges(ctx *fiber.Ctx) error {
 		zap.Int("update_count", len(changes.UpdateNew)),
 	)
 
-	if err := w.provider.ApplyChanges(ctx.Context(), &changes); err != nil {
+	if err := w.provider.ApplyChanges(ctx.UserContext(), &changes); err != nil {
 		w.logger.Error("Failed to apply changes",
 			zap.String(logFieldError, err.Error()))
 
-		switch {
-		case err == errors.ErrMissingAPIKey:
-			return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "API key is required",
-			})
-		case err == errors.ErrMissingAPISecret:
-			return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "API secret is required",
-			})
-		case err == errors.ErrDomainNotFound:
-			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Domain not found",
-			})
-		case err == errors.ErrAPIRequestFailed:
-			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "API request to MyraSec failed",
-			})
-		default:
-			// For other errors, return a generic error message
-			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":   "Failed to apply DNS changes",
-				"details": err.Error(),
-			})
+		status, message := providerErrorStatus(err, "Failed to apply DNS changes")
+		response := applyErrorResponse{
+			errorResponse: newErrorResponse(errors.Code(err), message, err),
+		}
+		if status == fiber.StatusConflict || status == fiber.StatusServiceUnavailable {
+			ctx.Set(fiber.HeaderRetryAfter, applyRetryAfter)
+		}
+		var limitErr *errors.ChangeLimitError
+		if errors.As(err, &limitErr) {
+			response.Limit = limitErr.Kind
+			response.Count = limitErr.Count
+			response.Max = limitErr.Limit
+		}
+		var invalidErr *errors.InvalidEndpointsError
+		if errors.As(err, &invalidErr) {
+			response.Invalid = invalidErr.Endpoints
+		}
+		var ownerErr *errors.OwnerMismatchError
+		if errors.As(err, &ownerErr) {
+			response.Invalid = ownerErr.Endpoints
 		}
+		var changesErr *errors.ChangesError
+		if errors.As(err, &changesErr) {
+			response.Total = len(changes.Create) + len(changes.UpdateNew) + len(changes.Delete)
+			response.Failed = len(changesErr.Failures)
+			response.Failures = changeFailures(changesErr.Failures)
+		}
+		var timeoutErr *errors.ApplyTimeoutError
+		if errors.As(err, &timeoutErr) {
+			response.Applied = timeoutErr.Applied
+			response.NotApplied = timeoutErr.NotApplied
+		}
+		return writeError(ctx, status, response)
 	}
 
 	ctx.Response().Header.Set("Content-Type", MediaTypeFormatAndVersion)
 	ctx.Status(fiber.StatusNoContent)
 	return nil
 }
+
+// changeFailures lists the failed changes for the error response, at most maxReportedFailures,
+// with the secrets redacted from their errors
+func changeFailures(failures []*errors.ChangeError) []changeFailure {
+	if len(failures) > maxReportedFailures {
+		failures = failures[:maxReportedFailures]
+	}
+	list := make([]changeFailure, 0, len(failures))
+	for _, f := range failures {
+		list = append(list, changeFailure{
+			DNSName:    f.DNSName,
+			RecordType: f.RecordType,
+			Action:     f.Action,
+			Error:      redact.Error(f.Err),
+		})
+	}
+	return list
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+// Defaults of the HTTP server limits.
+const (
+	DefaultReadTimeout  = 30 * time.Second
+	DefaultWriteTimeout = 30 * time.Second
+	DefaultIdleTimeout  = 120 * time.Second
+	DefaultMaxBodySize  = 4 * 1024 * 1024
+)
+
+// DefaultPprofAddress is the address the runtime profiles are served on when enabled, only
+// reachable from the pod itself.
+const DefaultPprofAddress = "localhost:6060"
+
+// Config holds the settings of the HTTP server.
+type Config struct {
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	MaxBodySize  int
+	// LocalhostAllInterfaces restores the former behavior of binding a localhost:<port> listen
+	// address to all interfaces.
+	LocalhostAllInterfaces bool
+	// EnablePprof serves the runtime profiles below /pprof/debug/pprof/ on PprofAddress, never on
+	// the webhook listener.
+	EnablePprof  bool
+	PprofAddress string
+	// StrictMediaType rejects webhook requests without a Content-Type or Accept header. Requests
+	// with a wrong media type are always rejected.
+	StrictMediaType bool
+	// HealthyThreshold makes /healthz report 503 when the provider was asked to sync within the
+	// threshold, but Records or ApplyChanges have kept failing for longer. 0 disables the check.
+	HealthyThreshold time.Duration
+}
+
+// DefaultConfig returns the server settings used by New.
+func DefaultConfig() Config {
+	return Config{
+		ReadTimeout:  DefaultReadTimeout,
+		WriteTimeout: DefaultWriteTimeout,
+		IdleTimeout:  DefaultIdleTimeout,
+		MaxBodySize:  DefaultMaxBodySize,
+		PprofAddress: DefaultPprofAddress,
+	}
+}
+
+// Validate checks that all limits are set.
+func (c Config) Validate() error {
+	for name, timeout := range map[string]time.Duration{
+		"read timeout":  c.ReadTimeout,
+		"write timeout": c.WriteTimeout,
+		"idle timeout":  c.IdleTimeout,
+	} {
+		if timeout <= 0 {
+			return fmt.Errorf("HTTP %s must be positive, got %s", name, timeout)
+		}
+	}
+	if c.MaxBodySize <= 0 {
+		return fmt.Errorf("HTTP max body size must be positive, got %d", c.MaxBodySize)
+	}
+	if c.HealthyThreshold < 0 {
+		return fmt.Errorf("healthy threshold must not be negative, got %s", c.HealthyThreshold)
+	}
+	if c.EnablePprof {
+		if _, err := ParseListenAddress(c.PprofAddress); err != nil {
+			return fmt.Errorf("invalid pprof address: %w", err)
+		}
+	}
+	return nil
+}
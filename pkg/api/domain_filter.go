@@ -2,11 +2,22 @@ package api
 
 import (
 	"encoding/json"
+
 	"github.com/gofiber/fiber/v2"
-	"github.com/netguru/myra-external-dns-webhook/pkg/errors"
 	"go.uber.org/zap"
+
+	"github.com/netguru/myra-external-dns-webhook/pkg/errors"
 )
 
+// domainFilterResponse is the negotiation response in the format ExternalDNS unmarshals into its
+// endpoint.DomainFilter. The include and exclude lists are always present, empty when unset.
+type domainFilterResponse struct {
+	Include      []string `json:"include"`
+	Exclude      []string `json:"exclude"`
+	RegexInclude string   `json:"regexInclude,omitempty"`
+	RegexExclude string   `json:"regexExclude,omitempty"`
+}
+
 func (w webhook) GetDomainFilter(ctx *fiber.Ctx) error {
 	w.logger.Info("GetDomainFilter endpoint called",
 		zap.String("remote_ip", ctx.IP()),
@@ -15,25 +26,37 @@ func (w webhook) GetDomainFilter(ctx *fiber.Ctx) error {
 		zap.String("user_agent", string(ctx.Request().Header.UserAgent())),
 		zap.String("request_id", ctx.GetRespHeader("X-Request-ID", "-")))
 
-	// Get domain filter from the provider
-	domainFilterInterface, err := json.Marshal(w.provider.GetDomainFilter())
+	body, err := w.domainFilterJSON()
 	if err != nil {
 		w.logger.Error("Failed to marshal domain filter response",
 			zap.Error(err))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to marshal domain filter response",
-			"details": err.Error(),
-		})
+		return writeError(ctx, fiber.StatusInternalServerError,
+			newErrorResponse(errors.CodeInternal, "Failed to marshal domain filter response", err))
 	}
+	ctx.Response().Header.Set("Content-Type", MediaTypeFormatAndVersion)
+
+	return ctx.Send(body)
+}
 
-	if domainFilterInterface == nil {
-		w.logger.Error("Domain filter is nil, provider returned no domain filter")
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   errors.ErrMissingZone.Error(),
-			"details": "Provider returned no domain filter",
-		})
+// domainFilterJSON converts the domain filter of the provider into the negotiation response.
+// The filter is read through its JSON form, which is the only way to reach its exclusions.
+func (w webhook) domainFilterJSON() ([]byte, error) {
+	response := domainFilterResponse{}
+	if filter := w.provider.GetDomainFilter(); filter != nil {
+		data, err := json.Marshal(filter)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &response); err != nil {
+			return nil, err
+		}
 	}
-	ctx.Response().Header.Set("Content-Type", MediaTypeFormatAndVersion)
 
-	return ctx.Send(domainFilterInterface)
+	if response.Include == nil {
+		response.Include = []string{}
+	}
+	if response.Exclude == nil {
+		response.Exclude = []string{}
+	}
+	return json.Marshal(response)
 }
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/netguru/myra-external-dns-webhook/pkg/errors"
+	"github.com/netguru/myra-external-dns-webhook/pkg/redact"
+)
+
+// errorResponse is the body of every error response of the webhook API. Code is one of the codes
+// of pkg/errors or, for failures of the request itself, derived from the HTTP status.
+type errorResponse struct {
+	Error   string `json:"error"`
+	Code    string `json:"code"`
+	Details string `json:"details,omitempty"`
+}
+
+// newErrorResponse creates the error response with the given code and message. The details are
+// the error with its secrets redacted, left out when err is nil.
+func newErrorResponse(code, message string, err error) errorResponse {
+	response := errorResponse{
+		Error: message,
+		Code:  code,
+	}
+	if err != nil {
+		response.Details = redact.Error(err)
+	}
+	return response
+}
+
+// writeError sends an error response as JSON with the given status. The body is an errorResponse
+// or a struct embedding it.
+func writeError(ctx *fiber.Ctx, status int, body any) error {
+	return ctx.Status(status).JSON(body, fiber.MIMEApplicationJSON)
+}
+
+// statusCode returns the error code for failures of the request itself, derived from the
+// HTTP status, e.g. "unsupported_media_type" for 415
+func statusCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return errors.CodeInternal
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
+
+// providerErrorStatus maps an error returned by the provider to the HTTP status and error message
+// of the response. Errors without a sentinel error from pkg/errors get a 500 with the fallback message.
+func providerErrorStatus(err error, fallback string) (int, string) {
+	switch {
+	case errors.Is(err, errors.ErrMissingAPIKey):
+		return fiber.StatusUnauthorized, "API key is required"
+	case errors.Is(err, errors.ErrMissingAPISecret):
+		return fiber.StatusUnauthorized, "API secret is required"
+	case errors.Is(err, errors.ErrDomainNotFound):
+		return fiber.StatusNotFound, "Domain not found"
+	case errors.Is(err, errors.ErrAuthenticationFailed):
+		return fiber.StatusUnauthorized, "Authentication with the MyraSec API failed"
+	case errors.Is(err, errors.ErrRateLimited):
+		return fiber.StatusTooManyRequests, "MyraSec API rate limit reached"
+	case errors.Is(err, errors.ErrApplyInProgress):
+		return fiber.StatusConflict, "Another sync is in progress"
+	case errors.Is(err, errors.ErrShuttingDown):
+		return fiber.StatusServiceUnavailable, "The webhook is shutting down"
+	case errors.Is(err, errors.ErrApplyTimeout):
+		return fiber.StatusGatewayTimeout, "Not all DNS changes were applied within the apply timeout"
+	case errors.Is(err, errors.ErrChangeLimitExceeded):
+		return fiber.StatusUnprocessableEntity, "Plan refused, it exceeds the change limit"
+	case errors.Is(err, errors.ErrInvalidEndpoints):
+		return fiber.StatusUnprocessableEntity, "Plan refused, it has invalid endpoints"
+	case errors.Is(err, errors.ErrOwnerMismatch):
+		return fiber.StatusUnprocessableEntity, "Plan refused, it has endpoints of another owner"
+	case errors.Is(err, errors.ErrValidation):
+		return fiber.StatusUnprocessableEntity, "MyraSec API rejected the changes as invalid"
+	case errors.Is(err, errors.ErrAPIRequestFailed):
+		return fiber.StatusBadGateway, "API request to MyraSec failed"
+	default:
+		return fiber.StatusInternalServerError, fallback
+	}
+}
+
+// providerError sends the error response for an error returned by the provider
+func providerError(ctx *fiber.Ctx, err error, fallback string) error {
+	status, message := providerErrorStatus(err, fallback)
+	return writeError(ctx, status, newErrorResponse(errors.Code(err), message, err))
+}
@@ -1,20 +1,73 @@
 package api
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/netguru/myra-external-dns-webhook/pkg/errors"
+	"github.com/netguru/myra-external-dns-webhook/pkg/status"
+)
+
+// unhealthyResponse is the error response of the health route when the synchronization stopped
+// succeeding, with the last failure of the provider
+type unhealthyResponse struct {
+	errorResponse
+	LastError *status.Error `json:"lastError,omitempty"`
+}
 
 // Health godoc
 // @Summary Health route
-// @Description Health route
+// @Description Health route. With a healthy threshold it fails while the provider keeps failing to sync.
 // @Accept  json
 // @Produce  json
 // @Success 200 {object} Message
+// @Failure 503 {object} unhealthyResponse
 // @Router /v1/healthz [get]
 // @Tags health
 // get route.
-func Health(c *fiber.Ctx) error {
+func (w webhook) Health(c *fiber.Ctx) error {
+	if reporter, ok := w.provider.(status.Reporter); ok && w.healthyThreshold > 0 {
+		sync := reporter.SyncStatus()
+		if reason := sync.Unhealthy(time.Now(), w.healthyThreshold); reason != "" {
+			w.logger.Warn("Reporting unhealthy", zap.String("reason", reason))
+			err := fmt.Errorf("%w: %s", errors.ErrUnhealthy, reason)
+			return writeError(c, fiber.StatusServiceUnavailable, unhealthyResponse{
+				errorResponse: newErrorResponse(errors.Code(err), "Unhealthy", err),
+				LastError:     sync.LastError,
+			})
+		}
+	}
+
 	c.Status(fiber.StatusOK)
 
 	return c.JSON(Message{
 		Message: "healthy",
 	})
 }
+
+// Ready godoc
+// @Summary Readiness route
+// @Description Readiness route. It fails while the provider cannot serve ExternalDNS, e.g. while the domain filter matches no domain.
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} Message
+// @Failure 503 {object} errorResponse
+// @Router /readyz [get]
+// @Tags health
+// get route.
+func (w webhook) Ready(c *fiber.Ctx) error {
+	if checker, ok := w.provider.(status.ReadinessChecker); ok {
+		if err := checker.Ready(); err != nil {
+			w.logger.Warn("Reporting not ready", zap.Error(err))
+			err = fmt.Errorf("%w: %w", errors.ErrNotReady, err)
+			return writeError(c, fiber.StatusServiceUnavailable, newErrorResponse(errors.Code(err), "Not ready", err))
+		}
+	}
+
+	return c.JSON(Message{
+		Message: "ready",
+	})
+}
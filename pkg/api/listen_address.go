@@ -0,0 +1,49 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ParseListenAddress validates a listen address and returns it in host:port form. It accepts a
+// bare port ("8080"), ":port", "host:port" and bracketed IPv6 addresses ("[::1]:8080").
+func ParseListenAddress(address string) (string, error) {
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return "", fmt.Errorf("listen address is empty")
+	}
+
+	// A bare port listens on all interfaces
+	if !strings.ContainsAny(address, ":[]") {
+		if err := validatePort(address); err != nil {
+			return "", fmt.Errorf("invalid listen address %q: %w", address, err)
+		}
+		return net.JoinHostPort("", address), nil
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid listen address %q, expected port, host:port or [ipv6]:port: %w", address, err)
+	}
+	if err := validatePort(port); err != nil {
+		return "", fmt.Errorf("invalid listen address %q: %w", address, err)
+	}
+	if strings.Contains(host, ":") && net.ParseIP(host) == nil {
+		return "", fmt.Errorf("invalid listen address %q: %q is not an IPv6 address", address, host)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// validatePort checks that port is a TCP port number
+func validatePort(port string) error {
+	if port == "" {
+		return fmt.Errorf("missing port")
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 0 || n > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	return nil
+}
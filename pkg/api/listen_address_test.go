@@ -0,0 +1,46 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseListenAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		want    string
+		wantErr bool
+	}{
+		{name: "bare port", address: "8888", want: ":8888"},
+		{name: "port only", address: ":8888", want: ":8888"},
+		{name: "ipv4", address: "127.0.0.1:8888", want: "127.0.0.1:8888"},
+		{name: "ipv6 loopback", address: "[::1]:8888", want: "[::1]:8888"},
+		{name: "ipv6 all interfaces", address: "[::]:8888", want: "[::]:8888"},
+		{name: "hostname", address: "localhost:8888", want: "localhost:8888"},
+		{name: "surrounding whitespace", address: " :8888 ", want: ":8888"},
+		{name: "empty", address: "", wantErr: true},
+		{name: "missing port", address: "localhost:", wantErr: true},
+		{name: "hostname without port", address: "localhost", wantErr: true},
+		{name: "unbracketed ipv6", address: "::1:8888", wantErr: true},
+		{name: "ipv6 without port", address: "[::1]", wantErr: true},
+		{name: "invalid ipv6", address: "[::zz]:8888", wantErr: true},
+		{name: "port out of range", address: ":70000", wantErr: true},
+		{name: "non-numeric port", address: "localhost:http", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseListenAddress(tt.address)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "listen address")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
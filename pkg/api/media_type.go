@@ -0,0 +1,79 @@
+package api
+
+import (
+	"mime"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+const (
+	mediaTypeWebhook = "application/external.dns.webhook+json"
+	mediaTypeVersion = "1"
+)
+
+// mediaTypes validates the media types of webhook requests as required by the ExternalDNS webhook
+// contract: POST bodies must be sent as MediaTypeFormatAndVersion (415 otherwise) and GET requests
+// must accept it (406 otherwise). Unless strict is set, requests without the header are accepted.
+func mediaTypes(logger *zap.Logger, strict bool) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if ctx.Method() == fiber.MethodPost {
+			contentType := ctx.Get(fiber.HeaderContentType)
+			if contentType == "" && !strict {
+				return ctx.Next()
+			}
+			if !isWebhookMediaType(contentType) {
+				logger.Warn("Rejecting request with unsupported content type",
+					zap.String("path", ctx.Path()),
+					zap.String("content_type", contentType))
+				return writeError(ctx, fiber.StatusUnsupportedMediaType, newErrorResponse(
+					statusCode(fiber.StatusUnsupportedMediaType),
+					"Unsupported content type, expected "+MediaTypeFormatAndVersion, nil))
+			}
+			return ctx.Next()
+		}
+
+		accept := ctx.Get(fiber.HeaderAccept)
+		if accept == "" && !strict {
+			return ctx.Next()
+		}
+		if !acceptsWebhookMediaType(accept) {
+			logger.Warn("Rejecting request with unsupported accept header",
+				zap.String("path", ctx.Path()),
+				zap.String("accept", accept))
+			return writeError(ctx, fiber.StatusNotAcceptable, newErrorResponse(
+				statusCode(fiber.StatusNotAcceptable),
+				"Not acceptable, responses are sent as "+MediaTypeFormatAndVersion, nil))
+		}
+		return ctx.Next()
+	}
+}
+
+// isWebhookMediaType reports whether value is the webhook media type in the supported version
+func isWebhookMediaType(value string) bool {
+	mediaType, params, err := mime.ParseMediaType(value)
+	if err != nil {
+		return false
+	}
+	return mediaType == mediaTypeWebhook && params["version"] == mediaTypeVersion
+}
+
+// acceptsWebhookMediaType reports whether an Accept header allows the webhook media type
+func acceptsWebhookMediaType(accept string) bool {
+	for _, value := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "*/*", "application/*":
+			return true
+		case mediaTypeWebhook:
+			if params["version"] == mediaTypeVersion {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/netguru/myra-external-dns-webhook/pkg/api/mock"
+)
+
+func TestMediaTypeNegotiation(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		header     string
+		value      string
+		strict     bool
+		wantStatus int
+	}{
+		{name: "post with webhook media type", method: http.MethodPost, path: "/records", header: "Content-Type", value: MediaTypeFormatAndVersion, wantStatus: http.StatusNoContent},
+		{name: "post with spaced parameters", method: http.MethodPost, path: "/records", header: "Content-Type", value: "application/external.dns.webhook+json; version=1", wantStatus: http.StatusNoContent},
+		{name: "post without content type", method: http.MethodPost, path: "/records", wantStatus: http.StatusNoContent},
+		{name: "strict post without content type", method: http.MethodPost, path: "/records", strict: true, wantStatus: http.StatusUnsupportedMediaType},
+		{name: "post with plain json", method: http.MethodPost, path: "/records", header: "Content-Type", value: "application/json", wantStatus: http.StatusUnsupportedMediaType},
+		{name: "post with unsupported version", method: http.MethodPost, path: "/adjustendpoints", header: "Content-Type", value: "application/external.dns.webhook+json;version=2", wantStatus: http.StatusUnsupportedMediaType},
+		{name: "get with webhook media type", method: http.MethodGet, path: "/records", header: "Accept", value: MediaTypeFormatAndVersion, wantStatus: http.StatusOK},
+		{name: "get with wildcard", method: http.MethodGet, path: "/", header: "Accept", value: "*/*", wantStatus: http.StatusOK},
+		{name: "get with accept list", method: http.MethodGet, path: "/records", header: "Accept", value: "text/html, " + MediaTypeFormatAndVersion, wantStatus: http.StatusOK},
+		{name: "get without accept", method: http.MethodGet, path: "/records", wantStatus: http.StatusOK},
+		{name: "strict get without accept", method: http.MethodGet, path: "/records", strict: true, wantStatus: http.StatusNotAcceptable},
+		{name: "get with unsupported version", method: http.MethodGet, path: "/records", header: "Accept", value: "application/external.dns.webhook+json;version=2", wantStatus: http.StatusNotAcceptable},
+		{name: "get with wrong media type", method: http.MethodGet, path: "/", header: "Accept", value: "text/html", wantStatus: http.StatusNotAcceptable},
+		{name: "health is not negotiated", method: http.MethodGet, path: "/healthz", header: "Accept", value: "text/html", strict: true, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.StrictMediaType = tt.strict
+			app := NewWithConfig(zap.NewNop(), &mock.MockProvider{}, config)
+
+			var body *strings.Reader
+			if tt.method == http.MethodPost {
+				body = strings.NewReader(`{}`)
+			} else {
+				body = strings.NewReader("")
+			}
+			req := httptest.NewRequest(tt.method, tt.path, body)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+		})
+	}
+}
@@ -5,12 +5,16 @@ import (
 
 	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
 )
 
 // MockProvider is a mock implementation of the provider.Provider interface for testing
 type MockProvider struct {
-	RecordsFn      func(ctx context.Context) ([]*endpoint.Endpoint, error)
-	ApplyChangesFn func(ctx context.Context, changes *plan.Changes) error
+	provider.BaseProvider
+	RecordsFn         func(ctx context.Context) ([]*endpoint.Endpoint, error)
+	ApplyChangesFn    func(ctx context.Context, changes *plan.Changes) error
+	AdjustEndpointsFn func(endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error)
+	DomainFilter      endpoint.DomainFilterInterface
 }
 
 // Records calls the RecordsFn or returns an empty slice if not set
@@ -28,3 +32,19 @@ func (m *MockProvider) ApplyChanges(ctx context.Context, changes *plan.Changes)
 	}
 	return nil
 }
+
+// AdjustEndpoints calls the AdjustEndpointsFn or returns the endpoints unchanged if not set
+func (m *MockProvider) AdjustEndpoints(endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	if m.AdjustEndpointsFn != nil {
+		return m.AdjustEndpointsFn(endpoints)
+	}
+	return endpoints, nil
+}
+
+// GetDomainFilter returns the DomainFilter or the filter of the BaseProvider if not set
+func (m *MockProvider) GetDomainFilter() endpoint.DomainFilterInterface {
+	if m.DomainFilter != nil {
+		return m.DomainFilter
+	}
+	return m.BaseProvider.GetDomainFilter()
+}
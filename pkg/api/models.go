@@ -1,9 +1,10 @@
 package api
 
+import "github.com/netguru/myra-external-dns-webhook/pkg/errors"
+
 const (
 	MediaTypeFormatAndVersion = "application/external.dns.webhook+json;version=1"
 	contentTypeHeader         = "Content-Type"
-	contentTypePlaintext      = "text/plain"
 	varyHeader                = "Vary"
 	logFieldError             = "err"
 )
@@ -24,3 +25,28 @@ type dnsEndpoint struct {
 	Targets    []string `json:"targets"`
 	RecordTTL  int64    `json:"recordTTL"`
 }
+
+// changeFailure is a change of the plan that could not be applied, as listed in the error
+// response of ApplyChanges
+type changeFailure struct {
+	DNSName    string `json:"dnsName"`
+	RecordType string `json:"recordType"`
+	Action     string `json:"action"`
+	Error      string `json:"error"`
+}
+
+// applyErrorResponse is the error response of ApplyChanges. Depending on the error it tells the
+// exceeded change limit, the invalid endpoints or those of another owner, the failed changes of the
+// plan or the changes applied before the apply timeout expired.
+type applyErrorResponse struct {
+	errorResponse
+	Limit      string                   `json:"limit,omitempty"`
+	Count      int                      `json:"count,omitempty"`
+	Max        int                      `json:"max,omitempty"`
+	Invalid    []errors.InvalidEndpoint `json:"invalid,omitempty"`
+	Total      int                      `json:"total,omitempty"`
+	Failed     int                      `json:"failed,omitempty"`
+	Failures   []changeFailure          `json:"failures,omitempty"`
+	Applied    []string                 `json:"applied,omitempty"`
+	NotApplied []string                 `json:"notApplied,omitempty"`
+}
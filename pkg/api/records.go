@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
+
+	"github.com/netguru/myra-external-dns-webhook/pkg/errors"
 )
 
 func (w webhook) Records(ctx *fiber.Ctx) error {
@@ -23,10 +25,7 @@ func (w webhook) Records(ctx *fiber.Ctx) error {
 			zap.String("error_type", "provider_error"))
 
 		// Return appropriate error based on the error type
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to retrieve DNS records",
-			"details": err.Error(),
-		})
+		return providerError(ctx, err, "Failed to retrieve DNS records")
 	}
 
 	// If no records were returned, log a warning but return an empty array (not an error)
@@ -42,9 +41,8 @@ func (w webhook) Records(ctx *fiber.Ctx) error {
 	if err != nil {
 		w.logger.Error("Failed to marshal records response",
 			zap.Error(err))
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to marshal records response",
-		})
+		return writeError(ctx, fiber.StatusInternalServerError,
+			newErrorResponse(errors.CodeInternal, "Failed to marshal records response", err))
 	}
 
 	ctx.Response().Header.Set("Vary", "Accept-Encoding")
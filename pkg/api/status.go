@@ -0,0 +1,28 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/netguru/myra-external-dns-webhook/pkg/status"
+)
+
+// Status godoc
+// @Summary Sync status route
+// @Description Last successful listing and apply, their outcome and the changed records
+// @Produce  json
+// @Success 200 {object} status.Sync
+// @Failure 404 {object} errorResponse
+// @Router /status [get]
+// @Tags health
+// get route.
+func (w webhook) Status(c *fiber.Ctx) error {
+	reporter, ok := w.provider.(status.Reporter)
+	if !ok {
+		return writeError(c, fiber.StatusNotFound, newErrorResponse(
+			statusCode(fiber.StatusNotFound), "The provider does not report its sync status", nil))
+	}
+
+	c.Status(fiber.StatusOK)
+
+	return c.JSON(reporter.SyncStatus())
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/netguru/myra-external-dns-webhook/pkg/api"
+
+// headerCarrier reads the propagated trace context from the request headers
+type headerCarrier struct {
+	c *fiber.Ctx
+}
+
+var _ propagation.TextMapCarrier = headerCarrier{}
+
+func (h headerCarrier) Get(key string) string {
+	return h.c.Get(key)
+}
+
+func (h headerCarrier) Set(key, value string) {
+	h.c.Request().Header.Set(key, value)
+}
+
+func (h headerCarrier) Keys() []string {
+	var keys []string
+	h.c.Request().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
+
+// tracing starts a server span per request, continuing the trace of the caller if the request
+// carries a trace context. The span is stored in the user context passed to the provider.
+// Without a configured tracer provider the spans are not recorded.
+func tracing() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), headerCarrier{c})
+		ctx, span := otel.Tracer(tracerName).Start(ctx, c.Method()+" "+c.Path(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Method()),
+				attribute.String("url.path", c.Path()),
+				attribute.String("user_agent.original", string(c.Request().Header.UserAgent())),
+			))
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		// The route is known once the request was routed
+		if route := c.Route(); route != nil && route.Path != "" {
+			span.SetName(c.Method() + " " + route.Path)
+			span.SetAttributes(attribute.String("http.route", route.Path))
+		}
+		status := c.Response().StatusCode()
+		if err != nil {
+			if e, ok := err.(*fiber.Error); ok {
+				status = e.Code
+			} else {
+				status = fiber.StatusInternalServerError
+			}
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, "")
+		}
+		if id := c.GetRespHeader(fiber.HeaderXRequestID); id != "" {
+			span.SetAttributes(attribute.String("request_id", id))
+		}
+		return err
+	}
+}
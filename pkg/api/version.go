@@ -0,0 +1,21 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/netguru/myra-external-dns-webhook/pkg/version"
+)
+
+// Version godoc
+// @Summary Version route
+// @Description Build information of the running webhook
+// @Produce  json
+// @Success 200 {object} version.Info
+// @Router /version [get]
+// @Tags health
+// get route.
+func Version(c *fiber.Ctx) error {
+	c.Status(fiber.StatusOK)
+
+	return c.JSON(version.Get())
+}
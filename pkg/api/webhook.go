@@ -1,6 +1,8 @@
 package api
 
 import (
+	"time"
+
 	"go.uber.org/zap"
 	"sigs.k8s.io/external-dns/provider"
 )
@@ -8,4 +10,6 @@ import (
 type webhook struct {
 	provider provider.Provider
 	logger   *zap.Logger
+	// healthyThreshold is Config.HealthyThreshold
+	healthyThreshold time.Duration
 }
@@ -0,0 +1,135 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ChangeError is returned when a single DNS record change could not be applied
+type ChangeError struct {
+	Action     string
+	DNSName    string
+	RecordType string
+	Err        error
+}
+
+func (e *ChangeError) Error() string {
+	return fmt.Sprintf("%s %s record %s: %v", e.Action, e.RecordType, e.DNSName, e.Err)
+}
+
+func (e *ChangeError) Unwrap() error {
+	return e.Err
+}
+
+// ChangesError is returned when one or more changes of a plan could not be applied.
+// It lists every failed change.
+type ChangesError struct {
+	Failures []*ChangeError
+}
+
+func (e *ChangesError) Error() string {
+	if len(e.Failures) == 1 {
+		return e.Failures[0].Error()
+	}
+	messages := make([]string, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		messages = append(messages, f.Error())
+	}
+	return fmt.Sprintf("%d DNS changes failed: %s", len(e.Failures), strings.Join(messages, "; "))
+}
+
+func (e *ChangesError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		errs = append(errs, f)
+	}
+	return errs
+}
+
+// ApplyTimeoutError is returned when ApplyChanges was aborted by the apply timeout. It lists the
+// changes that were applied before and those that were not, failed ones included, as
+// "<action> <type> <name>".
+type ApplyTimeoutError struct {
+	Timeout    time.Duration
+	Applied    []string
+	NotApplied []string
+}
+
+func (e *ApplyTimeoutError) Error() string {
+	msg := fmt.Sprintf("%v: %d of %d changes applied within %s", ErrApplyTimeout,
+		len(e.Applied), len(e.Applied)+len(e.NotApplied), e.Timeout)
+	if len(e.NotApplied) > 0 {
+		msg += ", not applied: " + strings.Join(e.NotApplied, "; ")
+	}
+	return msg
+}
+
+func (e *ApplyTimeoutError) Unwrap() []error {
+	return []error{ErrApplyTimeout, context.DeadlineExceeded}
+}
+
+// ChangeLimitError is returned when a plan is refused because it has more changes of a kind
+// than the configured limit. Kind is "deletions" or "changes".
+type ChangeLimitError struct {
+	Kind  string
+	Count int
+	Limit int
+}
+
+func (e *ChangeLimitError) Error() string {
+	return fmt.Sprintf("%v: plan has %d %s, at most %d are allowed per sync", ErrChangeLimitExceeded, e.Count, e.Kind, e.Limit)
+}
+
+func (e *ChangeLimitError) Unwrap() error {
+	return ErrChangeLimitExceeded
+}
+
+// InvalidEndpoint is an endpoint refused by the validation of its record values
+type InvalidEndpoint struct {
+	DNSName    string `json:"dnsName"`
+	RecordType string `json:"recordType"`
+	Reason     string `json:"reason"`
+}
+
+func (e InvalidEndpoint) String() string {
+	return fmt.Sprintf("%s record %s: %s", e.RecordType, e.DNSName, e.Reason)
+}
+
+// InvalidEndpointsError is returned when a plan is refused because endpoints failed the validation.
+// It lists every invalid endpoint.
+type InvalidEndpointsError struct {
+	Endpoints []InvalidEndpoint
+}
+
+func (e *InvalidEndpointsError) Error() string {
+	messages := make([]string, 0, len(e.Endpoints))
+	for _, ep := range e.Endpoints {
+		messages = append(messages, ep.String())
+	}
+	return fmt.Sprintf("%v: %s", ErrInvalidEndpoints, strings.Join(messages, "; "))
+}
+
+func (e *InvalidEndpointsError) Unwrap() error {
+	return ErrInvalidEndpoints
+}
+
+// OwnerMismatchError is returned when a plan is refused because endpoints are labelled with another
+// owner than Owner, the owner of the webhook. The reason of every endpoint names its owner.
+type OwnerMismatchError struct {
+	Owner     string
+	Endpoints []InvalidEndpoint
+}
+
+func (e *OwnerMismatchError) Error() string {
+	messages := make([]string, 0, len(e.Endpoints))
+	for _, ep := range e.Endpoints {
+		messages = append(messages, ep.String())
+	}
+	return fmt.Sprintf("%v %q: %s", ErrOwnerMismatch, e.Owner, strings.Join(messages, "; "))
+}
+
+func (e *OwnerMismatchError) Unwrap() error {
+	return ErrOwnerMismatch
+}
@@ -0,0 +1,68 @@
+package errors
+
+// Codes of the error responses of the webhook API. Clients tell the causes of failures apart by
+// the code instead of the message.
+const (
+	CodeMissingAPIKey        = "missing_api_key"
+	CodeMissingAPISecret     = "missing_api_secret"
+	CodeMissingZone          = "missing_zone"
+	CodeDomainNotFound       = "domain_not_found"
+	CodeInvalidDomainID      = "invalid_domain_id"
+	CodeAuthenticationFailed = "authentication_failed"
+	CodeRateLimited          = "rate_limited"
+	CodeValidation           = "validation_failed"
+	CodeDuplicateRecord      = "duplicate_record"
+	CodePrivateIPRejected    = "private_ip_rejected"
+	CodeInvalidJSON          = "invalid_json"
+	CodeNameOutsideZone      = "name_outside_zone"
+	CodeChangeLimitExceeded  = "change_limit_exceeded"
+	CodeInvalidEndpoints     = "invalid_endpoints"
+	CodeOwnerMismatch        = "owner_mismatch"
+	CodeApplyInProgress      = "apply_in_progress"
+	CodeShuttingDown         = "shutting_down"
+	CodeApplyTimeout         = "apply_timeout"
+	CodeUnhealthy            = "unhealthy"
+	CodeNotReady             = "not_ready"
+	CodeAPIRequestFailed     = "api_request_failed"
+	CodeInternal             = "internal_error"
+)
+
+// codes maps the sentinel errors to their codes. More specific errors come first, since an error
+// tree can hold several sentinel errors, e.g. a failed change wrapping ErrValidation. The health
+// errors come before all, their cause, e.g. ErrDomainNotFound, is only part of the details.
+var codes = []struct {
+	err  error
+	code string
+}{
+	{ErrUnhealthy, CodeUnhealthy},
+	{ErrNotReady, CodeNotReady},
+	{ErrMissingAPIKey, CodeMissingAPIKey},
+	{ErrMissingAPISecret, CodeMissingAPISecret},
+	{ErrMissingZone, CodeMissingZone},
+	{ErrDomainNotFound, CodeDomainNotFound},
+	{ErrInvalidDomainID, CodeInvalidDomainID},
+	{ErrAuthenticationFailed, CodeAuthenticationFailed},
+	{ErrRateLimited, CodeRateLimited},
+	{ErrApplyInProgress, CodeApplyInProgress},
+	{ErrShuttingDown, CodeShuttingDown},
+	{ErrApplyTimeout, CodeApplyTimeout},
+	{ErrChangeLimitExceeded, CodeChangeLimitExceeded},
+	{ErrInvalidEndpoints, CodeInvalidEndpoints},
+	{ErrOwnerMismatch, CodeOwnerMismatch},
+	{ErrInvalidJSONFormat, CodeInvalidJSON},
+	{ErrNameOutsideZone, CodeNameOutsideZone},
+	{ErrPrivateIPRejected, CodePrivateIPRejected},
+	{ErrDuplicateRecord, CodeDuplicateRecord},
+	{ErrValidation, CodeValidation},
+	{ErrAPIRequestFailed, CodeAPIRequestFailed},
+}
+
+// Code returns the code of the first sentinel error found in err's tree, CodeInternal when it has none
+func Code(err error) string {
+	for _, c := range codes {
+		if Is(err, c.err) {
+			return c.code
+		}
+	}
+	return CodeInternal
+}
@@ -15,9 +15,64 @@ var (
 	// ErrDomainNotFound is returned when the specified domain is not found
 	ErrDomainNotFound = errors.New("domain not found")
 
+	// ErrInvalidDomainID is returned when the selected domain has no valid ID
+	ErrInvalidDomainID = errors.New("invalid domain ID")
+
 	// ErrAPIRequestFailed is returned when a request to the MyraSec API fails
 	ErrAPIRequestFailed = errors.New("API request to MyraSec failed")
 
+	// ErrAuthenticationFailed is returned when the MyraSec API rejects the credentials
+	ErrAuthenticationFailed = errors.New("authentication with the MyraSec API failed")
+
+	// ErrRateLimited is returned when the MyraSec API rate limit is reached
+	ErrRateLimited = errors.New("MyraSec API rate limit reached")
+
+	// ErrValidation is returned when the MyraSec API rejects a record as invalid
+	ErrValidation = errors.New("MyraSec API rejected the record as invalid")
+
+	// ErrDuplicateRecord is returned when a record with the same name, type and value already exists
+	ErrDuplicateRecord = errors.New("DNS record already exists")
+
+	// ErrPrivateIPRejected is returned when the MyraSec API refuses a record pointing to a private IP address
+	ErrPrivateIPRejected = errors.New("MyraSec API rejected the private IP address")
+
 	// ErrInvalidJSONFormat is returned when the JSON payload cannot be parsed
 	ErrInvalidJSONFormat = errors.New("invalid JSON format in request")
+
+	// ErrNameOutsideZone is returned when a DNS name does not belong to the selected zone
+	ErrNameOutsideZone = errors.New("DNS name is outside the selected zone")
+
+	// ErrChangeLimitExceeded is returned when a plan has more changes than allowed per sync
+	ErrChangeLimitExceeded = errors.New("plan exceeds the change limit")
+
+	// ErrInvalidEndpoints is returned when changes are refused because record values are invalid
+	ErrInvalidEndpoints = errors.New("plan has invalid endpoints")
+
+	// ErrOwnerMismatch is returned when changes are refused because their endpoints carry another owner
+	ErrOwnerMismatch = errors.New("plan has endpoints of another owner")
+
+	// ErrApplyInProgress is returned when changes are refused because another apply is still running
+	ErrApplyInProgress = errors.New("another apply of changes is in progress")
+
+	// ErrShuttingDown is returned when changes are refused because the webhook is shutting down
+	ErrShuttingDown = errors.New("the webhook is shutting down")
+
+	// ErrApplyTimeout is returned when the changes were not all applied within the apply timeout
+	ErrApplyTimeout = errors.New("apply timeout exceeded")
+
+	// ErrUnhealthy is reported by the health route when the synchronization keeps failing
+	ErrUnhealthy = errors.New("synchronization with MyraSec keeps failing")
+
+	// ErrNotReady is reported by the readiness route when the provider cannot serve ExternalDNS
+	ErrNotReady = errors.New("the webhook is not ready")
 )
+
+// Is reports whether any error in err's tree matches target, see errors.Is
+func Is(err, target error) bool {
+	return errors.Is(err, target)
+}
+
+// As finds the first error in err's tree that matches target, see errors.As
+func As(err error, target any) bool {
+	return errors.As(err, target)
+}
@@ -0,0 +1,74 @@
+package redact
+
+import (
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// core is a zapcore.Core masking secrets in the message and the fields of every entry
+type core struct {
+	zapcore.Core
+	redactor *Redactor
+}
+
+// NewCore wraps c so that the secrets of r are masked before an entry is written.
+// It can be installed with zap.WrapCore.
+func NewCore(c zapcore.Core, r *Redactor) zapcore.Core {
+	return &core{Core: c, redactor: r}
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	return &core{Core: c.Core.With(c.fields(fields)), redactor: c.redactor}
+}
+
+// Check lets the wrapped core decide whether the entry is logged, e.g. a sampling core, and adds
+// the redacting core in place of the core it would have added, so the entry is written masked.
+func (c *core) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Core.Check(entry, nil) != nil {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = c.redactor.String(entry.Message)
+	return c.Core.Write(entry, c.fields(fields))
+}
+
+// fields returns the fields with the secrets masked. Fields that are not plain strings are
+// replaced by their masked string form only if they contain a secret.
+func (c *core) fields(fields []zapcore.Field) []zapcore.Field {
+	redacted := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		redacted[i] = c.field(f)
+	}
+	return redacted
+}
+
+func (c *core) field(f zapcore.Field) zapcore.Field {
+	switch f.Type {
+	case zapcore.StringType:
+		f.String = c.redactor.String(f.String)
+		return f
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok && c.redactor.Contains(err.Error()) {
+			return zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: c.redactor.String(err.Error())}
+		}
+		return f
+	case zapcore.ByteStringType, zapcore.BinaryType:
+		if b, ok := f.Interface.([]byte); ok && c.redactor.Contains(string(b)) {
+			return zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: c.redactor.String(string(b))}
+		}
+		return f
+	case zapcore.StringerType, zapcore.ReflectType, zapcore.ArrayMarshalerType, zapcore.ObjectMarshalerType:
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		if s := fmt.Sprint(enc.Fields[f.Key]); c.redactor.Contains(s) {
+			return zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: c.redactor.String(s)}
+		}
+		return f
+	default:
+		return f
+	}
+}
@@ -0,0 +1,114 @@
+// Package redact masks secret values, such as the MyraSec API credentials, in log output
+// and in error details returned by the HTTP API.
+package redact
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask replaces every occurrence of a secret
+const Mask = "[REDACTED]"
+
+// minSecretLength is the length below which values are not masked, so that short or
+// empty values can't garble unrelated output
+const minSecretLength = 4
+
+// Redactor masks a set of secret values in strings. It is safe for concurrent use.
+type Redactor struct {
+	mu       sync.RWMutex
+	secrets  []string
+	replacer *strings.Replacer
+}
+
+// New returns a Redactor masking the given secrets
+func New(secrets ...string) *Redactor {
+	r := &Redactor{}
+	r.Add(secrets...)
+	return r
+}
+
+// Add registers additional secrets, e.g. rotated credentials
+func (r *Redactor) Add(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, secret := range secrets {
+		if len(secret) < minSecretLength || contains(r.secrets, secret) {
+			continue
+		}
+		r.secrets = append(r.secrets, secret)
+	}
+
+	// Longer secrets first, so a secret containing another one is masked as a whole
+	sort.Slice(r.secrets, func(i, j int) bool { return len(r.secrets[i]) > len(r.secrets[j]) })
+	pairs := make([]string, 0, 2*len(r.secrets))
+	for _, secret := range r.secrets {
+		pairs = append(pairs, secret, Mask)
+	}
+	r.replacer = strings.NewReplacer(pairs...)
+}
+
+// Reset removes all registered secrets
+func (r *Redactor) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.secrets = nil
+	r.replacer = nil
+}
+
+// String returns s with every registered secret masked
+func (r *Redactor) String(s string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.replacer == nil {
+		return s
+	}
+	return r.replacer.Replace(s)
+}
+
+// Error returns the message of err with every registered secret masked
+func (r *Redactor) Error(err error) string {
+	if err == nil {
+		return ""
+	}
+	return r.String(err.Error())
+}
+
+// Contains reports whether s contains a registered secret
+func (r *Redactor) Contains(s string) bool {
+	return r.String(s) != s
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultRedactor is shared by the logger and the HTTP handlers
+var defaultRedactor = New()
+
+// Default returns the process wide Redactor
+func Default() *Redactor {
+	return defaultRedactor
+}
+
+// Add registers secrets with the process wide Redactor
+func Add(secrets ...string) {
+	defaultRedactor.Add(secrets...)
+}
+
+// String masks the secrets registered with the process wide Redactor
+func String(s string) string {
+	return defaultRedactor.String(s)
+}
+
+// Error masks the secrets registered with the process wide Redactor in the message of err
+func Error(err error) string {
+	return defaultRedactor.Error(err)
+}
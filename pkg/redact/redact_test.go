@@ -0,0 +1,74 @@
+package redact
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// newBufferLogger returns a JSON logger writing through the redacting core into a buffer
+func newBufferLogger(r *Redactor) (*zap.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	encoder := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	core := zapcore.NewCore(encoder, zapcore.AddSync(&buf), zapcore.DebugLevel)
+	return zap.New(NewCore(core, r)), &buf
+}
+
+func TestCoreMasksSecrets(t *testing.T) {
+	r := New("s3cr3t-api-secret", "my-api-key")
+	logger, buf := newBufferLogger(r)
+
+	logger.With(zap.String("key", "my-api-key")).Debug("request failed with s3cr3t-api-secret",
+		zap.String("body", `{"secret":"s3cr3t-api-secret"}`),
+		zap.Error(errors.New("signature for my-api-key rejected")),
+		zap.ByteString("raw", []byte("s3cr3t-api-secret")),
+		zap.Strings("values", []string{"a", "my-api-key"}),
+		zap.Any("object", map[string]string{"secret": "s3cr3t-api-secret"}),
+		zap.Int("count", 3),
+		zap.String("plain", "nothing to hide"))
+
+	out := buf.String()
+	assert.NotContains(t, out, "s3cr3t-api-secret")
+	assert.NotContains(t, out, "my-api-key")
+	assert.Contains(t, out, `"msg":"request failed with [REDACTED]"`)
+	assert.Contains(t, out, `"key":"[REDACTED]"`)
+	assert.Contains(t, out, `"error":"signature for [REDACTED] rejected"`)
+	assert.Contains(t, out, `"count":3`)
+	assert.Contains(t, out, `"plain":"nothing to hide"`)
+}
+
+func TestCoreKeepsSamplingOfWrappedCore(t *testing.T) {
+	r := New("s3cr3t-api-secret")
+	var buf bytes.Buffer
+	encoder := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	sampled := zapcore.NewSamplerWithOptions(zapcore.NewCore(encoder, zapcore.AddSync(&buf), zapcore.DebugLevel), time.Minute, 2, 0)
+	logger := zap.New(NewCore(sampled, r))
+
+	for i := 0; i < 5; i++ {
+		logger.Info("request failed with s3cr3t-api-secret")
+	}
+
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"), "only the sampled entries are written")
+	assert.NotContains(t, buf.String(), "s3cr3t-api-secret")
+	assert.Contains(t, buf.String(), Mask)
+}
+
+func TestRedactor(t *testing.T) {
+	r := New("old-secret", "abc", "")
+	assert.Equal(t, "using [REDACTED] and abc", r.String("using old-secret and abc"), "short values are not masked")
+
+	r.Add("new-secret", "old-secret-extended")
+	assert.Equal(t, "[REDACTED] [REDACTED] [REDACTED]", r.String("old-secret new-secret old-secret-extended"))
+	assert.True(t, r.Contains("x new-secret x"))
+	assert.Equal(t, "[REDACTED]", r.Error(errors.New("new-secret")))
+	assert.Empty(t, r.Error(nil))
+
+	r.Reset()
+	assert.Equal(t, "new-secret", r.String("new-secret"))
+}
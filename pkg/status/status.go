@@ -0,0 +1,74 @@
+// Package status defines the synchronization status a provider reports on the /status endpoint.
+package status
+
+import (
+	"fmt"
+	"time"
+)
+
+// Sync is the state of the synchronization with the DNS provider. Times are nil until the
+// operation happened for the first time.
+type Sync struct {
+	// LastRecords is when Records last listed the zone successfully
+	LastRecords *time.Time `json:"lastRecords,omitempty"`
+	// LastRecordsAttempt is when the last Records call finished, successful or not
+	LastRecordsAttempt *time.Time `json:"lastRecordsAttempt,omitempty"`
+	// RecordsFailingSince is when Records first failed after its last success, nil while it succeeds
+	RecordsFailingSince *time.Time `json:"recordsFailingSince,omitempty"`
+	// LastApply is when the last ApplyChanges call finished, successful or not
+	LastApply *time.Time `json:"lastApply,omitempty"`
+	// LastSuccessfulApply is when ApplyChanges last finished without error
+	LastSuccessfulApply *time.Time `json:"lastSuccessfulApply,omitempty"`
+	// ApplyFailingSince is when ApplyChanges first failed after its last success, nil while it succeeds
+	ApplyFailingSince *time.Time `json:"applyFailingSince,omitempty"`
+	// LastApplySucceeded tells whether the last ApplyChanges call finished without error
+	LastApplySucceeded bool `json:"lastApplySucceeded"`
+	// LastApplyDurationSeconds is how long the last ApplyChanges call took
+	LastApplyDurationSeconds float64 `json:"lastApplyDurationSeconds"`
+	// LastApplyChanges counts the records changed by the last ApplyChanges call, by action
+	LastApplyChanges map[string]int `json:"lastApplyChanges"`
+	// TotalChanges counts the records changed since the start, by action
+	TotalChanges map[string]int `json:"totalChanges"`
+	// LastError is the last error of Records or ApplyChanges, it is kept after later successes
+	LastError *Error `json:"lastError,omitempty"`
+}
+
+// Unhealthy returns why the synchronization is considered broken at now, or "" if it is not: an
+// operation was attempted within threshold, but has kept failing for longer than threshold.
+// Without recent attempts, e.g. while ExternalDNS is down, the synchronization is not broken.
+func (s Sync) Unhealthy(now time.Time, threshold time.Duration) string {
+	for _, op := range []struct {
+		name                  string
+		attempt, failingSince *time.Time
+	}{
+		{name: "records", attempt: s.LastRecordsAttempt, failingSince: s.RecordsFailingSince},
+		{name: "applyChanges", attempt: s.LastApply, failingSince: s.ApplyFailingSince},
+	} {
+		if op.attempt == nil || op.failingSince == nil || now.Sub(*op.attempt) > threshold {
+			continue
+		}
+		if failing := now.Sub(*op.failingSince); failing > threshold {
+			return fmt.Sprintf("%s has been failing for %s, longer than the healthy threshold of %s",
+				op.name, failing.Round(time.Second), threshold)
+		}
+	}
+	return ""
+}
+
+// Error is a failed operation
+type Error struct {
+	Operation string    `json:"operation"`
+	Message   string    `json:"message"`
+	Time      time.Time `json:"time"`
+}
+
+// Reporter is implemented by providers that track their synchronization status
+type Reporter interface {
+	SyncStatus() Sync
+}
+
+// ReadinessChecker is implemented by providers that can tell whether they are able to serve
+// ExternalDNS, e.g. whether the domain filter selects a domain
+type ReadinessChecker interface {
+	Ready() error
+}
@@ -0,0 +1,51 @@
+package version
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Build information, injected at build time via
+// -ldflags "-X github.com/netguru/myra-external-dns-webhook/pkg/version.Version=..."
+var (
+	Version = "dev"
+	Commit  = "unknown"
+	Date    = "unknown"
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Get returns the build information of the running binary
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+	}
+}
+
+// String returns the build information in a single line
+func (i Info) String() string {
+	return fmt.Sprintf("%s (commit %s, built %s, %s)", i.Version, i.Commit, i.Date, i.GoVersion)
+}
+
+// UserAgent returns the User-Agent sent with requests to the MyraSec API
+func UserAgent() string {
+	return fmt.Sprintf("external-dns-myrasec-webhook/%s (commit %s)", Version, Commit)
+}
+
+// UserAgentWithOwner returns the User-Agent with the owner ID of the instance added, so that
+// MyraSec can tell deployments apart. An empty owner gives UserAgent.
+func UserAgentWithOwner(owner string) string {
+	if owner == "" {
+		return UserAgent()
+	}
+	return fmt.Sprintf("external-dns-myrasec-webhook/%s (commit %s; +owner=%s)", Version, Commit, owner)
+}